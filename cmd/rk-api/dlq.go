@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"rk-api/internal/app/config"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/mq"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 队列死信命令行 和后台/admin/dead-letter功能一致
+// rk-api dlq list -t flow:create
+// rk-api dlq show -q default -i <id>
+// rk-api dlq replay -q default -i <id> [-p '{"uid":1}']
+// rk-api dlq discard -q default -i <id>
+func newDLQCmd(ctx context.Context) *cli.Command {
+	confFlag := &cli.StringFlag{
+		Name:    "conf",
+		Aliases: []string{"c"},
+		Usage:   "App configuration file(.yaml),like: configs/config.yaml",
+		Value:   "configs/config-linux.yaml",
+	}
+	queueFlag := &cli.StringFlag{Name: "queue", Aliases: []string{"q"}, Usage: "queue name", Value: "default"}
+	idFlag := &cli.StringFlag{Name: "id", Aliases: []string{"i"}, Usage: "task id", Required: true}
+
+	return &cli.Command{
+		Name:  "dlq",
+		Usage: "Inspect and replay dead-letter queue tasks",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List archived or retry tasks",
+				Flags: []cli.Flag{
+					confFlag,
+					&cli.StringFlag{Name: "state", Aliases: []string{"s"}, Usage: "archived or retry", Value: constant.DLQ_STATE_ARCHIVED},
+					&cli.StringFlag{Name: "type", Aliases: []string{"t"}, Usage: "task type, like: flow:create"},
+					&cli.IntFlag{Name: "page", Value: 1},
+					&cli.IntFlag{Name: "size", Value: 50},
+				},
+				Action: func(c *cli.Context) error {
+					if err := config.MustLoad(c.String("conf")); err != nil {
+						return err
+					}
+					list, count, err := mq.ListDeadTasks(c.String("state"), c.String("type"), c.Int("page"), c.Int("size"))
+					if err != nil {
+						return err
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(w, "QUEUE\tID\tTYPE\tRETRIED\tLAST FAILED\tLAST ERROR")
+					for _, item := range list {
+						fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%s\t%s\n", item.Queue, item.ID, item.Type, item.Retried, item.MaxRetry,
+							formatUnix(item.LastFailedAt), item.LastErr)
+					}
+					w.Flush()
+					fmt.Printf("total %d\n", count)
+					return nil
+				},
+			},
+			{
+				Name:  "show",
+				Usage: "Show task payload and last error",
+				Flags: []cli.Flag{confFlag, queueFlag, idFlag},
+				Action: func(c *cli.Context) error {
+					if err := config.MustLoad(c.String("conf")); err != nil {
+						return err
+					}
+					task, err := mq.GetDeadTask(c.String("queue"), c.String("id"))
+					if err != nil {
+						return err
+					}
+					data, _ := json.MarshalIndent(task, "", "  ")
+					fmt.Println(string(data))
+					return nil
+				},
+			},
+			{
+				Name:  "replay",
+				Usage: "Replay a task, optionally with an edited payload",
+				Flags: []cli.Flag{confFlag, queueFlag, idFlag,
+					&cli.StringFlag{Name: "payload", Aliases: []string{"p"}, Usage: "edited payload, empty to replay as is"},
+				},
+				Action: func(c *cli.Context) error {
+					if err := config.MustLoad(c.String("conf")); err != nil {
+						return err
+					}
+					mq.InitClient()
+					if err := mq.ReplayDeadTask(c.String("queue"), c.String("id"), []byte(c.String("payload"))); err != nil {
+						return err
+					}
+					fmt.Println("replayed")
+					return nil
+				},
+			},
+			{
+				Name:  "discard",
+				Usage: "Delete a task",
+				Flags: []cli.Flag{confFlag, queueFlag, idFlag},
+				Action: func(c *cli.Context) error {
+					if err := config.MustLoad(c.String("conf")); err != nil {
+						return err
+					}
+					if err := mq.DiscardDeadTask(c.String("queue"), c.String("id")); err != nil {
+						return err
+					}
+					fmt.Println("discarded")
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func formatUnix(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Format("2006-01-02 15:04:05")
+}
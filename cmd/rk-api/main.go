@@ -40,6 +40,7 @@ func main() {
 	app.Usage = "turbos landing system"
 	app.Commands = []*cli.Command{
 		newWebCmd(ctx),
+		newDLQCmd(ctx),
 	}
 	err := app.Run(os.Args)
 	if err != nil {
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/ginx"
 	"rk-api/internal/app/service"
@@ -13,7 +14,8 @@ import (
 var ActivityAPISet = wire.NewSet(wire.Struct(new(ActivityAPI), "*"))
 
 type ActivityAPI struct {
-	Srv *service.ActivityService
+	Srv         *service.ActivityService
+	ApprovalSrv *service.AdminApprovalService
 }
 
 func (c *ActivityAPI) AddRedEnvelope(ctx *gin.Context) {
@@ -25,13 +27,14 @@ func (c *ActivityAPI) AddRedEnvelope(ctx *gin.Context) {
 	req.UID = ginx.Mine(ctx)
 	req.IP = ctx.ClientIP()
 
-	err := c.Srv.AddRedEnvelope(&req)
+	// 发红包需要复核
+	resp, err := c.ApprovalSrv.Submit(constant.ADMIN_APPROVAL_ACTION_RED_ENVELOPE, &req, req.UID, req.IP)
 	if err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
 
-	ginx.RespSucc(ctx, nil)
+	ginx.RespSucc(ctx, resp)
 }
 
 func (c *ActivityAPI) DelRedEnvelope(ctx *gin.Context) {
@@ -104,13 +107,13 @@ func (c *ActivityAPI) GetRedEnvelope(ctx *gin.Context) {
 		return
 	}
 
-	err := c.Srv.GetRedEnvelope(&req)
+	amount, err := c.Srv.GetRedEnvelope(&req)
 	if err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
 
-	ginx.RespSucc(ctx, nil)
+	ginx.RespSucc(ctx, gin.H{"amount": amount})
 }
 
 func (c *ActivityAPI) JoinPinduo(ctx *gin.Context) {
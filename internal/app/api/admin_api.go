@@ -1,6 +1,7 @@
 package api
 
 import (
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/ginx"
 	"rk-api/internal/app/service"
@@ -12,8 +13,9 @@ import (
 var AdminAPISet = wire.NewSet(wire.Struct(new(AdminAPI), "*"))
 
 type AdminAPI struct {
-	Srv     *service.AdminService
-	UserSrv *service.UserService
+	Srv         *service.AdminService
+	UserSrv     *service.UserService
+	ApprovalSrv *service.AdminApprovalService
 }
 
 func (c *AdminAPI) GenAuthQRCode(ctx *gin.Context) {
@@ -56,37 +58,20 @@ func (c *AdminAPI) CheckGoogleAuthCodeBinded(ctx *gin.Context) {
 	ginx.RespSucc(ctx, ret)
 }
 
-func (c *AdminAPI) CallMonthBackupAndClean(ctx *gin.Context) {
-	var req entities.MonthBackupAndCleaReq
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ginx.RespErr(ctx, err)
-		return
-	}
-	err := c.Srv.CallMonthBackupAndClean(&req)
-	if err != nil {
-		ginx.RespErr(ctx, err)
-		return
-	}
-	ginx.RespSucc(ctx, nil)
-}
-
+// 业务员迁移需要复核 复核通过后再清缓存和迁移
 func (c *AdminAPI) CallChangePC(ctx *gin.Context) {
 	var req entities.CallChangePCReq
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
-	//清除原业务员用户的RDS缓存
-	err := c.UserSrv.BatchClearUserCacheByPC(req.SRC)
-	if err != nil {
-		ginx.RespErr(ctx, err)
-		return
-	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
 
-	err = c.Srv.CallChangePC(&req)
+	resp, err := c.ApprovalSrv.Submit(constant.ADMIN_APPROVAL_ACTION_CHANGE_PC, &req, req.OptionID, req.IP)
 	if err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
-	ginx.RespSucc(ctx, nil)
+	ginx.RespSucc(ctx, resp)
 }
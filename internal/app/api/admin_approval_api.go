@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	game "rk-api/internal/app/game/rg"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var AdminApprovalAPISet = wire.NewSet(wire.Struct(new(AdminApprovalAPI), "*"))
+
+type AdminApprovalAPI struct {
+	Srv         *service.AdminApprovalService
+	AdminSrv    *service.AdminService
+	UserSrv     *service.UserService
+	AgentSrv    *service.AgentService
+	ActivitySrv *service.ActivityService
+	WithdrawSrv *service.WithdrawService
+	Wingo       game.IWingo
+	Nine        game.INine
+}
+
+// 注册需要复核的操作 复核通过后按原接口的逻辑执行 服务启动时由路由调用
+func (c *AdminApprovalAPI) RegisterActions() {
+	c.Srv.RegisterAction(constant.ADMIN_APPROVAL_ACTION_WINGO_PERIOD, constant.ADMIN_PERM_PERIOD_OVERRIDE, func(payload []byte) error {
+		var req entities.UpdatePeriodReq
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return c.Wingo.UpdatePeriodNumber(&req)
+	})
+	c.Srv.RegisterAction(constant.ADMIN_APPROVAL_ACTION_NINE_PERIOD, constant.ADMIN_PERM_PERIOD_OVERRIDE, func(payload []byte) error {
+		var req entities.UpdatePeriodReq
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return c.Nine.UpdatePeriodNumber(&req)
+	})
+	c.Srv.RegisterAction(constant.ADMIN_APPROVAL_ACTION_CHANGE_PC, constant.ADMIN_PERM_PC_CHANGE, func(payload []byte) error {
+		var req entities.CallChangePCReq
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		//清除原业务员用户的RDS缓存
+		if err := c.UserSrv.BatchClearUserCacheByPC(req.SRC); err != nil {
+			return err
+		}
+		return c.AdminSrv.CallChangePC(&req)
+	})
+	c.Srv.RegisterAction(constant.ADMIN_APPROVAL_ACTION_INVITE_RELATION, constant.ADMIN_PERM_AGENT_MANAGE, func(payload []byte) error {
+		var req entities.FixInviteRelationReq
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return c.AgentSrv.FixInviteRelation(&req)
+	})
+	c.Srv.RegisterAction(constant.ADMIN_APPROVAL_ACTION_RED_ENVELOPE, constant.ADMIN_PERM_PROMO_MANAGE, func(payload []byte) error {
+		var req entities.AddRedEnvelopeReq
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return c.ActivitySrv.AddRedEnvelope(&req)
+	})
+	c.Srv.RegisterAction(constant.ADMIN_APPROVAL_ACTION_EDIT_BALANCE, constant.ADMIN_PERM_USER_EDIT, func(payload []byte) error {
+		var req entities.EditUserInfoReq
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return c.UserSrv.EditUserInfo(&req)
+	})
+	c.Srv.RegisterAction(constant.ADMIN_APPROVAL_ACTION_WITHDRAW, constant.ADMIN_PERM_WITHDRAW_REVIEW, func(payload []byte) error {
+		var req entities.ReviewWithdrawalReq
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return c.WithdrawSrv.ReviewWithdrawal(&req)
+	})
+}
+
+func (c *AdminApprovalAPI) GetApprovalList(ctx *gin.Context) {
+	var req entities.GetAdminApprovalListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetAdminApprovalList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+// 复核通过 通过后立即执行 执行失败也返回错误
+func (c *AdminApprovalAPI) Approve(ctx *gin.Context) {
+	var req entities.CheckAdminApprovalReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.Approve(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *AdminApprovalAPI) Reject(ctx *gin.Context) {
+	var req entities.CheckAdminApprovalReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.Reject(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var AdminAuthAPISet = wire.NewSet(wire.Struct(new(AdminAuthAPI), "*"))
+
+type AdminAuthAPI struct {
+	Srv *service.AdminAuthService
+}
+
+// 后台登录 账号密码+谷歌验证码
+func (c *AdminAuthAPI) Login(ctx *gin.Context) {
+	var req entities.AdminLoginReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.IP = ctx.ClientIP()
+	req.UserAgent = ctx.Request.UserAgent()
+	resp, err := c.Srv.Login(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, resp)
+}
+
+func (c *AdminAuthAPI) RefreshToken(ctx *gin.Context) {
+	resp, err := c.Srv.RefreshAdminToken(ginx.Mine(ctx), ginx.SessionID(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, resp)
+}
+
+func (c *AdminAuthAPI) Logout(ctx *gin.Context) {
+	if err := c.Srv.Logout(ginx.Mine(ctx), ginx.SessionID(ctx)); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *AdminAuthAPI) GetProfile(ctx *gin.Context) {
+	profile, err := c.Srv.GetAdminProfile(ginx.Mine(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, profile)
+}
+
+func (c *AdminAuthAPI) GetPermissionList(ctx *gin.Context) {
+	ginx.RespSucc(ctx, c.Srv.GetAdminPermissionList())
+}
+
+func (c *AdminAuthAPI) GetRoleList(ctx *gin.Context) {
+	list, err := c.Srv.GetAdminRoleList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *AdminAuthAPI) SaveRole(ctx *gin.Context) {
+	var req entities.SaveAdminRoleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveAdminRole(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *AdminAuthAPI) DelRole(ctx *gin.Context) {
+	var req entities.DelAdminRoleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DelAdminRole(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *AdminAuthAPI) GetAdminUserList(ctx *gin.Context) {
+	list, err := c.Srv.GetAdminUserList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *AdminAuthAPI) SaveAdminUser(ctx *gin.Context) {
+	var req entities.SaveAdminUserReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveAdminUser(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+// 还没有后台账号时创建第一个超级管理员
+func (c *AdminAuthAPI) BootstrapAdminUser(ctx *gin.Context) {
+	var req entities.BootstrapAdminUserReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.BootstrapAdminUser(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *AdminAuthAPI) GetSessionList(ctx *gin.Context) {
+	var req entities.GetAdminSessionListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetAdminSessionList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *AdminAuthAPI) RevokeSession(ctx *gin.Context) {
+	var req entities.RevokeAdminSessionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.RevokeAdminSession(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/ginx"
 	"rk-api/internal/app/service"
@@ -12,7 +13,8 @@ import (
 var AgentAPISet = wire.NewSet(wire.Struct(new(AgentAPI), "*"))
 
 type AgentAPI struct {
-	Srv *service.AgentService
+	Srv         *service.AgentService
+	ApprovalSrv *service.AdminApprovalService
 }
 
 func (c *AgentAPI) FixLevel1InviteCount(ctx *gin.Context) {
@@ -130,11 +132,28 @@ func (c *AgentAPI) FixInviteRelation(ctx *gin.Context) {
 		ginx.RespErr(ctx, err)
 		return
 	}
+	req.OptionID = ginx.Mine(ctx)
 	req.IP = ctx.ClientIP()
-	err := c.Srv.FixInviteRelation(&req)
+	// 迁移邀请关系需要复核 先用preview确认影响范围
+	resp, err := c.ApprovalSrv.Submit(constant.ADMIN_APPROVAL_ACTION_INVITE_RELATION, &req, req.OptionID, req.IP)
 	if err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
-	ginx.RespSucc(ctx, nil)
+	ginx.RespSucc(ctx, resp)
+}
+
+// 预览邀请关系迁移影响的用户和关系
+func (c *AgentAPI) PreviewInviteRelation(ctx *gin.Context) {
+	var req entities.FixInviteRelationReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	preview, err := c.Srv.PreviewInviteRelation(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, preview)
 }
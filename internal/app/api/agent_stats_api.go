@@ -0,0 +1,89 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var AgentStatsAPISet = wire.NewSet(wire.Struct(new(AgentStatsAPI), "*"))
+
+type AgentStatsAPI struct {
+	Srv *service.AgentStatsService
+}
+
+// 代理看板 团队人数/注册/首充/充提/投注/返利
+func (c *AgentStatsAPI) GetAgentDashboard(ctx *gin.Context) {
+	var req entities.GetAgentDashboardReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	dashboard, err := c.Srv.GetAgentDashboard(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, dashboard)
+}
+
+func (c *AgentStatsAPI) GetAgentDownlineList(ctx *gin.Context) {
+	var req entities.GetAgentDownlineListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.GetAgentDownlineList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *AgentStatsAPI) AdminGetAgentDashboard(ctx *gin.Context) {
+	var req entities.GetAgentDashboardReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	dashboard, err := c.Srv.GetAgentDashboard(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, dashboard)
+}
+
+func (c *AgentStatsAPI) AdminGetAgentDownlineList(ctx *gin.Context) {
+	var req entities.GetAgentDownlineListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetAgentDownlineList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+// 重新汇总某一天的团队统计
+func (c *AgentStatsAPI) AdminAggregateAgentDailyStats(ctx *gin.Context) {
+	var req entities.AggregateAgentStatsReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.AdminAggregateAgentDailyStats(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
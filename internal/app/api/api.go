@@ -34,4 +34,16 @@ var APISet = wire.NewSet(
 	MineGameAPISet,
 	DiceGameAPISet,
 	LimboGameAPISet,
+	HistoryAPISet,
+	GamingAPISet,
+	VipAPISet,
+	CashbackAPISet,
+	AgentStatsAPISet,
+	CommissionAPISet,
+	AdminAuthAPISet,
+	AdminApprovalAPISet,
+	ArchiveAPISet,
+	SchedulerAPISet,
+	DeadLetterAPISet,
+	EventAPISet,
 ) // end
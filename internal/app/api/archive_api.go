@@ -0,0 +1,45 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var ArchiveAPISet = wire.NewSet(wire.Struct(new(ArchiveAPI), "*"))
+
+type ArchiveAPI struct {
+	Srv *service.ArchiveService
+}
+
+// 手动执行归档 后台异步执行 进度看任务列表
+func (c *ArchiveAPI) RunArchive(ctx *gin.Context) {
+	var req entities.RunArchiveReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.RunArchive(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *ArchiveAPI) GetJobList(ctx *gin.Context) {
+	var req entities.GetArchiveJobListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetArchiveJobList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
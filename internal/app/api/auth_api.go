@@ -79,9 +79,7 @@ func (c *AuthAPI) MobileLogin(ctx *gin.Context) {
 		return
 	}
 	// 在此处验证用户名和密码，并生成 JWT
-	sso := entities.OAuthToken{
-		UID: user.ID,
-	}
+	sso := newSessionToken(ctx, user.ID)
 	err = c.Srv.CreateJWTAccessRefreshToken(&sso)
 	if err != nil {
 		ginx.RespErr(ctx, err)
@@ -112,9 +110,7 @@ func (c *AuthAPI) Login(ctx *gin.Context) {
 	}
 
 	// 在此处验证用户名和密码，并生成 JWT
-	sso := entities.OAuthToken{
-		UID: user.ID,
-	}
+	sso := newSessionToken(ctx, user.ID)
 	err = c.Srv.CreateJWTAccessRefreshToken(&sso)
 	if err != nil {
 		ginx.RespErr(ctx, err)
@@ -130,13 +126,105 @@ func (c *AuthAPI) Login(ctx *gin.Context) {
 	ginx.RespSucc(ctx, loginResp)
 }
 
+// 登录会话的设备信息
+func newSessionToken(ctx *gin.Context, uid uint) entities.OAuthToken {
+	userAgent := ctx.Request.Header.Get("User-Agent")
+	return entities.OAuthToken{
+		UID:       uid,
+		DeviceID:  ginx.GetDeviceID(ctx),
+		Device:    utils.GetDeviceOS(userAgent),
+		UserAgent: userAgent,
+		IP:        ctx.ClientIP(),
+	}
+}
+
+// refresh token 轮换 返回新的一对token
+func (c *AuthAPI) RefreshToken(ctx *gin.Context) {
+	var req entities.RefreshTokenReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if req.RefreshToken == "" {
+		if refreshToken, err := ctx.Cookie(constant.REFRESH_TOKEN); err == nil {
+			req.RefreshToken = refreshToken
+		} else {
+			req.RefreshToken = ctx.GetHeader(constant.REFRESH_TOKEN)
+		}
+	}
+	req.IP = ctx.ClientIP()
+
+	sso, err := c.Srv.RefreshSession(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.SetAuthTokens(ctx, sso.AccessToken, sso.RefreshToken, config.Get().ServiceSettings.TokenExpireTime)
+
+	loginResp := &entities.LoginResp{
+		Token: sso.AccessToken,
+		UID:   sso.UID,
+	}
+	ginx.RespSucc(ctx, loginResp)
+}
+
+func (c *AuthAPI) GetSessionList(ctx *gin.Context) {
+	list, err := c.Srv.GetUserSessionList(ginx.Mine(ctx), ginx.SessionID(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *AuthAPI) RevokeSession(ctx *gin.Context) {
+	var req entities.RevokeSessionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.RevokeSession(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *AuthAPI) GetUserSessionList(ctx *gin.Context) {
+	var req entities.GetUserSessionListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	list, err := c.Srv.GetUserSessionList(req.UID, "")
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *AuthAPI) ForceLogout(ctx *gin.Context) {
+	var req entities.ForceLogoutReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.ForceLogout(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
 func (c *AuthAPI) Logout(ctx *gin.Context) {
 
 	userID := ginx.Mine(ctx)
 
-	c.Srv.Logout(userID)
-
-	if err := c.Srv.Logout(userID); err != nil {
+	if err := c.Srv.Logout(userID, ginx.SessionID(ctx)); err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var CashbackAPISet = wire.NewSet(wire.Struct(new(CashbackAPI), "*"))
+
+type CashbackAPI struct {
+	Srv *service.CashbackService
+}
+
+// 当前累计和可领取的自返
+func (c *CashbackAPI) GetCashbackInfo(ctx *gin.Context) {
+	info, err := c.Srv.GetCashbackInfo(ginx.Mine(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, info)
+}
+
+func (c *CashbackAPI) ClaimCashback(ctx *gin.Context) {
+	amount, err := c.Srv.ClaimCashback(ginx.Mine(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, gin.H{"amount": amount})
+}
+
+func (c *CashbackAPI) GetCashbackClaimList(ctx *gin.Context) {
+	var req entities.GetCashbackClaimListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.GetCashbackClaimList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *CashbackAPI) AdminGetCashbackRuleList(ctx *gin.Context) {
+	list, err := c.Srv.AdminGetCashbackRuleList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *CashbackAPI) SaveCashbackRule(ctx *gin.Context) {
+	var req entities.SaveCashbackRuleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveCashbackRule(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *CashbackAPI) DelCashbackRule(ctx *gin.Context) {
+	var req entities.DelCashbackRuleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DelCashbackRule(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
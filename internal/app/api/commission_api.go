@@ -0,0 +1,147 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var CommissionAPISet = wire.NewSet(wire.Struct(new(CommissionAPI), "*"))
+
+type CommissionAPI struct {
+	Srv *service.CommissionService
+}
+
+// 当前适用的佣金方案
+func (c *CommissionAPI) GetCommissionPlanInfo(ctx *gin.Context) {
+	info, err := c.Srv.GetCommissionPlanInfo(ginx.Mine(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, info)
+}
+
+func (c *CommissionAPI) GetCommissionStatementList(ctx *gin.Context) {
+	var req entities.GetCommissionStatementListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.GetCommissionStatementList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *CommissionAPI) AdminGetCommissionStatementList(ctx *gin.Context) {
+	var req entities.GetCommissionStatementListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetCommissionStatementList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *CommissionAPI) AdminGetCommissionPlanList(ctx *gin.Context) {
+	list, err := c.Srv.AdminGetCommissionPlanList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *CommissionAPI) SaveCommissionPlan(ctx *gin.Context) {
+	var req entities.SaveCommissionPlanReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveCommissionPlan(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *CommissionAPI) DelCommissionPlan(ctx *gin.Context) {
+	var req entities.DelCommissionPlanReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DelCommissionPlan(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *CommissionAPI) AdminGetCommissionPlanAssignList(ctx *gin.Context) {
+	list, err := c.Srv.AdminGetCommissionPlanAssignList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *CommissionAPI) SaveCommissionPlanAssign(ctx *gin.Context) {
+	var req entities.SaveCommissionPlanAssignReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveCommissionPlanAssign(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *CommissionAPI) DelCommissionPlanAssign(ctx *gin.Context) {
+	var req entities.DelCommissionPlanAssignReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DelCommissionPlanAssign(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+// 手动结算指定周期 已结算的代理跳过
+func (c *CommissionAPI) SettleCommission(ctx *gin.Context) {
+	var req entities.SettleCommissionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.AdminSettleCommission(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var DeadLetterAPISet = wire.NewSet(wire.Struct(new(DeadLetterAPI), "*"))
+
+type DeadLetterAPI struct {
+	Srv *service.DeadLetterService
+}
+
+func (c *DeadLetterAPI) GetList(ctx *gin.Context) {
+	var req entities.GetDeadLetterListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetDeadLetterList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *DeadLetterAPI) GetTask(ctx *gin.Context) {
+	var req entities.GetDeadLetterReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	task, err := c.Srv.GetDeadLetter(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, task)
+}
+
+// 原样重放或修改内容后重放
+func (c *DeadLetterAPI) Replay(ctx *gin.Context) {
+	var req entities.ReplayDeadLetterReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.ReplayDeadLetter(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *DeadLetterAPI) Discard(ctx *gin.Context) {
+	var req entities.DiscardDeadLetterReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DiscardDeadLetter(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *DeadLetterAPI) GetStats(ctx *gin.Context) {
+	list, err := c.Srv.GetTaskFailureStats()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var EventAPISet = wire.NewSet(wire.Struct(new(EventAPI), "*"))
+
+type EventAPI struct {
+	Srv *service.EventBusService
+}
+
+func (c *EventAPI) GetGroupList(ctx *gin.Context) {
+	list, err := c.Srv.GetEventGroupList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *EventAPI) GetEventList(ctx *gin.Context) {
+	var req entities.GetEventListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	list, err := c.Srv.GetEventList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+// 消费组从指定时间重新处理事件
+func (c *EventAPI) Replay(ctx *gin.Context) {
+	var req entities.ReplayEventGroupReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.ReplayEventGroup(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *EventAPI) GetDailyStats(ctx *gin.Context) {
+	var req entities.GetEventDailyStatsReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	list, err := c.Srv.GetEventDailyStats(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
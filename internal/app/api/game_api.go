@@ -41,7 +41,7 @@ func (c *GameAPI) SearchGame(ctx *gin.Context) {
 		ginx.RespErr(ctx, err)
 		return
 	}
-	ginx.RespSucc(ctx, &req.Paginator)
+	ginx.RespSucc(ctx, &entities.SearchGameResp{Paginator: &req.Paginator, Facets: req.Facets})
 }
 
 func (c *GameAPI) RefreshGameList(ctx *gin.Context) {
@@ -52,3 +52,144 @@ func (c *GameAPI) RefreshGameList(ctx *gin.Context) {
 	}
 	ginx.RespSucc(ctx, list)
 }
+
+func (c *GameAPI) GetGameCollectionList(ctx *gin.Context) {
+	var req entities.GetGameCollectionListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	list, err := c.Srv.GetGameCollectionList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *GameAPI) AdminGetGameList(ctx *gin.Context) {
+	var req entities.AdminGetGameListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.AdminGetGameList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *GameAPI) SaveGame(ctx *gin.Context) {
+	var req entities.SaveGameReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveGame(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GameAPI) DelGame(ctx *gin.Context) {
+	var req entities.DelGameReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DelGame(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GameAPI) ImportGameList(ctx *gin.Context) {
+	var req entities.ImportGameListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.ImportGameList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GameAPI) SyncProviderGameList(ctx *gin.Context) {
+	var req entities.SyncProviderGameListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SyncProviderGameList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GameAPI) AdminGetGameCollectionList(ctx *gin.Context) {
+	list, err := c.Srv.AdminGetGameCollectionList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *GameAPI) SaveGameCollection(ctx *gin.Context) {
+	var req entities.SaveGameCollectionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveGameCollection(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GameAPI) DelGameCollection(ctx *gin.Context) {
+	var req entities.DelGameCollectionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DelGameCollection(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GameAPI) SetGameCollectionItems(ctx *gin.Context) {
+	var req entities.SetGameCollectionItemsReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SetGameCollectionItems(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var GamingAPISet = wire.NewSet(wire.Struct(new(GamingAPI), "*"))
+
+// 负责任博彩
+type GamingAPI struct {
+	Srv *service.GamingService
+}
+
+// 当前限额/冷静期/自我排除 以及会话时长提醒
+func (c *GamingAPI) GetGamingStatus(ctx *gin.Context) {
+	status, err := c.Srv.GetGamingStatus(&entities.GetGamingStatusReq{
+		UID:       ginx.Mine(ctx),
+		SessionID: ginx.SessionID(ctx),
+	})
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, status)
+}
+
+func (c *GamingAPI) SetGamingLimit(ctx *gin.Context) {
+	var req entities.SetGamingLimitReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.SetGamingLimit(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GamingAPI) SetSessionReminder(ctx *gin.Context) {
+	var req entities.SetSessionReminderReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.SetSessionReminder(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GamingAPI) CoolOff(ctx *gin.Context) {
+	var req entities.GamingCoolOffReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.CoolOff(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GamingAPI) SelfExclude(ctx *gin.Context) {
+	var req entities.GamingSelfExcludeReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.SelfExclude(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GamingAPI) AdminGetGamingStatus(ctx *gin.Context) {
+	var req entities.GetGamingStatusReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	status, err := c.Srv.GetGamingStatus(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, status)
+}
+
+func (c *GamingAPI) AdminSetGamingLimit(ctx *gin.Context) {
+	var req entities.AdminSetGamingLimitReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.AdminSetGamingLimit(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *GamingAPI) AdminSetGamingRestriction(ctx *gin.Context) {
+	var req entities.AdminSetGamingRestrictionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.AdminSetGamingRestriction(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var HistoryAPISet = wire.NewSet(wire.Struct(new(HistoryAPI), "*"))
+
+type HistoryAPI struct {
+	Srv     *service.HistoryService
+	GameSrv *service.GameService
+}
+
+func (c *HistoryAPI) GetBetHistory(ctx *gin.Context) {
+	var req entities.GetBetHistoryReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	resp, err := c.Srv.GetBetHistory(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, resp)
+}
+
+// 导出CSV文件
+func (c *HistoryAPI) ExportBetHistory(ctx *gin.Context) {
+	var req entities.ExportBetHistoryReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	data, err := c.Srv.ExportBetHistory(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	filename := fmt.Sprintf("bet_history_%s.csv", time.Now().Format("20060102150405"))
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+func (c *HistoryAPI) GetRecentGameList(ctx *gin.Context) {
+	var req entities.GetRecentGameListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	list, err := c.Srv.GetRecentGameList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *HistoryAPI) GetFavoriteGameList(ctx *gin.Context) {
+	list, err := c.GameSrv.GetFavoriteGameList(ginx.Mine(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *HistoryAPI) AddFavoriteGame(ctx *gin.Context) {
+	var req entities.FavoriteGameReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.GameSrv.AddFavoriteGame(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *HistoryAPI) DelFavoriteGame(ctx *gin.Context) {
+	var req entities.FavoriteGameReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.GameSrv.DelFavoriteGame(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
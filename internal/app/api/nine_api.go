@@ -1,6 +1,7 @@
 package api
 
 import (
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	game "rk-api/internal/app/game/rg"
 	"rk-api/internal/app/ginx"
@@ -15,7 +16,8 @@ var NineAPISet = wire.NewSet(wire.Struct(new(NineAPI), "*"))
 type NineAPI struct {
 	Srv *service.NineService
 	// Nine *game.Nine
-	Nine game.INine
+	Nine        game.INine
+	ApprovalSrv *service.AdminApprovalService
 }
 
 func (c *NineAPI) GetRoom(ctx *gin.Context) {
@@ -148,6 +150,7 @@ func (c *NineAPI) GetPeriodBetInfo(ctx *gin.Context) {
 	ginx.RespSucc(ctx, order)
 }
 
+// 修改开奖号码需要复核 这里只发起复核单
 func (c *NineAPI) ChangePeriodNumber(ctx *gin.Context) {
 
 	var req entities.UpdatePeriodReq
@@ -156,12 +159,12 @@ func (c *NineAPI) ChangePeriodNumber(ctx *gin.Context) {
 		return
 	}
 
-	err := c.Nine.UpdatePeriodNumber(&req)
+	resp, err := c.ApprovalSrv.Submit(constant.ADMIN_APPROVAL_ACTION_NINE_PERIOD, &req, ginx.Mine(ctx), ctx.ClientIP())
 	if err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
-	ginx.RespSucc(ctx, nil)
+	ginx.RespSucc(ctx, resp)
 }
 
 func (c *NineAPI) GetTodayPeriodList(ctx *gin.Context) {
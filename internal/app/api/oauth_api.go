@@ -98,9 +98,7 @@ func (c *OauthAPI) GoogleCallBack(ctx *gin.Context) {
 
 	ginx.RespSucc(ctx, oauthState)
 	// c.Redirect(http.StatusMovedPermanently, oauthState.OauthUrl)
-	sso := entities.OAuthToken{
-		UID: user.ID,
-	}
+	sso := newSessionToken(ctx, user.ID)
 
 	err = c.AuthSrv.CreateJWTAccessRefreshToken(&sso)
 	if err != nil {
@@ -156,9 +154,7 @@ func (c *OauthAPI) CallBackGithub(ctx *gin.Context) {
 	}
 
 	// c.Redirect(http.StatusMovedPermanently, oauthState.OauthUrl)
-	sso := entities.OAuthToken{
-		UID: user.ID,
-	}
+	sso := newSessionToken(ctx, user.ID)
 
 	err = c.AuthSrv.CreateJWTAccessRefreshToken(&sso)
 	if err != nil {
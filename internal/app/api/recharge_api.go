@@ -404,3 +404,47 @@ func (c *RechargeAPI) POLYCallback(ctx *gin.Context) {
 	// }
 	// ctx.String(http.StatusOK, resp)
 }
+
+func (c *RechargeAPI) GetRechargePromotionList(ctx *gin.Context) {
+	var req entities.GetRechargePromotionListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	err := c.Srv.GetRechargePromotionList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *RechargeAPI) SaveRechargePromotion(ctx *gin.Context) {
+	var req entities.SaveRechargePromotionReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	err := c.Srv.SaveRechargePromotion(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.RechargePromotion)
+}
+
+func (c *RechargeAPI) GetRechargePromotionRecordList(ctx *gin.Context) {
+	var req entities.GetRechargePromotionRecordListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	err := c.Srv.GetRechargePromotionRecordList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
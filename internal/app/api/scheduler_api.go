@@ -0,0 +1,69 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var SchedulerAPISet = wire.NewSet(wire.Struct(new(SchedulerAPI), "*"))
+
+type SchedulerAPI struct {
+	Srv *service.SchedulerService
+}
+
+func (c *SchedulerAPI) GetJobList(ctx *gin.Context) {
+	list, err := c.Srv.GetScheduledJobList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+// 修改时间表/暂停/恢复
+func (c *SchedulerAPI) SaveJob(ctx *gin.Context) {
+	var req entities.SaveScheduledJobReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveScheduledJob(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *SchedulerAPI) TriggerJob(ctx *gin.Context) {
+	var req entities.TriggerScheduledJobReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.TriggerScheduledJob(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *SchedulerAPI) GetRunList(ctx *gin.Context) {
+	var req entities.GetScheduledJobRunListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetScheduledJobRunList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
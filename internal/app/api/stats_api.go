@@ -12,7 +12,8 @@ import (
 var StatsAPISet = wire.NewSet(wire.Struct(new(StatsAPI), "*"))
 
 type StatsAPI struct {
-	Srv *service.StatsService
+	Srv          *service.StatsService
+	ReconcileSrv *service.ReconcileService
 }
 
 func (c *StatsAPI) GetGameStats(ctx *gin.Context) {
@@ -108,3 +109,71 @@ func (c *StatsAPI) GetProfitLeaderboard(ctx *gin.Context) {
 	}
 	ginx.RespSucc(ctx, list)
 }
+
+func (c *StatsAPI) BackfillGameRecord(ctx *gin.Context) {
+	var req entities.BackfillGameRecordReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.BackfillGameRecord(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *StatsAPI) GetGameRecordSyncWindowList(ctx *gin.Context) {
+	var req entities.GetGameRecordSyncWindowListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.Srv.GetGameRecordSyncWindowList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *StatsAPI) GetGameRecordSyncGapList(ctx *gin.Context) {
+	var req entities.GetGameRecordSyncGapListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	list, err := c.Srv.GetGameRecordSyncGapList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *StatsAPI) GetReconcileReportList(ctx *gin.Context) {
+	var req entities.GetReconcileReportListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.ReconcileSrv.GetReconcileReportList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *StatsAPI) GetReconcileDiscrepancyList(ctx *gin.Context) {
+	var req entities.GetReconcileDiscrepancyListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if err := c.ReconcileSrv.GetReconcileDiscrepancyList(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
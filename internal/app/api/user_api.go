@@ -1,7 +1,7 @@
 package api
 
 import (
-	"net/http"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/ginx"
 	"rk-api/internal/app/service"
@@ -14,23 +14,13 @@ import (
 var UserAPISet = wire.NewSet(wire.Struct(new(UserAPI), "*"))
 
 type UserAPI struct {
-	Srv *service.UserService
+	Srv         *service.UserService
+	ApprovalSrv *service.AdminApprovalService
 }
 
-// 获取用户信息时检验登录
+// 获取用户信息 登录会话已由JWTMiddleware校验
 func (c *UserAPI) GetUserInfo(ctx *gin.Context) {
-
-	authHeader := ctx.GetHeader("Authorization")
-	if authHeader == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid Authorization header"})
-		return
-	}
-	token := authHeader[7:] // Remove "Bearer " prefix
 	uid := ginx.Mine(ctx)
-	if err := c.Srv.VerifyLoginToken(uid, token); err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
-	}
 	user, err := c.Srv.GetUserProfile(uid)
 	// user, err := c.Srv.GetUserProfile(uid)
 	if err != nil {
@@ -157,6 +147,16 @@ func (c *UserAPI) EditUserInfo(ctx *gin.Context) {
 	}
 	req.OptionID = ginx.Mine(ctx)
 	req.IP = ctx.ClientIP()
+	// 手动调整余额需要复核 整个修改在复核通过后一起执行
+	if req.BalanceAdd != 0 {
+		resp, err := c.ApprovalSrv.Submit(constant.ADMIN_APPROVAL_ACTION_EDIT_BALANCE, &req, req.OptionID, req.IP)
+		if err != nil {
+			ginx.RespErr(ctx, err)
+			return
+		}
+		ginx.RespSucc(ctx, resp)
+		return
+	}
 	err := c.Srv.EditUserInfo(&req)
 	if err != nil {
 		ginx.RespErr(ctx, err)
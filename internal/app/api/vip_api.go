@@ -0,0 +1,102 @@
+package api
+
+import (
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/ginx"
+	"rk-api/internal/app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/wire"
+)
+
+var VipAPISet = wire.NewSet(wire.Struct(new(VipAPI), "*"))
+
+type VipAPI struct {
+	Srv *service.VipService
+}
+
+// 各等级的条件和权益
+func (c *VipAPI) GetVipTierList(ctx *gin.Context) {
+	list, err := c.Srv.GetVipTierList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *VipAPI) GetVipInfo(ctx *gin.Context) {
+	info, err := c.Srv.GetVipInfo(ginx.Mine(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, info)
+}
+
+func (c *VipAPI) SetVipBirthday(ctx *gin.Context) {
+	var req entities.SetVipBirthdayReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	if err := c.Srv.SetVipBirthday(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *VipAPI) AdminGetVipTierList(ctx *gin.Context) {
+	list, err := c.Srv.AdminGetVipTierList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *VipAPI) SaveVipTier(ctx *gin.Context) {
+	var req entities.SaveVipTierReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.SaveVipTier(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *VipAPI) DelVipTier(ctx *gin.Context) {
+	var req entities.DelVipTierReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	if err := c.Srv.DelVipTier(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
+
+func (c *VipAPI) AdminGetUserVip(ctx *gin.Context) {
+	var req entities.AdminGetUserVipReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	info, err := c.Srv.GetVipInfo(req.UID)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, info)
+}
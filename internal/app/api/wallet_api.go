@@ -79,3 +79,52 @@ func (c *WalletAPI) GetUserWallet(ctx *gin.Context) {
 	}
 	ginx.RespSucc(ctx, wallet)
 }
+
+func (c *WalletAPI) GetTurnoverSummary(ctx *gin.Context) {
+	summary, err := c.Srv.GetTurnoverSummary(ginx.Mine(ctx))
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, summary)
+}
+
+func (c *WalletAPI) GetTurnoverRequirementList(ctx *gin.Context) {
+	var req entities.GetTurnoverRequirementListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.UID = ginx.Mine(ctx)
+	err := c.Srv.GetTurnoverRequirementList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *WalletAPI) GetTurnoverRuleList(ctx *gin.Context) {
+	list, err := c.Srv.GetTurnoverRuleList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *WalletAPI) UpdateTurnoverRule(ctx *gin.Context) {
+	var req entities.UpdateTurnoverRuleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	err := c.Srv.UpdateTurnoverRule(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
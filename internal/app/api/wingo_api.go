@@ -1,6 +1,7 @@
 package api
 
 import (
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	game "rk-api/internal/app/game/rg"
 	"rk-api/internal/app/ginx"
@@ -13,8 +14,9 @@ import (
 var WingoAPISet = wire.NewSet(wire.Struct(new(WingoAPI), "*"))
 
 type WingoAPI struct {
-	Srv   *service.WingoService
-	Wingo game.IWingo
+	Srv         *service.WingoService
+	Wingo       game.IWingo
+	ApprovalSrv *service.AdminApprovalService
 }
 
 func (c *WingoAPI) GetRoom(ctx *gin.Context) {
@@ -147,6 +149,7 @@ func (c *WingoAPI) GetPeriodBetInfo(ctx *gin.Context) {
 	ginx.RespSucc(ctx, order)
 }
 
+// 修改开奖号码需要复核 这里只发起复核单
 func (c *WingoAPI) ChangePeriodNumber(ctx *gin.Context) {
 
 	var req entities.UpdatePeriodReq
@@ -155,12 +158,12 @@ func (c *WingoAPI) ChangePeriodNumber(ctx *gin.Context) {
 		return
 	}
 
-	err := c.Wingo.UpdatePeriodNumber(&req)
+	resp, err := c.ApprovalSrv.Submit(constant.ADMIN_APPROVAL_ACTION_WINGO_PERIOD, &req, ginx.Mine(ctx), ctx.ClientIP())
 	if err != nil {
 		ginx.RespErr(ctx, err)
 		return
 	}
-	ginx.RespSucc(ctx, nil)
+	ginx.RespSucc(ctx, resp)
 }
 
 func (c *WingoAPI) GetTodayPeriodList(ctx *gin.Context) {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/ginx"
 	"rk-api/internal/app/pay"
@@ -18,7 +19,9 @@ import (
 var WithdrawAPISet = wire.NewSet(wire.Struct(new(WithdrawAPI), "*"))
 
 type WithdrawAPI struct {
-	Srv *service.WithdrawService
+	Srv         *service.WithdrawService
+	RiskSrv     *service.RiskService
+	ApprovalSrv *service.AdminApprovalService
 }
 
 func (c *WithdrawAPI) GetWithdrawDetail(ctx *gin.Context) {
@@ -63,6 +66,8 @@ func (c *WithdrawAPI) ApplyForWithdrawal(ctx *gin.Context) {
 		return
 	}
 	req.UID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	req.DeviceID, _ = ctx.Cookie("deviceId")
 
 	err := c.Srv.ApplyForWithdrawal(&req)
 
@@ -128,7 +133,22 @@ func (c *WithdrawAPI) ReviewWithdrawal(ctx *gin.Context) {
 	}
 	req.SysUID = ginx.Mine(ctx)
 	req.IP = ctx.ClientIP()
-	err := c.Srv.ReviewWithdrawal(&req)
+	// 大额提现批准需要复核
+	need, err := c.Srv.NeedWithdrawalApproval(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	if need {
+		resp, err := c.ApprovalSrv.Submit(constant.ADMIN_APPROVAL_ACTION_WITHDRAW, &req, req.SysUID, req.IP)
+		if err != nil {
+			ginx.RespErr(ctx, err)
+			return
+		}
+		ginx.RespSucc(ctx, resp)
+		return
+	}
+	err = c.Srv.ReviewWithdrawal(&req)
 	// logger.ZError("---------------------2------", zap.Any("req", req), zap.Error(err))
 	if err != nil {
 		ginx.RespErr(ctx, err)
@@ -501,3 +521,42 @@ func (c *WithdrawAPI) GAGACallback(ctx *gin.Context) {
 	}
 	ctx.String(http.StatusOK, resp)
 }
+
+func (c *WithdrawAPI) GetWithdrawRiskLogList(ctx *gin.Context) {
+	var req entities.GetWithdrawRiskLogListReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	err := c.RiskSrv.GetWithdrawRiskLogList(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, &req.Paginator)
+}
+
+func (c *WithdrawAPI) GetWithdrawRiskRuleList(ctx *gin.Context) {
+	list, err := c.RiskSrv.GetWithdrawRiskRuleList()
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, list)
+}
+
+func (c *WithdrawAPI) UpdateWithdrawRiskRule(ctx *gin.Context) {
+	var req entities.UpdateWithdrawRiskRuleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	req.OptionID = ginx.Mine(ctx)
+	req.IP = ctx.ClientIP()
+	err := c.RiskSrv.UpdateWithdrawRiskRule(&req)
+	if err != nil {
+		ginx.RespErr(ctx, err)
+		return
+	}
+	ginx.RespSucc(ctx, nil)
+}
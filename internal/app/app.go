@@ -76,6 +76,7 @@ func Init(ctx context.Context, opts ...Option) (func(), error) {
 	if err != nil {
 		return nil, err
 	}
+	mq.InitOutbox(injector.Outbox.Repo.DB) //队列消息先写发件箱 所有节点都要初始化
 	httpServerCleanFunc := InitHTTPServer(ctx, injector.Engine)
 
 	if config.Get().ServiceSettings.EnableWingo {
@@ -87,10 +88,16 @@ func Init(ctx context.Context, opts ...Option) (func(), error) {
 	if config.Get().ServiceSettings.EnableMQ {
 		// 队列启动
 		mq.Start(injector.Service)
+		injector.Outbox.Start() //发件箱中继 多节点通过redis锁只有一个在投递
+
+		// 领域事件订阅者 多节点共用消费组
+		if err := injector.Events.Start(); err != nil {
+			return nil, err
+		}
 	}
 
 	if config.Get().ServiceSettings.EnableTask {
-		err = task.Start(injector.Service) //任务只能在一个地方启动
+		err = task.Start(injector.Service, injector.Scheduler) //多个节点开启时由调度器保证每次触发只执行一次
 		if err != nil {
 			log.Printf("Error starting the task scheduler: %v", err)
 			return nil, err
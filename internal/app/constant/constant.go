@@ -110,6 +110,19 @@ const (
 	REDIS_KEY_USER               = "USER_%d"
 	REDIS_KEY_USER_ACCESS_TOKEN  = "USER_ACCESS_TOKEN_%d"
 	REDIS_KEY_USER_REFRESH_TOKEN = "USER_REFRESH_TOKEN_%d"
+	REDIS_KEY_USER_SESSION       = "USER_SESSION_%s"
+	REDIS_KEY_ADMIN_SESSION      = "ADMIN_SESSION_%s"
+	REDIS_KEY_ADMIN_SESSION_SET  = "ADMIN_SESSION_SET_%d"
+	REDIS_KEY_ADMIN_LOGIN_FAIL   = "ADMIN_LOGIN_FAIL_%s"
+	REDIS_KEY_SCHEDULER_LEASE    = "SCHEDULER_LEASE_%s"
+	REDIS_KEY_OUTBOX_RELAY_LOCK  = "OUTBOX_RELAY_LOCK"
+	REDIS_KEY_MQ_TASK_STATS      = "MQ_TASK_STATS_%s_%d" // 任务类型 分钟
+	REDIS_KEY_MQ_FAILURE_ALERT   = "MQ_FAILURE_ALERT_%s"
+	REDIS_KEY_EVENT_STREAM       = "EVENT_STREAM_{event}_%s"   // 同一个hash tag 集群下可以一次读多个stream
+	REDIS_KEY_EVENT_APPENDED     = "EVENT_APPENDED_{event}_%s" // 事件ID 防止发件箱重投重复写入
+	REDIS_KEY_EVENT_DAILY_STATS  = "EVENT_DAILY_STATS_%s"
+	REDIS_KEY_GAMING_LIMIT       = "GAMING_LIMIT_%d"
+	REDIS_KEY_GAMING_USAGE       = "GAMING_USAGE_%d_%s_%s"
 	REDIS_USER_EXPIRE_TIME       = 3600 * 24 * 30 // seconds   1 month
 	REDIS_WINGO_PRESET           = "winGo:presetValue:%s"
 	REDIS_NINE_PRESET            = "nine:presetValue:%s"
@@ -167,11 +180,22 @@ const (
 	FLOW_TYPE_APPLY_FOR_WITHDRAW_CASH = 9  //提现
 	FLOW_TYPE_WITHDRAW_LOCK_CASH      = 12 //提现锁定金额 返回
 	FLOW_TYPE_GM_CASH                 = 11 //gm操作给的
+	FLOW_TYPE_RECHARGE_PROMOTION      = 13 //充值优惠奖励
+	FLOW_TYPE_VIP_LEVEL_UP            = 14 //VIP升级奖励
+	FLOW_TYPE_VIP_BIRTHDAY            = 15 //VIP生日奖励
+	FLOW_TYPE_CASHBACK                = 16 //自返 领取投注返水
+	FLOW_TYPE_COMMISSION              = 17 //代理佣金方案结算
 
 	FLOW_TYPE_INTEREST = 7  //利息
 	FLOW_TYPE_PINDUO   = 20 //拼多多
 )
 
+const (
+	TURNOVER_STATUS_ACTIVE   = 0 // 打码中
+	TURNOVER_STATUS_FINISHED = 1 // 打码完成
+	TURNOVER_STATUS_VOID     = 2 // 作废
+)
+
 const (
 	FUND_STATUS_FREEZE = 1 // 冻结
 	FUND_STATUS_THAW   = 2 // 解冻
@@ -214,6 +238,11 @@ const (
 
 	WITHDRAW_DAY_MAX_COUNT = 3 //提现一天最大的次数
 
+	WITHDRAW_RISK_STATE_NONE   = 0 //未评估
+	WITHDRAW_RISK_STATE_PASS   = 1 //风控自动通过
+	WITHDRAW_RISK_STATE_HOLD   = 2 //风控挂起
+	WITHDRAW_RISK_STATE_MANUAL = 3 //未命中规则 但需人工审核
+
 	//支付状态 0未支付 1支付成功 2取消 3支付失败 4谷歌已支付但未消耗'
 
 	//状态 0待审核 1通过 2驳回 3打款成功 4打款失败
@@ -223,6 +252,20 @@ const (
 	RECHARGE_ORDER_ACT_TYPE_10000 = 1 //充值 1W 送 2000 的订单类型
 )
 
+// 充值优惠
+const (
+	RECHARGE_PROMOTION_TRIGGER_FIRST = 1 //首充
+	RECHARGE_PROMOTION_TRIGGER_NTH   = 2 //第N次充值
+	RECHARGE_PROMOTION_TRIGGER_DAILY = 3 //每日首充
+	RECHARGE_PROMOTION_TRIGGER_EVERY = 4 //每笔充值
+
+	RECHARGE_PROMOTION_BENEFICIARY_SELF    = 1 //充值本人
+	RECHARGE_PROMOTION_BENEFICIARY_INVITER = 2 //一级上级
+
+	RECHARGE_PROMOTION_BONUS_PERCENT = 1 //按比例
+	RECHARGE_PROMOTION_BONUS_FIXED   = 2 //固定金额
+)
+
 // /系统操作类型
 const (
 	SYS_OPTION_TYPE_ADD_USER            = 1  // 添加用户
@@ -230,10 +273,204 @@ const (
 	SYS_OPTION_TYPE_WITHDRAWAL_REVIEW   = 79 // 操作类型  提现审核
 	SYS_OPTION_TYPE_WITHDRAWAL_CARD_ADD = 7  // 操作类型  提现卡添加
 	SYS_OPTION_TYPE_INVITE_RELATION_FIX = 5  //邀请关系修改
+	SYS_OPTION_TYPE_WITHDRAW_RISK_RULE  = 80 // 提现风控规则修改
+	SYS_OPTION_TYPE_TURNOVER_RULE       = 81 // 打码量规则修改
+	SYS_OPTION_TYPE_RECHARGE_PROMOTION  = 82 // 充值优惠规则修改
+	SYS_OPTION_TYPE_FORCE_LOGOUT        = 83 // 强制用户下线
+	SYS_OPTION_TYPE_RECORD_BACKFILL     = 84 // 三方游戏记录补录
+	SYS_OPTION_TYPE_GAME_CATALOG        = 85 // 游戏目录修改
+	SYS_OPTION_TYPE_GAMING_LIMIT        = 86 // 负责任博彩限额/限制修改
+	SYS_OPTION_TYPE_VIP_TIER            = 87 // VIP等级配置修改
+	SYS_OPTION_TYPE_CASHBACK_RULE       = 88 // 自返规则修改
+	SYS_OPTION_TYPE_AGENT_STATS         = 89 // 代理统计重新汇总
+	SYS_OPTION_TYPE_COMMISSION_PLAN     = 90 // 佣金方案修改/结算
+	SYS_OPTION_TYPE_ADMIN_ACTION        = 91 // 后台接口调用
+	SYS_OPTION_TYPE_ADMIN_AUTH          = 92 // 后台登录/角色/账号修改
+	SYS_OPTION_TYPE_ADMIN_APPROVAL      = 93 // 后台操作复核 发起/通过/驳回
+	SYS_OPTION_TYPE_ARCHIVE             = 94 // 手动执行数据归档
+	SYS_OPTION_TYPE_SCHEDULER           = 95 // 定时任务修改/手动执行
+	SYS_OPTION_TYPE_DEAD_LETTER         = 96 // 队列死信重放/丢弃
+	SYS_OPTION_TYPE_EVENT_REPLAY        = 97 // 领域事件消费组重放
+)
+
+// 单一钱包
+const (
+	SEAMLESS_PROVIDER_ZF      = "zf"
+	SEAMLESS_PROVIDER_R8      = "r8"
+	SEAMLESS_PROVIDER_JHSZ    = "jhsz"
+	SEAMLESS_PROVIDER_LINKEVO = "linkevo" // 目前只有启动游戏 还没有钱包回调
+
+	SEAMLESS_ACTION_DEBIT    = "debit"
+	SEAMLESS_ACTION_CREDIT   = "credit"
+	SEAMLESS_ACTION_ROLLBACK = "rollback"
+
+	SEAMLESS_STATUS_OPEN        = 0 // 扣款未结算
+	SEAMLESS_STATUS_SETTLED     = 1 // 已派彩/已结算
+	SEAMLESS_STATUS_ROLLED_BACK = 2 // 已回滚
+)
+
+// 用户会话
+const (
+	USER_SESSION_STATUS_ACTIVE  = 1
+	USER_SESSION_STATUS_REVOKED = 2
+
+	USER_SESSION_MAX           = 10 // 每个用户最多保留的有效会话 超出踢掉最早的
+	USER_SESSION_SEEN_INTERVAL = 60 // 最近访问时间的刷新间隔(秒)
+
+	USER_SESSION_REVOKE_LOGOUT  = "logout"        // 主动退出
+	USER_SESSION_REVOKE_USER    = "user_revoke"   // 用户在其他设备上撤销
+	USER_SESSION_REVOKE_ADMIN   = "admin"         // 后台强制下线
+	USER_SESSION_REVOKE_REUSE   = "refresh_reuse" // refresh token被重复使用
+	USER_SESSION_REVOKE_LIMIT   = "limit"         // 超出会话数量
+	USER_SESSION_REVOKE_BLOCKED = "blocked"       // 用户被封
+
+	JWT_TOKEN_TYPE_ACCESS  = "access"
+	JWT_TOKEN_TYPE_REFRESH = "refresh"
+	JWT_TOKEN_TYPE_ADMIN   = "admin"
+)
+
+// 后台账号和会话
+const (
+	ADMIN_USER_STATUS_ACTIVE   = 1
+	ADMIN_USER_STATUS_DISABLED = 2
+
+	ADMIN_TOKEN_EXPIRE       = 60 * 30   // 后台token有效期(秒) 过期前调用刷新
+	ADMIN_SESSION_EXPIRE     = 3600 * 12 // 后台会话最长有效期(秒) 到期需要重新登录
+	ADMIN_LOGIN_FAIL_MAX     = 5         // 连续登录失败次数 超出后锁定
+	ADMIN_LOGIN_FAIL_EXPIRE  = 60 * 15   // 登录失败计数的有效期(秒) 即锁定时长
+	ADMIN_SESSION_REVOKE_OUT = "logout"  // 主动退出
+	ADMIN_SESSION_REVOKE_OPT = "admin"   // 被其他管理员撤销
+	ADMIN_SESSION_REVOKE_ACC = "account" // 账号被禁用或修改密码
+)
+
+// 后台权限 按接口划分 角色配置为权限列表 *为全部权限
+const (
+	ADMIN_PERM_ALL             = "*"
+	ADMIN_PERM_ADMIN_MANAGE    = "admin.manage"    // 后台账号/角色/会话
+	ADMIN_PERM_WITHDRAW_REVIEW = "withdraw.review" // 提现审核
+	ADMIN_PERM_WITHDRAW_CARD   = "withdraw.card"   // 用户提现卡修改
+	ADMIN_PERM_RISK_MANAGE     = "risk.manage"     // 提现风控规则和日志
+	ADMIN_PERM_PERIOD_VIEW     = "period.view"     // 查看期号和下注
+	ADMIN_PERM_PERIOD_OVERRIDE = "period.override" // 修改开奖号码和房间限额
+	ADMIN_PERM_USER_EDIT       = "user.edit"       // 修改用户信息
+	ADMIN_PERM_USER_SESSION    = "user.session"    // 用户会话查看和强制下线
+	ADMIN_PERM_PC_CHANGE       = "pc.change"       // 变更PC号
+	ADMIN_PERM_AGENT_MANAGE    = "agent.manage"    // 代理返利/统计/佣金方案
+	ADMIN_PERM_PROMO_MANAGE    = "promo.manage"    // 红包/充值活动/VIP/自返
+	ADMIN_PERM_GAME_MANAGE     = "game.manage"     // 游戏和游戏合集
+	ADMIN_PERM_GAMING_MANAGE   = "gaming.manage"   // 负责任博彩限制
+	ADMIN_PERM_STATS_MANAGE    = "stats.manage"    // 投注记录补录和对账
+	ADMIN_PERM_SYSTEM_MANAGE   = "system.manage"   // 短信/打码量等系统配置
+)
+
+// 后台操作复核 敏感操作先建待复核单 由另一个有同样权限的管理员通过后执行
+const (
+	ADMIN_APPROVAL_STATUS_PENDING  = 1 // 待复核
+	ADMIN_APPROVAL_STATUS_RUNNING  = 2 // 已通过 执行中
+	ADMIN_APPROVAL_STATUS_EXECUTED = 3 // 执行成功
+	ADMIN_APPROVAL_STATUS_FAILED   = 4 // 执行失败
+	ADMIN_APPROVAL_STATUS_REJECTED = 5 // 已驳回/撤回
+	ADMIN_APPROVAL_STATUS_EXPIRED  = 6 // 超时未复核
+
+	ADMIN_APPROVAL_EXPIRE             = 60 * 30 // 复核时限 秒
+	ADMIN_APPROVAL_WITHDRAW_THRESHOLD = 50000   // 提现批准金额达到此值需要复核
+
+	ADMIN_APPROVAL_ACTION_WINGO_PERIOD    = "wingo.change-period-number"
+	ADMIN_APPROVAL_ACTION_NINE_PERIOD     = "nine.change-period-number"
+	ADMIN_APPROVAL_ACTION_CHANGE_PC       = "admin.call-change-pc"
+	ADMIN_APPROVAL_ACTION_INVITE_RELATION = "agent.fix-invite-relation"
+	ADMIN_APPROVAL_ACTION_RED_ENVELOPE    = "activity.add-red-envelope"
+	ADMIN_APPROVAL_ACTION_EDIT_BALANCE    = "user.edit-balance"
+	ADMIN_APPROVAL_ACTION_WITHDRAW        = "withdraw.approve"
+)
+
+// 数据归档 按月把旧数据搬到 表名_200601 归档表 复制->校验->删除 每步都记录进度可续跑
+const (
+	ARCHIVE_STATUS_COPYING   = 1 // 复制中
+	ARCHIVE_STATUS_VERIFYING = 2 // 校验中
+	ARCHIVE_STATUS_DELETING  = 3 // 删除源数据中
+	ARCHIVE_STATUS_DONE      = 4 // 完成
+	ARCHIVE_STATUS_FAILED    = 5 // 失败 下次执行从复制续跑
+
+	ARCHIVE_KEEP_MONTHS    = 2    // 在线表保留的月数 含当月 上月数据还要用于佣金结算
+	ARCHIVE_BATCH_SIZE     = 5000 // 每批复制/删除的行数
+	ARCHIVE_BATCH_INTERVAL = 100  // 批次间隔 毫秒 减少锁表和主从延迟
+)
+
+// 定时任务 定义在数据库 多节点通过redis租约保证每次触发只有一个节点执行
+const (
+	SCHEDULER_JOB_STATUS_ENABLED = 1 // 启用
+	SCHEDULER_JOB_STATUS_PAUSED  = 2 // 暂停
+
+	SCHEDULER_RUN_STATUS_RUNNING = 1 // 执行中
+	SCHEDULER_RUN_STATUS_SUCCESS = 2 // 成功
+	SCHEDULER_RUN_STATUS_FAILED  = 3 // 失败 panic
+
+	SCHEDULER_TRIGGER_CRON    = "cron"    // 按时间表触发
+	SCHEDULER_TRIGGER_CATCHUP = "catchup" // 补跑错过的触发
+	SCHEDULER_TRIGGER_MANUAL  = "manual"  // 后台手动触发
+
+	SCHEDULER_TICK         = 5  // 调度检查间隔 秒
+	SCHEDULER_LEASE_EXPIRE = 60 // 执行租约有效期 秒 执行期间定时续期
+	SCHEDULER_MISFIRE      = 60 // 触发时间过去超过该秒数视为错过
+)
+
+// 队列发件箱 消息和业务数据在同一个事务写入 提交后由中继投递到asynq 至少一次
+const (
+	OUTBOX_STATUS_PENDING   = 1 // 待投递
+	OUTBOX_STATUS_PUBLISHED = 2 // 已投递
+	OUTBOX_STATUS_FAILED    = 3 // 超过重试次数 需要人工处理
+
+	OUTBOX_RELAY_INTERVAL = 1000  // 中继轮询间隔 毫秒 本节点提交事务后会立即唤醒
+	OUTBOX_BATCH_SIZE     = 200   // 每批投递条数
+	OUTBOX_LOCK_EXPIRE    = 30    // 中继锁有效期 秒 同一时间只有一个节点投递
+	OUTBOX_MAX_ATTEMPTS   = 20    // 投递失败的最大重试次数
+	OUTBOX_MAX_BACKOFF    = 300   // 投递失败的最大退避 秒
+	OUTBOX_RETENTION      = 86400 // asynq保留已完成任务的时间 秒 期间同一去重键不会重复入队
+	OUTBOX_KEEP_DAYS      = 7     // 已投递消息保留天数
+)
+
+// 队列死信 重试耗尽的任务在asynq的archived里 后台和命令行查看/修改/重放/丢弃
+const (
+	DLQ_STATE_ARCHIVED = "archived" // 重试耗尽
+	DLQ_STATE_RETRY    = "retry"    // 失败等待重试
+
+	DLQ_SCAN_LIMIT = 5000 // 按类型筛选时每个队列最多扫描的任务数
+
+	DLQ_STATS_EXPIRE   = 7200 // 每分钟成功/失败计数保留时间 秒
+	DLQ_ALERT_WINDOW   = 10   // 失败率统计窗口 分钟
+	DLQ_ALERT_MIN_FAIL = 20   // 窗口内失败次数达到才告警
+	DLQ_ALERT_RATE     = 0.2  // 窗口内失败率达到才告警
+	DLQ_ALERT_COOLDOWN = 1800 // 同一类型告警间隔 秒
+)
+
+// 领域事件 经发件箱写入redis stream 每个订阅者一个消费组 可以从指定时间重放
+const (
+	EVENT_STREAM_MAXLEN    = 200000 // 每个事件stream保留的条数 近似裁剪
+	EVENT_APPENDED_EXPIRE  = 86400  // 事件ID去重保留时间 秒
+	EVENT_BATCH_SIZE       = 100    // 每次读取条数
+	EVENT_BLOCK            = 5000   // 读取阻塞时间 毫秒
+	EVENT_CLAIM_IDLE       = 60     // 处理失败未确认的事件超过该秒数重新认领
+	EVENT_CLAIM_INTERVAL   = 30     // 检查未确认事件的间隔 秒
+	EVENT_MAX_DELIVERY     = 10     // 超过投递次数的事件确认丢弃并记录日志
+	EVENT_STATS_EXPIRE     = 90     // 每日事件统计保留天数
+	EVENT_ALERT_DEPOSIT    = 50000  // 大额充值告警金额
+	EVENT_ALERT_WITHDRAWAL = 50000  // 大额提现申请告警金额
 )
 
+// 提现风控规则编码
 const (
-	RED_GET_TYPE_NORMAL = 1
+	WITHDRAW_RISK_RULE_TURNOVER     = "turnover"     // 充值后投注倍数不足 阈值为倍数
+	WITHDRAW_RISK_RULE_ACCOUNT_AGE  = "account_age"  // 新注册账号 阈值为小时
+	WITHDRAW_RISK_RULE_SHARED_IP    = "shared_ip"    // IP/设备被其他账号共用 阈值为账号数
+	WITHDRAW_RISK_RULE_WIN_STREAK   = "win_streak"   // 连赢局数异常 阈值为局数
+	WITHDRAW_RISK_RULE_CARD_REUSE   = "card_reuse"   // 银行卡被其他UID使用过 阈值为UID数
+	WITHDRAW_RISK_RULE_AUTO_APPROVE = "auto_approve" // 自动通过的最大金额 关闭则全部人工审核
+)
+
+const (
+	RED_GET_TYPE_NORMAL = 1 // 普通红包 每人固定金额
+	RED_GET_TYPE_LUCKY  = 2 // 拼手气红包 总金额随机拆分
 )
 
 const (
@@ -270,6 +507,9 @@ const (
 	GameNameCrash string = "Crash"
 	GameNameMine  string = "Mine"
 	GameNameDice  string = "Dice"
+	GameNameWingo string = "Wingo"
+	GameNameNine  string = "Nine"
+	GameNameHash  string = "Hash"
 )
 
 const (
@@ -281,3 +521,84 @@ const (
 	ERC20 = "ERC20"
 	BTC   = "BTC"
 )
+
+// 三方游戏记录同步
+const (
+	RECORD_SYNC_STATUS_RUNNING  = 0 // 同步中
+	RECORD_SYNC_STATUS_DONE     = 1 // 完成
+	RECORD_SYNC_STATUS_FAILED   = 2 // 失败
+	RECORD_SYNC_STATUS_MISMATCH = 3 // 与三方总数不一致
+
+	RECORD_SYNC_TRIGGER_AUTO     = "auto"
+	RECORD_SYNC_TRIGGER_BACKFILL = "backfill"
+
+	RECORD_SYNC_GAP_UNCOVERED = "uncovered"
+	RECORD_SYNC_GAP_MISMATCH  = "mismatch"
+)
+
+// 三方转账对账
+const (
+	RECONCILE_STATUS_OK          = 1 // 一致
+	RECONCILE_STATUS_DISCREPANCY = 2 // 有差异
+	RECONCILE_STATUS_FAILED      = 3 // 失败
+
+	RECONCILE_TYPE_ORPHAN_DEBIT     = "orphan_debit"     // 有扣款没有投注记录
+	RECONCILE_TYPE_ORPHAN_CREDIT    = "orphan_credit"    // 有加款没有扣款也没有投注记录
+	RECONCILE_TYPE_MISSING_TRANSFER = "missing_transfer" // 有投注记录没有转账
+	RECONCILE_TYPE_AMOUNT_MISMATCH  = "amount_mismatch"  // 金额不一致
+)
+
+// 我的投注记录
+const (
+	BET_RESULT_WIN     = "win"     // 赢
+	BET_RESULT_LOSE    = "lose"    // 输
+	BET_RESULT_PENDING = "pending" // 未结算
+	BET_RESULT_CANCEL  = "cancel"  // 已取消
+
+	BET_SOURCE_PROVIDER = "provider" // 三方游戏记录
+)
+
+// 负责任博彩
+const (
+	GAMING_LIMIT_TYPE_DEPOSIT = "deposit" // 充值限额
+	GAMING_LIMIT_TYPE_LOSS    = "loss"    // 输钱限额
+	GAMING_LIMIT_TYPE_WAGER   = "wager"   // 投注限额
+
+	GAMING_LIMIT_PERIOD_DAY   = "day"
+	GAMING_LIMIT_PERIOD_WEEK  = "week"
+	GAMING_LIMIT_PERIOD_MONTH = "month"
+
+	GAMING_LIMIT_SOURCE_USER     = "user"     // 用户自己设置
+	GAMING_LIMIT_SOURCE_OPERATOR = "operator" // 运营设置
+)
+
+// 自返
+const (
+	CASHBACK_MODE_TURNOVER = "turnover" // 按有效投注
+	CASHBACK_MODE_LOSS     = "loss"     // 按净输
+
+	CASHBACK_VIP_LEVEL_ANY = -1 // 规则不限VIP等级
+)
+
+// 代理统计周期
+const (
+	AGENT_STATS_PERIOD_TODAY      = "today"
+	AGENT_STATS_PERIOD_YESTERDAY  = "yesterday"
+	AGENT_STATS_PERIOD_7D         = "7d"
+	AGENT_STATS_PERIOD_30D        = "30d"
+	AGENT_STATS_PERIOD_MONTH      = "month"
+	AGENT_STATS_PERIOD_LAST_MONTH = "last_month"
+)
+
+// 代理佣金方案
+const (
+	COMMISSION_TYPE_TURNOVER = "turnover" // 按团队有效投注
+	COMMISSION_TYPE_REVSHARE = "revshare" // 按团队净输分成 负数结转
+	COMMISSION_TYPE_CPA      = "cpa"      // 按合格首充人数
+
+	COMMISSION_PERIOD_WEEK  = "week"
+	COMMISSION_PERIOD_MONTH = "month"
+
+	COMMISSION_STATEMENT_PENDING = 0
+	COMMISSION_STATEMENT_PAID    = 1
+)
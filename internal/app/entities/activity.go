@@ -31,14 +31,27 @@ type PinduoRecord struct {
 // 红包设置表
 type HongbaoSetting struct {
 	BaseModel     `json:"-"`
-	Name          string  `gorm:"column:name;size:32;;unique"`                //红包名
-	Amount        float64 `gorm:"column:amount;type:decimal(10,2);default:0"` //红包金额
-	Number        uint    `gorm:"column:number;default:0"`                    //红包数量
-	ReceiveNumber uint    `gorm:"column:receive_number;default:0"`            //接收数目
-	Remark        string  `gorm:"column:remark"`                              //备注
-	Type          uint8   `gorm:"column:type;default:0"`                      //领取类型
-	SYSUID        uint    `gorm:"column:sysuid;default:0"`                    //添加人
-	Status        uint8   `gorm:"column:status;default:0"`                    //状态
+	Name          string  `gorm:"column:name;size:32;;unique"`                      //红包名
+	Amount        float64 `gorm:"column:amount;type:decimal(10,2);default:0"`       //红包金额
+	Number        uint    `gorm:"column:number;default:0"`                          //红包数量
+	ReceiveNumber uint    `gorm:"column:receive_number;default:0"`                  //接收数目
+	Remark        string  `gorm:"column:remark"`                                    //备注
+	Type          uint8   `gorm:"column:type;default:0"`                            //领取类型
+	SYSUID        uint    `gorm:"column:sysuid;default:0"`                          //添加人
+	Status        uint8   `gorm:"column:status;default:0"`                          //状态
+	ExpireTime    int64   `gorm:"column:expire_time;default:0"`                     //过期时间 0为创建后24小时
+	MinRecharge   float64 `gorm:"column:min_recharge;type:decimal(10,2);default:0"` //领取条件 累计充值
+	MinVipLevel   uint8   `gorm:"column:min_vip_level;default:0"`                   //领取条件 VIP等级
+	Channel       string  `gorm:"column:channel;size:20"`                           //领取条件 注册渠道 空不限
+	ChatChannel   string  `gorm:"column:chat_channel;size:32"`                      //投放的聊天频道
+}
+
+// 拼手气红包预分配的份额 创建红包时一次性生成 领取时按序号依次发放
+type HongbaoShare struct {
+	BaseModel
+	HongID uint    `gorm:"column:hong_id;default:0;uniqueIndex:idx_hong_seq"` //红包ID
+	Seq    uint    `gorm:"column:seq;default:0;uniqueIndex:idx_hong_seq"`     //序号 从1开始
+	Amount float64 `gorm:"column:amount;type:decimal(10,2);default:0"`        //金额
 }
 
 // 红包设置表
@@ -110,13 +123,28 @@ type GetRedEnvelopeReq struct {
 }
 
 type AddRedEnvelopeReq struct {
-	UID      uint
-	Remark   string  `json:"remark"`
-	Type     uint8   `json:"type"`
-	Amount   float64 `json:"amount"`   //红包金额
-	Number   uint    `json:"number"`   //红包数量
-	OptionID uint    `json:"optionID"` //添加人
-	IP       string
+	UID         uint
+	Remark      string  `json:"remark"`
+	Type        uint8   `json:"type"`
+	Amount      float64 `json:"amount"`      //红包金额 拼手气红包为总金额
+	Number      uint    `json:"number"`      //红包数量
+	ExpireTime  int64   `json:"expireTime"`  //过期时间 0为24小时后
+	MinRecharge float64 `json:"minRecharge"` //领取条件 累计充值
+	MinVipLevel uint8   `json:"minVipLevel"` //领取条件 VIP等级
+	Channel     string  `json:"channel"`     //领取条件 注册渠道
+	ChatChannel string  `json:"chatChannel"` //投放的聊天频道 空不投放
+	OptionID    uint    `json:"optionID"`    //添加人
+	IP          string
+}
+
+// 红包投放到聊天频道的消息内容
+type RedEnvelopeChatContent struct {
+	Name       string  `json:"name"`
+	Type       uint8   `json:"type"`
+	Amount     float64 `json:"amount"`
+	Number     uint    `json:"number"`
+	ExpireTime int64   `json:"expireTime"`
+	Remark     string  `json:"remark"`
 }
 
 type DelRedEnvelopeReq struct {
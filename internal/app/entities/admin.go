@@ -104,12 +104,6 @@ type SysUserAdmin struct {
 	TelegramCustomer string `gorm:"column:telegram_customer;"`
 }
 
-type MonthBackupAndCleaReq struct {
-	TableNames string `json:"tableNames"`
-
-	OptionID uint `json:"optionID"`
-	IP       string
-}
 type CallChangePCReq struct {
 	SRC uint `json:"pcSRC"`
 	DST uint `json:"pcDST"`
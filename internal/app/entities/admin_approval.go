@@ -0,0 +1,45 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 后台操作复核单 发起人和复核人都记录在单上 Payload为原接口的请求参数
+type AdminApproval struct {
+	BaseModel
+	Action     string `gorm:"size:64;index" json:"action"`
+	Permission string `gorm:"size:32" json:"permission"` // 复核人需要具备的权限
+	Payload    string `gorm:"type:text" json:"payload"`
+	MakerID    uint   `gorm:"index" json:"maker_id"`
+	MakerIP    string `gorm:"size:40" json:"maker_ip"`
+	CheckerID  uint   `gorm:"default:0" json:"checker_id"`
+	CheckerIP  string `gorm:"size:40" json:"checker_ip"`
+	CheckTime  int64  `gorm:"default:0" json:"check_time"`
+	Status     uint8  `gorm:"default:1;index" json:"status"` // 1待复核 2执行中 3执行成功 4执行失败 5驳回 6超时
+	ExpireTime int64  `gorm:"default:0" json:"expire_time"`
+	Result     string `gorm:"size:512" json:"result"` // 执行失败原因
+	Remark     string `gorm:"size:255" json:"remark"` // 复核备注
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 敏感接口不再直接执行 返回待复核单
+type AdminApprovalResp struct {
+	ApprovalID uint   `json:"approval_id"`
+	Action     string `json:"action"`
+	Status     uint8  `json:"status"`
+	ExpireTime int64  `json:"expire_time"`
+}
+
+type GetAdminApprovalListReq struct {
+	Paginator
+	Status  uint8  `json:"status"`
+	Action  string `json:"action"`
+	MakerID uint   `json:"maker_id"`
+}
+
+// 通过/驳回 发起人自己驳回即撤回
+type CheckAdminApprovalReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	Remark   string `json:"remark"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
@@ -0,0 +1,104 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 后台角色 权限为逗号分隔的权限编码
+type AdminRole struct {
+	BaseModel
+	Name        string `gorm:"size:32;uniqueIndex" json:"name"`
+	Permissions string `gorm:"type:text" json:"permissions"`
+	Remark      string `gorm:"size:255" json:"remark"`
+}
+
+// 后台账号 UID对应sys_user的uid 操作日志里的操作者ID沿用它 谷歌验证码也按sys_user校验
+type AdminUser struct {
+	BaseModel
+	UID           uint   `gorm:"column:uid;uniqueIndex" json:"uid"`
+	Username      string `gorm:"size:50;uniqueIndex" json:"username"`
+	Password      string `gorm:"size:100" json:"-"`
+	RoleID        uint   `gorm:"index" json:"role_id"`
+	Status        uint8  `gorm:"default:1" json:"status"` // 1正常 2禁用
+	LastLoginTime int64  `gorm:"default:0" json:"last_login_time"`
+	LastLoginIP   string `gorm:"size:40" json:"last_login_ip"`
+}
+
+// 后台登录会话 有效性以redis为准 表里留作审计
+type AdminSession struct {
+	BaseModel
+	SessionID    string `gorm:"column:session_id;size:40;uniqueIndex" json:"session_id"`
+	UID          uint   `gorm:"column:uid;index" json:"uid"`
+	IP           string `gorm:"size:40" json:"ip"`
+	UserAgent    string `gorm:"size:255" json:"user_agent"`
+	ExpireTime   int64  `gorm:"default:0" json:"expire_time"`
+	Status       uint8  `gorm:"default:1" json:"status"` // 1有效 2已退出/撤销
+	RevokeReason string `gorm:"size:32" json:"revoke_reason"`
+	RevokeTime   int64  `gorm:"default:0" json:"revoke_time"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type AdminLoginReq struct {
+	Username  string `json:"username" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	AuthCode  string `json:"auth_code" binding:"required"` // 谷歌验证码
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type AdminLoginResp struct {
+	Token       string   `json:"token"`
+	ExpireTime  int64    `json:"expire_time"`
+	UID         uint     `json:"uid"`
+	Username    string   `json:"username"`
+	Permissions []string `json:"permissions"`
+}
+
+type AdminProfile struct {
+	User        *AdminUser `json:"user"`
+	Role        *AdminRole `json:"role"`
+	Permissions []string   `json:"permissions"`
+}
+
+type SaveAdminRoleReq struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name" binding:"required"`
+	Permissions []string `json:"permissions"`
+	Remark      string   `json:"remark"`
+	OptionID    uint     `json:"-"`
+	IP          string   `json:"-"`
+}
+
+type DelAdminRoleReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+// 新建时账号需要在sys_user中存在 修改时密码为空则不修改
+type SaveAdminUserReq struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password"`
+	RoleID   uint   `json:"role_id" binding:"required"`
+	Status   uint8  `json:"status"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type BootstrapAdminUserReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	IP       string `json:"-"`
+}
+
+type RevokeAdminSessionReq struct {
+	UID       uint   `json:"uid" binding:"required"`
+	SessionID string `json:"session_id"` // 为空时撤销该账号全部会话
+	OptionID  uint   `json:"-"`
+	IP        string `json:"-"`
+}
+
+type GetAdminSessionListReq struct {
+	Paginator
+	UID uint `json:"uid"`
+}
@@ -102,3 +102,19 @@ type FinalizeRechargeReturnReq struct {
 	OptionID uint `json:"optionID"` // SysUID refers to the system user ID associated with the request.
 	IP       string
 }
+
+// 邀请关系迁移的影响 迁移前可以先预览
+type InviteMovePreview struct {
+	UID                  uint                  `json:"uid"`
+	OldPID               uint                  `json:"old_pid"`
+	NewPID               uint                  `json:"new_pid"`
+	Promoter             string                `json:"promoter"` // 迁移后的推广信息
+	PromoterCode         int                   `json:"pc"`
+	Inviter              string                `json:"inviter"`
+	AffectedUIDs         []uint                `json:"affected_uids"` // 需要重建上级关系的用户 包括自己和下级
+	InsertCount          int                   `json:"insert_count"`  // 重建后新增的上级关系数
+	Before               []*HallInviteRelation `json:"before"`        // 迁移用户当前的上级关系
+	After                []*HallInviteRelation `json:"after"`
+	OldParentInviteCount int64                 `json:"old_parent_invite_count"` // 迁移后原上级的直属人数
+	NewParentInviteCount int64                 `json:"new_parent_invite_count"` // 迁移后新上级的直属人数
+}
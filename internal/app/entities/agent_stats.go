@@ -0,0 +1,92 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 代理团队每日统计 按上级和下级所在层级汇总 当天的每小时刷新 前一天在凌晨定稿
+type AgentDailyStats struct {
+	BaseModel
+	PID                uint    `gorm:"column:pid;uniqueIndex:idx_pid_date_level,priority:1" json:"pid"`
+	Date               int     `gorm:"uniqueIndex:idx_pid_date_level,priority:2;index" json:"date"` // 20060102
+	Level              uint8   `gorm:"uniqueIndex:idx_pid_date_level,priority:3" json:"level"`
+	NewMembers         int     `gorm:"default:0" json:"new_members"`                             // 新注册
+	FirstDeposits      int     `gorm:"default:0" json:"first_deposits"`                          // 首充人数
+	FirstDepositAmount float64 `gorm:"type:decimal(20,2);default:0" json:"first_deposit_amount"` // 首充用户当天的充值
+	DepositAmount      float64 `gorm:"type:decimal(20,2);default:0" json:"deposit_amount"`       // 充值成功
+	WithdrawAmount     float64 `gorm:"type:decimal(20,2);default:0" json:"withdraw_amount"`      // 打款成功
+	Turnover           float64 `gorm:"type:decimal(20,2);default:0" json:"turnover"`             // 有效投注
+	Commission         float64 `gorm:"type:decimal(20,3);default:0" json:"commission"`           // 上级从该层级获得的返利
+}
+
+// 代理团队每日按游戏分类统计 返利按层级返利比例从有效投注折算
+type AgentDailyCategoryStats struct {
+	BaseModel
+	PID        uint    `gorm:"column:pid;uniqueIndex:idx_pid_date_category,priority:1" json:"pid"`
+	Date       int     `gorm:"uniqueIndex:idx_pid_date_category,priority:2;index" json:"date"`
+	Category   string  `gorm:"size:50;uniqueIndex:idx_pid_date_category,priority:3" json:"category"`
+	Turnover   float64 `gorm:"type:decimal(20,2);default:0" json:"turnover"`
+	Commission float64 `gorm:"type:decimal(20,3);default:0" json:"commission"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type AgentLevelStats struct {
+	Level              uint8   `json:"level"` // 0为合计
+	NewMembers         int     `json:"new_members"`
+	FirstDeposits      int     `json:"first_deposits"`
+	FirstDepositAmount float64 `json:"first_deposit_amount"`
+	DepositAmount      float64 `json:"deposit_amount"`
+	WithdrawAmount     float64 `json:"withdraw_amount"`
+	Turnover           float64 `json:"turnover"`
+	Commission         float64 `json:"commission"`
+}
+
+type AgentCategoryStats struct {
+	Category   string  `json:"category"`
+	Turnover   float64 `json:"turnover"`
+	Commission float64 `json:"commission"`
+}
+
+type AgentTeamSize struct {
+	Level uint8 `json:"level"`
+	Count int64 `json:"count"`
+}
+
+type AgentDashboard struct {
+	Period     string                `json:"period"`
+	StartDate  int                   `json:"start_date"`
+	EndDate    int                   `json:"end_date"`
+	TeamSize   []*AgentTeamSize      `json:"team_size"` // 当前各层级人数
+	Total      *AgentLevelStats      `json:"total"`
+	Levels     []*AgentLevelStats    `json:"levels"`
+	Categories []*AgentCategoryStats `json:"categories"`
+}
+
+// 下级成员 统计为所选周期内的数据
+type AgentDownline struct {
+	UID           uint    `json:"uid"`
+	Level         uint8   `json:"level"`
+	Mobile        string  `json:"mobile"`
+	Nickname      string  `json:"nickname"`
+	RegisterTime  int64   `json:"register_time"`
+	DepositAmount float64 `json:"deposit_amount"`
+	Turnover      float64 `json:"turnover"`
+	Commission    float64 `json:"commission"` // 该成员为上级产生的返利
+}
+
+type GetAgentDashboardReq struct {
+	UID    uint   `json:"uid"`    // 后台查看指定代理 前台为自己
+	Period string `json:"period"` // today yesterday 7d 30d month last_month
+}
+
+type GetAgentDownlineListReq struct {
+	Paginator
+	UID    uint   `json:"uid"`
+	Level  uint8  `json:"level"` // 0为全部层级
+	Period string `json:"period"`
+}
+
+type AggregateAgentStatsReq struct {
+	Date     int    `json:"date" binding:"required"` // 20060102
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
@@ -0,0 +1,40 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 归档任务 一张表一个月一条 MaxID和源数据合计在创建时取快照 校验以快照为准
+type ArchiveJob struct {
+	BaseModel
+	SourceTable  string  `gorm:"size:64;uniqueIndex:idx_table_month" json:"source_table"`
+	Month        string  `gorm:"size:6;uniqueIndex:idx_table_month" json:"month"` // 200601
+	ArchiveTable string  `gorm:"size:72" json:"archive_table"`
+	StartTime    int64   `gorm:"default:0" json:"start_time"` // created_at范围 包含开始不包含结束
+	EndTime      int64   `gorm:"default:0" json:"end_time"`
+	MaxID        uint    `gorm:"default:0" json:"max_id"`
+	LastID       uint    `gorm:"default:0" json:"last_id"` // 已复制到的ID
+	SourceCount  int64   `gorm:"default:0" json:"source_count"`
+	SourceSum    float64 `gorm:"type:decimal(20,3);default:0" json:"source_sum"`
+	ArchiveCount int64   `gorm:"default:0" json:"archive_count"`
+	ArchiveSum   float64 `gorm:"type:decimal(20,3);default:0" json:"archive_sum"`
+	DeletedCount int64   `gorm:"default:0" json:"deleted_count"`
+	Status       uint8   `gorm:"default:1;index" json:"status"` // 1复制 2校验 3删除 4完成 5失败
+	Error        string  `gorm:"size:512" json:"error"`
+	FinishTime   int64   `gorm:"default:0" json:"finish_time"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 表名为空时归档全部支持的表 月份为空时取保留期之前的一个月
+type RunArchiveReq struct {
+	Tables   []string `json:"tables"`
+	Month    string   `json:"month"`
+	OptionID uint     `json:"-"`
+	IP       string   `json:"-"`
+}
+
+type GetArchiveJobListReq struct {
+	Paginator
+	SourceTable string `json:"source_table"`
+	Month       string `json:"month"`
+	Status      uint8  `json:"status"`
+}
@@ -0,0 +1,66 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 自返规则 渠道 > VIP等级 > 通用 分类精确匹配优先于全部分类
+// 都没有匹配时按VIP等级配置的RakebackRate按投注返
+type CashbackRule struct {
+	BaseModel
+	Category     string  `gorm:"size:32;index" json:"category"`           // 游戏分类 空表示全部
+	VipLevel     int     `gorm:"not null" json:"vip_level"`               // -1不限
+	PromoterCode int     `gorm:"column:pc;default:0;index" json:"pc"`     // 0不限
+	Mode         string  `gorm:"size:16" json:"mode"`                     // turnover按投注 loss按净输
+	Rate         float64 `gorm:"type:decimal(6,4);default:0" json:"rate"` // 百分比
+	Status       uint8   `gorm:"default:0" json:"status"`                 // 0关闭 1开启
+	Remark       string  `gorm:"size:128" json:"remark"`
+}
+
+// 用户各分类累计的自返 领取时扣减 净输为负(赢钱)的分类结转到下次
+type UserCashback struct {
+	BaseModel
+	UID          uint    `gorm:"column:uid;uniqueIndex:idx_uid_category" json:"uid"`
+	Category     string  `gorm:"size:32;uniqueIndex:idx_uid_category" json:"category"`
+	Turnover     float64 `gorm:"type:decimal(20,2);default:0" json:"turnover"`      // 上次领取后的投注
+	NetLoss      float64 `gorm:"type:decimal(20,2);default:0" json:"net_loss"`      // 上次领取后的净输
+	Amount       float64 `gorm:"type:decimal(20,4);default:0" json:"amount"`        // 待领取 按净输时可能为负
+	TotalClaimed float64 `gorm:"type:decimal(20,2);default:0" json:"total_claimed"` // 累计已领取
+}
+
+type CashbackClaim struct {
+	BaseModel
+	UID       uint    `gorm:"column:uid;index" json:"uid"`
+	Amount    float64 `gorm:"type:decimal(20,2);default:0" json:"amount"`
+	Detail    string  `gorm:"type:text" json:"detail"` // 各分类领取明细
+	ClaimTime int64   `gorm:"default:0" json:"claim_time"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type CashbackInfo struct {
+	Amount float64         `json:"amount"` // 当前可领取
+	List   []*UserCashback `json:"list"`
+}
+
+type SaveCashbackRuleReq struct {
+	ID           uint    `json:"id"`
+	Category     string  `json:"category"`
+	VipLevel     int     `json:"vip_level"`
+	PromoterCode int     `json:"pc"`
+	Mode         string  `json:"mode" binding:"required"`
+	Rate         float64 `json:"rate"`
+	Status       uint8   `json:"status"`
+	Remark       string  `json:"remark"`
+	OptionID     uint    `json:"-"`
+	IP           string  `json:"-"`
+}
+
+type DelCashbackRuleReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type GetCashbackClaimListReq struct {
+	Paginator
+	UID uint `json:"-"`
+}
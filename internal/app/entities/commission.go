@@ -0,0 +1,98 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 代理佣金方案 按投注/按净输分成/按合格首充(CPA) 周结或月结
+type CommissionPlan struct {
+	BaseModel
+	Name          string  `gorm:"size:32" json:"name"`
+	Type          string  `gorm:"size:16" json:"type"`                                 // turnover revshare cpa
+	Period        string  `gorm:"size:8" json:"period"`                                // week month
+	Rate          float64 `gorm:"type:decimal(6,4);default:0" json:"rate"`             // 投注/净输分成比例 百分比
+	CpaAmount     float64 `gorm:"type:decimal(10,2);default:0" json:"cpa_amount"`      // 每个合格首充的佣金
+	CpaMinDeposit float64 `gorm:"type:decimal(10,2);default:0" json:"cpa_min_deposit"` // 首充当期累计充值达到才算合格
+	MaxLevel      uint8   `gorm:"default:0" json:"max_level"`                          // 计入的下级层级 0为全部
+	Status        uint8   `gorm:"default:0" json:"status"`                             // 0关闭 1开启
+}
+
+// 方案分配 指定代理优先于渠道
+type CommissionPlanAssign struct {
+	BaseModel
+	PlanID       uint `gorm:"index" json:"plan_id"`
+	UID          uint `gorm:"column:uid;uniqueIndex:idx_uid_pc" json:"uid"` // 0表示按渠道
+	PromoterCode int  `gorm:"column:pc;uniqueIndex:idx_uid_pc" json:"pc"`   // 渠道下的所有代理
+}
+
+// 佣金结算单 每个代理每期一条
+type CommissionStatement struct {
+	BaseModel
+	UID           uint    `gorm:"column:uid;uniqueIndex:idx_uid_period" json:"uid"`
+	Period        string  `gorm:"size:16;uniqueIndex:idx_uid_period" json:"period"` // 2006W01 或 200601
+	PlanID        uint    `json:"plan_id"`
+	PlanType      string  `gorm:"size:16" json:"plan_type"`
+	StartTime     int64   `json:"start_time"`
+	EndTime       int64   `json:"end_time"`
+	Turnover      float64 `gorm:"type:decimal(20,2);default:0" json:"turnover"`          // 团队有效投注
+	NetRevenue    float64 `gorm:"type:decimal(20,2);default:0" json:"net_revenue"`       // 团队净输 本期
+	CarryIn       float64 `gorm:"type:decimal(20,2);default:0" json:"carry_in"`          // 上期结转的负数
+	CarryOut      float64 `gorm:"type:decimal(20,2);default:0" json:"carry_out"`         // 结转到下期的负数
+	QualifiedFTDs int     `gorm:"column:qualified_ftds;default:0" json:"qualified_ftds"` // 合格首充人数
+	Rate          float64 `gorm:"type:decimal(6,4);default:0" json:"rate"`
+	Amount        float64 `gorm:"type:decimal(20,2);default:0" json:"amount"` // 佣金
+	Status        uint8   `gorm:"default:0" json:"status"`                    // 0待发放 1已发放
+	PaidTime      int64   `gorm:"default:0" json:"paid_time"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type SaveCommissionPlanReq struct {
+	ID            uint    `json:"id"`
+	Name          string  `json:"name" binding:"required"`
+	Type          string  `json:"type" binding:"required"`
+	Period        string  `json:"period" binding:"required"`
+	Rate          float64 `json:"rate"`
+	CpaAmount     float64 `json:"cpa_amount"`
+	CpaMinDeposit float64 `json:"cpa_min_deposit"`
+	MaxLevel      uint8   `json:"max_level"`
+	Status        uint8   `json:"status"`
+	OptionID      uint    `json:"-"`
+	IP            string  `json:"-"`
+}
+
+type DelCommissionPlanReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type SaveCommissionPlanAssignReq struct {
+	PlanID       uint   `json:"plan_id" binding:"required"`
+	UID          uint   `json:"uid"`
+	PromoterCode int    `json:"pc"`
+	OptionID     uint   `json:"-"`
+	IP           string `json:"-"`
+}
+
+type DelCommissionPlanAssignReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type GetCommissionStatementListReq struct {
+	Paginator
+	UID    uint   `json:"uid"`
+	Period string `json:"period"`
+}
+
+type SettleCommissionReq struct {
+	Period   string `json:"period" binding:"required"` // 2006W01 或 200601
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+// 代理当前适用的方案
+type CommissionPlanInfo struct {
+	Plan   *CommissionPlan `json:"plan"`
+	Source string          `json:"source"` // uid 指定代理 pc 渠道
+}
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"fmt"
 	"rk-api/pkg/math"
 
 	"github.com/shopspring/decimal"
@@ -62,6 +63,11 @@ func (o *CrashGameOrder) CalculateFee() { //抽水处理
 	o.Fee = fee
 }
 
+// 批量结算时订单没有ID 按唯一索引拼出单号
+func (o *CrashGameOrder) EventOrderID() string {
+	return fmt.Sprintf("%d-%d", o.RoundID, o.BetIndex)
+}
+
 type CrashAutoBet struct {
 	BaseModel
 	UID              uint    `gorm:"column:uid;uniqueIndex:idx_uid" json:"uid"`
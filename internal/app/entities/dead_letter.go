@@ -0,0 +1,50 @@
+package entities
+
+// 队列死信 来自asynq的archived/retry 不落库
+type DeadLetterTask struct {
+	ID            string `json:"id"`
+	Queue         string `json:"queue"`
+	Type          string `json:"type"`
+	State         string `json:"state"` // archived重试耗尽 retry等待重试
+	Payload       string `json:"payload"`
+	MaxRetry      int    `json:"max_retry"`
+	Retried       int    `json:"retried"`
+	LastErr       string `json:"last_err"`
+	LastFailedAt  int64  `json:"last_failed_at"`
+	NextProcessAt int64  `json:"next_process_at"`
+}
+
+type GetDeadLetterListReq struct {
+	Paginator
+	State string `json:"state"` // 默认archived
+	Type  string `json:"type"`
+}
+
+type GetDeadLetterReq struct {
+	Queue string `json:"queue" form:"queue" binding:"required"`
+	ID    string `json:"id" form:"id" binding:"required"`
+}
+
+// Payload不为空时按修改后的内容重新入队
+type ReplayDeadLetterReq struct {
+	Queue    string `json:"queue" binding:"required"`
+	ID       string `json:"id" binding:"required"`
+	Payload  string `json:"payload"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type DiscardDeadLetterReq struct {
+	Queue    string `json:"queue" binding:"required"`
+	ID       string `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+// 每个任务类型统计窗口内的处理情况
+type TaskFailureStat struct {
+	Type      string  `json:"type"`
+	Processed int64   `json:"processed"`
+	Failed    int64   `json:"failed"`
+	Rate      float64 `json:"rate"`
+}
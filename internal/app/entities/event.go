@@ -0,0 +1,48 @@
+package entities
+
+import "encoding/json"
+
+// 领域事件 存在redis stream 不落库
+// ID是发件箱的去重键 StreamID是stream里的位置
+type EventEnvelope struct {
+	ID       string          `json:"id"`
+	StreamID string          `json:"stream_id"`
+	Name     string          `json:"name"`
+	Time     int64           `json:"time"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// 消费组在某个事件stream上的进度
+type EventGroupInfo struct {
+	Group           string `json:"group"`
+	Name            string `json:"name"`
+	Pending         int64  `json:"pending"` // 已读取未确认
+	Lag             int64  `json:"lag"`     // 未读取
+	LastDeliveredID string `json:"last_delivered_id"`
+}
+
+// EndID为空从最新开始 翻页时传上一页最后一条的StreamID
+type GetEventListReq struct {
+	Name  string `json:"name" binding:"required"`
+	EndID string `json:"end_id"`
+	Count int64  `json:"count"`
+}
+
+// 把消费组在该事件上的进度重置到StartTime 之后的事件重新投递
+type ReplayEventGroupReq struct {
+	Group     string `json:"group" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+	StartTime int64  `json:"start_time" binding:"required"`
+	OptionID  uint   `json:"-"`
+	IP        string `json:"-"`
+}
+
+type GetEventDailyStatsReq struct {
+	Date string `json:"date"` // 20060102 默认今天
+}
+
+type EventDailyStat struct {
+	Name   string  `json:"name"`
+	Count  int64   `json:"count"`
+	Amount float64 `json:"amount"`
+}
@@ -1,5 +1,7 @@
 package entities
 
+import "rk-api/pkg/search"
+
 //-------------------------------------------------------------------------------------------------------------------------------------------------------------
 
 // ---------------------------------------------------------------------------jhsz---------------------------------------------------------------------------------------------------------------
@@ -23,6 +25,41 @@ type Game struct {
 	ServiceStatus int8    `json:"service_status"`                  // 服务状态
 	Language      string  `gorm:"size:8" json:"language"`          // 游戏支持的语言类型
 	Priority      int     `json:"priority"`                        // 游戏优先级(较高数字表示较高优先级)
+
+	Provider       string  `gorm:"size:16;index" json:"provider"`             // 三方平台 自研游戏为空
+	Tags           string  `gorm:"size:255" json:"tags"`                      // 标签 逗号分隔 如hot,new
+	Regions        string  `gorm:"size:255" json:"regions"`                   // 可见地区 逗号分隔 为空全部可见
+	Languages      string  `gorm:"size:128" json:"languages"`                 // 可见语言 逗号分隔 为空全部可见
+	MaintainStart  int64   `json:"maintain_start"`                            // 计划维护开始时间
+	MaintainEnd    int64   `json:"maintain_end"`                              // 计划维护结束时间
+	RecentTurnover float64 `gorm:"type:decimal(20,2)" json:"recent_turnover"` // 近期投注额
+	RecentPlayers  int     `json:"recent_players"`                            // 近期投注人数
+	LobbyScore     float64 `gorm:"index" json:"lobby_score"`                  // 大厅排序分 由近期投注数据计算
+	LocalizedNames string  `gorm:"size:1024" json:"localized_names"`          // 多语言名称 JSON 如{"en":"Aviator","zh":"飞行员"}
+}
+
+// 游戏合集 大厅专题
+type GameCollection struct {
+	BaseModel
+	Code   string  `gorm:"size:32;uniqueIndex" json:"code"` // 合集编码
+	Name   string  `gorm:"size:64" json:"name"`             // 合集名
+	Sort   int     `json:"sort"`                            // 排序 越大越靠前
+	Status uint8   `gorm:"default:1" json:"status"`         // 1显示 0隐藏
+	Games  []*Game `gorm:"-" json:"games"`
+}
+
+type GameCollectionItem struct {
+	BaseModel
+	CollectionID uint `gorm:"uniqueIndex:idx_collection_game" json:"collection_id"`
+	GameID       uint `gorm:"uniqueIndex:idx_collection_game" json:"game_id"`
+	Sort         int  `json:"sort"` // 合集内排序 越大越靠前
+}
+
+// 游戏近期投注统计 用于计算大厅排序
+type GamePlayStats struct {
+	Game     string  `json:"game"`
+	Turnover float64 `json:"turnover"`
+	Players  int     `json:"players"`
 }
 
 type GameIdentification struct {
@@ -37,8 +74,13 @@ type GameRefresh struct {
 
 type GetGameListReq struct {
 	Paginator
-	Category string `json:"category"`
-	Status   int8   `json:"status"`
+	Category   string `json:"category"`
+	Status     int8   `json:"status"`
+	Provider   string `json:"provider"`
+	Tag        string `json:"tag"`
+	Collection string `json:"collection"` // 合集编码
+	Region     string `json:"region"`
+	Language   string `json:"language"`
 }
 
 type SearchGameReq struct {
@@ -46,6 +88,98 @@ type SearchGameReq struct {
 	Category string `json:"category"`
 	Status   int8   `json:"status"`
 	Name     string `json:"name"` // 游戏名
+	Tag      string `json:"tag"`
+	Region   string `json:"region"`
+	Language string `json:"language"`
+	Provider string `json:"provider"`
+
+	Facets map[string][]search.FacetTerm `json:"-"` // 分类/三方/标签的分面统计
+}
+
+type SearchGameResp struct {
+	*Paginator
+	Facets map[string][]search.FacetTerm `json:"facets"`
+}
+
+type AdminGetGameListReq struct {
+	Paginator
+	Category string `json:"category"`
+	Provider string `json:"provider"`
+	Name     string `json:"name"`
+	IsActive *bool  `json:"is_active"`
+}
+
+type SaveGameReq struct {
+	ID             uint   `json:"id"` // 为0时新增
+	Name           string `json:"name" binding:"required"`
+	Category       string `json:"category" binding:"required"`
+	GameCode       string `json:"game_code" binding:"required"`
+	Provider       string `json:"provider"`
+	IconURL        string `json:"icon_url"`
+	CompanyLogoURL string `json:"company_logo_url"`
+	GameplayType   string `json:"gameplay_type"`
+	Description    string `json:"description"`
+	IsActive       bool   `json:"is_active"`
+	ServiceStatus  int8   `json:"service_status"`
+	Language       string `json:"language"`
+	Priority       int    `json:"priority"`
+	Tags           string `json:"tags"`
+	Regions        string `json:"regions"`
+	Languages      string `json:"languages"`
+	MaintainStart  int64  `json:"maintain_start"`
+	MaintainEnd    int64  `json:"maintain_end"`
+	LocalizedNames string `json:"localized_names"`
+	OptionID       uint   `json:"-"`
+	IP             string `json:"-"`
+}
+
+type DelGameReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+// 批量导入 按provider+game_code新增或更新
+type ImportGameListReq struct {
+	Provider string  `json:"provider" binding:"required"`
+	List     []*Game `json:"list" binding:"required"`
+	OptionID uint    `json:"-"`
+	IP       string  `json:"-"`
+}
+
+type SyncProviderGameListReq struct {
+	Provider string `json:"provider"` // 为空时同步全部三方
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type SaveGameCollectionReq struct {
+	ID       uint   `json:"id"` // 为0时新增
+	Code     string `json:"code" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Sort     int    `json:"sort"`
+	Status   uint8  `json:"status"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type DelGameCollectionReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+// 设置合集内的游戏 按数组顺序排序
+type SetGameCollectionItemsReq struct {
+	CollectionID uint   `json:"collection_id" binding:"required"`
+	GameIDs      []uint `json:"game_ids"`
+	OptionID     uint   `json:"-"`
+	IP           string `json:"-"`
+}
+
+type GetGameCollectionListReq struct {
+	Region   string `json:"region"`
+	Language string `json:"language"`
 }
 
 //---------------------------------------------------------------------------jhsz---------------------------------------------------------------------------------------------------------------
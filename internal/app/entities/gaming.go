@@ -0,0 +1,87 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 负责任博彩限额 用户设置和运营设置分开存储 实际生效的取两者中较小的
+type GamingLimit struct {
+	BaseModel
+	UID           uint    `gorm:"column:uid;uniqueIndex:idx_uid_limit" json:"uid"`
+	Type          string  `gorm:"size:16;uniqueIndex:idx_uid_limit" json:"type"`  // deposit loss wager
+	Period        string  `gorm:"size:8;uniqueIndex:idx_uid_limit" json:"period"` // day week month
+	Source        string  `gorm:"size:8;uniqueIndex:idx_uid_limit" json:"source"` // user operator
+	Amount        float64 `gorm:"type:decimal(20,2);default:0" json:"amount"`     // 当前额度 0为不限制
+	PendingAmount float64 `gorm:"type:decimal(20,2);default:0" json:"pending_amount"`
+	PendingTime   int64   `gorm:"default:0" json:"pending_time"` // 提高/取消额度的生效时间 0为没有待生效的修改
+	Remark        string  `gorm:"size:255" json:"remark"`
+	Usage         float64 `gorm:"-" json:"usage"` // 当前周期已使用
+}
+
+// 用户的冷静期/自我排除/会话提醒设置
+type GamingRestriction struct {
+	BaseModel
+	UID              uint   `gorm:"column:uid;uniqueIndex" json:"uid"`
+	SessionReminder  int    `gorm:"default:0" json:"session_reminder"`   // 会话时长提醒间隔(分钟) 0为不提醒
+	CoolOffUntil     int64  `gorm:"default:0" json:"cool_off_until"`     // 冷静期结束时间
+	SelfExcludeUntil int64  `gorm:"default:0" json:"self_exclude_until"` // 自我排除结束时间
+	ExcludeSource    string `gorm:"size:8" json:"exclude_source"`        // user operator
+	Remark           string `gorm:"size:255" json:"remark"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type GamingStatus struct {
+	Limits         []*GamingLimit     `json:"limits"`
+	Restriction    *GamingRestriction `json:"restriction"`
+	SessionStart   int64              `json:"session_start"`    // 当前会话登录时间
+	SessionMinutes int64              `json:"session_minutes"`  // 当前会话已持续分钟数
+	NextReminderAt int64              `json:"next_reminder_at"` // 下次会话提醒时间 0为不提醒
+}
+
+type GetGamingStatusReq struct {
+	UID       uint   `json:"uid"`
+	SessionID string `json:"-"`
+}
+
+// Amount为0时取消该限额
+type SetGamingLimitReq struct {
+	UID    uint    `json:"-"`
+	Type   string  `json:"type" binding:"required"`
+	Period string  `json:"period" binding:"required"`
+	Amount float64 `json:"amount"`
+}
+
+type AdminSetGamingLimitReq struct {
+	UID      uint    `json:"uid" binding:"required"`
+	Type     string  `json:"type" binding:"required"`
+	Period   string  `json:"period" binding:"required"`
+	Amount   float64 `json:"amount"`
+	Remark   string  `json:"remark"`
+	OptionID uint    `json:"-"`
+	IP       string  `json:"-"`
+}
+
+type SetSessionReminderReq struct {
+	UID     uint `json:"-"`
+	Minutes int  `json:"minutes"` // 0为关闭提醒
+}
+
+type GamingCoolOffReq struct {
+	UID  uint `json:"-"`
+	Days int  `json:"days" binding:"required"`
+}
+
+// Months为0时永久排除
+type GamingSelfExcludeReq struct {
+	UID    uint `json:"-"`
+	Months int  `json:"months"`
+}
+
+// 运营修改冷静期/自我排除 可以提前解除
+type AdminSetGamingRestrictionReq struct {
+	UID              uint   `json:"uid" binding:"required"`
+	CoolOffUntil     int64  `json:"cool_off_until"`
+	SelfExcludeUntil int64  `json:"self_exclude_until"`
+	Remark           string `json:"remark"`
+	OptionID         uint   `json:"-"`
+	IP               string `json:"-"`
+}
@@ -0,0 +1,74 @@
+package entities
+
+// 我的投注记录 自研游戏订单和三方游戏记录统一后的格式
+type BetHistory struct {
+	ID        string  `json:"id"`         // 来源:记录ID
+	Source    string  `json:"source"`     // 来源 自研游戏为游戏标识 三方为provider
+	Game      string  `json:"game"`       // 游戏名
+	Category  string  `json:"category"`   // 游戏分类
+	RoundID   string  `json:"round_id"`   // 局号/期号/三方注单号
+	BetTime   int64   `json:"bet_time"`   // 投注时间
+	BetAmount float64 `json:"bet_amount"` // 投注金额
+	Payout    float64 `json:"payout"`     // 派彩金额
+	Profit    float64 `json:"profit"`     // 盈亏
+	Result    string  `json:"result"`     // win lose pending cancel
+}
+
+// 最近玩过的游戏
+type RecentGame struct {
+	Source       string `json:"source"`
+	Game         string `json:"game"`
+	LastPlayTime int64  `json:"last_play_time"`
+	Info         *Game  `json:"info"` // 游戏目录中的信息 找不到时为空
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 用户收藏的游戏
+type UserFavoriteGame struct {
+	BaseModel
+	UID    uint `gorm:"column:uid;uniqueIndex:idx_uid_game" json:"uid"`
+	GameID uint `gorm:"uniqueIndex:idx_uid_game" json:"game_id"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type BetHistoryFilter struct {
+	Game      string `json:"game"`       // 自研游戏标识或三方游戏名
+	Result    string `json:"result"`     // win lose pending cancel
+	StartTime int64  `json:"start_time"` // 投注时间范围
+	EndTime   int64  `json:"end_time"`
+	UID       uint   `json:"-"`
+}
+
+type GetBetHistoryReq struct {
+	BetHistoryFilter
+	Cursor string `json:"cursor"` // 上一页返回的next_cursor 为空时从最新开始
+	Limit  int    `json:"limit"`
+}
+
+type GetBetHistoryResp struct {
+	List       []*BetHistory `json:"list"`
+	NextCursor string        `json:"next_cursor"` // 为空表示没有更多
+}
+
+type ExportBetHistoryReq struct {
+	BetHistoryFilter
+}
+
+type GetRecentGameListReq struct {
+	Limit int  `json:"limit"`
+	UID   uint `json:"-"`
+}
+
+type FavoriteGameReq struct {
+	GameID uint `json:"game_id" binding:"required"`
+	UID    uint `json:"-"`
+}
+
+// 投注记录游标 上一页最后一条的投注时间/来源序号/记录ID
+type BetHistoryCursor struct {
+	BetTime int64
+	Source  int
+	ID      uint
+}
@@ -47,4 +47,9 @@ type OAuthToken struct {
 	ExpiresIn    time.Duration `json:"expires_in,omitempty"`
 	RedirectUrl  string        `json:"redirect_url,omitempty"`
 	UID          uint          `json:"uid,omitempty"`
+	SessionID    string        `json:"-"` // 会话ID
+	DeviceID     string        `json:"-"` // 登录设备
+	Device       string        `json:"-"` // 设备系统
+	UserAgent    string        `json:"-"`
+	IP           string        `json:"-"`
 }
@@ -0,0 +1,19 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 队列发件箱 和业务数据在同一个事务写入 事务提交后由中继投递到asynq
+// DedupKey同时作为asynq的TaskID 中继重复投递时不会重复入队
+type OutboxMessage struct {
+	BaseModel
+	Type        string `gorm:"size:64" json:"type"`
+	Payload     string `gorm:"type:text" json:"payload"`
+	DedupKey    string `gorm:"size:128;uniqueIndex" json:"dedup_key"`
+	Status      uint8  `gorm:"default:1;index:idx_status_next" json:"status"` // 1待投递 2已投递 3失败
+	NextTime    int64  `gorm:"default:0;index:idx_status_next" json:"next_time"`
+	Attempts    int    `gorm:"default:0" json:"attempts"`
+	Error       string `gorm:"size:512" json:"error"`
+	PublishTime int64  `gorm:"default:0" json:"publish_time"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
@@ -1,6 +1,10 @@
 package entities
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/shopspring/decimal"
+)
 
 // ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
 // 充值商品表
@@ -124,3 +128,75 @@ type GetRechargeUrlReq struct {
 type RechargeUrlInfo struct {
 	Url string `json:"url"`
 }
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 充值优惠规则
+type RechargePromotion struct {
+	BaseModel
+	Name          string  `gorm:"column:name;size:64" json:"name"`
+	Trigger       uint8   `gorm:"column:trigger_type;default:0" json:"trigger"`                            // 触发 1首充 2第N次充值 3每日首充 4每笔充值
+	NthDeposit    int     `gorm:"column:nth_deposit;default:0" json:"nthDeposit"`                          // 第N次充值 Trigger=2时有效
+	Beneficiary   uint8   `gorm:"column:beneficiary;default:1" json:"beneficiary"`                         // 受益人 1充值本人 2一级上级
+	BonusType     uint8   `gorm:"column:bonus_type;default:1" json:"bonusType"`                            // 1按比例 2固定金额
+	BonusValue    float64 `gorm:"column:bonus_value;default:0;type:decimal(12,2)" json:"bonusValue"`       // 比例(百分比)或固定金额
+	MaxBonus      float64 `gorm:"column:max_bonus;default:0;type:decimal(12,2)" json:"maxBonus"`           // 单次奖励上限 0不限
+	MinAmount     float64 `gorm:"column:min_amount;default:0;type:decimal(12,2)" json:"minAmount"`         // 最低充值金额
+	MaxAmount     float64 `gorm:"column:max_amount;default:0;type:decimal(12,2)" json:"maxAmount"`         // 最高充值金额 0不限
+	PromoterCode  int     `gorm:"column:pc;default:0" json:"pc"`                                           // 限定推销码 0不限
+	Channel       string  `gorm:"column:channel;size:20" json:"channel"`                                   // 限定注册渠道 空不限
+	NewUserDays   int     `gorm:"column:new_user_days;default:0" json:"newUserDays"`                       // 限定注册N天内的用户 0不限
+	UserLimit     int     `gorm:"column:user_limit;default:0" json:"userLimit"`                            // 每个用户最多获得次数 0不限
+	Budget        float64 `gorm:"column:budget;default:0;type:decimal(14,2)" json:"budget"`                // 总预算 0不限
+	GrantedAmount float64 `gorm:"column:granted_amount;default:0;type:decimal(14,2)" json:"grantedAmount"` // 已发放金额
+	Priority      int     `gorm:"column:priority;default:0" json:"priority"`                               // 优先级 越大越先计算
+	Exclusive     uint8   `gorm:"column:exclusive;default:0" json:"exclusive"`                             // 1命中后不再计算后续规则
+	StartTime     int64   `gorm:"column:start_time;default:0" json:"startTime"`                            // 开始时间 0不限
+	EndTime       int64   `gorm:"column:end_time;default:0" json:"endTime"`                                // 结束时间 0不限
+	Status        uint8   `gorm:"column:status;default:0" json:"status"`                                   // 0关闭 1开启
+}
+
+// 计算奖励金额
+func (p *RechargePromotion) CalculateBonus(amount float64) float64 {
+	var bonus decimal.Decimal
+	if p.BonusType == 1 {
+		bonus = decimal.NewFromFloat(amount).Mul(decimal.NewFromFloat(p.BonusValue)).Div(decimal.NewFromInt(100))
+	} else {
+		bonus = decimal.NewFromFloat(p.BonusValue)
+	}
+	if p.MaxBonus > 0 && bonus.GreaterThan(decimal.NewFromFloat(p.MaxBonus)) {
+		bonus = decimal.NewFromFloat(p.MaxBonus)
+	}
+	return bonus.Round(2).InexactFloat64()
+}
+
+// 充值优惠发放记录
+type RechargePromotionRecord struct {
+	BaseModel
+	PromotionID    uint    `gorm:"column:promotion_id;default:0;uniqueIndex:idx_promotion_order" json:"promotionID"`
+	OrderID        string  `gorm:"column:order_id;size:26;uniqueIndex:idx_promotion_order" json:"orderID"` // 充值订单号
+	UID            uint    `gorm:"column:uid;default:0;index" json:"uid"`                                  // 充值用户
+	BeneficiaryUID uint    `gorm:"column:beneficiary_uid;default:0;index" json:"beneficiaryUID"`           // 获得奖励的用户
+	Amount         float64 `gorm:"column:amount;default:0;type:decimal(12,2)" json:"amount"`               // 充值金额
+	Bonus          float64 `gorm:"column:bonus;default:0;type:decimal(12,2)" json:"bonus"`                 // 奖励金额
+	PromoterCode   int     `gorm:"column:pc;default:0" json:"pc"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type GetRechargePromotionListReq struct {
+	Paginator
+	Status *uint8 `json:"status"`
+}
+
+type GetRechargePromotionRecordListReq struct {
+	Paginator
+	PromotionID uint `json:"promotionID"`
+	UID         uint `json:"uid"`
+}
+
+type SaveRechargePromotionReq struct {
+	RechargePromotion
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
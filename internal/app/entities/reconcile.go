@@ -0,0 +1,59 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 三方转账与投注记录对账报告 每个三方每个时间段一条
+type ReconcileReport struct {
+	BaseModel
+	Provider      string `gorm:"column:provider;size:16;uniqueIndex:idx_provider_start" json:"provider"` // 三方平台
+	StartTime     int64  `gorm:"column:start_time;uniqueIndex:idx_provider_start" json:"startTime"`      // 对账开始时间(秒)
+	EndTime       int64  `gorm:"column:end_time" json:"endTime"`                                         // 对账结束时间(秒)
+	Transfers     int    `gorm:"column:transfers;default:0" json:"transfers"`                            // 转账注单数(按注单合并)
+	Records       int    `gorm:"column:records;default:0" json:"records"`                                // 投注记录数
+	Matched       int    `gorm:"column:matched;default:0" json:"matched"`                                // 对上的注单数
+	Discrepancies int    `gorm:"column:discrepancies;default:0" json:"discrepancies"`                    // 差异数
+	Status        uint8  `gorm:"column:status;default:0;index" json:"status"`                            // 1一致 2有差异 3失败
+	Error         string `gorm:"column:error;size:255" json:"error"`
+}
+
+// 对账差异明细
+type ReconcileDiscrepancy struct {
+	BaseModel
+	ReportID        uint    `gorm:"column:report_id;default:0;index" json:"reportID"`
+	Provider        string  `gorm:"column:provider;size:16" json:"provider"`
+	UID             uint    `gorm:"column:uid;default:0;index" json:"uid"`
+	RecordID        string  `gorm:"column:record_id;size:64" json:"recordID"` // 注单号
+	RoundID         string  `gorm:"column:round_id;size:64" json:"roundID"`   // 局号
+	Type            string  `gorm:"column:type;size:32;index" json:"type"`    // 差异类型
+	DebitAmount     float64 `gorm:"column:debit_amount;type:decimal(12,2);default:0" json:"debitAmount"`
+	CreditAmount    float64 `gorm:"column:credit_amount;type:decimal(12,2);default:0" json:"creditAmount"`
+	RollbackAmount  float64 `gorm:"column:rollback_amount;type:decimal(12,2);default:0" json:"rollbackAmount"`
+	RecordBetAmount float64 `gorm:"column:record_bet_amount;type:decimal(12,2);default:0" json:"recordBetAmount"`
+	RecordProfit    float64 `gorm:"column:record_profit;type:decimal(12,2);default:0" json:"recordProfit"`
+	Remark          string  `gorm:"column:remark;size:128" json:"remark"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 三方投注记录 各三方的记录表转换后用于对账
+type ReconcileBetRecord struct {
+	UID       uint
+	RecordID  string
+	BetAmount float64
+	Profit    float64
+	Time      int64
+}
+
+type GetReconcileReportListReq struct {
+	Paginator
+	Provider string `json:"provider"`
+	Status   uint8  `json:"status"`
+}
+
+type GetReconcileDiscrepancyListReq struct {
+	Paginator
+	ReportID uint   `json:"reportID"`
+	Provider string `json:"provider"`
+	Type     string `json:"type"`
+	UID      uint   `json:"uid"`
+}
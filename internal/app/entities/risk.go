@@ -0,0 +1,62 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 提现风控规则
+type WithdrawRiskRule struct {
+	BaseModel
+	Code      string  `gorm:"column:code;size:32;uniqueIndex" json:"code"`                    // 规则编码
+	Name      string  `gorm:"column:name;size:64" json:"name"`                                // 规则名称
+	Threshold float64 `gorm:"column:threshold;default:0;type:decimal(12,2)" json:"threshold"` // 阈值 含义由规则决定
+	Window    int64   `gorm:"column:window;default:0" json:"window"`                          // 统计窗口(秒)
+	Status    uint8   `gorm:"column:status;default:1" json:"status"`                          // 0关闭 1开启
+}
+
+// 提现风控决策日志 每一次评估都会记录
+type WithdrawRiskLog struct {
+	BaseModel
+	UID      uint    `gorm:"column:uid;default:0;index" json:"uid"`
+	RecordID uint    `gorm:"column:record_id;default:0;index" json:"recordID"`     // 提现记录ID
+	OrderID  string  `gorm:"column:order_id;size:26" json:"orderID"`               // 提现订单号
+	Cash     float64 `gorm:"column:cash;default:0;type:decimal(10,2)" json:"cash"` // 提现金额
+	IP       string  `gorm:"column:ip;size:40;index" json:"ip"`                    // 申请IP
+	DeviceID string  `gorm:"column:device_id;size:40;index" json:"deviceID"`       // 申请设备
+	Decision uint8   `gorm:"column:decision;default:0;index" json:"decision"`      // 1自动通过 2风控挂起 3人工审核
+	Reasons  string  `gorm:"column:reasons;size:512" json:"reasons"`               // 命中原因
+	Snapshot string  `gorm:"column:snapshot;type:text" json:"snapshot"`            // 评估时的各项指标
+	Result   string  `gorm:"column:result;size:255" json:"result"`                 // 自动通过的执行结果
+	Time     int64   `gorm:"column:time;default:0" json:"time"`                    // 评估时间
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 风控评估时采集的指标
+type WithdrawRiskMetrics struct {
+	RecentRecharge float64 `json:"recentRecharge"` // 窗口内充值
+	Turnover       float64 `json:"turnover"`       // 窗口内首笔充值以来的投注额
+	AccountAge     int64   `json:"accountAge"`     // 账号注册时长(秒)
+	SharedAccounts int64   `json:"sharedAccounts"` // 共用IP/设备的其他账号数量
+	WinStreak      int     `json:"winStreak"`      // 最长连赢局数
+	CardSharedUIDs int64   `json:"cardSharedUIDs"` // 同一银行卡的其他UID数量
+}
+
+type WithdrawRiskResult struct {
+	Decision uint8
+	Reasons  []string
+	Metrics  WithdrawRiskMetrics
+}
+
+type GetWithdrawRiskLogListReq struct {
+	Paginator
+	UID      uint  `json:"uid"`
+	Decision uint8 `json:"decision"`
+}
+
+type UpdateWithdrawRiskRuleReq struct {
+	Code      string  `json:"code" binding:"required"`
+	Threshold float64 `json:"threshold"`
+	Window    int64   `json:"window"`
+	Status    uint8   `json:"status"`
+	OptionID  uint    `json:"-"`
+	IP        string  `json:"-"`
+}
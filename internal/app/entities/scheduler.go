@@ -0,0 +1,58 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 定时任务 任务逻辑在代码里注册 时间表/启停/补跑在这里配置
+// LastFireTime是已处理到的触发时间 各节点用它做CAS 保证每次触发只执行一次
+type ScheduledJob struct {
+	BaseModel
+	Name         string `gorm:"size:64;uniqueIndex" json:"name"`
+	Spec         string `gorm:"size:64" json:"spec"`           // cron表达式 支持@every
+	Status       uint8  `gorm:"default:1" json:"status"`       // 1启用 2暂停
+	CatchUp      bool   `gorm:"default:false" json:"catch_up"` // 错过的触发是否补跑一次
+	Remark       string `gorm:"size:255" json:"remark"`
+	LastFireTime int64  `gorm:"default:0" json:"last_fire_time"`
+	NextFireTime int64  `gorm:"default:0" json:"next_fire_time"`
+	TriggerTime  int64  `gorm:"default:0" json:"trigger_time"` // 后台手动触发 非0表示待执行
+	LastRunTime  int64  `gorm:"default:0" json:"last_run_time"`
+	LastDuration int64  `gorm:"default:0" json:"last_duration"` // 毫秒
+	LastStatus   uint8  `gorm:"default:0" json:"last_status"`
+	LastNode     string `gorm:"size:64" json:"last_node"`
+}
+
+// 每次执行的记录
+type ScheduledJobRun struct {
+	BaseModel
+	JobName   string `gorm:"size:64;index" json:"job_name"`
+	Trigger   string `gorm:"size:16" json:"trigger"` // cron catchup manual
+	FireTime  int64  `gorm:"default:0" json:"fire_time"`
+	Node      string `gorm:"size:64" json:"node"`
+	StartTime int64  `gorm:"default:0" json:"start_time"`
+	EndTime   int64  `gorm:"default:0" json:"end_time"`
+	Duration  int64  `gorm:"default:0" json:"duration"` // 毫秒
+	Status    uint8  `gorm:"default:1" json:"status"`   // 1执行中 2成功 3失败
+	Error     string `gorm:"size:512" json:"error"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type SaveScheduledJobReq struct {
+	Name     string `json:"name" binding:"required"`
+	Spec     string `json:"spec" binding:"required"`
+	Status   uint8  `json:"status" binding:"required"`
+	CatchUp  bool   `json:"catch_up"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type TriggerScheduledJobReq struct {
+	Name     string `json:"name" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type GetScheduledJobRunListReq struct {
+	Paginator
+	JobName string `json:"job_name"`
+	Status  uint8  `json:"status"`
+}
@@ -0,0 +1,45 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 三方游戏单一钱包交易流水 (provider, external_id) 唯一 重复回调直接返回第一次的结果
+type SeamlessTransaction struct {
+	BaseModel
+	Provider      string  `gorm:"column:provider;size:16;uniqueIndex:idx_provider_external" json:"provider"`      // 三方平台
+	ExternalID    string  `gorm:"column:external_id;size:96;uniqueIndex:idx_provider_external" json:"externalID"` // 三方交易号
+	UID           uint    `gorm:"column:uid;default:0;index" json:"uid"`
+	Action        string  `gorm:"column:action;size:16" json:"action"`                        // debit/credit/rollback
+	Amount        float64 `gorm:"column:amount;type:decimal(12,2);default:0" json:"amount"`   // 实际变动金额 扣款为负
+	Balance       float64 `gorm:"column:balance;type:decimal(12,2);default:0" json:"balance"` // 变动后余额
+	GameCode      string  `gorm:"column:game_code;size:32" json:"gameCode"`                   // 游戏代码
+	RoundID       string  `gorm:"column:round_id;size:64;index" json:"roundID"`               // 局号
+	RecordID      string  `gorm:"column:record_id;size:64;index" json:"recordID"`             // 注单号
+	RefExternalID string  `gorm:"column:ref_external_id;size:96" json:"refExternalID"`        // 关联的原交易号 派彩/回滚时使用
+	FlowType      uint16  `gorm:"column:flow_type;default:0" json:"flowType"`                 // 流水类型
+	Status        uint8   `gorm:"column:status;default:0" json:"status"`                      // 扣款状态 0未结算 1已结算 2已回滚
+	Remark        string  `gorm:"column:remark;size:128" json:"remark"`
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 单一钱包交易请求 由各三方适配层从协议转换而来
+type SeamlessTxReq struct {
+	Provider      string
+	ExternalID    string
+	UID           uint
+	Amount        float64 // 金额 始终为正数 方向由操作决定
+	GameCode      string
+	RoundID       string
+	RecordID      string
+	RefExternalID string // 关联的原交易号
+	FlowType      uint16
+	Remark        string
+	Partial       bool // 余额不足时扣除全部余额 (JHSZ freeze)
+}
+
+type SeamlessTxResult struct {
+	Balance     float64              // 当前余额
+	Amount      float64              // 实际变动金额 扣款为负
+	Duplicate   bool                 // 重复的交易 未再次变动余额
+	Transaction *SeamlessTransaction // 本次(或首次)的交易记录
+}
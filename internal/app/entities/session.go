@@ -0,0 +1,45 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 用户登录会话 每个设备一条
+type UserSession struct {
+	BaseModel
+	UID          uint   `gorm:"column:uid;default:0;index" json:"uid"`
+	SessionID    string `gorm:"column:session_id;size:40;uniqueIndex" json:"sessionID"` // 会话ID 写入token
+	RefreshJti   string `gorm:"column:refresh_jti;size:40" json:"-"`                    // 当前有效的refresh token编号 轮换时更新
+	DeviceID     string `gorm:"column:device_id;size:40" json:"deviceID"`               // 设备ID
+	Device       string `gorm:"column:device;size:20" json:"device"`                    // 设备系统
+	UserAgent    string `gorm:"column:user_agent;size:255" json:"userAgent"`            // UA
+	IP           string `gorm:"column:ip;size:40" json:"ip"`                            // 登录IP
+	LastIP       string `gorm:"column:last_ip;size:40" json:"lastIP"`                   // 最近访问IP
+	LastSeen     int64  `gorm:"column:last_seen;default:0" json:"lastSeen"`             // 最近访问时间
+	ExpireTime   int64  `gorm:"column:expire_time;default:0" json:"expireTime"`         // 过期时间
+	Status       uint8  `gorm:"column:status;default:1;index" json:"status"`            // 1有效 2已退出/撤销
+	RevokeReason string `gorm:"column:revoke_reason;size:32" json:"revokeReason"`       // 撤销原因
+	RevokeTime   int64  `gorm:"column:revoke_time;default:0" json:"revokeTime"`         // 撤销时间
+	Current      bool   `gorm:"-" json:"current"`                                       // 是否为当前请求的会话
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refreshToken"`
+	IP           string `json:"-"`
+}
+
+type RevokeSessionReq struct {
+	SessionID string `json:"sessionID" binding:"required"`
+	UID       uint   `json:"-"`
+}
+
+type ForceLogoutReq struct {
+	UID       uint   `json:"uid" binding:"required"`
+	SessionID string `json:"sessionID"` // 为空时踢掉该用户全部会话
+	OptionID  uint   `json:"-"`
+	IP        string `json:"-"`
+}
+
+type GetUserSessionListReq struct {
+	UID uint `json:"uid" binding:"required"`
+}
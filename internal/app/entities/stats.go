@@ -95,3 +95,49 @@ type GlobalSyncStatus struct {
 	LastRecordTime int64  // 最后处理的记录时间（断点续传）
 	SyncWindow     int    // 最大同步窗口（防大时间跨度）
 }
+
+// 三方游戏记录同步窗口 每个时间段一条 用于断点续传/缺口检测/补录
+type GameRecordSyncWindow struct {
+	BaseModel
+	Source        string `gorm:"column:source;size:50;index:idx_source_start,priority:1" json:"source"` // 第三方标识
+	StartTime     int64  `gorm:"column:start_time;index:idx_source_start,priority:2" json:"startTime"`  // 窗口开始时间(毫秒)
+	EndTime       int64  `gorm:"column:end_time" json:"endTime"`                                        // 窗口结束时间(毫秒)
+	Pages         int    `gorm:"column:pages;default:0" json:"pages"`                                   // 拉取页数
+	Fetched       int    `gorm:"column:fetched;default:0" json:"fetched"`                               // 拉取到的记录数(去重)
+	Inserted      int    `gorm:"column:inserted;default:0" json:"inserted"`                             // 新增记录数
+	Updated       int    `gorm:"column:updated;default:0" json:"updated"`                               // 更新记录数
+	ProviderTotal int    `gorm:"column:provider_total;default:-1" json:"providerTotal"`                 // 三方返回的总数 -1为三方不支持
+	Status        uint8  `gorm:"column:status;default:0;index" json:"status"`                           // 0同步中 1完成 2失败 3数量不一致
+	TriggerType   string `gorm:"column:trigger_type;size:16" json:"triggerType"`                        // auto定时任务 backfill后台补录
+	OptionID      uint   `gorm:"column:option_id;default:0" json:"optionID"`                            // 补录的操作人
+	Error         string `gorm:"column:error;size:255" json:"error"`
+}
+
+type BackfillGameRecordReq struct {
+	Source    string `json:"source" binding:"required"`
+	StartTime int64  `json:"startTime" binding:"required"` // 毫秒
+	EndTime   int64  `json:"endTime" binding:"required"`   // 毫秒
+	OptionID  uint   `json:"-"`
+	IP        string `json:"-"`
+}
+
+type GetGameRecordSyncWindowListReq struct {
+	Paginator
+	Source string `json:"source"`
+	Status *uint8 `json:"status"`
+}
+
+type GetGameRecordSyncGapListReq struct {
+	Source    string `json:"source" binding:"required"`
+	StartTime int64  `json:"startTime" binding:"required"` // 毫秒
+	EndTime   int64  `json:"endTime" binding:"required"`   // 毫秒
+}
+
+// 同步缺口 没有成功同步过的时间段 或者与三方总数不一致的窗口
+type GameRecordSyncGap struct {
+	StartTime     int64  `json:"startTime"`
+	EndTime       int64  `json:"endTime"`
+	Reason        string `json:"reason"` // uncovered/mismatch
+	Fetched       int    `json:"fetched"`
+	ProviderTotal int    `json:"providerTotal"`
+}
@@ -26,6 +26,7 @@ type UserProfile struct {
 	*User
 	Wallet  *UserWallet       `json:"wallet"`
 	Summary *FinancialSummary `json:"summary"`
+	Vip     *VipInfo          `json:"vip"`
 }
 
 // 用户基础统计
@@ -101,6 +102,7 @@ type User struct {
 	Inviter     string `gorm:"column:inviter;size:20;default:null" redis:"inviter" json:"-"`          // 一级邀请人ID
 	Promoter    string `gorm:"column:promoter;size:20;default:null" redis:"promoter" json:"-"`        // 分销推广人
 	Telegram    string `gorm:"column:telegram;default:null;size:20" redis:"telegram" json:"telegram"` // 电报
+	VipLevel    uint8  `gorm:"column:vip_level;default:0" redis:"vip_level" json:"vipLevel"`          // VIP等级
 
 	Status uint8 `gorm:"column:status;default:0" redis:"status" json:"status"` // 用户状态
 }
@@ -0,0 +1,75 @@
+package entities
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// VIP等级配置 按滚动周期内的打码量和充值额评定 两个条件都要满足
+type VipTier struct {
+	BaseModel
+	Level            uint8   `gorm:"uniqueIndex" json:"level"`
+	Name             string  `gorm:"size:32" json:"name"`
+	MinTurnover      float64 `gorm:"type:decimal(20,2);default:0" json:"min_turnover"`      // 周期内打码量
+	MinDeposit       float64 `gorm:"type:decimal(20,2);default:0" json:"min_deposit"`       // 周期内充值
+	RakebackRate     float64 `gorm:"type:decimal(6,4);default:0" json:"rakeback_rate"`      // 自返比例 百分比
+	WithdrawDayCount int     `gorm:"default:0" json:"withdraw_day_count"`                   // 每日提现次数 0按默认
+	WithdrawDayCash  float64 `gorm:"type:decimal(20,2);default:0" json:"withdraw_day_cash"` // 每日提现金额上限 0不限
+	WithdrawPriority uint8   `gorm:"default:0" json:"withdraw_priority"`                    // 提现审核优先级 越大越优先
+	LevelUpBonus     float64 `gorm:"type:decimal(10,2);default:0" json:"level_up_bonus"`    // 升级奖励 每个等级只发一次
+	BirthdayBonus    float64 `gorm:"type:decimal(10,2);default:0" json:"birthday_bonus"`    // 生日奖励 每年一次
+}
+
+// 用户VIP状态 等级同步到User.VipLevel
+type UserVip struct {
+	BaseModel
+	UID          uint    `gorm:"column:uid;uniqueIndex" json:"uid"`
+	Level        uint8   `gorm:"default:0" json:"level"`
+	MaxLevel     uint8   `gorm:"default:0" json:"max_level"`                   // 达到过的最高等级 升级奖励只发更高的等级
+	Turnover     float64 `gorm:"type:decimal(20,2);default:0" json:"turnover"` // 最近一次评定时周期内打码量
+	Deposit      float64 `gorm:"type:decimal(20,2);default:0" json:"deposit"`  // 最近一次评定时周期内充值
+	Birthday     string  `gorm:"size:5;index" json:"birthday"`                 // 生日 MM-DD 只能设置一次
+	BirthdayTime int64   `gorm:"default:0" json:"-"`                           // 设置生日的时间
+	BirthdayYear int     `gorm:"default:0" json:"-"`                           // 最近一次发放生日奖励的年份
+	CalcTime     int64   `gorm:"default:0" json:"calc_time"`                   // 最近一次评定时间
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type VipInfo struct {
+	Level    uint8    `json:"level"`
+	Turnover float64  `json:"turnover"`
+	Deposit  float64  `json:"deposit"`
+	Birthday string   `json:"birthday"`
+	CalcTime int64    `json:"calc_time"`
+	Tier     *VipTier `json:"tier"`      // 当前等级的权益 没有配置时为空
+	NextTier *VipTier `json:"next_tier"` // 下一等级的条件 已是最高级时为空
+}
+
+type SaveVipTierReq struct {
+	ID               uint    `json:"id"`
+	Level            uint8   `json:"level"`
+	Name             string  `json:"name" binding:"required"`
+	MinTurnover      float64 `json:"min_turnover"`
+	MinDeposit       float64 `json:"min_deposit"`
+	RakebackRate     float64 `json:"rakeback_rate"`
+	WithdrawDayCount int     `json:"withdraw_day_count"`
+	WithdrawDayCash  float64 `json:"withdraw_day_cash"`
+	WithdrawPriority uint8   `json:"withdraw_priority"`
+	LevelUpBonus     float64 `json:"level_up_bonus"`
+	BirthdayBonus    float64 `json:"birthday_bonus"`
+	OptionID         uint    `json:"-"`
+	IP               string  `json:"-"`
+}
+
+type DelVipTierReq struct {
+	ID       uint   `json:"id" binding:"required"`
+	OptionID uint   `json:"-"`
+	IP       string `json:"-"`
+}
+
+type SetVipBirthdayReq struct {
+	UID      uint   `json:"-"`
+	Birthday string `json:"birthday" binding:"required"` // MM-DD
+}
+
+type AdminGetUserVipReq struct {
+	UID uint `json:"uid" binding:"required"`
+}
@@ -71,3 +71,56 @@ type UpdateWalletPasswordReq struct {
 type EnableWalletPasswordReq struct {
 	Password string `json:"password" binding:"required"` //密码
 }
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+// 打码量规则 按入账的流水类型配置倍数
+type TurnoverRule struct {
+	BaseModel
+	FlowType   uint16  `gorm:"column:flow_type;uniqueIndex" json:"flowType"`                    // 入账流水类型
+	Multiplier float64 `gorm:"column:multiplier;default:0;type:decimal(8,2)" json:"multiplier"` // 打码倍数
+	Status     uint8   `gorm:"column:status;default:1" json:"status"`                           // 0关闭 1开启
+}
+
+// 打码量要求 入账金额*倍数 需要投注完才能提现
+type TurnoverRequirement struct {
+	BaseModel
+	UID        uint    `gorm:"column:uid;index:idx_uid_status" json:"-"`
+	FlowType   uint16  `gorm:"column:flow_type;default:0" json:"flowType"`                      // 来源流水类型
+	SourceID   string  `gorm:"column:source_id;size:32" json:"sourceID"`                        // 来源订单
+	Amount     float64 `gorm:"column:amount;default:0;type:decimal(18,2)" json:"amount"`        // 入账金额
+	Multiplier float64 `gorm:"column:multiplier;default:0;type:decimal(8,2)" json:"multiplier"` // 打码倍数
+	Required   float64 `gorm:"column:required;default:0;type:decimal(18,2)" json:"required"`    // 需要的投注额
+	Wagered    float64 `gorm:"column:wagered;default:0;type:decimal(18,2)" json:"wagered"`      // 已完成的投注额
+	Status     uint8   `gorm:"column:status;default:0;index:idx_uid_status" json:"status"`      // 0进行中 1已完成 2作废
+	FinishTime int64   `gorm:"column:finish_time;default:0" json:"finishTime"`
+}
+
+func (t *TurnoverRequirement) Remaining() float64 {
+	remaining := AddPrecise(t.Required, -t.Wagered)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ////////////////////////////////////////////////////////////DB table ////////////////////////////////////////////////////////////////////////////////////////
+
+type GetTurnoverRequirementListReq struct {
+	Paginator
+	UID    uint  `json:"-"`
+	Status uint8 `json:"status"`
+}
+
+type TurnoverSummary struct {
+	Remaining float64                `json:"remaining"` // 剩余需要投注额
+	List      []*TurnoverRequirement `json:"list"`      // 进行中的要求
+}
+
+type UpdateTurnoverRuleReq struct {
+	FlowType   uint16  `json:"flowType" binding:"required"`
+	Multiplier float64 `json:"multiplier"`
+	Status     uint8   `json:"status"`
+	OptionID   uint    `json:"-"`
+	IP         string  `json:"-"`
+}
@@ -46,6 +46,9 @@ type HallWithdrawRecord struct {
 	Remark        string  `gorm:"column:remark;size:64;" json:"-"`                        // 备注
 	Reason        string  `gorm:"column:reason;size:255;" json:"-"`                       // 驳回理由
 	Status        uint8   `gorm:"column:status;default:0" json:"status"`                  // 状态 0待审核 1通过 2驳回 3打款成功 4打款失败
+	RiskState     uint8   `gorm:"column:risk_state;default:0" json:"riskState"`           // 风控 0未评估 1自动通过 2风控挂起 3人工审核
+	RiskReason    string  `gorm:"column:risk_reason;size:255;" json:"riskReason"`         // 风控命中原因
+	Priority      uint8   `gorm:"column:priority;default:0;index" json:"-"`               // 审核优先级 按VIP等级
 
 	StartTime    int64 `gorm:"column:start_time;default:0" json:"start_time"`   //订单发起时间
 	FinishTime   int64 `gorm:"column:finish_time;default:0" json:"finish_time"` //订单结束时间
@@ -95,6 +98,8 @@ type WithdrawDetail struct {
 	MaxWithdraw  float64         `json:"max_withdraw,omitempty"`
 	WithdrawCash float64         `json:"withdraw_cash,omitempty"`
 	RechargeAll  float64         `json:"recharge_all,omitempty"`
+
+	TurnoverRemaining float64 `json:"turnover_remaining"` // 剩余打码量 大于0不可提现
 }
 
 type GetHallWithdrawRecordListReq struct {
@@ -106,6 +111,15 @@ type ApplyForWithdrawalReq struct {
 	UID        uint
 	Cash       float64
 	VerifyCode string //暂时不需要
+	IP         string `json:"-"`
+	DeviceID   string `json:"-"`
+}
+
+// 提现风控评估任务 申请提现的事务里写入发件箱 提交后由队列评估 失败会重试
+type WithdrawRiskTask struct {
+	RecordID uint   `json:"recordID"`
+	IP       string `json:"ip"`
+	DeviceID string `json:"deviceID"`
 }
 
 type AddWithdrawCardReq struct {
@@ -127,4 +141,6 @@ type ReviewWithdrawalReq struct {
 	Reason  string `json:"reason"`  // Reason is the explanation provided for the withdrawal.
 	IP      string `json:"ip"`      // IP is the IP address from where the request was made.
 	OptType uint8  `json:"optType"` // OptType indicates the type of operation performed.
+	// RiskOverride confirms approving an order the risk engine has put on hold.
+	RiskOverride bool `json:"riskOverride"`
 }
@@ -49,6 +49,12 @@ const (
 
 	DuplicatePassword = 10020023 // 重复密码
 
+	SessionNotExist     = 10020024 // 会话不存在或已失效
+	RefreshTokenInvalid = 10020025 // refresh token无效
+	RefreshTokenReused  = 10020026 // refresh token重复使用 会话已被撤销
+
+	InviteRelationCycle = 10020027 // 不能迁移到自己的下级
+
 	RetryFrequenceLimit  = 10020115 //email 请求验证码频率太高
 	RetryCountLimit      = 10020116 //email 请求验证码频率太高
 	VerifiedCodeExpire   = 10020117 //验证码已过期
@@ -58,6 +64,8 @@ const (
 	RedEnvelopeExpire       = 10020302 //红包信息过期
 	InsufficientRedEnvelope = 10020303 //红包数量不足
 	RedEnvelopeRepeatGet    = 10020304 //红包重复领取
+	RedEnvelopeNoCondition  = 10020305 //红包领取条件不满足
+	RedEnvelopeInvalidParam = 10020306 //红包参数错误
 
 	PinduoNotExist       = 10020401 //拼多多不存在
 	PinduoHasGet         = 10020402 //拼多多已经领了
@@ -72,20 +80,90 @@ const (
 	WithdralCardAccountNumberExist = 10040038 //提现卡账号已经存在
 	WithdralCardIFSCExist          = 10040039 //提现卡IFSC已经存在
 	WithdrawalDayCountLimit        = 10040040 //提现每日限制
+	WithdrawalDayCashLimit         = 10040041 //提现每日金额限制
 
 	InvalidWithdrawalReview      = 10040050 //不可用的订单审核状态
 	InsufficientWithdrawLockCash = 10040051 //提现冻结的资金不足
-
-	MinRechargeCashLimit           = 10060027 //最低充值金额限制
-	RechargeConfigNotExist         = 10060028 //支付配置不存在
-	RechargeConfigNotAvailable     = 10060029 //支付配置不可用
-	RechargeChannelSettingNotExist = 10060030 //支付渠道配置不存在
-	InvalidRechargeOrderActType    = 10060041 //充值活动类型不匹配
-	InvalidRechargeReturn          = 10060101 //支付配置不可用
-	RechargeReturnNotExist         = 10060102 //支付配置不可用
+	WithdrawRiskRuleNotExist     = 10040060 //提现风控规则不存在
+	WithdrawalTurnoverNotMet     = 10040061 //打码量未完成
+	WithdrawAutoApproveTooHigh   = 10040062 //自动通过金额不能达到复核金额
+	WithdrawalRiskHold           = 10040063 //风控挂起的订单需要确认放行
+
+	MinRechargeCashLimit            = 10060027 //最低充值金额限制
+	RechargeConfigNotExist          = 10060028 //支付配置不存在
+	RechargeConfigNotAvailable      = 10060029 //支付配置不可用
+	RechargeChannelSettingNotExist  = 10060030 //支付渠道配置不存在
+	InvalidRechargeOrderActType     = 10060041 //充值活动类型不匹配
+	InvalidRechargeReturn           = 10060101 //支付配置不可用
+	RechargeReturnNotExist          = 10060102 //支付配置不可用
+	RechargePromotionNotExist       = 10060103 //充值优惠不存在
+	RechargePromotionBudgetExceeded = 10060104 //充值优惠预算不足
+
+	SeamlessTxNotExist    = 10070001 //单一钱包原交易不存在
+	SeamlessInvalidAmount = 10070002 //单一钱包交易金额错误
+	SeamlessTxRolledBack  = 10070003 //单一钱包原交易已回滚
+	SeamlessTxSettled     = 10070004 //单一钱包原交易已结算
+	SeamlessTxAmbiguous   = 10070005 //单一钱包匹配到多笔未结算的原交易
+
+	RecordSyncSourceNotExist = 10070101 //游戏记录同步来源不存在
+	RecordSyncInvalidRange   = 10070102 //游戏记录同步时间范围无效
+	RecordSyncRunning        = 10070103 //游戏记录正在同步
+
+	GamingSelfExcluded   = 10080001 //用户已自我排除
+	GamingCoolOff        = 10080002 //用户处于冷静期
+	GamingDepositLimit   = 10080003 //超出充值限额
+	GamingLossLimit      = 10080004 //超出输钱限额
+	GamingWagerLimit     = 10080005 //超出投注限额
+	GamingInvalidSetting = 10080006 //负责任博彩设置无效
+
+	VipTierNotExist       = 10090001 //VIP等级配置不存在
+	VipTierInvalid        = 10090002 //VIP等级配置无效
+	VipBirthdayInvalid    = 10090003 //生日格式错误
+	VipBirthdayAlreadySet = 10090004 //生日已经设置过
+
+	CashbackRuleNotExist = 10100001 //自返规则不存在
+	CashbackRuleInvalid  = 10100002 //自返规则无效
+	CashbackNotEnough    = 10100003 //没有可领取的自返
+
+	CommissionPlanNotExist  = 10110001 //佣金方案不存在
+	CommissionPlanInvalid   = 10110002 //佣金方案配置无效
+	CommissionPeriodInvalid = 10110003 //结算周期无效
+
+	AdminLoginFailed      = 10120001 //后台账号或密码错误
+	AdminLoginLocked      = 10120002 //后台登录失败次数过多
+	AdminSessionInvalid   = 10120003 //后台会话不存在或已失效
+	AdminPermissionDenied = 10120004 //后台没有操作权限
+	AdminRoleNotExist     = 10120005 //后台角色不存在
+	AdminRoleInUse        = 10120006 //后台角色还有账号在使用
+	AdminUserNotExist     = 10120007 //后台账号不存在
+	AdminUserExist        = 10120008 //后台账号已存在
+
+	AdminApprovalNotExist   = 10130001 //复核单不存在
+	AdminApprovalNotPending = 10130002 //复核单已处理
+	AdminApprovalExpired    = 10130003 //复核单已超时
+	AdminApprovalSelfCheck  = 10130004 //不能复核自己发起的操作
+	AdminApprovalNoAction   = 10130005 //复核操作未注册
+
+	ArchiveRunning         = 10140001 //归档任务正在执行
+	ArchiveTableNotAllowed = 10140002 //不支持归档的表
+	ArchiveVerifyFailed    = 10140003 //归档校验不一致
+
+	SchedulerJobNotExist = 10150001 //定时任务不存在
+	SchedulerSpecInvalid = 10150002 //定时任务时间表达式错误
+
+	DeadLetterNotExist       = 10160001 //死信任务不存在
+	DeadLetterPayloadInvalid = 10160002 //修改后的任务内容格式错误
+
+	EventGroupNotExist = 10170001 //事件消费组不存在或未订阅该事件
 
 	RoomNotExist                = 10050001 //房间不存在
 	GameStrategyNotExist        = 10050002 //游戏策略不存在
+	GameNotExist                = 10050003 //游戏不存在
+	GameCollectionNotExist      = 10050004 //游戏合集不存在
+	GameProviderNotExist        = 10050005 //游戏三方不存在
+	FavoriteGameLimit           = 10050006 //收藏游戏数量超出上限
+	BetHistoryInvalidCursor     = 10050007 //投注记录游标无效
+	BetHistoryInvalidRange      = 10050008 //投注记录时间范围无效
 	BettingNotAllowed           = 10050022 // 不允许下注
 	UserBettingAmountLimit      = 10050023 //用户下注限制
 	UserDayBettingTimesLimit    = 10060024 //用户每天下注次数限制
@@ -133,6 +211,12 @@ var ErrCodeMsg = map[int]string{
 	InviteRelationExist:  "invite-relation-exist",
 	DuplicatePassword:    "duplicate-password",
 
+	SessionNotExist:     "session-does-not-exist",
+	RefreshTokenInvalid: "refresh-token-invalid",
+	RefreshTokenReused:  "refresh-token-reused",
+
+	InviteRelationCycle: "invite-relation-cycle",
+
 	RetryFrequenceLimit:  "retry-frequency-too-high",
 	RetryCountLimit:      "retry-count-limit",
 	VerifiedCodeExpire:   "verification-code-expired",
@@ -142,6 +226,8 @@ var ErrCodeMsg = map[int]string{
 	RedEnvelopeExpire:       "red-envelope-expired",
 	InsufficientRedEnvelope: "insufficient-red-envelope-quantity",
 	RedEnvelopeRepeatGet:    "red-envelope-already-claimed",
+	RedEnvelopeNoCondition:  "red-envelope-condition-not-met",
+	RedEnvelopeInvalidParam: "red-envelope-invalid-param",
 
 	PinduoNotExist:       "pinduoduo-does-not-exist",
 	PinduoHasGet:         "pinduoduo-already-claimed",
@@ -156,19 +242,91 @@ var ErrCodeMsg = map[int]string{
 	WithdralCardAccountNumberExist: "withdrawal-card-account-number-already-exists",
 	WithdralCardIFSCExist:          "withdrawal-card-ifsc-already-exists",
 	WithdrawalDayCountLimit:        "withdrawal-day-count-limit",
+	WithdrawalDayCashLimit:         "withdrawal-day-cash-limit",
 
 	InvalidWithdrawalReview:      "invalid-withdrawal-review-status",
 	InsufficientWithdrawLockCash: "insufficient-withdrawal-locked-funds",
-
-	MinRechargeCashLimit:           "minimum-recharge-limit",
-	RechargeConfigNotExist:         "recharge-configuration-does-not-exist",
-	RechargeConfigNotAvailable:     "recharge-configuration-not-available",
-	RechargeChannelSettingNotExist: "recharge-channel-setting-not-exist",
-	InvalidRechargeOrderActType:    "invalid-recharge-order-act-type",
-	InvalidRechargeReturn:          "invalid-recharge-return",
-	RechargeReturnNotExist:         "recharge-return-does-not-exist",
+	WithdrawRiskRuleNotExist:     "withdraw-risk-rule-not-exist",
+	WithdrawalTurnoverNotMet:     "withdrawal-turnover-not-met",
+	WithdrawAutoApproveTooHigh:   "withdraw-auto-approve-too-high",
+	WithdrawalRiskHold:           "withdrawal-risk-hold",
+
+	MinRechargeCashLimit:            "minimum-recharge-limit",
+	RechargeConfigNotExist:          "recharge-configuration-does-not-exist",
+	RechargeConfigNotAvailable:      "recharge-configuration-not-available",
+	RechargeChannelSettingNotExist:  "recharge-channel-setting-not-exist",
+	InvalidRechargeOrderActType:     "invalid-recharge-order-act-type",
+	InvalidRechargeReturn:           "invalid-recharge-return",
+	RechargeReturnNotExist:          "recharge-return-does-not-exist",
+	RechargePromotionNotExist:       "recharge-promotion-not-exist",
+	RechargePromotionBudgetExceeded: "recharge-promotion-budget-exceeded",
+
+	SeamlessTxNotExist:    "seamless-transaction-not-exist",
+	SeamlessInvalidAmount: "seamless-invalid-amount",
+	SeamlessTxRolledBack:  "seamless-transaction-rolled-back",
+	SeamlessTxSettled:     "seamless-transaction-settled",
+	SeamlessTxAmbiguous:   "seamless-transaction-ambiguous",
+
+	RecordSyncSourceNotExist: "record-sync-source-not-exist",
+	RecordSyncInvalidRange:   "record-sync-invalid-range",
+	RecordSyncRunning:        "record-sync-running",
+
+	GamingSelfExcluded:   "gaming-self-excluded",
+	GamingCoolOff:        "gaming-cool-off",
+	GamingDepositLimit:   "gaming-deposit-limit-exceeded",
+	GamingLossLimit:      "gaming-loss-limit-exceeded",
+	GamingWagerLimit:     "gaming-wager-limit-exceeded",
+	GamingInvalidSetting: "gaming-invalid-setting",
+
+	VipTierNotExist:       "vip-tier-not-exist",
+	VipTierInvalid:        "vip-tier-invalid",
+	VipBirthdayInvalid:    "vip-birthday-invalid",
+	VipBirthdayAlreadySet: "vip-birthday-already-set",
+
+	CashbackRuleNotExist: "cashback-rule-not-exist",
+	CashbackRuleInvalid:  "cashback-rule-invalid",
+	CashbackNotEnough:    "cashback-not-enough",
+
+	CommissionPlanNotExist:  "commission-plan-not-exist",
+	CommissionPlanInvalid:   "commission-plan-invalid",
+	CommissionPeriodInvalid: "commission-period-invalid",
+
+	AdminLoginFailed:      "admin-login-failed",
+	AdminLoginLocked:      "admin-login-locked",
+	AdminSessionInvalid:   "admin-session-invalid",
+	AdminPermissionDenied: "admin-permission-denied",
+	AdminRoleNotExist:     "admin-role-not-exist",
+	AdminRoleInUse:        "admin-role-in-use",
+	AdminUserNotExist:     "admin-user-not-exist",
+	AdminUserExist:        "admin-user-exist",
+
+	AdminApprovalNotExist:   "admin-approval-not-exist",
+	AdminApprovalNotPending: "admin-approval-not-pending",
+	AdminApprovalExpired:    "admin-approval-expired",
+	AdminApprovalSelfCheck:  "admin-approval-self-check",
+	AdminApprovalNoAction:   "admin-approval-no-action",
+
+	ArchiveRunning:         "archive-running",
+	ArchiveTableNotAllowed: "archive-table-not-allowed",
+	ArchiveVerifyFailed:    "archive-verify-failed",
+
+	SchedulerJobNotExist: "scheduler-job-not-exist",
+	SchedulerSpecInvalid: "scheduler-spec-invalid",
+
+	DeadLetterNotExist:       "dead-letter-not-exist",
+	DeadLetterPayloadInvalid: "dead-letter-payload-invalid",
+
+	EventGroupNotExist: "event-group-not-exist",
 
 	RoomNotExist:           "room-does-not-exist",
+	GameNotExist:           "game-not-exist",
+	GameCollectionNotExist: "game-collection-not-exist",
+	GameProviderNotExist:   "game-provider-not-exist",
+	FavoriteGameLimit:      "favorite-game-limit",
+
+	BetHistoryInvalidCursor: "bet-history-invalid-cursor",
+	BetHistoryInvalidRange:  "bet-history-invalid-range",
+
 	BettingNotAllowed:      "betting-not-allowed",
 	UserBettingAmountLimit: "user-betting-amount-limit",
 
@@ -252,3 +410,10 @@ func IsAccountBlocked(err error) bool {
 	}
 	return false
 }
+
+func IsCode(err error, code int) bool {
+	if appError, ok := err.(*Error); ok {
+		return appError.Code == code
+	}
+	return false
+}
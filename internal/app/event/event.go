@@ -0,0 +1,105 @@
+package event
+
+import "fmt"
+
+// 领域事件 业务只负责发布 统计/VIP/返利/通知/告警各自订阅 互不影响
+const (
+	NameUserRegistered      = "user_registered"
+	NameDepositSucceeded    = "deposit_succeeded"
+	NameBetPlaced           = "bet_placed"
+	NameBetSettled          = "bet_settled"
+	NameWithdrawalRequested = "withdrawal_requested"
+	NameWithdrawalPaid      = "withdrawal_paid"
+	NameBonusGranted        = "bonus_granted"
+)
+
+// 所有事件 后台查看消费组和统计时使用
+var Names = []string{
+	NameUserRegistered,
+	NameDepositSucceeded,
+	NameBetPlaced,
+	NameBetSettled,
+	NameWithdrawalRequested,
+	NameWithdrawalPaid,
+	NameBonusGranted,
+}
+
+// EventKey是事件的业务标识 同一个事件重复发布只会写入一次
+type Event interface {
+	EventName() string
+	EventKey() string
+}
+
+// 注册成功 InviterID为0表示没有邀请人
+type UserRegistered struct {
+	UID          uint   `json:"uid"`
+	Mobile       string `json:"mobile"`
+	InviterID    uint   `json:"inviter_id"`
+	PromoterCode int    `json:"pc"`
+	Channel      string `json:"channel"`
+	IP           string `json:"ip"`
+}
+
+// 充值到账 次数包含本次 在同一个事务里统计 重放时不会变
+type DepositSucceeded struct {
+	UID          uint    `json:"uid"`
+	OrderID      string  `json:"order_id"`
+	Amount       float64 `json:"amount"`
+	Channel      string  `json:"channel"`
+	PromoterCode int     `json:"pc"`
+	DepositCount int64   `json:"deposit_count"`
+	TodayCount   int64   `json:"today_count"`
+}
+
+type BetPlaced struct {
+	UID     uint    `json:"uid"`
+	Game    string  `json:"game"`
+	OrderID string  `json:"order_id"`
+	Amount  float64 `json:"amount"`
+}
+
+type BetSettled struct {
+	UID          uint    `json:"uid"`
+	Game         string  `json:"game"`
+	OrderID      string  `json:"order_id"`
+	BetAmount    float64 `json:"bet_amount"`
+	RewardAmount float64 `json:"reward_amount"`
+}
+
+type WithdrawalRequested struct {
+	UID     uint    `json:"uid"`
+	OrderID string  `json:"order_id"`
+	Amount  float64 `json:"amount"`
+}
+
+type WithdrawalPaid struct {
+	UID     uint    `json:"uid"`
+	OrderID string  `json:"order_id"`
+	Amount  float64 `json:"amount"`
+}
+
+// 奖励到账 SourceID是奖励来源的业务单号
+type BonusGranted struct {
+	UID      uint    `json:"uid"`
+	FlowType uint16  `json:"flow_type"`
+	SourceID string  `json:"source_id"`
+	Amount   float64 `json:"amount"`
+}
+
+func (e *UserRegistered) EventName() string      { return NameUserRegistered }
+func (e *DepositSucceeded) EventName() string    { return NameDepositSucceeded }
+func (e *BetPlaced) EventName() string           { return NameBetPlaced }
+func (e *BetSettled) EventName() string          { return NameBetSettled }
+func (e *WithdrawalRequested) EventName() string { return NameWithdrawalRequested }
+func (e *WithdrawalPaid) EventName() string      { return NameWithdrawalPaid }
+func (e *BonusGranted) EventName() string        { return NameBonusGranted }
+
+func (e *UserRegistered) EventKey() string      { return fmt.Sprintf("%d", e.UID) }
+func (e *DepositSucceeded) EventKey() string    { return e.OrderID }
+func (e *BetPlaced) EventKey() string           { return e.Game + ":" + e.OrderID }
+func (e *BetSettled) EventKey() string          { return e.Game + ":" + e.OrderID }
+func (e *WithdrawalRequested) EventKey() string { return e.OrderID }
+func (e *WithdrawalPaid) EventKey() string      { return e.OrderID }
+func (e *BonusGranted) EventKey() string {
+	return fmt.Sprintf("%d:%d:%s", e.UID, e.FlowType, e.SourceID)
+}
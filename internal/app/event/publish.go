@@ -0,0 +1,45 @@
+package event
+
+import (
+	"strings"
+
+	"rk-api/internal/app/mq"
+	"rk-api/pkg/cjson"
+
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+)
+
+// 事件和队列消息共用发件箱 中继按类型前缀写入redis stream
+const taskPrefix = "event:"
+
+// 在业务事务内发布 事务回滚事件也不会发出
+func PublishTx(tx *gorm.DB, evt Event) error {
+	task, err := newTask(evt)
+	if err != nil {
+		return err
+	}
+	return mq.EnqueueTxWithKey(tx, task, evt.EventKey())
+}
+
+// 不在事务中的业务发布
+func Publish(evt Event) error {
+	task, err := newTask(evt)
+	if err != nil {
+		return err
+	}
+	return mq.PublishWithKey(task, evt.EventKey())
+}
+
+// 发件箱消息类型是否是事件 返回事件名
+func ParseTaskType(typename string) (string, bool) {
+	return strings.CutPrefix(typename, taskPrefix)
+}
+
+func newTask(evt Event) (*asynq.Task, error) {
+	payload, err := cjson.Cjson.Marshal(evt)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(taskPrefix+evt.EventName(), payload), nil
+}
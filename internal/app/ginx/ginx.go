@@ -196,3 +196,17 @@ func SetDeviceID(ctx *gin.Context) string {
 	ctx.SetCookie("deviceId", newDeviceId, 3600*24*30, "/", "", false, true) // 有效期30天
 	return newDeviceId
 }
+
+// 获取设备ID cookie中没有时生成新的
+func GetDeviceID(ctx *gin.Context) string {
+	deviceId, err := ctx.Cookie("deviceId")
+	if err != nil || deviceId == "" {
+		deviceId = SetDeviceID(ctx)
+	}
+	return deviceId
+}
+
+// 当前请求的会话ID 由JWTMiddleware/AdminMiddleware写入
+func SessionID(c *gin.Context) string {
+	return c.GetString("sessionID")
+}
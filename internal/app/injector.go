@@ -2,6 +2,7 @@ package internal
 
 import (
 	game "rk-api/internal/app/game/rg"
+	"rk-api/internal/app/service"
 	"rk-api/internal/app/service/async"
 
 	"github.com/gin-gonic/gin"
@@ -11,8 +12,11 @@ import (
 var InjectorSet = wire.NewSet(wire.Struct(new(Injector), "*"))
 
 type Injector struct {
-	Engine  *gin.Engine
-	Service async.IAsyncService
-	Nine    game.INine
-	Wingo   game.IWingo
+	Engine    *gin.Engine
+	Service   async.IAsyncService
+	Nine      game.INine
+	Wingo     game.IWingo
+	Scheduler *service.SchedulerService
+	Outbox    *service.OutboxService
+	Events    *service.EventSubscriberService
 }
@@ -1,54 +1,61 @@
 package middleware
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"bytes"
+	"io"
 	"net/http"
-	"rk-api/internal/app/config"
-	"rk-api/pkg/logger"
-	"time"
+	"rk-api/internal/app/errors"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// GenerateMD5Token 根据 userID, appKey 和当前日期生成MD5
-func GenerateMD5Token(userID string, timezone string, appKey string) string {
-
-	// 加载时区，例如东京时间
-	location, err := time.LoadLocation(timezone)
-	if err != nil {
-		return ""
-	}
-	// 使用加载的时区创建一个时间对象
-	currentDate := time.Now().In(location).Format("2006-01-02")
-	data := userID + appKey + currentDate
-	logger.Info("----------------GenerateMD5Token-----------------" + data)
-	hash := md5.Sum([]byte(data))
-	return hex.EncodeToString(hash[:])
-}
-
-// ValidateMD5Token 核对提供的MD5值是否和生成的相同  1julier@landing20232024-02-19
-func ValidateMD5Token(userID string, timezone, appKey string, token string) bool {
-	// 生成 token
-	// logger.Info("--------ValidateMD5Token---------------", userID, appKey, token)
-	generatedToken := GenerateMD5Token(userID, timezone, appKey)
-	// 比较提供的 token 与生成的 token
+// 后台鉴权和操作日志 服务启动时由路由注入 中间件不直接依赖service
+var (
+	adminAuthorizer   func(token, permission, ip string) (string, string, error)
+	adminActionLogger func(userID, sid, permission, path, body, ip string, status int)
+)
 
-	return generatedToken == token
+func SetAdminAuthorizer(authorize func(token, permission, ip string) (string, string, error), logAction func(userID, sid, permission, path, body, ip string, status int)) {
+	adminAuthorizer = authorize
+	adminActionLogger = logAction
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// AdminMiddleware 校验后台token和接口权限 permission为空时只要求登录
+// 处理完成后记录一条后台操作日志
+func AdminMiddleware(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		timezone := c.Query("timezone")
-		userID := c.Query("uid")
-		token := c.Query("token")
-		if !ValidateMD5Token(userID, timezone, config.Get().ServiceSettings.JwtSignKey, token) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid  token"})
-			c.Abort()
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if token == "" || adminAuthorizer == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
 			return
 		}
-		// Extract userID from JWT claims
+		userID, sid, err := adminAuthorizer(token, permission, c.ClientIP())
+		if err != nil {
+			if errors.IsCode(err, errors.AdminPermissionDenied) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+			return
+		}
+
+		// 请求体读出来留给日志 再放回去给后面的处理函数
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+		}
+
 		c.Set("userID", userID)
+		c.Set("sessionID", sid)
 		c.Next()
+
+		if adminActionLogger != nil {
+			adminActionLogger(userID, sid, permission, c.FullPath(), string(body), c.ClientIP(), c.Writer.Status())
+		}
 	}
 }
@@ -63,7 +63,15 @@ import (
 // 	}
 // }
 
-// AuthMiddleware 处理 access_token 和 refresh_token 的中间件
+// 会话校验 服务启动时由路由注入 中间件不直接依赖service
+var sessionValidator func(userID, sid, token, ip string) error
+
+func SetSessionValidator(fn func(userID, sid, token, ip string) error) {
+	sessionValidator = fn
+}
+
+// AuthMiddleware 处理 access_token 的中间件
+// access_token 过期后由客户端调用 /auth/refresh-token 轮换 这里不再静默续期
 func JWTMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		//从链接参数获取token
@@ -80,7 +88,7 @@ func JWTMiddleware() gin.HandlerFunc {
 			accessToken = strings.TrimPrefix(authHeader, "Bearer ")
 		}
 
-		// 解析 access_token
+		// 解析 access_token 过期的token在这里就会解析失败
 		claims, err := utils.ParseJWT(accessToken, config.Get().ServiceSettings.JwtSignKey)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token"})
@@ -93,67 +101,36 @@ func JWTMiddleware() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid exp claim"})
 			return
 		}
-		expirationTime := time.Unix(int64(exp), 0)
-		if time.Now().After(expirationTime) {
-			// 如果 access_token 过期，尝试使用 refresh_token 刷新
-			var refreshToken string
-
-			// 1. 从 Cookie 中获取
-			refreshToken, err = c.Cookie(constant.REFRESH_TOKEN)
-			if err != nil {
-				// 2. 从 Header 中获取
-				refreshToken = c.GetHeader(constant.REFRESH_TOKEN)
-			}
-
-			// 如果仍然没有获取到 refresh_token
-			if refreshToken == "" {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-					"error": "Refresh token is required. Please enable cookies or provide a refresh token in the header.",
-				})
-				return
-			}
+		if time.Now().After(time.Unix(int64(exp), 0)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Expired access token"})
+			return
+		}
 
-			// 验证 refresh_token 是否有效
-			refreshClaims, err := utils.ParseJWT(refreshToken, config.Get().ServiceSettings.JwtSignKey)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
-				return
-			}
+		// refresh_token 不能当作 access_token 使用
+		if typ, _ := claims["typ"].(string); typ == constant.JWT_TOKEN_TYPE_REFRESH {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token"})
+			return
+		}
 
-			// 检查 refresh_token 是否过期
-			refreshExp, ok := refreshClaims["exp"].(float64)
-			if !ok {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid exp claim in refresh token"})
-				return
-			}
-			refreshExpirationTime := time.Unix(int64(refreshExp), 0)
-			if time.Now().After(refreshExpirationTime) {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Expired refresh token"})
-				return
-			}
+		userID, ok := claims["userID"].(string)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid userID claim"})
+			return
+		}
 
-			// 检查 refresh_token 是否被踢下线
-			userID := refreshClaims["userID"].(string)
-			// 生成新的 access_token
-			newAccessToken, err := utils.GenerateJWT(config.Get().ServiceSettings.JwtSignKey, time.Hour, userID)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new access token"})
+		// 检查 access_token 所属会话是否被踢下线
+		sid, _ := claims["sid"].(string)
+		if sessionValidator != nil {
+			if err := sessionValidator(userID, sid, accessToken, c.ClientIP()); err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
 				return
 			}
-
-			// 将新的 access_token 返回给客户端
-			c.Header("Authorization", "Bearer "+newAccessToken)
 		}
 
-		// 检查 access_token 是否被踢下线
-		userID := claims["userID"].(string)
 		// 将用户信息存储到上下文中
 		c.Set("userID", userID)
+		c.Set("sessionID", sid)
 		// 继续处理请求
 		c.Next()
 	}
 }
-
-// accessToken
-
-// refreshToken
@@ -0,0 +1,134 @@
+package mq
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/mq/handle"
+
+	"github.com/hibiken/asynq"
+)
+
+const dlqPageSize = 100
+
+var (
+	inspector     *asynq.Inspector
+	inspectorOnce sync.Once
+
+	ErrDeadTaskPayload = errors.New("mq: payload is not valid json")
+)
+
+func Inspector() *asynq.Inspector {
+	inspectorOnce.Do(func() {
+		inspector = asynq.NewInspector(redisConnOpt())
+	})
+	return inspector
+}
+
+func IsDeadTaskNotFound(err error) bool {
+	return errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound)
+}
+
+// 所有队列里重试耗尽或等待重试的任务 按类型筛选后分页
+func ListDeadTasks(state, typename string, page, pageSize int) ([]*entities.DeadLetterTask, int64, error) {
+	queues, err := Inspector().Queues()
+	if err != nil {
+		return nil, 0, err
+	}
+	list := make([]*entities.DeadLetterTask, 0)
+	for _, queue := range queues {
+		for p := 1; (p-1)*dlqPageSize < constant.DLQ_SCAN_LIMIT; p++ {
+			var tasks []*asynq.TaskInfo
+			if state == constant.DLQ_STATE_RETRY {
+				tasks, err = Inspector().ListRetryTasks(queue, asynq.PageSize(dlqPageSize), asynq.Page(p))
+			} else {
+				tasks, err = Inspector().ListArchivedTasks(queue, asynq.PageSize(dlqPageSize), asynq.Page(p))
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, task := range tasks {
+				if typename == "" || task.Type == typename {
+					list = append(list, toDeadLetterTask(task))
+				}
+			}
+			if len(tasks) < dlqPageSize {
+				break
+			}
+		}
+	}
+
+	count := int64(len(list))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = dlqPageSize
+	}
+	start, end := (page-1)*pageSize, page*pageSize
+	if start > len(list) {
+		start = len(list)
+	}
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[start:end], count, nil
+}
+
+func GetDeadTask(queue, id string) (*entities.DeadLetterTask, error) {
+	task, err := Inspector().GetTaskInfo(queue, id)
+	if err != nil {
+		return nil, err
+	}
+	return toDeadLetterTask(task), nil
+}
+
+// payload为空时原样重放 否则按修改后的内容重新入队并删除原任务
+// 原内容是json的 修改后也必须是json
+func ReplayDeadTask(queue, id string, payload []byte) error {
+	if len(payload) == 0 {
+		return Inspector().RunTask(queue, id)
+	}
+	info, err := Inspector().GetTaskInfo(queue, id)
+	if err != nil {
+		return err
+	}
+	if json.Valid(info.Payload) && !json.Valid(payload) {
+		return ErrDeadTaskPayload
+	}
+	if MClient == nil {
+		return errors.New("mq: client not initialized")
+	}
+	task := asynq.NewTask(info.Type, payload, handle.TaskOptions(info.Type)...)
+	if _, err := Enqueue(task, asynq.Queue(info.Queue)); err != nil {
+		return err
+	}
+	return Inspector().DeleteTask(queue, id)
+}
+
+func DiscardDeadTask(queue, id string) error {
+	return Inspector().DeleteTask(queue, id)
+}
+
+func toDeadLetterTask(task *asynq.TaskInfo) *entities.DeadLetterTask {
+	item := &entities.DeadLetterTask{
+		ID:       task.ID,
+		Queue:    task.Queue,
+		Type:     task.Type,
+		State:    task.State.String(),
+		Payload:  string(task.Payload),
+		MaxRetry: task.MaxRetry,
+		Retried:  task.Retried,
+		LastErr:  task.LastErr,
+	}
+	if !task.LastFailedAt.IsZero() {
+		item.LastFailedAt = task.LastFailedAt.Unix()
+	}
+	if !task.NextProcessAt.IsZero() {
+		item.NextProcessAt = task.NextProcessAt.Unix()
+	}
+	return item
+}
@@ -18,7 +18,7 @@ func NewCreateFlowQueue(flow *entities.Flow) (*asynq.Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	return asynq.NewTask(QueueCreateFlow, payload, asynq.MaxRetry(3)), nil
+	return asynq.NewTask(QueueCreateFlow, payload, TaskOptions(QueueCreateFlow)...), nil
 }
 
 func CreateFlowHandle(ctx context.Context, t *asynq.Task) error {
@@ -27,7 +27,7 @@ func NewInvitePinduoQueue(relation *entities.HallInvitePinduo) (*asynq.Task, err
 	if err != nil {
 		return nil, err
 	}
-	return asynq.NewTask(QueueInvitePinduo, payload, asynq.MaxRetry(1)), nil
+	return asynq.NewTask(QueueInvitePinduo, payload, TaskOptions(QueueInvitePinduo)...), nil
 }
 
 func InvitePinduoHandle(ctx context.Context, t *asynq.Task) error {
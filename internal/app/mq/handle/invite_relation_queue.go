@@ -18,7 +18,7 @@ func NewInviteRelationQueue(relation *entities.HallInviteRelation) (*asynq.Task,
 	if err != nil {
 		return nil, err
 	}
-	return asynq.NewTask(QueueInviteRelation, payload, asynq.MaxRetry(2)), nil
+	return asynq.NewTask(QueueInviteRelation, payload, TaskOptions(QueueInviteRelation)...), nil
 }
 
 func InviteRelationHandle(ctx context.Context, t *asynq.Task) error {
@@ -18,7 +18,7 @@ func NewNotificationQueue(flow *entities.Notification) (*asynq.Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	return asynq.NewTask(QueueNotification, payload, asynq.MaxRetry(1)), nil
+	return asynq.NewTask(QueueNotification, payload, TaskOptions(QueueNotification)...), nil
 }
 
 func NotificationHandle(ctx context.Context, t *asynq.Task) error {
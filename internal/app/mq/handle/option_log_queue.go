@@ -19,7 +19,7 @@ func NewOptionLogQueue(log *entities.SystemOptionLog) (*asynq.Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	return asynq.NewTask(QueueOptionLog, payload, asynq.MaxRetry(1)), nil
+	return asynq.NewTask(QueueOptionLog, payload, TaskOptions(QueueOptionLog)...), nil
 }
 
 func OptionLogHandle(ctx context.Context, t *asynq.Task) error {
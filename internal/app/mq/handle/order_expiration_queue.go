@@ -9,7 +9,7 @@ import (
 const QueueOrderExpiration = "order:expiration"
 
 func NewOrderExpirationQueue(tradeId string) (*asynq.Task, error) {
-	return asynq.NewTask(QueueOrderExpiration, []byte(tradeId)), nil
+	return asynq.NewTask(QueueOrderExpiration, []byte(tradeId), TaskOptions(QueueOrderExpiration)...), nil
 }
 
 // OrderExpirationHandle 设置订单过期
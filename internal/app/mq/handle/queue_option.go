@@ -0,0 +1,33 @@
+package handle
+
+import (
+	"sort"
+
+	"github.com/hibiken/asynq"
+)
+
+// 各队列的投递参数 生产者和outbox中继共用 中继重建任务时要带上同样的参数
+var queueOptions = map[string][]asynq.Option{
+	QueueCreateFlow:      {asynq.MaxRetry(3)},
+	QueueUserExpiration:  {asynq.MaxRetry(3)},
+	QueueInviteRelation:  {asynq.MaxRetry(2)},
+	QueueInvitePinduo:    {asynq.MaxRetry(1)},
+	QueueOptionLog:       {asynq.MaxRetry(1)},
+	QueueNotification:    {asynq.MaxRetry(1)},
+	QueueWithdrawRisk:    {asynq.MaxRetry(3)},
+	QueueOrderExpiration: {},
+}
+
+func TaskOptions(typename string) []asynq.Option {
+	return queueOptions[typename]
+}
+
+// 所有队列类型 按名称排序
+func QueueTypes() []string {
+	list := make([]string, 0, len(queueOptions))
+	for typename := range queueOptions {
+		list = append(list, typename)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -12,7 +12,7 @@ import (
 const QueueUserExpiration = "user:expiration"
 
 func NewUserExpirationQueue(uid string) (*asynq.Task, error) {
-	return asynq.NewTask(QueueUserExpiration, []byte(uid), asynq.MaxRetry(3)), nil
+	return asynq.NewTask(QueueUserExpiration, []byte(uid), TaskOptions(QueueUserExpiration)...), nil
 }
 
 func UserExpirationHandle(ctx context.Context, t *asynq.Task) error {
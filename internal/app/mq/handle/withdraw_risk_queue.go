@@ -0,0 +1,39 @@
+package handle
+
+import (
+	"context"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/service/async"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+const QueueWithdrawRisk = "withdraw:risk"
+
+func NewWithdrawRiskQueue(task *entities.WithdrawRiskTask) (*asynq.Task, error) {
+	payload, err := cjson.Cjson.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(QueueWithdrawRisk, payload, TaskOptions(QueueWithdrawRisk)...), nil
+}
+
+func NewWithdrawRiskHandle(srv async.IAsyncService) asynq.HandlerFunc {
+	return func(ctx context.Context, t *asynq.Task) error {
+		var task entities.WithdrawRiskTask
+		if err := cjson.Cjson.Unmarshal(t.Payload(), &task); err != nil {
+			return err
+		}
+		if err := srv.CheckWithdrawRisk(&task); err != nil {
+			logger.ZError("CheckWithdrawRisk:",
+				zap.String("task", string(t.Payload())),
+				zap.Error(err),
+			)
+			return err
+		}
+		return nil
+	}
+}
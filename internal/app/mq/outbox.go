@@ -0,0 +1,76 @@
+package mq
+
+import (
+	"errors"
+	"time"
+
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	outboxDB     *gorm.DB
+	outboxNotify = make(chan struct{}, 1)
+)
+
+// 设置不在事务中写发件箱用的连接 启动时调用
+func InitOutbox(db *gorm.DB) {
+	outboxDB = db
+}
+
+// 在业务事务内写入发件箱 事务回滚消息就不会投递 写入失败应返回错误让事务回滚
+// 没有业务标识的消息 每次写入都是一条新消息
+func EnqueueTx(tx *gorm.DB, task *asynq.Task) error {
+	return EnqueueTxWithKey(tx, task, "")
+}
+
+// key是消息的业务标识 同一类型同一个key只写入一次 重复写入直接忽略 不影响业务事务
+func EnqueueTxWithKey(tx *gorm.DB, task *asynq.Task, key string) error {
+	if task == nil {
+		return errors.New("mq: nil task")
+	}
+	if key == "" {
+		key = uuid.NewString()
+	}
+	msg := &entities.OutboxMessage{
+		Type:     task.Type(),
+		Payload:  string(task.Payload()),
+		DedupKey: task.Type() + ":" + key,
+		Status:   constant.OUTBOX_STATUS_PENDING,
+		NextTime: time.Now().Unix(),
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(msg).Error
+}
+
+// 不在事务中的消息也经过发件箱 写入后唤醒本节点的中继
+func Publish(task *asynq.Task) error {
+	return PublishWithKey(task, "")
+}
+
+func PublishWithKey(task *asynq.Task, key string) error {
+	if outboxDB == nil {
+		return errors.New("mq: outbox not initialized")
+	}
+	if err := EnqueueTxWithKey(outboxDB, task, key); err != nil {
+		return err
+	}
+	NotifyOutbox()
+	return nil
+}
+
+// 事务提交后唤醒中继 不阻塞
+func NotifyOutbox() {
+	select {
+	case outboxNotify <- struct{}{}:
+	default:
+	}
+}
+
+func OutboxNotified() <-chan struct{} {
+	return outboxNotify
+}
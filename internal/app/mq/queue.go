@@ -18,6 +18,17 @@ import (
 var MClient *asynq.Client
 
 func Start(service async.IAsyncService) {
+	opt := redisConnOpt()
+	initClient(opt)
+	go initListen(opt, service)
+}
+
+// 只投递不消费 命令行工具使用
+func InitClient() {
+	initClient(redisConnOpt())
+}
+
+func redisConnOpt() asynq.RedisConnOpt {
 	setting := config.Get().RDBSettings
 
 	var opt asynq.RedisConnOpt
@@ -37,9 +48,7 @@ func Start(service async.IAsyncService) {
 		}
 
 	}
-
-	initClient(opt)
-	go initListen(opt, service)
+	return opt
 }
 
 func initClient(redis asynq.RedisConnOpt) {
@@ -108,12 +117,14 @@ func initListen(redis asynq.RedisConnOpt, service async.IAsyncService) {
 		},
 	)
 	mux := asynq.NewServeMux()
+	mux.Use(taskStatsMiddleware(service))                                               //按类型统计成功/失败 用于失败率告警
 	mux.HandleFunc(handle.QueueCreateFlow, handle.NewCreateFlowHandler(service))        //用户流水处理
 	mux.HandleFunc(handle.QueueUserExpiration, handle.NewUserExpirationHandle(service)) //用户缓存已过期处理
 	mux.HandleFunc(handle.QueueInviteRelation, handle.NewInviteRelationHandle(service)) //邀请关系添加处理
 	mux.HandleFunc(handle.QueueInvitePinduo, handle.NewInvitePinduoHandle(service))     //邀请pinduo添加处理
 	mux.HandleFunc(handle.QueueOptionLog, handle.NewOptionLogHandle(service))           //后台操作日志
 	mux.HandleFunc(handle.QueueNotification, handle.NewNotificationHandler(service))    //用户通知信息
+	mux.HandleFunc(handle.QueueWithdrawRisk, handle.NewWithdrawRiskHandle(service))     //提现风控评估
 
 	if err := srv.Run(mux); err != nil {
 		logger.ZError("MClient RUN", zap.Error(err))
@@ -140,6 +151,16 @@ func Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
 	return nil, nil
 }
 
+func taskStatsMiddleware(service async.IAsyncService) asynq.MiddlewareFunc {
+	return func(h asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			err := h.ProcessTask(ctx, t)
+			service.RecordTaskResult(t.Type(), err != nil)
+			return err
+		})
+	}
+}
+
 // 检查是否是集群重定向错误
 func isClusterRedirectError(err error) bool {
 	if err == nil {
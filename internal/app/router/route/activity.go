@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -16,7 +17,7 @@ func RegisterActivityRoutes(r *gin.RouterGroup, activityAPI *api.ActivityAPI) {
 		activity.POST("/join-pinduo", middleware.JWTMiddleware(), activityAPI.JoinPinduo)
 		activity.POST("/get-pinduo-cash", middleware.JWTMiddleware(), activityAPI.GetPinduoCash)
 
-		activity.POST("/admin/add-red-envelope", middleware.AdminMiddleware(), activityAPI.AddRedEnvelope)
-		activity.POST("/admin/del-red-envelope", middleware.AdminMiddleware(), activityAPI.DelRedEnvelope)
+		activity.POST("/admin/add-red-envelope", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), activityAPI.AddRedEnvelope)
+		activity.POST("/admin/del-red-envelope", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), activityAPI.DelRedEnvelope)
 	}
 }
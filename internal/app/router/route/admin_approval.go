@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/middleware"
+)
+
+// 复核人的权限按复核单的操作在service里校验
+func RegisterAdminApprovalRoutes(r *gin.RouterGroup, adminApprovalAPI *api.AdminApprovalAPI) {
+	approval := r.Group("/admin/approval")
+	{
+		approval.POST("/get-list", middleware.AdminMiddleware(""), adminApprovalAPI.GetApprovalList)
+		approval.POST("/approve", middleware.AdminMiddleware(""), adminApprovalAPI.Approve)
+		approval.POST("/reject", middleware.AdminMiddleware(""), adminApprovalAPI.Reject)
+	}
+}
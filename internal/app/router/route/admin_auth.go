@@ -0,0 +1,27 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterAdminAuthRoutes(r *gin.RouterGroup, adminAuthAPI *api.AdminAuthAPI) {
+	admin := r.Group("/admin")
+	{
+		admin.POST("/login", adminAuthAPI.Login)
+		admin.POST("/bootstrap-admin-user", middleware.OptMiddleware(), adminAuthAPI.BootstrapAdminUser)
+		admin.POST("/refresh-token", middleware.AdminMiddleware(""), adminAuthAPI.RefreshToken)
+		admin.POST("/logout", middleware.AdminMiddleware(""), adminAuthAPI.Logout)
+		admin.POST("/get-profile", middleware.AdminMiddleware(""), adminAuthAPI.GetProfile)
+		admin.POST("/get-permission-list", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.GetPermissionList)
+		admin.POST("/get-role-list", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.GetRoleList)
+		admin.POST("/save-role", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.SaveRole)
+		admin.POST("/del-role", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.DelRole)
+		admin.POST("/get-admin-user-list", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.GetAdminUserList)
+		admin.POST("/save-admin-user", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.SaveAdminUser)
+		admin.POST("/get-session-list", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.GetSessionList)
+		admin.POST("/revoke-session", middleware.AdminMiddleware(constant.ADMIN_PERM_ADMIN_MANAGE), adminAuthAPI.RevokeSession)
+	}
+}
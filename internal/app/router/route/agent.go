@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -16,8 +17,9 @@ func RegisterAgentRoutes(r *gin.RouterGroup, agentAPI *api.AgentAPI) {
 		agent.POST("/get-promotion-link", middleware.JWTMiddleware(), agentAPI.GetPromotionLink)
 		agent.POST("/get-game-rabate-receipt-list", middleware.JWTMiddleware(), agentAPI.GetGameRebateReceiptList)
 
-		agent.POST("/admin/finalize-recharge-return", middleware.AdminMiddleware(), agentAPI.FinalizeRechargeCashReturn)
-		agent.POST("/admin/fix-invite-relation", middleware.OptMiddleware(), agentAPI.FixInviteRelation)
-		agent.POST("/admin/fix-level1-invite-count", agentAPI.FixLevel1InviteCount)
+		agent.POST("/admin/finalize-recharge-return", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), agentAPI.FinalizeRechargeCashReturn)
+		agent.POST("/admin/preview-invite-relation", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), agentAPI.PreviewInviteRelation)
+		agent.POST("/admin/fix-invite-relation", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), agentAPI.FixInviteRelation)
+		agent.POST("/admin/fix-level1-invite-count", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), agentAPI.FixLevel1InviteCount)
 	}
 }
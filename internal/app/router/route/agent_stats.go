@@ -0,0 +1,19 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterAgentStatsRoutes(r *gin.RouterGroup, agentStatsAPI *api.AgentStatsAPI) {
+	agent := r.Group("/agent")
+	{
+		agent.POST("/get-dashboard", middleware.JWTMiddleware(), agentStatsAPI.GetAgentDashboard)
+		agent.POST("/get-downline-list", middleware.JWTMiddleware(), agentStatsAPI.GetAgentDownlineList)
+		agent.POST("/admin/get-dashboard", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), agentStatsAPI.AdminGetAgentDashboard)
+		agent.POST("/admin/get-downline-list", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), agentStatsAPI.AdminGetAgentDownlineList)
+		agent.POST("/admin/aggregate-daily-stats", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), agentStatsAPI.AdminAggregateAgentDailyStats)
+	}
+}
@@ -0,0 +1,16 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterArchiveRoutes(r *gin.RouterGroup, archiveAPI *api.ArchiveAPI) {
+	archive := r.Group("/admin/archive")
+	{
+		archive.POST("/run", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), archiveAPI.RunArchive)
+		archive.POST("/get-job-list", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), archiveAPI.GetJobList)
+	}
+}
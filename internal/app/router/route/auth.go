@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -13,11 +14,16 @@ func RegisterAuthRoutes(r *gin.RouterGroup, authAPI *api.AuthAPI) {
 		auth.POST("/login", authAPI.Login)
 		auth.POST("/mobile-login", authAPI.MobileLogin)
 		auth.POST("/verify-credentials", authAPI.VerifyCredentials)
-		auth.POST("/logout", authAPI.Logout)
+		auth.POST("/logout", middleware.JWTMiddleware(), authAPI.Logout)
+		auth.POST("/refresh-token", authAPI.RefreshToken)
+		auth.POST("/get-session-list", middleware.JWTMiddleware(), authAPI.GetSessionList)
+		auth.POST("/revoke-session", middleware.JWTMiddleware(), authAPI.RevokeSession)
 		auth.POST("/update-password", authAPI.ChangePassword)
 		auth.POST("/reset-password", authAPI.ResetPassword)
 
-		auth.POST("/admin/register-auth-user", middleware.AdminMiddleware(), authAPI.ReigsterAuthUser)
+		auth.POST("/admin/register-auth-user", middleware.AdminMiddleware(constant.ADMIN_PERM_USER_EDIT), authAPI.ReigsterAuthUser)
+		auth.POST("/admin/get-user-session-list", middleware.AdminMiddleware(constant.ADMIN_PERM_USER_SESSION), authAPI.GetUserSessionList)
+		auth.POST("/admin/force-logout", middleware.AdminMiddleware(constant.ADMIN_PERM_USER_SESSION), authAPI.ForceLogout)
 		auth.GET("/ping", authAPI.Ping)
 	}
 }
@@ -0,0 +1,20 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterCashbackRoutes(r *gin.RouterGroup, cashbackAPI *api.CashbackAPI) {
+	cashback := r.Group("/cashback")
+	{
+		cashback.POST("/get-info", middleware.JWTMiddleware(), cashbackAPI.GetCashbackInfo)
+		cashback.POST("/claim", middleware.JWTMiddleware(), cashbackAPI.ClaimCashback)
+		cashback.POST("/get-claim-list", middleware.JWTMiddleware(), cashbackAPI.GetCashbackClaimList)
+		cashback.POST("/admin/get-rule-list", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), cashbackAPI.AdminGetCashbackRuleList)
+		cashback.POST("/admin/save-rule", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), cashbackAPI.SaveCashbackRule)
+		cashback.POST("/admin/del-rule", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), cashbackAPI.DelCashbackRule)
+	}
+}
@@ -0,0 +1,24 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterCommissionRoutes(r *gin.RouterGroup, commissionAPI *api.CommissionAPI) {
+	commission := r.Group("/commission")
+	{
+		commission.POST("/get-plan-info", middleware.JWTMiddleware(), commissionAPI.GetCommissionPlanInfo)
+		commission.POST("/get-statement-list", middleware.JWTMiddleware(), commissionAPI.GetCommissionStatementList)
+		commission.POST("/admin/get-statement-list", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.AdminGetCommissionStatementList)
+		commission.POST("/admin/get-plan-list", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.AdminGetCommissionPlanList)
+		commission.POST("/admin/save-plan", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.SaveCommissionPlan)
+		commission.POST("/admin/del-plan", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.DelCommissionPlan)
+		commission.POST("/admin/get-assign-list", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.AdminGetCommissionPlanAssignList)
+		commission.POST("/admin/save-assign", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.SaveCommissionPlanAssign)
+		commission.POST("/admin/del-assign", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.DelCommissionPlanAssign)
+		commission.POST("/admin/settle", middleware.AdminMiddleware(constant.ADMIN_PERM_AGENT_MANAGE), commissionAPI.SettleCommission)
+	}
+}
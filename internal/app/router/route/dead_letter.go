@@ -0,0 +1,19 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterDeadLetterRoutes(r *gin.RouterGroup, deadLetterAPI *api.DeadLetterAPI) {
+	deadLetter := r.Group("/admin/dead-letter")
+	{
+		deadLetter.POST("/get-list", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), deadLetterAPI.GetList)
+		deadLetter.POST("/get-task", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), deadLetterAPI.GetTask)
+		deadLetter.POST("/replay", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), deadLetterAPI.Replay)
+		deadLetter.POST("/discard", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), deadLetterAPI.Discard)
+		deadLetter.POST("/get-stats", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), deadLetterAPI.GetStats)
+	}
+}
@@ -0,0 +1,18 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterEventRoutes(r *gin.RouterGroup, eventAPI *api.EventAPI) {
+	event := r.Group("/admin/event")
+	{
+		event.POST("/get-group-list", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), eventAPI.GetGroupList)
+		event.POST("/get-event-list", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), eventAPI.GetEventList)
+		event.POST("/replay", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), eventAPI.Replay)
+		event.POST("/get-daily-stats", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), eventAPI.GetDailyStats)
+	}
+}
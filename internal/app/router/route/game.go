@@ -3,6 +3,8 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
 )
 
 func RegisterGameRoutes(r *gin.RouterGroup, gameAPI *api.GameAPI) {
@@ -11,5 +13,15 @@ func RegisterGameRoutes(r *gin.RouterGroup, gameAPI *api.GameAPI) {
 		game.POST("/get-game-list", gameAPI.GetGameList)
 		game.POST("/search", gameAPI.SearchGame)
 		game.POST("/refresh-game-list", gameAPI.RefreshGameList)
+		game.POST("/get-game-collection-list", gameAPI.GetGameCollectionList)
+		game.POST("/admin/get-game-list", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.AdminGetGameList)
+		game.POST("/admin/save-game", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.SaveGame)
+		game.POST("/admin/del-game", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.DelGame)
+		game.POST("/admin/import-game-list", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.ImportGameList)
+		game.POST("/admin/sync-provider-game-list", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.SyncProviderGameList)
+		game.POST("/admin/get-game-collection-list", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.AdminGetGameCollectionList)
+		game.POST("/admin/save-game-collection", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.SaveGameCollection)
+		game.POST("/admin/del-game-collection", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.DelGameCollection)
+		game.POST("/admin/set-game-collection-items", middleware.AdminMiddleware(constant.ADMIN_PERM_GAME_MANAGE), gameAPI.SetGameCollectionItems)
 	}
 }
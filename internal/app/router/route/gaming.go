@@ -0,0 +1,22 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterGamingRoutes(r *gin.RouterGroup, gamingAPI *api.GamingAPI) {
+	gaming := r.Group("/gaming")
+	{
+		gaming.POST("/get-status", middleware.JWTMiddleware(), gamingAPI.GetGamingStatus)
+		gaming.POST("/set-limit", middleware.JWTMiddleware(), gamingAPI.SetGamingLimit)
+		gaming.POST("/set-session-reminder", middleware.JWTMiddleware(), gamingAPI.SetSessionReminder)
+		gaming.POST("/cool-off", middleware.JWTMiddleware(), gamingAPI.CoolOff)
+		gaming.POST("/self-exclude", middleware.JWTMiddleware(), gamingAPI.SelfExclude)
+		gaming.POST("/admin/get-status", middleware.AdminMiddleware(constant.ADMIN_PERM_GAMING_MANAGE), gamingAPI.AdminGetGamingStatus)
+		gaming.POST("/admin/set-limit", middleware.AdminMiddleware(constant.ADMIN_PERM_GAMING_MANAGE), gamingAPI.AdminSetGamingLimit)
+		gaming.POST("/admin/set-restriction", middleware.AdminMiddleware(constant.ADMIN_PERM_GAMING_MANAGE), gamingAPI.AdminSetGamingRestriction)
+	}
+}
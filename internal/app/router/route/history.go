@@ -0,0 +1,19 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterHistoryRoutes(r *gin.RouterGroup, historyAPI *api.HistoryAPI) {
+	history := r.Group("/history")
+	{
+		history.POST("/get-bet-history", middleware.JWTMiddleware(), historyAPI.GetBetHistory)
+		history.POST("/export-bet-history", middleware.JWTMiddleware(), historyAPI.ExportBetHistory)
+		history.POST("/get-recent-game-list", middleware.JWTMiddleware(), historyAPI.GetRecentGameList)
+		history.POST("/get-favorite-game-list", middleware.JWTMiddleware(), historyAPI.GetFavoriteGameList)
+		history.POST("/add-favorite-game", middleware.JWTMiddleware(), historyAPI.AddFavoriteGame)
+		history.POST("/del-favorite-game", middleware.JWTMiddleware(), historyAPI.DelFavoriteGame)
+	}
+}
@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -17,10 +18,10 @@ func RegisterNineRoutes(r *gin.RouterGroup, nineAPI *api.NineAPI) {
 
 		nine.POST("/simulate-settle-orders", nineAPI.SimulateSettleOrders)
 
-		nine.POST("/admin/get-period-player-orders", middleware.AdminMiddleware(), nineAPI.GetPeriodPlayerOrderList)
-		nine.POST("/admin/get-period-bet-info", middleware.AdminMiddleware(), nineAPI.GetPeriodBetInfo)
-		nine.POST("/admin/change-period-number", middleware.AdminMiddleware(), nineAPI.ChangePeriodNumber)
-		nine.POST("/admin/get-today-period-list", middleware.AdminMiddleware(), nineAPI.GetTodayPeriodList)
-		nine.POST("/admin/get-period-info", middleware.AdminMiddleware(), nineAPI.GetPeriodInfo)
+		nine.POST("/admin/get-period-player-orders", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), nineAPI.GetPeriodPlayerOrderList)
+		nine.POST("/admin/get-period-bet-info", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), nineAPI.GetPeriodBetInfo)
+		nine.POST("/admin/change-period-number", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_OVERRIDE), nineAPI.ChangePeriodNumber)
+		nine.POST("/admin/get-today-period-list", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), nineAPI.GetTodayPeriodList)
+		nine.POST("/admin/get-period-info", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), nineAPI.GetPeriodInfo)
 	}
 }
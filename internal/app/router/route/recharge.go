@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -23,5 +24,10 @@ func RegisterRechargeRoutes(r *gin.RouterGroup, rechargeAPI *api.RechargeAPI) {
 		recharge.POST("/callback/ant", rechargeAPI.ANTCallback)
 		recharge.POST("/callback/dy", rechargeAPI.DYCallback)
 		recharge.POST("/callback/gaga", rechargeAPI.GAGACallback)
+
+		// 后台接口
+		recharge.POST("/admin/get-recharge-promotion-list", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), rechargeAPI.GetRechargePromotionList)
+		recharge.POST("/admin/save-recharge-promotion", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), rechargeAPI.SaveRechargePromotion)
+		recharge.POST("/admin/get-recharge-promotion-record-list", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), rechargeAPI.GetRechargePromotionRecordList)
 	}
 }
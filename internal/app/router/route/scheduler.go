@@ -0,0 +1,18 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterSchedulerRoutes(r *gin.RouterGroup, schedulerAPI *api.SchedulerAPI) {
+	scheduler := r.Group("/admin/scheduler")
+	{
+		scheduler.POST("/get-job-list", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), schedulerAPI.GetJobList)
+		scheduler.POST("/save-job", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), schedulerAPI.SaveJob)
+		scheduler.POST("/trigger-job", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), schedulerAPI.TriggerJob)
+		scheduler.POST("/get-run-list", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), schedulerAPI.GetRunList)
+	}
+}
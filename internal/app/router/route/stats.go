@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -15,5 +16,10 @@ func RegisterStatsRoutes(r *gin.RouterGroup, statsAPI *api.StatsAPI) {
 		stats.POST("/get-gamer-daily-stats-list", middleware.JWTMiddleware(), statsAPI.GetGamerDailyStatsList)
 		stats.POST("/get-gamer-profit-leaderboard", middleware.JWTMiddleware(), statsAPI.GetUserProfitLeaderboard)
 		stats.POST("/get-profit-leaderboard", statsAPI.GetProfitLeaderboard)
+		stats.POST("/admin/backfill-game-record", middleware.AdminMiddleware(constant.ADMIN_PERM_STATS_MANAGE), statsAPI.BackfillGameRecord)
+		stats.POST("/admin/get-game-record-sync-window-list", middleware.AdminMiddleware(constant.ADMIN_PERM_STATS_MANAGE), statsAPI.GetGameRecordSyncWindowList)
+		stats.POST("/admin/get-game-record-sync-gap-list", middleware.AdminMiddleware(constant.ADMIN_PERM_STATS_MANAGE), statsAPI.GetGameRecordSyncGapList)
+		stats.POST("/admin/get-reconcile-report-list", middleware.AdminMiddleware(constant.ADMIN_PERM_STATS_MANAGE), statsAPI.GetReconcileReportList)
+		stats.POST("/admin/get-reconcile-discrepancy-list", middleware.AdminMiddleware(constant.ADMIN_PERM_STATS_MANAGE), statsAPI.GetReconcileDiscrepancyList)
 	}
 }
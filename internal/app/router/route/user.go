@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -18,7 +19,7 @@ func RegisterUserRoutes(r *gin.RouterGroup, userAPI *api.UserAPI) {
 		user.POST("/bind-telegram", middleware.JWTMiddleware(), userAPI.BindTelegram)
 		user.POST("/bind-email", middleware.JWTMiddleware(), userAPI.BindEmail)
 		user.POST("/upload-avatar", middleware.JWTMiddleware(), userAPI.UploadAvatar)
-		user.POST("/admin/edit-user", middleware.AdminMiddleware(), userAPI.EditUserInfo)
+		user.POST("/admin/edit-user", middleware.AdminMiddleware(constant.ADMIN_PERM_USER_EDIT), userAPI.EditUserInfo)
 		user.POST("/admin/clear-user-cache", userAPI.ClearUserCache)
 	}
 }
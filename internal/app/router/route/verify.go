@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -11,7 +12,7 @@ func RegisterVerifyRoutes(r *gin.RouterGroup, verifyAPI *api.VerifyAPI) {
 	{
 		verify.POST("/send-verify-code", verifyAPI.SendVerifyCode)
 		verify.POST("/admin/get-sms-verification-state", verifyAPI.GetSMSVerificationState)
-		verify.POST("/admin/switch-sms-channel", middleware.AdminMiddleware(), verifyAPI.SwitchSmsChannel)
-		verify.POST("/admin/toggle-sms-verification-state", middleware.AdminMiddleware(), verifyAPI.ToggleSMSVerificationState)
+		verify.POST("/admin/switch-sms-channel", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), verifyAPI.SwitchSmsChannel)
+		verify.POST("/admin/toggle-sms-verification-state", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), verifyAPI.ToggleSMSVerificationState)
 	}
 }
@@ -0,0 +1,21 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/middleware"
+)
+
+func RegisterVipRoutes(r *gin.RouterGroup, vipAPI *api.VipAPI) {
+	vip := r.Group("/vip")
+	{
+		vip.POST("/get-tier-list", vipAPI.GetVipTierList)
+		vip.POST("/get-info", middleware.JWTMiddleware(), vipAPI.GetVipInfo)
+		vip.POST("/set-birthday", middleware.JWTMiddleware(), vipAPI.SetVipBirthday)
+		vip.POST("/admin/get-tier-list", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), vipAPI.AdminGetVipTierList)
+		vip.POST("/admin/save-tier", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), vipAPI.SaveVipTier)
+		vip.POST("/admin/del-tier", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), vipAPI.DelVipTier)
+		vip.POST("/admin/get-user-vip", middleware.AdminMiddleware(constant.ADMIN_PERM_PROMO_MANAGE), vipAPI.AdminGetUserVip)
+	}
+}
@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -12,5 +13,11 @@ func RegisterWalletRoutes(r *gin.RouterGroup, walletAPI *api.WalletAPI) {
 		wallet.POST("/update-wallet-password", middleware.JWTMiddleware(), walletAPI.UpdateWalletPassword)
 		wallet.POST("/enable-wallet-password", middleware.JWTMiddleware(), walletAPI.EnableWalletPassword)
 		wallet.POST("/get-user-wallet", middleware.JWTMiddleware(), walletAPI.GetUserWallet)
+		wallet.POST("/get-turnover-summary", middleware.JWTMiddleware(), walletAPI.GetTurnoverSummary)
+		wallet.POST("/get-turnover-requirement-list", middleware.JWTMiddleware(), walletAPI.GetTurnoverRequirementList)
+
+		// 后台接口
+		wallet.POST("/admin/get-turnover-rule-list", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), walletAPI.GetTurnoverRuleList)
+		wallet.POST("/admin/update-turnover-rule", middleware.AdminMiddleware(constant.ADMIN_PERM_SYSTEM_MANAGE), walletAPI.UpdateTurnoverRule)
 	}
 }
@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -17,11 +18,11 @@ func RegisterWingoRoutes(r *gin.RouterGroup, wingoAPI *api.WingoAPI) {
 		wingo.POST("/create-order", middleware.JWTMiddleware(), wingoAPI.CreateOrder)
 		wingo.POST("/simulate-settle-orders", wingoAPI.SimulateSettleOrders)
 
-		wingo.POST("/admin/get-period-player-orders", middleware.AdminMiddleware(), wingoAPI.GetPeriodPlayerOrderList)
-		wingo.POST("/admin/get-period-bet-info", middleware.AdminMiddleware(), wingoAPI.GetPeriodBetInfo)
-		wingo.POST("/admin/change-period-number", middleware.AdminMiddleware(), wingoAPI.ChangePeriodNumber)
-		wingo.POST("/admin/get-today-period-list", middleware.AdminMiddleware(), wingoAPI.GetTodayPeriodList)
-		wingo.POST("/admin/get-period-info", middleware.AdminMiddleware(), wingoAPI.GetPeriodInfo)
-		wingo.POST("/admin/update-room-limit", middleware.AdminMiddleware(), wingoAPI.UpdateRoomLimit)
+		wingo.POST("/admin/get-period-player-orders", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), wingoAPI.GetPeriodPlayerOrderList)
+		wingo.POST("/admin/get-period-bet-info", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), wingoAPI.GetPeriodBetInfo)
+		wingo.POST("/admin/change-period-number", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_OVERRIDE), wingoAPI.ChangePeriodNumber)
+		wingo.POST("/admin/get-today-period-list", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), wingoAPI.GetTodayPeriodList)
+		wingo.POST("/admin/get-period-info", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_VIEW), wingoAPI.GetPeriodInfo)
+		wingo.POST("/admin/update-room-limit", middleware.AdminMiddleware(constant.ADMIN_PERM_PERIOD_OVERRIDE), wingoAPI.UpdateRoomLimit)
 	}
 }
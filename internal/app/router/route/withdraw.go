@@ -3,6 +3,7 @@ package route
 import (
 	"github.com/gin-gonic/gin"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/middleware"
 )
 
@@ -28,9 +29,12 @@ func RegisterWithdrawRoutes(r *gin.RouterGroup, withdrawAPI *api.WithdrawAPI) {
 		withdraw.POST("/callback/gaga", withdrawAPI.GAGACallback)
 
 		// 后台接口
-		withdraw.POST("/admin/review-withdrawal", middleware.AdminMiddleware(), withdrawAPI.ReviewWithdrawal)
-		withdraw.POST("/admin/add-user-withdraw-card", middleware.AdminMiddleware(), withdrawAPI.AddUserWithdrawCard)
-		withdraw.POST("/admin/del-user-withdraw-card", middleware.AdminMiddleware(), withdrawAPI.DelUserWithdrawCard)
-		withdraw.POST("/admin/fix-user-withdraw-card", middleware.AdminMiddleware(), withdrawAPI.FixUserWithdrawCard)
+		withdraw.POST("/admin/review-withdrawal", middleware.AdminMiddleware(constant.ADMIN_PERM_WITHDRAW_REVIEW), withdrawAPI.ReviewWithdrawal)
+		withdraw.POST("/admin/add-user-withdraw-card", middleware.AdminMiddleware(constant.ADMIN_PERM_WITHDRAW_CARD), withdrawAPI.AddUserWithdrawCard)
+		withdraw.POST("/admin/del-user-withdraw-card", middleware.AdminMiddleware(constant.ADMIN_PERM_WITHDRAW_CARD), withdrawAPI.DelUserWithdrawCard)
+		withdraw.POST("/admin/fix-user-withdraw-card", middleware.AdminMiddleware(constant.ADMIN_PERM_WITHDRAW_CARD), withdrawAPI.FixUserWithdrawCard)
+		withdraw.POST("/admin/get-withdraw-risk-log-list", middleware.AdminMiddleware(constant.ADMIN_PERM_RISK_MANAGE), withdrawAPI.GetWithdrawRiskLogList)
+		withdraw.POST("/admin/get-withdraw-risk-rule-list", middleware.AdminMiddleware(constant.ADMIN_PERM_RISK_MANAGE), withdrawAPI.GetWithdrawRiskRuleList)
+		withdraw.POST("/admin/update-withdraw-risk-rule", middleware.AdminMiddleware(constant.ADMIN_PERM_RISK_MANAGE), withdrawAPI.UpdateWithdrawRiskRule)
 	}
 }
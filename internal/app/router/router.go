@@ -6,6 +6,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 	"rk-api/internal/app/api"
+	"rk-api/internal/app/middleware"
 	"rk-api/internal/app/router/route"
 	"rk-api/internal/app/utils"
 	"rk-api/pkg/logger"
@@ -48,6 +49,18 @@ type Router struct {
 	MineGameAPI     *api.MineGameAPI
 	DiceGameAPI     *api.DiceGameAPI
 	LimboGameAPI    *api.LimboGameAPI
+	HistoryAPI      *api.HistoryAPI
+	GamingAPI       *api.GamingAPI
+	VipAPI          *api.VipAPI
+	CashbackAPI     *api.CashbackAPI
+	AgentStatsAPI   *api.AgentStatsAPI
+	CommissionAPI   *api.CommissionAPI
+	AdminAuthAPI    *api.AdminAuthAPI
+	ApprovalAPI     *api.AdminApprovalAPI
+	ArchiveAPI      *api.ArchiveAPI
+	SchedulerAPI    *api.SchedulerAPI
+	DeadLetterAPI   *api.DeadLetterAPI
+	EventAPI        *api.EventAPI
 }
 
 func (a *Router) Register(app *gin.Engine) error {
@@ -67,6 +80,10 @@ func (a *Router) RegisterAPI(app *gin.Engine) {
 		}
 	}
 
+	middleware.SetSessionValidator(a.AuthAPI.Srv.CheckSession)
+	middleware.SetAdminAuthorizer(a.AdminAuthAPI.Srv.AuthorizeAdmin, a.AdminAuthAPI.Srv.LogAdminAction)
+	a.ApprovalAPI.RegisterActions()
+
 	r := app.Group("/api")
 
 	// 分组注册
@@ -87,6 +104,12 @@ func (a *Router) RegisterAPI(app *gin.Engine) {
 	route.RegisterActivityRoutes(r, a.ActivityAPI)
 	route.RegisterAgentRoutes(r, a.AgentAPI)
 	route.RegisterAdminRoutes(r, a.AdminAPI)
+	route.RegisterAdminAuthRoutes(r, a.AdminAuthAPI)
+	route.RegisterAdminApprovalRoutes(r, a.ApprovalAPI)
+	route.RegisterArchiveRoutes(r, a.ArchiveAPI)
+	route.RegisterSchedulerRoutes(r, a.SchedulerAPI)
+	route.RegisterDeadLetterRoutes(r, a.DeadLetterAPI)
+	route.RegisterEventRoutes(r, a.EventAPI)
 	route.RegisterWithdrawRoutes(r, a.WithdrawAPI)
 	route.RegisterRechargeRoutes(r, a.RechargeAPI)
 	route.RegisterStatsRoutes(r, a.StatsAPI)
@@ -95,6 +118,12 @@ func (a *Router) RegisterAPI(app *gin.Engine) {
 	route.RegisterZFGameRoutes(r, a.ZfAPI)
 	route.RegisterJHSZGameRoutes(r, a.JhszAPI)
 	route.RegisterGameRoutes(r, a.GameAPI)
+	route.RegisterHistoryRoutes(r, a.HistoryAPI)
+	route.RegisterGamingRoutes(r, a.GamingAPI)
+	route.RegisterVipRoutes(r, a.VipAPI)
+	route.RegisterCashbackRoutes(r, a.CashbackAPI)
+	route.RegisterAgentStatsRoutes(r, a.AgentStatsAPI)
+	route.RegisterCommissionRoutes(r, a.CommissionAPI)
 	route.RegisterChatRoutes(r, a.ChatAPI)
 	route.RegisterPlatRoutes(r, a.PlatAPI)
 	route.RegisterRealRoutes(r, a.RealAPI)
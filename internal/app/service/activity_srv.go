@@ -2,6 +2,8 @@ package service
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
@@ -31,21 +33,23 @@ const (
 )
 
 type ActivityService struct {
-	Repo             *repository.ActivityRepository
-	UserSrv          *UserService
-	WalletSrv        *WalletService
-	redEnvelopeMutex sync.Mutex //红包锁
-	actCache         *ecache.Cache
+	Repo        *repository.ActivityRepository
+	UserSrv     *UserService
+	WalletSrv   *WalletService
+	ChatSrv     *ChatService
+	pinduoMutex sync.Mutex //拼多多锁
+	actCache    *ecache.Cache
 }
 
-func ProvideActivityService(repo *repository.ActivityRepository, userSrv *UserService, walletSrv *WalletService) *ActivityService {
+func ProvideActivityService(repo *repository.ActivityRepository, userSrv *UserService, walletSrv *WalletService, chatSrv *ChatService) *ActivityService {
 	actCache := ecache.NewLRUCache(1, 6, 5*time.Minute) //初始化缓存
 	return &ActivityService{
-		Repo:             repo,
-		UserSrv:          userSrv,
-		WalletSrv:        walletSrv,
-		redEnvelopeMutex: sync.Mutex{},
-		actCache:         actCache,
+		Repo:        repo,
+		UserSrv:     userSrv,
+		WalletSrv:   walletSrv,
+		ChatSrv:     chatSrv,
+		pinduoMutex: sync.Mutex{},
+		actCache:    actCache,
 	}
 }
 
@@ -125,7 +129,7 @@ func (s *ActivityService) DelRedEnvelope(req *entities.DelRedEnvelopeReq) (err e
 			logger.ZInfo("DelRedEnvelope", zap.Any("req", req))
 		}
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Error(err))
 		}
 	}()
@@ -157,121 +161,246 @@ func (s *ActivityService) AddRedEnvelope(req *entities.AddRedEnvelopeReq) (err e
 			logger.ZInfo("AddRedEnvelope", zap.Any("req", req))
 		}
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Error(err))
 		}
 	}()
 
-	hong := entities.HongbaoSetting{
-		Number: req.Number,
-		Amount: req.Amount,
-		SYSUID: req.OptionID,
-		Type:   req.Type,
-		Remark: req.Remark,
-		Name:   utils.NewRandomString(8),
+	if req.Number == 0 || req.Amount <= 0 {
+		return errors.WithCode(errors.RedEnvelopeInvalidParam)
+	}
+	now := time.Now()
+	if req.ExpireTime == 0 {
+		req.ExpireTime = now.Add(24 * time.Hour).Unix()
+	} else if req.ExpireTime <= now.Unix() {
+		return errors.WithCode(errors.RedEnvelopeInvalidParam)
+	}
+
+	var shares []*entities.HongbaoShare
+	switch req.Type {
+	case constant.RED_GET_TYPE_NORMAL:
+	case constant.RED_GET_TYPE_LUCKY:
+		amounts, ok := splitLuckyRedEnvelope(req.Amount, req.Number)
+		if !ok {
+			return errors.WithCode(errors.RedEnvelopeInvalidParam)
+		}
+		shares = make([]*entities.HongbaoShare, 0, len(amounts))
+		for i, amount := range amounts {
+			shares = append(shares, &entities.HongbaoShare{Seq: uint(i + 1), Amount: amount})
+		}
+	default:
+		return errors.WithCode(errors.RedEnvelopeInvalidParam)
 	}
 
-	if err = s.Repo.CreateHongbaoSetting(&hong); err != nil {
+	hong := entities.HongbaoSetting{
+		Number:      req.Number,
+		Amount:      req.Amount,
+		SYSUID:      req.OptionID,
+		Type:        req.Type,
+		Remark:      req.Remark,
+		Name:        utils.NewRandomString(8),
+		ExpireTime:  req.ExpireTime,
+		MinRecharge: req.MinRecharge,
+		MinVipLevel: req.MinVipLevel,
+		Channel:     req.Channel,
+		ChatChannel: req.ChatChannel,
+	}
+
+	if err = s.Repo.CreateHongbaoSettingWithShares(&hong, shares); err != nil {
 		return err
 	}
+
+	if hong.ChatChannel != "" {
+		content := cjson.StringifyIgnore(&entities.RedEnvelopeChatContent{
+			Name:       hong.Name,
+			Type:       hong.Type,
+			Amount:     hong.Amount,
+			Number:     hong.Number,
+			ExpireTime: hong.ExpireTime,
+			Remark:     hong.Remark,
+		})
+		if err := s.ChatSrv.SendSystemMessage(hong.ChatChannel, "red_envelope", content); err != nil {
+			logger.ZError("AddRedEnvelope SendSystemMessage", zap.String("name", hong.Name), zap.String("channel", hong.ChatChannel), zap.Error(err))
+		}
+	}
 	return nil
 
 }
 
-func (s *ActivityService) GetRedEnvelope(req *entities.GetRedEnvelopeReq) error {
+// 拼手气红包拆分 以分为单位使用二倍均值法 每份至少0.01 最后打乱顺序保证每个序号的期望一致
+func splitLuckyRedEnvelope(total float64, number uint) ([]float64, bool) {
+	cents := int64(math.Round(total * 100))
+	n := int64(number)
+	if n == 0 || cents < n {
+		return nil, false
+	}
+	list := make([]float64, 0, n)
+	remaining := cents
+	for left := n; left > 1; left-- {
+		max := remaining / left * 2
+		if max > remaining-(left-1) {
+			max = remaining - (left - 1)
+		}
+		amount := int64(1)
+		if max > 1 {
+			amount = rand.Int63n(max) + 1
+		}
+		remaining -= amount
+		list = append(list, float64(amount)/100)
+	}
+	list = append(list, float64(remaining)/100)
+	rand.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+	return list, true
+}
 
-	s.redEnvelopeMutex.Lock() //互斥锁
-	defer s.redEnvelopeMutex.Unlock()
+// 红包过期时间 老数据没有设置过期时间的按创建后24小时
+func redEnvelopeExpireTime(setting *entities.HongbaoSetting) int64 {
+	if setting.ExpireTime > 0 {
+		return setting.ExpireTime
+	}
+	return time.Unix(setting.CreatedAt, 0).Add(24 * time.Hour).Unix()
+}
+
+// 领取条件 累计充值/VIP等级/注册渠道
+func (s *ActivityService) checkRedEnvelopeCondition(setting *entities.HongbaoSetting, user *entities.User) error {
+	if setting.Channel != "" && setting.Channel != user.Channel {
+		return errors.WithCode(errors.RedEnvelopeNoCondition)
+	}
+	if setting.MinVipLevel > 0 && user.VipLevel < setting.MinVipLevel {
+		return errors.WithCode(errors.RedEnvelopeNoCondition)
+	}
+	if setting.MinRecharge > 0 {
+		total, err := s.Repo.GetRechargeTotal(user.ID)
+		if err != nil {
+			return err
+		}
+		if total < setting.MinRecharge {
+			return errors.WithCode(errors.RedEnvelopeNoCondition)
+		}
+	}
+	return nil
+}
+
+// 领取红包 在钱包事务内锁定红包设置行 多节点部署时同一红包的领取也是串行的
+func (s *ActivityService) GetRedEnvelope(req *entities.GetRedEnvelopeReq) (float64, error) {
 
 	if req.UID == 0 {
-		return errors.WithCode(errors.AccountNotExist)
+		return 0, errors.WithCode(errors.AccountNotExist)
 	}
 
 	setting, err := s.Repo.GetHongbaoSettingByName(req.RedName)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if setting == nil {
-		return errors.WithCode(errors.RedEnvelopeNotExist)
+		return 0, errors.WithCode(errors.RedEnvelopeNotExist)
 	}
 
-	if time.Unix(setting.CreatedAt, 0).Add(24 * time.Hour).Before(time.Now()) {
-		return errors.WithCode(errors.RedEnvelopeExpire)
+	if redEnvelopeExpireTime(setting) < time.Now().Unix() {
+		return 0, errors.WithCode(errors.RedEnvelopeExpire)
 	}
 
-	count, err := s.Repo.GetHongbaoCountByHongID(setting.ID)
-	if err != nil {
-		return err
+	if setting.ReceiveNumber >= setting.Number {
+		return 0, errors.WithCode(errors.InsufficientRedEnvelope)
 	}
 
-	if count >= int64(setting.Number) {
-		return errors.WithCode(errors.InsufficientRedEnvelope)
+	if setting.Type != constant.RED_GET_TYPE_NORMAL && setting.Type != constant.RED_GET_TYPE_LUCKY {
+		return 0, errors.With(fmt.Sprintf("not support type %d", setting.Type))
 	}
 
-	hongbao, err := s.Repo.GetHongbao(&entities.HongbaoRecord{HongID: setting.ID, UID: req.UID})
-
+	user, err := s.UserSrv.GetUserByUID(req.UID)
 	if err != nil {
-		return err
-	}
-	if hongbao != nil {
-		return errors.WithCode(errors.RedEnvelopeRepeatGet)
+		return 0, err
 	}
 
-	user, err := s.UserSrv.GetUserByUID(req.UID)
-	if err != nil {
-		return err
+	if err := s.checkRedEnvelopeCondition(setting, user); err != nil {
+		return 0, err
 	}
 
-	if setting.Type == constant.RED_GET_TYPE_NORMAL {
+	var hongbao *entities.HongbaoRecord
+	err = s.WalletSrv.HandleWallet(req.UID, func(wallet *entities.UserWallet, tx *gorm.DB) error {
+		locked, err := s.Repo.GetHongbaoSettingForUpdate(tx, setting.ID)
+		if err != nil {
+			return err
+		}
+		if locked == nil {
+			return errors.WithCode(errors.RedEnvelopeNotExist)
+		}
+		if locked.ReceiveNumber >= locked.Number {
+			return errors.WithCode(errors.InsufficientRedEnvelope)
+		}
+		count, err := s.Repo.CountHongbaoRecordWithTx(tx, locked.ID, wallet.UID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.WithCode(errors.RedEnvelopeRepeatGet)
+		}
 
-		err := s.WalletSrv.HandleWallet(req.UID, func(wallet *entities.UserWallet, tx *gorm.DB) error {
-			redAmount := setting.Amount //金额 直接等于 设置中的金额
-			hongbao = &entities.HongbaoRecord{
-				UID:          wallet.UID,
-				PromoterCode: wallet.PromoterCode,
-				HongID:       setting.ID,
-				HongName:     setting.Name,
-				Amount:       redAmount,
-				Username:     user.Username,
-			}
-			wallet.SafeAdjustCash(hongbao.Amount) //增加金额
-			setting.ReceiveNumber += 1
-			if err := s.Repo.UpdatebaoSettingByWithTx(tx, setting); err != nil {
+		redAmount := locked.Amount //普通红包 金额直接等于设置中的金额
+		if locked.Type == constant.RED_GET_TYPE_LUCKY {
+			share, err := s.Repo.GetHongbaoShareWithTx(tx, locked.ID, locked.ReceiveNumber+1)
+			if err != nil {
 				return err
 			}
-			if err := s.Repo.CreateHongbaoRecordWithTx(tx, hongbao); err != nil {
-				return err
+			if share == nil {
+				return errors.WithCode(errors.InsufficientRedEnvelope)
 			}
+			redAmount = share.Amount
+		}
 
-			logger.ZInfo("GetRedEnvelope UpdateUserWithTx", zap.Uint("uid", wallet.ID), zap.Float64("cash", wallet.Cash))
-			if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
-				return err
-			}
-			createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
-				UID:          hongbao.UID,
-				FlowType:     constant.FLOW_TYPE_GET_RED_ENVELOPE,
-				Number:       hongbao.Amount,
-				Balance:      wallet.Cash,
-				PromoterCode: wallet.PromoterCode,
-			})
-			if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-				logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
-			}
-			logger.ZInfo("GetRedEnvelope succ",
-				zap.Uint("uid", hongbao.UID),
-				zap.String("username", hongbao.Username),
-				zap.Float64("balance", wallet.Cash),
-				zap.Uint("hong_id", hongbao.HongID),
-				zap.String("hong_name", hongbao.HongName),
-				zap.Float64("amount", hongbao.Amount),
-			)
-			return nil
-		})
+		ok, err := s.Repo.IncrHongbaoReceiveNumberWithTx(tx, locked.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.WithCode(errors.InsufficientRedEnvelope)
+		}
 
-		return err
-	}
+		hongbao = &entities.HongbaoRecord{
+			UID:          wallet.UID,
+			PromoterCode: wallet.PromoterCode,
+			HongID:       locked.ID,
+			HongName:     locked.Name,
+			Amount:       redAmount,
+			Username:     user.Username,
+		}
+		wallet.SafeAdjustCash(hongbao.Amount) //增加金额
+		if err := s.Repo.CreateHongbaoRecordWithTx(tx, hongbao); err != nil {
+			return err
+		}
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, wallet.UID, constant.FLOW_TYPE_GET_RED_ENVELOPE, locked.Name, hongbao.Amount); err != nil {
+			return err
+		}
 
-	return errors.With(fmt.Sprintf("not support type %d", setting.Type))
+		logger.ZInfo("GetRedEnvelope UpdateUserWithTx", zap.Uint("uid", wallet.ID), zap.Float64("cash", wallet.Cash))
+		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
+			return err
+		}
+		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
+			UID:          hongbao.UID,
+			FlowType:     constant.FLOW_TYPE_GET_RED_ENVELOPE,
+			Number:       hongbao.Amount,
+			Balance:      wallet.Cash,
+			PromoterCode: wallet.PromoterCode,
+		})
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		logger.ZInfo("GetRedEnvelope succ",
+			zap.Uint("uid", hongbao.UID),
+			zap.String("username", hongbao.Username),
+			zap.Float64("balance", wallet.Cash),
+			zap.Uint("hong_id", hongbao.HongID),
+			zap.String("hong_name", hongbao.HongName),
+			zap.Float64("amount", hongbao.Amount),
+		)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return hongbao.Amount, nil
 }
 
 func countRatio(base, cbxRatio float64) float64 {
@@ -352,8 +481,8 @@ func (s *ActivityService) InvitePinduoOnce(relation *entities.HallInvitePinduo)
 }
 
 func (s *ActivityService) JoinPinDuo(uid uint) (*entities.PinduoInfo, error) {
-	s.redEnvelopeMutex.Lock() //互斥锁
-	defer s.redEnvelopeMutex.Unlock()
+	s.pinduoMutex.Lock() //互斥锁
+	defer s.pinduoMutex.Unlock()
 
 	var setting *entities.PinduoSetting
 	setting, err := s.Repo.GetPinduoSetting(&entities.PinduoSetting{
@@ -429,6 +558,9 @@ func (s *ActivityService) ReceivePinduoBonus(uid uint) (*entities.PinduoRecord,
 		if err := s.Repo.UpdatePinduoRecordWithTx(tx, pinduo); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, uid, constant.FLOW_TYPE_PINDUO, fmt.Sprintf("%d", pinduo.ID), money); err != nil {
+			return err
+		}
 
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
 			return err
@@ -440,8 +572,8 @@ func (s *ActivityService) ReceivePinduoBonus(uid uint) (*entities.PinduoRecord,
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 		return nil
 
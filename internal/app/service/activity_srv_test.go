@@ -0,0 +1,66 @@
+package service
+
+import (
+	"math"
+	"rk-api/internal/app/entities"
+	"testing"
+	"time"
+)
+
+// 拆分后份数正确 每份至少0.01 合计等于总额(按分比较)
+func TestSplitLuckyRedEnvelope(t *testing.T) {
+	tests := []struct {
+		name   string
+		total  float64
+		number uint
+		wantOK bool
+	}{
+		{name: "even", total: 100, number: 10, wantOK: true},
+		{name: "cents", total: 0.37, number: 7, wantOK: true},
+		{name: "exactly one cent each", total: 0.05, number: 5, wantOK: true},
+		{name: "single", total: 8.88, number: 1, wantOK: true},
+		{name: "float total", total: 19.99, number: 3, wantOK: true},
+		{name: "not enough cents", total: 0.04, number: 5, wantOK: false},
+		{name: "zero number", total: 10, number: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for round := 0; round < 200; round++ {
+				list, ok := splitLuckyRedEnvelope(tt.total, tt.number)
+				if ok != tt.wantOK {
+					t.Fatalf("splitLuckyRedEnvelope() ok = %v, want %v", ok, tt.wantOK)
+				}
+				if !ok {
+					return
+				}
+				if len(list) != int(tt.number) {
+					t.Fatalf("len = %d, want %d", len(list), tt.number)
+				}
+				var sum int64
+				for _, amount := range list {
+					cents := int64(math.Round(amount * 100))
+					if cents < 1 {
+						t.Fatalf("amount %v less than 0.01 in %v", amount, list)
+					}
+					sum += cents
+				}
+				if sum != int64(math.Round(tt.total*100)) {
+					t.Fatalf("sum = %d cents, want %v in %v", sum, tt.total, list)
+				}
+			}
+		})
+	}
+}
+
+func TestRedEnvelopeExpireTime(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).Unix()
+	setting := &entities.HongbaoSetting{}
+	setting.CreatedAt = created
+	if got, want := redEnvelopeExpireTime(setting), created+24*3600; got != want {
+		t.Fatalf("redEnvelopeExpireTime() without expire = %d, want %d", got, want)
+	}
+	setting.ExpireTime = created + 3600
+	if got := redEnvelopeExpireTime(setting); got != setting.ExpireTime {
+		t.Fatalf("redEnvelopeExpireTime() = %d, want %d", got, setting.ExpireTime)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+)
+
+var AdminApprovalServiceSet = wire.NewSet(
+	ProvideAdminApprovalService,
+)
+
+// 复核通过后执行 参数为发起时的请求内容
+type AdminApprovalExecutor func(payload []byte) error
+
+type adminApprovalAction struct {
+	permission string
+	execute    AdminApprovalExecutor
+}
+
+// 后台操作复核 敏感操作先建待复核单 另一个具备同样权限的管理员在时限内通过后才执行
+type AdminApprovalService struct {
+	Repo    *repository.AdminApprovalRepository
+	AuthSrv *AdminAuthService
+
+	mu      sync.RWMutex
+	actions map[string]*adminApprovalAction
+}
+
+func ProvideAdminApprovalService(repo *repository.AdminApprovalRepository, authSrv *AdminAuthService) *AdminApprovalService {
+	return &AdminApprovalService{
+		Repo:    repo,
+		AuthSrv: authSrv,
+		actions: make(map[string]*adminApprovalAction),
+	}
+}
+
+// 注册需要复核的操作 permission为复核人需要的权限 与原接口一致
+func (s *AdminApprovalService) RegisterAction(action, permission string, execute AdminApprovalExecutor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions[action] = &adminApprovalAction{permission: permission, execute: execute}
+}
+
+func (s *AdminApprovalService) getAction(action string) *adminApprovalAction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.actions[action]
+}
+
+// 发起复核 req原样保存 复核通过后交给注册的执行函数
+func (s *AdminApprovalService) Submit(action string, req interface{}, makerID uint, ip string) (resp *entities.AdminApprovalResp, err error) {
+	var approval *entities.AdminApproval
+	defer func() {
+		s.writeAdminApprovalLog(makerID, ip, approval, err, "发起复核")
+	}()
+
+	item := s.getAction(action)
+	if item == nil {
+		return nil, errors.WithCode(errors.AdminApprovalNoAction)
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	approval = &entities.AdminApproval{
+		Action:     action,
+		Permission: item.permission,
+		Payload:    string(payload),
+		MakerID:    makerID,
+		MakerIP:    ip,
+		Status:     constant.ADMIN_APPROVAL_STATUS_PENDING,
+		ExpireTime: time.Now().Add(constant.ADMIN_APPROVAL_EXPIRE * time.Second).Unix(),
+	}
+	if err = s.Repo.CreateAdminApproval(approval); err != nil {
+		return nil, err
+	}
+	return &entities.AdminApprovalResp{
+		ApprovalID: approval.ID,
+		Action:     approval.Action,
+		Status:     approval.Status,
+		ExpireTime: approval.ExpireTime,
+	}, nil
+}
+
+// 取待复核单 超时的顺便改为超时
+func (s *AdminApprovalService) getPendingApproval(id uint) (*entities.AdminApproval, error) {
+	approval, err := s.Repo.GetAdminApprovalByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if approval == nil {
+		return nil, errors.WithCode(errors.AdminApprovalNotExist)
+	}
+	if approval.Status != constant.ADMIN_APPROVAL_STATUS_PENDING {
+		return nil, errors.WithCode(errors.AdminApprovalNotPending)
+	}
+	if approval.ExpireTime <= time.Now().Unix() {
+		if err := s.Repo.ExpireAdminApprovals(); err != nil {
+			logger.ZError("ExpireAdminApprovals", zap.Error(err))
+		}
+		return nil, errors.WithCode(errors.AdminApprovalExpired)
+	}
+	return approval, nil
+}
+
+// 复核通过并执行 复核人不能是发起人 且需要具备该操作的权限
+func (s *AdminApprovalService) Approve(req *entities.CheckAdminApprovalReq) (err error) {
+	var approval *entities.AdminApproval
+	defer func() {
+		s.writeAdminApprovalLog(req.OptionID, req.IP, approval, err, "复核通过")
+	}()
+
+	approval, err = s.getPendingApproval(req.ID)
+	if err != nil {
+		return
+	}
+	if approval.MakerID == req.OptionID {
+		return errors.WithCode(errors.AdminApprovalSelfCheck)
+	}
+	item := s.getAction(approval.Action)
+	if item == nil {
+		return errors.WithCode(errors.AdminApprovalNoAction)
+	}
+	if err = s.AuthSrv.CheckAdminPermission(req.OptionID, approval.Permission); err != nil {
+		return
+	}
+
+	// 先抢占状态 防止两个复核人同时通过
+	ok, err := s.Repo.CheckAdminApproval(approval.ID, constant.ADMIN_APPROVAL_STATUS_RUNNING, req.OptionID, req.IP, req.Remark)
+	if err != nil {
+		return
+	}
+	if !ok {
+		return errors.WithCode(errors.AdminApprovalNotPending)
+	}
+	approval.CheckerID = req.OptionID
+	approval.CheckerIP = req.IP
+	approval.Remark = req.Remark
+
+	approval.Status = constant.ADMIN_APPROVAL_STATUS_EXECUTED
+	if err = item.execute([]byte(approval.Payload)); err != nil {
+		approval.Status = constant.ADMIN_APPROVAL_STATUS_FAILED
+		approval.Result = err.Error()
+	}
+	if updateErr := s.Repo.UpdateAdminApprovalResult(approval.ID, approval.Status, approval.Result); updateErr != nil {
+		logger.ZError("UpdateAdminApprovalResult", zap.Uint("id", approval.ID), zap.Error(updateErr))
+	}
+	return
+}
+
+// 驳回 发起人可以撤回自己的复核单 其他人需要具备该操作的权限
+func (s *AdminApprovalService) Reject(req *entities.CheckAdminApprovalReq) (err error) {
+	var approval *entities.AdminApproval
+	defer func() {
+		s.writeAdminApprovalLog(req.OptionID, req.IP, approval, err, "复核驳回")
+	}()
+
+	approval, err = s.getPendingApproval(req.ID)
+	if err != nil {
+		return
+	}
+	if approval.MakerID != req.OptionID {
+		if err = s.AuthSrv.CheckAdminPermission(req.OptionID, approval.Permission); err != nil {
+			return
+		}
+	}
+	ok, err := s.Repo.CheckAdminApproval(approval.ID, constant.ADMIN_APPROVAL_STATUS_REJECTED, req.OptionID, req.IP, req.Remark)
+	if err != nil {
+		return
+	}
+	if !ok {
+		return errors.WithCode(errors.AdminApprovalNotPending)
+	}
+	approval.Status = constant.ADMIN_APPROVAL_STATUS_REJECTED
+	approval.CheckerID = req.OptionID
+	approval.CheckerIP = req.IP
+	approval.Remark = req.Remark
+	return nil
+}
+
+func (s *AdminApprovalService) GetAdminApprovalList(req *entities.GetAdminApprovalListReq) error {
+	if err := s.Repo.ExpireAdminApprovals(); err != nil {
+		return err
+	}
+	return s.Repo.GetAdminApprovalList(req)
+}
+
+// 日志内容为复核单 发起人和复核人都在里面
+func (s *AdminApprovalService) writeAdminApprovalLog(optionID uint, ip string, approval *entities.AdminApproval, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_ADMIN_APPROVAL,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(approval),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+		logger.ZError(remark, zap.Any("approval", approval), zap.Error(err))
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
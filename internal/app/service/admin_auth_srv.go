@@ -0,0 +1,578 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"rk-api/internal/app/config"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/internal/app/utils"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/odeke-em/go-uuid"
+	"github.com/orca-zhang/ecache"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	adminPasswordMinLen = 8
+	adminActionDataMax  = 2000 // 操作日志里请求内容的最大长度
+)
+
+// 可分配的权限 *为全部权限
+var adminPermissions = []string{
+	constant.ADMIN_PERM_ADMIN_MANAGE,
+	constant.ADMIN_PERM_WITHDRAW_REVIEW,
+	constant.ADMIN_PERM_WITHDRAW_CARD,
+	constant.ADMIN_PERM_RISK_MANAGE,
+	constant.ADMIN_PERM_PERIOD_VIEW,
+	constant.ADMIN_PERM_PERIOD_OVERRIDE,
+	constant.ADMIN_PERM_USER_EDIT,
+	constant.ADMIN_PERM_USER_SESSION,
+	constant.ADMIN_PERM_PC_CHANGE,
+	constant.ADMIN_PERM_AGENT_MANAGE,
+	constant.ADMIN_PERM_PROMO_MANAGE,
+	constant.ADMIN_PERM_GAME_MANAGE,
+	constant.ADMIN_PERM_GAMING_MANAGE,
+	constant.ADMIN_PERM_STATS_MANAGE,
+	constant.ADMIN_PERM_SYSTEM_MANAGE,
+}
+
+var AdminAuthServiceSet = wire.NewSet(
+	ProvideAdminAuthService,
+)
+
+// 后台登录和权限 账号密码+谷歌验证码登录 token短期有效 会话存在redis可随时撤销
+type AdminAuthService struct {
+	Repo     *repository.AdminAuthRepository
+	AdminSrv *AdminService
+
+	authCache *ecache.Cache
+}
+
+func ProvideAdminAuthService(repo *repository.AdminAuthRepository, adminSrv *AdminService) *AdminAuthService {
+	return &AdminAuthService{
+		Repo:      repo,
+		AdminSrv:  adminSrv,
+		authCache: ecache.NewLRUCache(4, 64, time.Minute),
+	}
+}
+
+func parseAdminPermissions(permissions string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(permissions, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func hasAdminPermission(permissions []string, permission string) bool {
+	if permission == "" {
+		return true
+	}
+	for _, item := range permissions {
+		if item == constant.ADMIN_PERM_ALL || item == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func isAdminPermission(permission string) bool {
+	if permission == constant.ADMIN_PERM_ALL {
+		return true
+	}
+	for _, item := range adminPermissions {
+		if item == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AdminAuthService) getAdminUser(uid uint) (*entities.AdminUser, error) {
+	key := fmt.Sprintf("user_%d", uid)
+	if val, ok := s.authCache.Get(key); ok {
+		return val.(*entities.AdminUser), nil
+	}
+	user, err := s.Repo.GetAdminUserByUID(uid)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	s.authCache.Put(key, user)
+	return user, nil
+}
+
+func (s *AdminAuthService) getAdminRole(id uint) (*entities.AdminRole, error) {
+	key := fmt.Sprintf("role_%d", id)
+	if val, ok := s.authCache.Get(key); ok {
+		return val.(*entities.AdminRole), nil
+	}
+	role, err := s.Repo.GetAdminRoleByID(id)
+	if err != nil || role == nil {
+		return nil, err
+	}
+	s.authCache.Put(key, role)
+	return role, nil
+}
+
+func (s *AdminAuthService) getAdminPermissions(user *entities.AdminUser) ([]string, error) {
+	role, err := s.getAdminRole(user.RoleID)
+	if err != nil || role == nil {
+		return []string{}, err
+	}
+	return parseAdminPermissions(role.Permissions), nil
+}
+
+// AdminMiddleware每次请求调用 校验token签名/会话/账号状态/权限 返回操作者ID和会话ID
+func (s *AdminAuthService) AuthorizeAdmin(token, permission, ip string) (string, string, error) {
+	claims, err := utils.ParseJWT(token, config.Get().ServiceSettings.JwtSignKey)
+	if err != nil {
+		return "", "", errors.WithCode(errors.AdminSessionInvalid)
+	}
+	if typ, _ := claims["typ"].(string); typ != constant.JWT_TOKEN_TYPE_ADMIN {
+		return "", "", errors.WithCode(errors.AdminSessionInvalid)
+	}
+	userID, _ := claims["userID"].(string)
+	sid, _ := claims["sid"].(string)
+	if userID == "" || sid == "" {
+		return "", "", errors.WithCode(errors.AdminSessionInvalid)
+	}
+	cache, err := s.Repo.GetAdminSessionCache(sid)
+	if err != nil {
+		return "", "", err
+	}
+	if cache != userID {
+		return "", "", errors.WithCode(errors.AdminSessionInvalid)
+	}
+
+	uid, _ := strconv.ParseUint(userID, 10, 64)
+	user, err := s.getAdminUser(uint(uid))
+	if err != nil {
+		return "", "", err
+	}
+	if user == nil || user.Status != constant.ADMIN_USER_STATUS_ACTIVE {
+		return "", "", errors.WithCode(errors.AdminSessionInvalid)
+	}
+	permissions, err := s.getAdminPermissions(user)
+	if err != nil {
+		return "", "", err
+	}
+	if !hasAdminPermission(permissions, permission) {
+		return "", "", errors.WithCode(errors.AdminPermissionDenied)
+	}
+	return userID, sid, nil
+}
+
+// 账号可用且具备权限 复核等不经过AdminMiddleware的场景使用
+func (s *AdminAuthService) CheckAdminPermission(uid uint, permission string) error {
+	user, err := s.getAdminUser(uid)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Status != constant.ADMIN_USER_STATUS_ACTIVE {
+		return errors.WithCode(errors.AdminPermissionDenied)
+	}
+	permissions, err := s.getAdminPermissions(user)
+	if err != nil {
+		return err
+	}
+	if !hasAdminPermission(permissions, permission) {
+		return errors.WithCode(errors.AdminPermissionDenied)
+	}
+	return nil
+}
+
+func (s *AdminAuthService) issueAdminToken(uid uint, sid string) (string, int64, error) {
+	expire := constant.ADMIN_TOKEN_EXPIRE * time.Second
+	token, err := utils.GenerateJWTWithClaims(config.Get().ServiceSettings.JwtSignKey, expire, fmt.Sprintf("%d", uid), map[string]interface{}{
+		"sid": sid,
+		"typ": constant.JWT_TOKEN_TYPE_ADMIN,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return token, time.Now().Add(expire).Unix(), nil
+}
+
+// 密码和谷歌验证码都通过才创建会话 连续失败超过次数锁定一段时间
+func (s *AdminAuthService) Login(req *entities.AdminLoginReq) (resp *entities.AdminLoginResp, err error) {
+	var optionID uint
+	defer func() {
+		s.writeAdminAuthLog(optionID, req.IP, map[string]string{"username": req.Username}, err, "后台登录")
+	}()
+
+	fails, err := s.Repo.GetAdminLoginFail(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if fails >= constant.ADMIN_LOGIN_FAIL_MAX {
+		return nil, errors.WithCode(errors.AdminLoginLocked)
+	}
+
+	user, err := s.Repo.GetAdminUserByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.Status != constant.ADMIN_USER_STATUS_ACTIVE ||
+		bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil ||
+		s.AdminSrv.VerifyGoogleAuthCode(user.Username, req.AuthCode) != nil {
+		if _, err := s.Repo.IncrAdminLoginFail(req.Username); err != nil {
+			logger.ZError("IncrAdminLoginFail", zap.String("username", req.Username), zap.Error(err))
+		}
+		return nil, errors.WithCode(errors.AdminLoginFailed)
+	}
+	optionID = user.UID
+	if err := s.Repo.DelAdminLoginFail(req.Username); err != nil {
+		logger.ZError("DelAdminLoginFail", zap.String("username", req.Username), zap.Error(err))
+	}
+
+	session := &entities.AdminSession{
+		SessionID:  uuid.New(),
+		UID:        user.UID,
+		IP:         req.IP,
+		UserAgent:  req.UserAgent,
+		ExpireTime: time.Now().Unix() + constant.ADMIN_SESSION_EXPIRE,
+		Status:     constant.USER_SESSION_STATUS_ACTIVE,
+	}
+	if len(session.UserAgent) > 255 {
+		session.UserAgent = session.UserAgent[:255]
+	}
+	if err := s.Repo.CreateAdminSession(session); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.SetAdminSessionCache(session.SessionID, user.UID, constant.ADMIN_SESSION_EXPIRE*time.Second); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.UpdateAdminUserLogin(user.ID, req.IP); err != nil {
+		logger.ZError("UpdateAdminUserLogin", zap.Uint("uid", user.UID), zap.Error(err))
+	}
+
+	resp = &entities.AdminLoginResp{UID: user.UID, Username: user.Username}
+	if resp.Token, resp.ExpireTime, err = s.issueAdminToken(user.UID, session.SessionID); err != nil {
+		return nil, err
+	}
+	if resp.Permissions, err = s.getAdminPermissions(user); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// token快过期时换新 会话本身的有效期不变
+func (s *AdminAuthService) RefreshAdminToken(uid uint, sid string) (*entities.AdminLoginResp, error) {
+	user, err := s.getAdminUser(uid)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.WithCode(errors.AdminSessionInvalid)
+	}
+	resp := &entities.AdminLoginResp{UID: user.UID, Username: user.Username}
+	if resp.Token, resp.ExpireTime, err = s.issueAdminToken(uid, sid); err != nil {
+		return nil, err
+	}
+	if resp.Permissions, err = s.getAdminPermissions(user); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *AdminAuthService) Logout(uid uint, sid string) error {
+	return s.Repo.RevokeAdminSession(uid, sid, constant.ADMIN_SESSION_REVOKE_OUT)
+}
+
+func (s *AdminAuthService) revokeAllAdminSessions(uid uint, reason string) error {
+	sids, err := s.Repo.GetAdminSessionIDs(uid)
+	if err != nil {
+		return err
+	}
+	list, err := s.Repo.GetActiveAdminSessionList(uid)
+	if err != nil {
+		return err
+	}
+	for _, session := range list {
+		sids = append(sids, session.SessionID)
+	}
+	for _, sid := range sids {
+		if err := s.Repo.RevokeAdminSession(uid, sid, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AdminAuthService) RevokeAdminSession(req *entities.RevokeAdminSessionReq) (err error) {
+	defer func() {
+		s.writeAdminAuthLog(req.OptionID, req.IP, req, err, "撤销后台会话")
+	}()
+	if req.SessionID != "" {
+		return s.Repo.RevokeAdminSession(req.UID, req.SessionID, constant.ADMIN_SESSION_REVOKE_OPT)
+	}
+	return s.revokeAllAdminSessions(req.UID, constant.ADMIN_SESSION_REVOKE_OPT)
+}
+
+func (s *AdminAuthService) GetAdminSessionList(req *entities.GetAdminSessionListReq) error {
+	return s.Repo.GetAdminSessionList(req)
+}
+
+func (s *AdminAuthService) GetAdminProfile(uid uint) (*entities.AdminProfile, error) {
+	user, err := s.Repo.GetAdminUserByUID(uid)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.WithCode(errors.AdminUserNotExist)
+	}
+	profile := &entities.AdminProfile{User: user}
+	if profile.Role, err = s.Repo.GetAdminRoleByID(user.RoleID); err != nil {
+		return nil, err
+	}
+	if profile.Role != nil {
+		profile.Permissions = parseAdminPermissions(profile.Role.Permissions)
+	}
+	return profile, nil
+}
+
+func (s *AdminAuthService) GetAdminPermissionList() []string {
+	return adminPermissions
+}
+
+func (s *AdminAuthService) GetAdminRoleList() ([]*entities.AdminRole, error) {
+	return s.Repo.GetAdminRoleList()
+}
+
+func (s *AdminAuthService) SaveAdminRole(req *entities.SaveAdminRoleReq) (err error) {
+	defer func() {
+		s.writeAdminAuthLog(req.OptionID, req.IP, req, err, "后台角色保存")
+	}()
+
+	for _, permission := range req.Permissions {
+		if !isAdminPermission(permission) {
+			return errors.WithCode(errors.InvalidParam)
+		}
+	}
+	role := new(entities.AdminRole)
+	if req.ID > 0 {
+		if role, err = s.Repo.GetAdminRoleByID(req.ID); err != nil {
+			return err
+		}
+		if role == nil {
+			return errors.WithCode(errors.AdminRoleNotExist)
+		}
+	}
+	role.Name = req.Name
+	role.Permissions = strings.Join(req.Permissions, ",")
+	role.Remark = req.Remark
+	if err = s.Repo.SaveAdminRole(role); err != nil {
+		return err
+	}
+	s.authCache.Del(fmt.Sprintf("role_%d", role.ID))
+	return nil
+}
+
+func (s *AdminAuthService) DelAdminRole(req *entities.DelAdminRoleReq) (err error) {
+	defer func() {
+		s.writeAdminAuthLog(req.OptionID, req.IP, req, err, "后台角色删除")
+	}()
+
+	count, err := s.Repo.CountAdminUserByRole(req.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.WithCode(errors.AdminRoleInUse)
+	}
+	if err = s.Repo.DelAdminRole(req.ID); err != nil {
+		return err
+	}
+	s.authCache.Del(fmt.Sprintf("role_%d", req.ID))
+	return nil
+}
+
+func (s *AdminAuthService) GetAdminUserList() ([]*entities.AdminUser, error) {
+	return s.Repo.GetAdminUserList()
+}
+
+// 新建账号 或修改角色/密码/状态 改密码和禁用会撤销该账号的全部会话
+func (s *AdminAuthService) SaveAdminUser(req *entities.SaveAdminUserReq) (err error) {
+	logReq := *req
+	if logReq.Password != "" {
+		logReq.Password = "***"
+	}
+	defer func() {
+		s.writeAdminAuthLog(req.OptionID, req.IP, &logReq, err, "后台账号保存")
+	}()
+
+	if req.Password != "" && len(req.Password) < adminPasswordMinLen {
+		return errors.WithCode(errors.InvalidParam)
+	}
+	role, err := s.Repo.GetAdminRoleByID(req.RoleID)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return errors.WithCode(errors.AdminRoleNotExist)
+	}
+
+	var user *entities.AdminUser
+	if req.ID > 0 {
+		if user, err = s.Repo.GetAdminUserByID(req.ID); err != nil {
+			return err
+		}
+		if user == nil {
+			return errors.WithCode(errors.AdminUserNotExist)
+		}
+	} else {
+		if req.Password == "" {
+			return errors.WithCode(errors.InvalidParam)
+		}
+		exist, err := s.Repo.GetAdminUserByUsername(req.Username)
+		if err != nil {
+			return err
+		}
+		if exist != nil {
+			return errors.WithCode(errors.AdminUserExist)
+		}
+		sysUser, err := s.AdminSrv.Repo.GetSysUserByUsername(req.Username)
+		if err != nil {
+			return err
+		}
+		if sysUser == nil {
+			return errors.WithCode(errors.AdminUserNotExist)
+		}
+		user = &entities.AdminUser{UID: uint(sysUser.UID), Username: sysUser.Username}
+	}
+
+	revoke := req.Password != "" && user.ID > 0
+	if req.Password != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		user.Password = string(hashed)
+	}
+	user.RoleID = req.RoleID
+	user.Status = constant.ADMIN_USER_STATUS_ACTIVE
+	if req.Status == constant.ADMIN_USER_STATUS_DISABLED {
+		user.Status = constant.ADMIN_USER_STATUS_DISABLED
+		revoke = true
+	}
+	if err = s.Repo.SaveAdminUser(user); err != nil {
+		return err
+	}
+	s.authCache.Del(fmt.Sprintf("user_%d", user.UID))
+	if revoke {
+		return s.revokeAllAdminSessions(user.UID, constant.ADMIN_SESSION_REVOKE_ACC)
+	}
+	return nil
+}
+
+// 还没有任何后台账号时 用运维token创建第一个超级管理员
+func (s *AdminAuthService) BootstrapAdminUser(req *entities.BootstrapAdminUserReq) (err error) {
+	defer func() {
+		s.writeAdminAuthLog(0, req.IP, map[string]string{"username": req.Username}, err, "初始化后台账号")
+	}()
+
+	count, err := s.Repo.CountAdminUser()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.WithCode(errors.AdminUserExist)
+	}
+	if len(req.Password) < adminPasswordMinLen {
+		return errors.WithCode(errors.InvalidParam)
+	}
+	sysUser, err := s.AdminSrv.Repo.GetSysUserByUsername(req.Username)
+	if err != nil {
+		return err
+	}
+	if sysUser == nil {
+		return errors.WithCode(errors.AdminUserNotExist)
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user := &entities.AdminUser{
+		UID:      uint(sysUser.UID),
+		Username: sysUser.Username,
+		Password: string(hashed),
+		Status:   constant.ADMIN_USER_STATUS_ACTIVE,
+	}
+	role := &entities.AdminRole{Name: "super", Permissions: constant.ADMIN_PERM_ALL, Remark: "超级管理员"}
+	return s.Repo.CreateAdminUserWithRole(user, role)
+}
+
+// 去掉请求内容里的密码字段 过长的截断
+func maskAdminActionData(body string) string {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(body), &data); err == nil {
+		for key := range data {
+			if strings.Contains(strings.ToLower(key), "password") {
+				data[key] = "***"
+			}
+		}
+		body = cjson.StringifyIgnore(data)
+	}
+	if runes := []rune(body); len(runes) > adminActionDataMax {
+		body = string(runes[:adminActionDataMax])
+	}
+	return body
+}
+
+// AdminMiddleware在请求处理完后调用 所有后台接口都记录一条操作日志
+func (s *AdminAuthService) LogAdminAction(userID, sid, permission, path, body, ip string, status int) {
+	uid, _ := strconv.ParseUint(userID, 10, 64)
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_ADMIN_ACTION,
+		OptionID: uint(uid),
+		IP:       ip,
+		Content:  path,
+		Data:     maskAdminActionData(body),
+		Remark:   fmt.Sprintf("%s %s", permission, sid),
+		Result:   "true",
+	}
+	if user, _ := s.getAdminUser(uint(uid)); user != nil {
+		log.OptionName = user.Username
+	}
+	if status >= 400 {
+		log.Result = "false"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
+
+func (s *AdminAuthService) writeAdminAuthLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_ADMIN_AUTH,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
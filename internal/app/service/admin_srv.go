@@ -172,23 +172,6 @@ func (s *AdminService) VerifyGoogleAuthCode(account string, authCode string) err
 	return nil
 }
 
-// 月度备份和清理
-func (s *AdminService) CallMonthBackupAndClean(req *entities.MonthBackupAndCleaReq) error {
-	logger.ZInfo("CallMonthBackupAndClean", zap.Any("req", req))
-	go func() {
-		defer utils.PrintPanicStack()
-		s.StateSrv.SetState(constant.StateMonthBackupAndClean, true)
-		// 确保在函数结束时重新启用返利
-		defer func() {
-			s.StateSrv.SetState(constant.StateMonthBackupAndClean, false)
-		}()
-		if err := s.Repo.CallMonthBackupAndCleanV4(req.TableNames); err != nil {
-			logger.ZError("CallMonthBackupAndClean", zap.Error(err))
-		}
-	}()
-	return nil
-}
-
 // 变更PC号业务员合并
 func (s *AdminService) CallChangePC(req *entities.CallChangePCReq) error {
 	logger.ZInfo("CallChangePC", zap.Any("req", req))
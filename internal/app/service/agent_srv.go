@@ -12,7 +12,7 @@ import (
 	"rk-api/internal/app/service/repository"
 	"rk-api/pkg/cjson"
 	"rk-api/pkg/logger"
-	"rk-api/pkg/structure"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +23,8 @@ import (
 	"gorm.io/gorm"
 )
 
+const inviteCycleCheckDepth = 1000 // 查找上级的最大层数
+
 var AgentServiceSet = wire.NewSet(
 	ProvideAgentService,
 )
@@ -95,113 +97,229 @@ func (s *AgentService) GetInviteRelationList(uid uint) ([]*entities.HallInviteRe
 	return list, nil
 }
 
-// 修改代理关系
-func (s *AgentService) FixInviteRelation(req *entities.FixInviteRelationReq) (err error) {
+// 邀请关系迁移的计划 子树内每个用户删除迁移用户以上的关系 再按新上级重建
+type inviteMovePlan struct {
+	user    *entities.User
+	parent  *entities.User
+	preview *entities.InviteMovePreview
+	depths  map[uint]uint8 // 相对迁移用户的层级 自己为0
+	inserts []*entities.HallInviteRelation
+}
 
+func (s *AgentService) buildInviteMovePlan(req *entities.FixInviteRelationReq) (*inviteMovePlan, error) {
 	user, err := s.UserSrv.GetUserByUID(req.UID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	plan := &inviteMovePlan{
+		user:    user,
+		preview: &entities.InviteMovePreview{UID: user.ID, Promoter: user.Promoter, PromoterCode: user.PromoterCode, Inviter: user.Inviter},
+		depths:  map[uint]uint8{user.ID: 0},
+	}
+	preview := plan.preview
+
+	if req.PromotionCode != 0 { //业务员邀请
+		sysUser, err := s.AdminSrv.GetSysUser(&entities.SysUser{UID: int(req.PromotionCode)}) //用户的分销人处理
+		if err != nil {
+			return nil, errors.WithCode(errors.InvalidPromotionCode) //
+		}
+		preview.Promoter = sysUser.Username //
+		preview.PromoterCode = int(req.PromotionCode)
+		preview.Inviter = "" //置空
 	}
-	var parent *entities.User
+
+	// 新上级和它的上级 作为迁移用户的新祖先
+	ancestors := make([]*entities.HallInviteRelation, 0)
 	if req.PID != 0 {
-		parent, err = s.UserSrv.GetUserByUID(req.PID)
+		parent, err := s.UserSrv.GetUserByUID(req.PID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if user.Inviter == parent.Mobile {
-			return errors.WithCode(errors.InviteRelationExist)
+			return nil, errors.WithCode(errors.InviteRelationExist)
+		}
+		if err := s.checkInviteCycle(user.ID, parent.ID); err != nil {
+			return nil, err
+		}
+		plan.parent = parent
+		preview.NewPID = parent.ID
+		preview.Promoter = parent.Promoter // 设置为父亲的推广信息
+		preview.PromoterCode = parent.PromoterCode
+		preview.Inviter = parent.Mobile
+
+		list, err := s.Repo.GetRelationList(parent.ID)
+		if err != nil {
+			return nil, err
+		}
+		ancestors = append(ancestors, &entities.HallInviteRelation{PID: parent.ID, Level: 1})
+		for _, relation := range list {
+			ancestors = append(ancestors, &entities.HallInviteRelation{PID: relation.PID, Level: relation.Level + 1})
+		}
+		sort.Slice(ancestors, func(i, j int) bool { return ancestors[i].Level < ancestors[j].Level })
+	}
+
+	old, err := s.Repo.GetParentRelation(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if old != nil {
+		preview.OldPID = old.PID
+	}
+	if preview.Before, err = s.Repo.GetRelationList(user.ID); err != nil {
+		return nil, err
+	}
+
+	// 层级达到AGENT_LEVEL_MAX的下级没有迁移用户以上的关系 不受影响
+	mobiles := map[uint]string{user.ID: user.Mobile}
+	descendants, err := s.Repo.GetDescendantRelationList(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, relation := range descendants {
+		if relation.Level < constant.AGENT_LEVEL_MAX {
+			plan.depths[relation.UID] = relation.Level
+			mobiles[relation.UID] = relation.Mobile
 		}
 	}
-	userHistory := new(entities.User)
-	structure.Copy(user, userHistory)
 
+	preview.AffectedUIDs = make([]uint, 0, len(plan.depths))
+	preview.After = make([]*entities.HallInviteRelation, 0)
+	for uid, depth := range plan.depths {
+		preview.AffectedUIDs = append(preview.AffectedUIDs, uid)
+		for _, ancestor := range ancestors {
+			level := depth + ancestor.Level
+			if level > constant.AGENT_LEVEL_MAX {
+				break
+			}
+			relation := &entities.HallInviteRelation{UID: uid, Mobile: mobiles[uid], PID: ancestor.PID, Level: level}
+			plan.inserts = append(plan.inserts, relation)
+			if uid == user.ID {
+				preview.After = append(preview.After, relation)
+			}
+		}
+	}
+	preview.InsertCount = len(plan.inserts)
+
+	// 直属人数的变化
+	if preview.OldPID != 0 && preview.OldPID != preview.NewPID {
+		count, err := s.Repo.GetImmediateInviteCount(preview.OldPID)
+		if err != nil {
+			return nil, err
+		}
+		preview.OldParentInviteCount = count - 1
+	}
+	if preview.NewPID != 0 {
+		count, err := s.Repo.GetImmediateInviteCount(preview.NewPID)
+		if err != nil {
+			return nil, err
+		}
+		preview.NewParentInviteCount = count + 1
+	}
+	return plan, nil
+}
+
+// 沿一级关系向上查找 新上级不能是自己或自己的下级
+func (s *AgentService) checkInviteCycle(uid, pid uint) error {
+	for i := 0; i < inviteCycleCheckDepth && pid != 0; i++ {
+		if pid == uid {
+			return errors.WithCode(errors.InviteRelationCycle)
+		}
+		relation, err := s.Repo.GetParentRelation(pid)
+		if err != nil {
+			return err
+		}
+		if relation == nil {
+			return nil
+		}
+		pid = relation.PID
+	}
+	return nil
+}
+
+// 预览迁移的影响 不做修改
+func (s *AgentService) PreviewInviteRelation(req *entities.FixInviteRelationReq) (*entities.InviteMovePreview, error) {
+	plan, err := s.buildInviteMovePlan(req)
+	if err != nil {
+		return nil, err
+	}
+	return plan.preview, nil
+}
+
+// 修改代理关系 整棵子树的上级关系在一个事务里重建
+func (s *AgentService) FixInviteRelation(req *entities.FixInviteRelationReq) (err error) {
+	var plan *inviteMovePlan
 	defer func() {
+		content := map[string]interface{}{"req": req}
+		if plan != nil {
+			content["before"] = map[string]interface{}{
+				"promoter":  plan.user.Promoter,
+				"pc":        plan.user.PromoterCode,
+				"inviter":   plan.user.Inviter,
+				"pid":       plan.preview.OldPID,
+				"relations": plan.preview.Before,
+			}
+			content["after"] = plan.preview
+		}
 		log := entities.SystemOptionLog{
 			Type:     constant.SYS_OPTION_TYPE_INVITE_RELATION_FIX,
 			OptionID: req.OptionID,
 			IP:       req.IP,
-			Content:  cjson.StringifyIgnore(userHistory),
+			Content:  cjson.StringifyIgnore(content),
 		}
 		if err != nil {
 			log.Result = "false"
 			log.Remark = "修改用户邀请关系失败"
-			logger.ZError("ReviewWithdrawal fail", zap.Any("req", req), zap.Error(err))
+			logger.ZError("FixInviteRelation fail", zap.Any("req", req), zap.Error(err))
 		} else {
 			log.Result = "true"
 			log.Remark = "修改用户邀请关系成功"
 		}
 
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue fail", zap.Error(err))
 		}
 	}()
-	if user.Inviter != "" { //处理之前邀请
-		invite, _ := s.UserSrv.GetUserByname(user.Inviter)
-		if invite != nil {
-			inviteForUpdate := new(entities.User)
-			inviteForUpdate.ID = invite.ID
-			inviteForUpdate.InviteCount -= 1 //原先邀请的一级减少一个
-			if inviteForUpdate.InviteCount < 0 {
-				inviteForUpdate.InviteCount = 0
-			}
 
-			if err = s.UserSrv.UpdateUser(inviteForUpdate); err != nil {
+	if plan, err = s.buildInviteMovePlan(req); err != nil {
+		return err
+	}
+	preview := plan.preview
+
+	err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+		for uid, depth := range plan.depths {
+			if err := s.Repo.DelAncestorRelationWithTx(tx, uid, depth); err != nil {
 				return err
 			}
 		}
-
-		if err = s.Repo.DelRelationByUID(user.ID); err != nil {
+		if len(plan.inserts) > 0 {
+			if err := s.Repo.AddRelationListWithTx(tx, plan.inserts); err != nil {
+				return err
+			}
+		}
+		if err := s.UserSrv.Repo.UpdateUserInviterWithTx(tx, plan.user.ID, preview.Promoter, preview.PromoterCode, preview.Inviter); err != nil {
 			return err
 		}
-	}
-
-	if req.PromotionCode != 0 { //业务员邀请
-		sysUser, err := s.AdminSrv.GetSysUser(&entities.SysUser{UID: int(req.PromotionCode)}) //用户的分销人处理
-		if err != nil {
-			return errors.WithCode(errors.InvalidPromotionCode) //
+		for _, pid := range []uint{preview.OldPID, preview.NewPID} {
+			if pid == 0 {
+				continue
+			}
+			if err := s.Repo.UpdateInviteCountWithTx(tx, pid); err != nil {
+				return err
+			}
 		}
-		user.Promoter = sysUser.Username //
-		user.PromoterCode = int(req.PromotionCode)
-		user.Inviter = "" //置空
-	}
-
-	if parent != nil { //玩家邀请
-		user.Promoter = parent.Promoter // 设置为父亲的推广信息
-		user.PromoterCode = parent.PromoterCode
-		user.Inviter = parent.Mobile
-	}
-
-	userForUpdate := entities.User{
-		Promoter:     user.Promoter,
-		PromoterCode: user.PromoterCode,
-		Inviter:      user.Inviter, //邀请者手机号
-	}
-	userForUpdate.ID = user.ID
-
-	if err = s.UserSrv.UpdateUser(&userForUpdate); err != nil {
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
-	if parent != nil {
-		inviteForUpdate := new(entities.User)
-		inviteForUpdate.ID = parent.ID
-		inviteForUpdate.InviteCount += 1 //邀请的一级增加一个
-
-		if err = s.UserSrv.UpdateUser(inviteForUpdate); err != nil {
-			return err
-		}
-		relation := &entities.HallInviteRelation{
-			UID:    user.ID,
-			Mobile: user.Mobile,
-			PID:    parent.ID,
-			Level:  1, //1级
-		}
-		if err = s.FillInviteRelation(relation); err != nil {
-			return err
-		}
+	for uid := range plan.depths {
+		s.relationPIDCache.Del(fmt.Sprintf("%d", uid))
+	}
+	if err := s.UserSrv.Repo.BatchClearUserCache([]uint{plan.user.ID, preview.OldPID, preview.NewPID}); err != nil {
+		logger.ZError("BatchClearUserCache", zap.Uint("uid", plan.user.ID), zap.Error(err))
 	}
-
 	return nil
 }
 
@@ -337,8 +455,8 @@ func (s *AgentService) dealGameReturnCashForUser(user *entities.User, returnCash
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 		return nil
 	})
@@ -346,28 +464,6 @@ func (s *AgentService) dealGameReturnCashForUser(user *entities.User, returnCash
 	return err
 }
 
-// 按充值返回对应的
-func getLevelReturnCash(cash float64) float64 {
-	if cash >= 100000 {
-		return 20000
-	} else if cash >= 50000 {
-		return 5500
-	} else if cash >= 10000 {
-		return 1500
-	} else if cash >= 5000 {
-		return 1000
-	} else if cash >= 3000 {
-		return 600
-	} else if cash >= 1000 {
-		return 300
-	} else if cash >= 500 {
-		return 150
-	} else if cash >= 200 {
-		return 20
-	}
-	return 0
-}
-
 func (s *AgentService) GetMonthRechargeCashAlreadyReturn(uid uint) (float64, error) {
 	return s.Repo.GetMonthRechargeCashAlreadyReturn(uid)
 }
@@ -399,6 +495,9 @@ func (s *AgentService) FinalizeRechargeCashReturn(req *entities.FinalizeRecharge
 		if err := s.Repo.UpdateRechargeReturnWithTx(tx, &returnForUpdate); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, profitReturn.PID, constant.FLOW_TYPE_RECHARGE_RETURN_CASH, fmt.Sprintf("%d", profitReturn.ID), profitReturn.ReturnCash); err != nil {
+			return err
+		}
 
 		logger.ZInfo("FinalizeRechargeCashReturn UpdateUserWithTx", zap.Any("user", wallet))
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
@@ -411,8 +510,8 @@ func (s *AgentService) FinalizeRechargeCashReturn(req *entities.FinalizeRecharge
 			Balance:      wallet.Cash,
 			PromoterCode: wallet.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 		return nil
 	})
@@ -429,72 +528,59 @@ func (s *AgentService) AutoFinalizeRechargeCashReturn(id uint) error {
 	})
 }
 
-// 检测和处理充值返利
-func (s *AgentService) CheckReturnRechargeCash(uid uint, cash float64, orderID string) error {
-	user, err := s.UserSrv.GetUserByUID(uid)
-	if err != nil {
-		return err
-	}
-	hpr, err := s.Repo.GetRechargeReturn(uid)
-	if err != nil {
-		return err
-	}
-	if hpr != nil {
-		return errors.With("already exit recharge cash") //只允许一次
-	}
-
-	levelCash := getLevelReturnCash(cash)
-	// if levelCash <= 0 {
-	// 	return nil
-	// }
-
+// 获取一级上级 没有返回0
+func (s *AgentService) GetInviterUID(uid uint) (uint, error) {
 	relation, err := s.Repo.GetRelation(&entities.HallInviteRelation{
 		Level: 1,
-		UID:   user.ID,
+		UID:   uid,
 	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if relation == nil {
-		return nil
+		return 0, nil
 	}
+	return relation.PID, nil
+}
 
-	hpr = &entities.RechargeReturn{
+// 创建充值返利并直接发放给上级, 返利金额由充值优惠规则计算
+// 需在上级钱包的HandleWallet事务中调用, 与优惠发放记录同一事务提交
+func (s *AgentService) CreateRechargeReturnWithTx(tx *gorm.DB, wallet *entities.UserWallet, user *entities.User, cash float64, returnCash float64, orderID string) error {
+	hpr := &entities.RechargeReturn{
 		UID:          user.ID,
 		PromoterCode: user.PromoterCode,
 		Cash:         cash,
-		Level:        relation.Level,
-		PID:          relation.PID,
+		Level:        1,
+		PID:          wallet.UID,
 		Percent:      0,
-		ReturnCash:   levelCash,
+		ReturnCash:   returnCash,
 		Remark:       orderID,
-		Status:       0,
+		Status:       1,
+		GetTime:      time.Now().Unix(),
 	}
+	if err := s.Repo.CreateRechargeReturnWithTx(tx, hpr); err != nil {
+		return err
+	}
+	logger.ZInfo("CreateRechargeReturnWithTx", zap.Any("hpr", hpr))
 
 	if hpr.ReturnCash == 0 {
-		hpr.Status = 1
+		return nil
 	}
-
-	tx := s.Repo.DB.Begin()
-	if err := s.Repo.CreateRechargeReturnWithTx(tx, hpr); err != nil {
-		tx.Rollback()
+	wallet.SafeAdjustCash(hpr.ReturnCash)
+	if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, wallet.UID, constant.FLOW_TYPE_RECHARGE_RETURN_CASH, fmt.Sprintf("%d", hpr.ID), hpr.ReturnCash); err != nil {
 		return err
 	}
-	tx.Commit()
-
-	logger.ZInfo("CreateReturnRechargeCash", zap.Any("hpr", hpr))
-
-	go func() {
-		defer utils.PrintPanicStack()
-
-		if err := s.AutoFinalizeRechargeCashReturn(hpr.ID); err != nil {
-			logger.ZError("AutoFinalizeRechargeCashReturn", zap.Uint("id", hpr.ID), zap.Error(err))
-		} else {
-			logger.ZInfo("AutoFinalizeRechargeCashReturn", zap.Uint("id", hpr.ID))
-		}
-	}()
-
-	return nil
+	if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
+		return err
+	}
+	createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
+		UID:          wallet.UID,
+		FlowType:     constant.FLOW_TYPE_RECHARGE_RETURN_CASH,
+		Number:       hpr.ReturnCash,
+		Balance:      wallet.Cash,
+		PromoterCode: wallet.PromoterCode,
+	})
+	return mq.EnqueueTx(tx, createFlowQueue)
 }
 
 func (s *AgentService) GetPromotionProfit(uid uint) (*entities.PromotionProfit, error) {
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"strconv"
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+)
+
+const agentStatsDateLayout = "20060102"
+
+var AgentStatsServiceSet = wire.NewSet(
+	ProvideAgentStatsService,
+)
+
+// 代理数据看板 团队数据按天预先汇总到agent_daily_stats 查询时按周期累加
+type AgentStatsService struct {
+	Repo     *repository.AgentStatsRepository
+	AgentSrv *AgentService
+}
+
+func ProvideAgentStatsService(repo *repository.AgentStatsRepository, agentSrv *AgentService) *AgentStatsService {
+	return &AgentStatsService{
+		Repo:     repo,
+		AgentSrv: agentSrv,
+	}
+}
+
+func agentStatsDate(t time.Time) int {
+	date, _ := strconv.Atoi(t.Format(agentStatsDateLayout))
+	return date
+}
+
+// 统计周期的开始和结束日期 包含两端
+func agentStatsPeriod(period string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case "", constant.AGENT_STATS_PERIOD_TODAY:
+		return today, today, nil
+	case constant.AGENT_STATS_PERIOD_YESTERDAY:
+		yesterday := today.AddDate(0, 0, -1)
+		return yesterday, yesterday, nil
+	case constant.AGENT_STATS_PERIOD_7D:
+		return today.AddDate(0, 0, -6), today, nil
+	case constant.AGENT_STATS_PERIOD_30D:
+		return today.AddDate(0, 0, -29), today, nil
+	case constant.AGENT_STATS_PERIOD_MONTH:
+		return today.AddDate(0, 0, 1-today.Day()), today, nil
+	case constant.AGENT_STATS_PERIOD_LAST_MONTH:
+		firstDay := today.AddDate(0, 0, 1-today.Day())
+		return firstDay.AddDate(0, -1, 0), firstDay.AddDate(0, 0, -1), nil
+	}
+	return today, today, errors.WithCode(errors.InvalidParam)
+}
+
+type agentLevelKey struct {
+	PID   uint
+	Level uint8
+}
+
+type agentCategoryKey struct {
+	PID      uint
+	Category string
+}
+
+// 汇总某一天的团队数据 可重复执行
+func (s *AgentStatsService) AggregateAgentDailyStats(day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	date := agentStatsDate(start)
+
+	levels := make(map[agentLevelKey]*entities.AgentDailyStats)
+	getLevel := func(pid uint, level uint8) *entities.AgentDailyStats {
+		key := agentLevelKey{PID: pid, Level: level}
+		if item, ok := levels[key]; ok {
+			return item
+		}
+		item := &entities.AgentDailyStats{PID: pid, Date: date, Level: level}
+		levels[key] = item
+		return item
+	}
+	categories := make(map[agentCategoryKey]*entities.AgentDailyCategoryStats)
+	getCategory := func(pid uint, category string) *entities.AgentDailyCategoryStats {
+		key := agentCategoryKey{PID: pid, Category: category}
+		if item, ok := categories[key]; ok {
+			return item
+		}
+		item := &entities.AgentDailyCategoryStats{PID: pid, Date: date, Category: category}
+		categories[key] = item
+		return item
+	}
+
+	registers, err := s.Repo.SumTeamRegister(start.Unix(), end.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range registers {
+		getLevel(item.PID, item.Level).NewMembers = int(item.Count)
+	}
+
+	firstDeposits, err := s.Repo.SumTeamFirstDeposit(start.Unix(), end.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range firstDeposits {
+		stats := getLevel(item.PID, item.Level)
+		stats.FirstDeposits = int(item.Count)
+		stats.FirstDepositAmount = item.Amount
+	}
+
+	deposits, err := s.Repo.SumTeamDeposit(start.Unix(), end.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range deposits {
+		getLevel(item.PID, item.Level).DepositAmount = item.Amount
+	}
+
+	withdraws, err := s.Repo.SumTeamWithdraw(start.Unix(), end.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range withdraws {
+		getLevel(item.PID, item.Level).WithdrawAmount = item.Amount
+	}
+
+	commissions, err := s.Repo.SumTeamCommission(start.Unix(), end.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range commissions {
+		getLevel(item.PID, item.Level).Commission = item.Amount
+	}
+
+	// 游戏返利只按game_record的有效流水计算 分类返利按层级比例折算 万分比
+	rbMap, err := s.AgentSrv.GetRakeBackMap(0)
+	if err != nil {
+		return err
+	}
+	records, err := s.Repo.SumTeamGameRecordTurnover(start, end)
+	if err != nil {
+		return err
+	}
+	for _, item := range records {
+		getLevel(item.PID, item.Level).Turnover += item.Amount
+		category := getCategory(item.PID, item.Category)
+		category.Turnover += item.Amount
+		category.Commission += item.Amount * float64(rbMap[item.Level]) / 10000
+	}
+
+	flows, err := s.Repo.SumTeamGameFlowBet(start.Unix(), end.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range flows {
+		getLevel(item.PID, item.Level).Turnover += item.Amount
+		decade, _ := strconv.Atoi(item.Category)
+		name, ok := cashbackCategories[uint16(decade)]
+		if !ok {
+			name = item.Category
+		}
+		getCategory(item.PID, name).Turnover += item.Amount
+	}
+
+	levelList := make([]*entities.AgentDailyStats, 0, len(levels))
+	for _, item := range levels {
+		levelList = append(levelList, item)
+	}
+	categoryList := make([]*entities.AgentDailyCategoryStats, 0, len(categories))
+	for _, item := range categories {
+		categoryList = append(categoryList, item)
+	}
+	return s.Repo.ReplaceAgentDailyStats(date, levelList, categoryList)
+}
+
+// 定时任务 每小时刷新当天 0点那次把前一天定稿
+func (s *AgentStatsService) RefreshAgentDailyStats() error {
+	now := time.Now()
+	if now.Hour() == 0 {
+		if err := s.AggregateAgentDailyStats(now.AddDate(0, 0, -1)); err != nil {
+			return err
+		}
+	}
+	return s.AggregateAgentDailyStats(now)
+}
+
+func (s *AgentStatsService) AdminAggregateAgentDailyStats(req *entities.AggregateAgentStatsReq) (err error) {
+	defer func() {
+		log := entities.SystemOptionLog{
+			Type:     constant.SYS_OPTION_TYPE_AGENT_STATS,
+			OptionID: req.OptionID,
+			IP:       req.IP,
+			Content:  cjson.StringifyIgnore(req),
+		}
+		if err != nil {
+			log.Result = "false"
+			log.Remark = "代理统计重新汇总失败"
+		} else {
+			log.Result = "true"
+			log.Remark = "代理统计重新汇总成功"
+		}
+		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+		if err := mq.Publish(optionLogQueue); err != nil {
+			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+		}
+	}()
+
+	day, err := time.ParseInLocation(agentStatsDateLayout, strconv.Itoa(req.Date), time.Local)
+	if err != nil || day.After(time.Now()) {
+		return errors.WithCode(errors.InvalidParam)
+	}
+	return s.AggregateAgentDailyStats(day)
+}
+
+func (s *AgentStatsService) GetAgentDashboard(req *entities.GetAgentDashboardReq) (*entities.AgentDashboard, error) {
+	start, end, err := agentStatsPeriod(req.Period, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	dashboard := &entities.AgentDashboard{
+		Period:    req.Period,
+		StartDate: agentStatsDate(start),
+		EndDate:   agentStatsDate(end),
+		Total:     new(entities.AgentLevelStats),
+	}
+	if dashboard.TeamSize, err = s.Repo.GetAgentTeamSize(req.UID); err != nil {
+		return nil, err
+	}
+	if dashboard.Levels, err = s.Repo.GetAgentLevelStats(req.UID, dashboard.StartDate, dashboard.EndDate); err != nil {
+		return nil, err
+	}
+	if dashboard.Categories, err = s.Repo.GetAgentCategoryStats(req.UID, dashboard.StartDate, dashboard.EndDate); err != nil {
+		return nil, err
+	}
+	total := dashboard.Total
+	for _, item := range dashboard.Levels {
+		total.NewMembers += item.NewMembers
+		total.FirstDeposits += item.FirstDeposits
+		total.FirstDepositAmount += item.FirstDepositAmount
+		total.DepositAmount += item.DepositAmount
+		total.WithdrawAmount += item.WithdrawAmount
+		total.Turnover += item.Turnover
+		total.Commission += item.Commission
+	}
+	return dashboard, nil
+}
+
+// 下级列表 当前页的成员补充所选周期的充值/投注/返利
+func (s *AgentStatsService) GetAgentDownlineList(req *entities.GetAgentDownlineListReq) error {
+	start, end, err := agentStatsPeriod(req.Period, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.GetAgentDownlineList(req); err != nil {
+		return err
+	}
+	list, ok := req.List.([]*entities.AgentDownline)
+	if !ok || len(list) == 0 {
+		return nil
+	}
+	members := make(map[uint]*entities.AgentDownline, len(list))
+	uids := make([]uint, 0, len(list))
+	for _, item := range list {
+		members[item.UID] = item
+		uids = append(uids, item.UID)
+	}
+	startTime, endTime := start.Unix(), end.AddDate(0, 0, 1).Unix()
+
+	deposits, err := s.Repo.SumMemberDeposit(uids, startTime, endTime)
+	if err != nil {
+		return err
+	}
+	for _, item := range deposits {
+		if member, ok := members[item.UID]; ok {
+			member.DepositAmount = item.Amount
+		}
+	}
+	turnovers, err := s.Repo.SumMemberTurnover(uids, startTime, endTime)
+	if err != nil {
+		return err
+	}
+	for _, item := range turnovers {
+		if member, ok := members[item.UID]; ok {
+			member.Turnover += item.Amount
+		}
+	}
+	commissions, err := s.Repo.SumMemberCommission(req.UID, uids, startTime, endTime)
+	if err != nil {
+		return err
+	}
+	for _, item := range commissions {
+		if member, ok := members[item.UID]; ok {
+			member.Commission = item.Amount
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/internal/app/utils"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+)
+
+var ArchiveServiceSet = wire.NewSet(
+	ProvideArchiveService,
+)
+
+// 支持归档的表和校验用的金额字段
+var archiveSumColumns = map[string]string{
+	"flow":              "number",
+	"game_return":       "return_cash",
+	"crash_game_round":  "crash_multi",
+	"wingo_order":       "bet_amount",
+	"nine_order":        "bet_amount",
+	"r8_transfer_order": "amount",
+	"zf_transfer_order": "amount",
+}
+
+var archiveTables = []string{"flow", "game_return", "crash_game_round", "wingo_order", "nine_order", "r8_transfer_order", "zf_transfer_order"}
+
+// 数据归档 取代原来的月度备份存储过程
+// 每张表每个月一个任务 复制到归档表->核对行数和金额->分批删除源数据 中断后下次从检查点续跑
+type ArchiveService struct {
+	Repo     *repository.ArchiveRepository
+	StateSrv *StateService
+
+	mu sync.Mutex
+}
+
+func ProvideArchiveService(repo *repository.ArchiveRepository, stateSrv *StateService) *ArchiveService {
+	return &ArchiveService{
+		Repo:     repo,
+		StateSrv: stateSrv,
+	}
+}
+
+// 保留期之前的那个月
+func defaultArchiveMonth(now time.Time) string {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -constant.ARCHIVE_KEEP_MONTHS, 0).Format("200601")
+}
+
+func checkArchiveTables(tables []string) ([]string, error) {
+	if len(tables) == 0 {
+		return archiveTables, nil
+	}
+	for _, table := range tables {
+		if _, ok := archiveSumColumns[table]; !ok {
+			return nil, errors.WithCode(errors.ArchiveTableNotAllowed)
+		}
+	}
+	return tables, nil
+}
+
+// 后台手动执行 后台异步跑 进度看任务列表
+func (s *ArchiveService) RunArchive(req *entities.RunArchiveReq) (err error) {
+	defer func() {
+		s.writeArchiveLog(req.OptionID, req.IP, req, err)
+	}()
+
+	tables, err := checkArchiveTables(req.Tables)
+	if err != nil {
+		return
+	}
+	month := req.Month
+	if month != "" {
+		if _, err = time.ParseInLocation("200601", month, time.Local); err != nil {
+			return errors.WithCode(errors.InvalidParam)
+		}
+		if month > defaultArchiveMonth(time.Now()) {
+			return errors.WithCode(errors.InvalidParam)
+		}
+	}
+	if !s.mu.TryLock() {
+		return errors.WithCode(errors.ArchiveRunning)
+	}
+	go func() {
+		defer utils.PrintPanicStack()
+		defer s.mu.Unlock()
+		if err := s.archiveTables(tables, month); err != nil {
+			logger.ZError("RunArchive", zap.Any("req", req), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// 定时任务调用 同步执行
+func (s *ArchiveService) ArchiveTables(tables []string) error {
+	tables, err := checkArchiveTables(tables)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.archiveTables(tables, "")
+}
+
+// 先续跑之前未完成的任务 再跑指定月份 某张表失败不影响其他表
+func (s *ArchiveService) archiveTables(tables []string, month string) error {
+	s.StateSrv.SetState(constant.StateMonthBackupAndClean, true)
+	defer s.StateSrv.SetState(constant.StateMonthBackupAndClean, false)
+
+	if month == "" {
+		month = defaultArchiveMonth(time.Now())
+	}
+	jobs, err := s.Repo.GetUnfinishedArchiveJobs(tables)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, job := range jobs {
+		if err := s.processArchiveJob(job); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, table := range tables {
+		job, err := s.ensureArchiveJob(table, month)
+		if err == nil && job.Status != constant.ARCHIVE_STATUS_DONE {
+			err = s.processArchiveJob(job)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// 创建任务时取源数据快照 之后只处理快照范围内的数据
+func (s *ArchiveService) ensureArchiveJob(table, month string) (*entities.ArchiveJob, error) {
+	job, err := s.Repo.GetArchiveJob(table, month)
+	if err != nil || job != nil {
+		return job, err
+	}
+	startDate, err := time.ParseInLocation("200601", month, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	start, end := startDate.Unix(), startDate.AddDate(0, 1, 0).Unix()
+	maxID, err := s.Repo.GetRangeMaxID(table, start, end)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := s.Repo.StatRange(table, archiveSumColumns[table], start, end, maxID)
+	if err != nil {
+		return nil, err
+	}
+	job = &entities.ArchiveJob{
+		SourceTable:  table,
+		Month:        month,
+		ArchiveTable: fmt.Sprintf("%s_%s", table, month),
+		StartTime:    start,
+		EndTime:      end,
+		MaxID:        maxID,
+		SourceCount:  stat.Count,
+		SourceSum:    stat.Amount,
+		Status:       constant.ARCHIVE_STATUS_COPYING,
+	}
+	if err := s.Repo.CreateArchiveJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *ArchiveService) processArchiveJob(job *entities.ArchiveJob) (err error) {
+	logger.ZInfo("processArchiveJob", zap.String("table", job.SourceTable), zap.String("month", job.Month), zap.Uint8("status", job.Status))
+	defer func() {
+		if err != nil {
+			logger.ZError("processArchiveJob", zap.String("table", job.SourceTable), zap.String("month", job.Month), zap.Error(err))
+			s.updateArchiveJob(job, map[string]interface{}{"status": constant.ARCHIVE_STATUS_FAILED, "error": err.Error()})
+		}
+	}()
+
+	// 失败的任务从复制续跑 复制和删除都可以重复执行
+	if job.Status == constant.ARCHIVE_STATUS_FAILED {
+		if err = s.updateArchiveJob(job, map[string]interface{}{"status": constant.ARCHIVE_STATUS_COPYING, "error": ""}); err != nil {
+			return
+		}
+	}
+	if job.Status == constant.ARCHIVE_STATUS_COPYING {
+		if err = s.copyArchiveJob(job); err != nil {
+			return
+		}
+	}
+	if job.Status == constant.ARCHIVE_STATUS_VERIFYING {
+		if err = s.verifyArchiveJob(job); err != nil {
+			return
+		}
+	}
+	if job.Status == constant.ARCHIVE_STATUS_DELETING {
+		if err = s.deleteArchiveJob(job); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// 更新数据库并同步到内存里的任务
+func (s *ArchiveService) updateArchiveJob(job *entities.ArchiveJob, values map[string]interface{}) error {
+	if err := s.Repo.UpdateArchiveJob(job.ID, values); err != nil {
+		return err
+	}
+	if status, ok := values["status"]; ok {
+		job.Status = uint8(status.(int))
+	}
+	if lastID, ok := values["last_id"]; ok {
+		job.LastID = lastID.(uint)
+	}
+	return nil
+}
+
+func (s *ArchiveService) copyArchiveJob(job *entities.ArchiveJob) error {
+	if err := s.Repo.CreateArchiveTable(job.SourceTable, job.ArchiveTable); err != nil {
+		return err
+	}
+	for {
+		nextID, err := s.Repo.GetNextBatchID(job.SourceTable, job.StartTime, job.EndTime, job.LastID, job.MaxID, constant.ARCHIVE_BATCH_SIZE)
+		if err != nil {
+			return err
+		}
+		if nextID == 0 {
+			break
+		}
+		if _, err := s.Repo.CopyArchiveBatch(job.SourceTable, job.ArchiveTable, job.StartTime, job.EndTime, job.LastID, nextID); err != nil {
+			return err
+		}
+		// 每批记录检查点
+		if err := s.updateArchiveJob(job, map[string]interface{}{"last_id": nextID}); err != nil {
+			return err
+		}
+		time.Sleep(constant.ARCHIVE_BATCH_INTERVAL * time.Millisecond)
+	}
+	return s.updateArchiveJob(job, map[string]interface{}{"status": constant.ARCHIVE_STATUS_VERIFYING})
+}
+
+// 归档表的行数和金额要和快照一致才允许删除源数据
+func (s *ArchiveService) verifyArchiveJob(job *entities.ArchiveJob) error {
+	stat, err := s.Repo.StatRange(job.ArchiveTable, archiveSumColumns[job.SourceTable], job.StartTime, job.EndTime, job.MaxID)
+	if err != nil {
+		return err
+	}
+	job.ArchiveCount, job.ArchiveSum = stat.Count, stat.Amount
+	if err := s.updateArchiveJob(job, map[string]interface{}{"archive_count": stat.Count, "archive_sum": stat.Amount}); err != nil {
+		return err
+	}
+	if stat.Count != job.SourceCount || math.Abs(stat.Amount-job.SourceSum) > 0.001 {
+		logger.ZError("verifyArchiveJob mismatch", zap.Any("job", job))
+		return errors.WithCode(errors.ArchiveVerifyFailed)
+	}
+	return s.updateArchiveJob(job, map[string]interface{}{"status": constant.ARCHIVE_STATUS_DELETING})
+}
+
+func (s *ArchiveService) deleteArchiveJob(job *entities.ArchiveJob) error {
+	for {
+		n, err := s.Repo.DeleteArchivedBatch(job.SourceTable, job.StartTime, job.EndTime, job.MaxID, constant.ARCHIVE_BATCH_SIZE)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		job.DeletedCount += n
+		if err := s.updateArchiveJob(job, map[string]interface{}{"deleted_count": job.DeletedCount}); err != nil {
+			return err
+		}
+		time.Sleep(constant.ARCHIVE_BATCH_INTERVAL * time.Millisecond)
+	}
+	return s.updateArchiveJob(job, map[string]interface{}{"status": constant.ARCHIVE_STATUS_DONE, "finish_time": time.Now().Unix()})
+}
+
+func (s *ArchiveService) GetArchiveJobList(req *entities.GetArchiveJobListReq) error {
+	return s.Repo.GetArchiveJobList(req)
+}
+
+func (s *ArchiveService) writeArchiveLog(optionID uint, ip string, req interface{}, err error) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_ARCHIVE,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = "执行数据归档失败"
+	} else {
+		log.Result = "true"
+		log.Remark = "执行数据归档成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
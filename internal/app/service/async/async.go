@@ -19,11 +19,24 @@ type IAsyncService interface {
 	SyncThirdPartyData()      //同步第三方游戏数据
 	SyncThirdOnlineCount()    // 同步第三方在线人数
 
+	ReconcileProviderTransfers()   //三方转账与投注记录对账
+	RecalculateVipLevels() error   //重新评定VIP等级
+	RefreshAgentDailyStats() error //刷新代理团队每日统计
+	SettleDueCommission() error    //结算代理佣金方案
+
+	RefreshGameLobbyRank() error //刷新大厅游戏排序
+	SyncAllProviderGameList()    //同步三方游戏列表
+
 	QuerySettleExpiredWingos() error             //查询结算wingo
 	QuerySettleExpiredNines() error              //查询结算nine
 	MonthBackupAndClean(tableNames string) error //每月备份并清理数据
+	CleanPublishedOutbox() error                 //清理已投递的发件箱消息
+
+	RecordTaskResult(typename string, failed bool) //队列任务处理计数
+	CheckQueueFailureRate() error                  //检查队列失败率
 
 	HandleNotification(notification *entities.Notification) error //处理通知
+	CheckWithdrawRisk(task *entities.WithdrawRiskTask) error      //提现风控评估
 
 	// ProcessChainRetryTransaction(transation *entities.ChainTransaction, failed bool) error //上交易
 }
@@ -6,15 +6,18 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
 	"rk-api/internal/app/utils"
 	"rk-api/pkg/cjson"
 	"rk-api/pkg/logger"
+	"strconv"
 	"time"
 
 	"github.com/google/wire"
+	"github.com/odeke-em/go-uuid"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -26,14 +29,16 @@ var AuthServiceSet = wire.NewSet(
 )
 
 type AuthService struct {
-	Repo      *repository.UserRepository
-	UserSrv   *UserService
-	VerifySrv *VerifyService
-	AdminSrv  *AdminService
-	StateSrv  *StateService
+	Repo        *repository.UserRepository
+	SessionRepo *repository.SessionRepository
+	UserSrv     *UserService
+	VerifySrv   *VerifyService
+	AdminSrv    *AdminService
+	StateSrv    *StateService
 }
 
 func ProvideAuthService(repo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
 	adminSrv *AdminService,
 	userSrv *UserService,
 	verifySrv *VerifyService,
@@ -41,11 +46,12 @@ func ProvideAuthService(repo *repository.UserRepository,
 ) *AuthService {
 
 	service := &AuthService{
-		Repo:      repo,
-		UserSrv:   userSrv,
-		VerifySrv: verifySrv,
-		AdminSrv:  adminSrv,
-		StateSrv:  stateSrv,
+		Repo:        repo,
+		SessionRepo: sessionRepo,
+		UserSrv:     userSrv,
+		VerifySrv:   verifySrv,
+		AdminSrv:    adminSrv,
+		StateSrv:    stateSrv,
 	}
 	return service
 }
@@ -169,18 +175,6 @@ func (s *AuthService) RegisterUser(registerCredentials *entities.RegisterCredent
 			return nil, err
 		}
 
-		relation := &entities.HallInviteRelation{
-			UID:    user.ID, //注册完成后才能拿到
-			Mobile: user.Mobile,
-			PID:    invite.ID,
-			Level:  1, //1级
-		}
-
-		inviteRelationQueue, _ := handle.NewInviteRelationQueue(relation) //消息队列去处理 代理关系
-		if _, err := mq.MClient.Enqueue(inviteRelationQueue); err != nil {
-			logger.ZError("inviteRelationQueue", zap.Any("relation", relation), zap.Error(err))
-		}
-
 		// pinduo := &entities.HallInvitePinduo{
 		// 	InviteID:   invite.ID,
 		// 	InviteName: invite.Username,
@@ -193,6 +187,20 @@ func (s *AuthService) RegisterUser(registerCredentials *entities.RegisterCredent
 		// }
 	}
 
+	registered := &event.UserRegistered{ //代理关系由订阅者处理
+		UID:          user.ID,
+		Mobile:       user.Mobile,
+		PromoterCode: user.PromoterCode,
+		Channel:      user.Channel,
+		IP:           user.LoginIP,
+	}
+	if invite != nil {
+		registered.InviterID = invite.ID
+	}
+	if err := event.Publish(registered); err != nil {
+		logger.ZError("UserRegistered", zap.Any("event", registered), zap.Error(err))
+	}
+
 	return user, nil
 
 }
@@ -293,10 +301,55 @@ func (s *AuthService) Login(loginCredentials *entities.LoginCredentials) (*entit
 	return user, nil
 }
 
-func (s *AuthService) VerifyLoginToken(uid uint, token string) error {
-	keepToken, _ := s.Repo.GetUserAccessToken(uid)
-	if keepToken != token {
-		return errors.WithCode(errors.AccountLoginExpire)
+// 校验会话 JWTMiddleware每次请求调用
+// 没有会话ID的老token 仍按单点登录时代的access token校验
+func (s *AuthService) CheckSession(userID string, sid string, token string, ip string) error {
+	uid, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		return errors.WithCode(errors.SessionNotExist)
+	}
+	if sid == "" {
+		keepToken, _ := s.Repo.GetUserAccessToken(uint(uid))
+		if keepToken != token {
+			return errors.WithCode(errors.AccountLoginExpire)
+		}
+		return nil
+	}
+
+	now := time.Now().Unix()
+	cache, err := s.SessionRepo.GetUserSessionCache(sid)
+	if err != nil {
+		return err
+	}
+	if len(cache) == 0 { //缓存丢失时从库里恢复
+		session, err := s.SessionRepo.GetUserSessionBySID(sid)
+		if err != nil {
+			return err
+		}
+		if session == nil || session.Status != constant.USER_SESSION_STATUS_ACTIVE || session.ExpireTime <= now {
+			return errors.WithCode(errors.SessionNotExist)
+		}
+		// 封禁时会撤销全部会话并删除缓存 重建缓存前再确认一次用户状态
+		if _, err := s.UserSrv.GetUserByUID(session.UID); err != nil {
+			return err
+		}
+		if err := s.SessionRepo.SetUserSessionCache(sid, session.UID, session.LastSeen, time.Duration(session.ExpireTime-now)*time.Second); err != nil {
+			return err
+		}
+		cache = map[string]string{"uid": fmt.Sprintf("%d", session.UID), "seen": fmt.Sprintf("%d", session.LastSeen)}
+	}
+	if cache["uid"] != userID {
+		return errors.WithCode(errors.SessionNotExist)
+	}
+
+	seen, _ := strconv.ParseInt(cache["seen"], 10, 64)
+	if now-seen > constant.USER_SESSION_SEEN_INTERVAL {
+		s.SessionRepo.SetUserSessionCacheSeen(sid, now)
+		go func() {
+			if err := s.SessionRepo.UpdateUserSessionSeen(sid, ip, now); err != nil {
+				logger.ZError("UpdateUserSessionSeen", zap.String("sid", sid), zap.Error(err))
+			}
+		}()
 	}
 	return nil
 }
@@ -368,38 +421,206 @@ func (s *AuthService) ResetPassword(resetPasswordCredentials *entities.ResetPass
 	return nil
 }
 
-func (s *AuthService) CreateJWTAccessRefreshToken(sso *entities.OAuthToken) error {
-
+// 签发 access/refresh token 两者都带会话ID refresh token另带轮换编号
+func (s *AuthService) signSessionTokens(sso *entities.OAuthToken, jti string) (time.Duration, error) {
 	accessExpireTime := time.Duration(config.Get().ServiceSettings.TokenExpireTime*24) * time.Hour
 
 	// 过期的时间
 	sso.ExpiresIn = accessExpireTime
 
-	accessToken, err := utils.GenerateJWT(config.Get().ServiceSettings.JwtSignKey, accessExpireTime, fmt.Sprintf("%d", sso.UID))
+	accessToken, err := utils.GenerateJWTWithClaims(config.Get().ServiceSettings.JwtSignKey, accessExpireTime, fmt.Sprintf("%d", sso.UID), map[string]interface{}{
+		"sid": sso.SessionID,
+		"typ": constant.JWT_TOKEN_TYPE_ACCESS,
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
-
 	sso.AccessToken = accessToken
 
 	refreshExpireTime := time.Duration(config.Get().ServiceSettings.TokenRefreshTime*24) * time.Hour
 
-	refreshToken, err := utils.GenerateJWT(config.Get().ServiceSettings.JwtSignKey, refreshExpireTime, fmt.Sprintf("%d", sso.UID))
+	refreshToken, err := utils.GenerateJWTWithClaims(config.Get().ServiceSettings.JwtSignKey, refreshExpireTime, fmt.Sprintf("%d", sso.UID), map[string]interface{}{
+		"sid": sso.SessionID,
+		"typ": constant.JWT_TOKEN_TYPE_REFRESH,
+		"jti": jti,
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	sso.RefreshToken = refreshToken
+	return refreshExpireTime, nil
+}
 
-	// refreshExpiration := time.Duration(*conf.JWTSettings.RefreshTime) * time.Hour
-	// err = models.CreateAccessRefreshToken(sso.AccessToken, sso.RefreshToken, sso.ExpiresIn, refreshExpiration)
+// 登录成功后创建设备会话 不影响同一用户其他设备上的会话
+func (s *AuthService) CreateJWTAccessRefreshToken(sso *entities.OAuthToken) error {
+	sso.SessionID = uuid.New()
+	jti := uuid.New()
+
+	refreshExpireTime, err := s.signSessionTokens(sso, jti)
+	if err != nil {
+		return err
+	}
 
-	if err := s.Repo.SetUserAccessToken(sso.UID, sso.AccessToken, sso.ExpiresIn); err != nil {
+	now := time.Now().Unix()
+	session := &entities.UserSession{
+		UID:        sso.UID,
+		SessionID:  sso.SessionID,
+		RefreshJti: jti,
+		DeviceID:   sso.DeviceID,
+		Device:     sso.Device,
+		UserAgent:  sso.UserAgent,
+		IP:         sso.IP,
+		LastIP:     sso.IP,
+		LastSeen:   now,
+		ExpireTime: now + int64(refreshExpireTime/time.Second),
+		Status:     constant.USER_SESSION_STATUS_ACTIVE,
+	}
+	if len(session.UserAgent) > 255 {
+		session.UserAgent = session.UserAgent[:255]
+	}
+	if err := s.SessionRepo.CreateUserSession(session); err != nil {
+		return err
+	}
+	if err := s.SessionRepo.SetUserSessionCache(session.SessionID, session.UID, now, refreshExpireTime); err != nil {
 		return err
 	}
+
+	// 超出数量 踢掉最早登录的会话
+	list, err := s.SessionRepo.GetActiveUserSessionList(sso.UID)
+	if err != nil {
+		logger.ZError("GetActiveUserSessionList", zap.Uint("uid", sso.UID), zap.Error(err))
+		return nil
+	}
+	for i := constant.USER_SESSION_MAX; i < len(list); i++ {
+		if err := s.SessionRepo.RevokeUserSession(list[i].SessionID, constant.USER_SESSION_REVOKE_LIMIT); err != nil {
+			logger.ZError("RevokeUserSession", zap.String("sid", list[i].SessionID), zap.Error(err))
+		}
+	}
 	return nil
 }
 
-func (s *AuthService) Logout(uid uint) error {
+// refresh token 轮换 旧的refresh token再次出现视为泄露 撤销整个会话
+func (s *AuthService) RefreshSession(req *entities.RefreshTokenReq) (*entities.OAuthToken, error) {
+	claims, err := utils.ParseJWT(req.RefreshToken, config.Get().ServiceSettings.JwtSignKey)
+	if err != nil {
+		return nil, errors.WithCode(errors.RefreshTokenInvalid)
+	}
+	typ, _ := claims["typ"].(string)
+	sid, _ := claims["sid"].(string)
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["userID"].(string)
+	if typ != constant.JWT_TOKEN_TYPE_REFRESH || sid == "" || jti == "" {
+		return nil, errors.WithCode(errors.RefreshTokenInvalid)
+	}
+
+	session, err := s.SessionRepo.GetUserSessionBySID(sid)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || fmt.Sprintf("%d", session.UID) != userID {
+		return nil, errors.WithCode(errors.RefreshTokenInvalid)
+	}
+	if session.Status != constant.USER_SESSION_STATUS_ACTIVE || session.ExpireTime <= time.Now().Unix() {
+		return nil, errors.WithCode(errors.SessionNotExist)
+	}
+
+	user, err := s.UserSrv.GetUserByUID(session.UID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == constant.USER_STATE_BLOCKED {
+		return nil, errors.WithCode(errors.AccountBlocked)
+	}
+
+	sso := &entities.OAuthToken{UID: session.UID, SessionID: sid}
+	newJti := uuid.New()
+	refreshExpireTime, err := s.signSessionTokens(sso, newJti)
+	if err != nil {
+		return nil, err
+	}
+	expireTime := time.Now().Add(refreshExpireTime).Unix()
+
+	ok, err := s.SessionRepo.RotateUserSessionRefresh(sid, jti, newJti, expireTime, req.IP)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		logger.ZError("RefreshSession reuse detected", zap.Uint("uid", session.UID), zap.String("sid", sid), zap.String("ip", req.IP))
+		if err := s.SessionRepo.RevokeUserSession(sid, constant.USER_SESSION_REVOKE_REUSE); err != nil {
+			logger.ZError("RevokeUserSession", zap.String("sid", sid), zap.Error(err))
+		}
+		return nil, errors.WithCode(errors.RefreshTokenReused)
+	}
+	if err := s.SessionRepo.SetUserSessionCache(sid, session.UID, time.Now().Unix(), refreshExpireTime); err != nil {
+		return nil, err
+	}
+	return sso, nil
+}
+
+// 用户的有效会话 标记当前请求的会话
+func (s *AuthService) GetUserSessionList(uid uint, currentSID string) ([]*entities.UserSession, error) {
+	list, err := s.SessionRepo.GetActiveUserSessionList(uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range list {
+		session.Current = session.SessionID == currentSID
+	}
+	return list, nil
+}
+
+// 用户撤销自己的某个会话
+func (s *AuthService) RevokeSession(req *entities.RevokeSessionReq) error {
+	session, err := s.SessionRepo.GetUserSessionBySID(req.SessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UID != req.UID || session.Status != constant.USER_SESSION_STATUS_ACTIVE {
+		return errors.WithCode(errors.SessionNotExist)
+	}
+	return s.SessionRepo.RevokeUserSession(session.SessionID, constant.USER_SESSION_REVOKE_USER)
+}
+
+// 后台强制下线 指定会话或者该用户全部会话
+func (s *AuthService) ForceLogout(req *entities.ForceLogoutReq) (err error) {
+	defer func() {
+		log := entities.SystemOptionLog{
+			Type:     constant.SYS_OPTION_TYPE_FORCE_LOGOUT,
+			OptionID: req.OptionID,
+			IP:       req.IP,
+			Content:  cjson.StringifyIgnore(req),
+		}
+		if err != nil {
+			log.Result = "false"
+			log.Remark = "强制下线失败"
+		} else {
+			log.Result = "true"
+			log.Remark = "强制下线成功"
+		}
+		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+		if err := mq.Publish(optionLogQueue); err != nil {
+			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+		}
+	}()
+
+	if req.SessionID != "" {
+		session, err := s.SessionRepo.GetUserSessionBySID(req.SessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil || session.UID != req.UID {
+			return errors.WithCode(errors.SessionNotExist)
+		}
+		return s.SessionRepo.RevokeUserSession(session.SessionID, constant.USER_SESSION_REVOKE_ADMIN)
+	}
+
+	return s.UserSrv.RevokeUserSessions(req.UID, constant.USER_SESSION_REVOKE_ADMIN)
+}
+
+func (s *AuthService) Logout(uid uint, sid string) error {
+	if sid != "" {
+		return s.SessionRepo.RevokeUserSession(sid, constant.USER_SESSION_REVOKE_LOGOUT)
+	}
 	if err := s.Repo.ExpireUserAccessToken(uid); err != nil {
 		return err
 	}
@@ -426,7 +647,7 @@ func (r *AuthService) ReigsterAuthUser(req *entities.AddUserReq) (err error) {
 			logger.ZError("AddUser", zap.Any("req", req))
 		}
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rk-api/internal/app/config"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestAuthService(t *testing.T) (*AuthService, *miniredis.Miniredis) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := "ServiceSettings:\n  JwtSignKey: test\n  TokenExpireTime: 1\n  TokenRefreshTime: 7\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.MustLoad(file); err != nil {
+		t.Fatal(err)
+	}
+
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	db := newTestDB(t, new(entities.User), new(entities.UserSession))
+	if err := db.Create(&entities.User{ID: 1, Username: "u1", Status: constant.USER_STATE_NORMAL}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := &repository.UserRepository{DB: db, RDS: rds}
+	sessionRepo := &repository.SessionRepository{DB: db, RDS: rds}
+	return &AuthService{
+		Repo:        userRepo,
+		SessionRepo: sessionRepo,
+		UserSrv:     &UserService{Repo: userRepo, SessionRepo: sessionRepo},
+	}, mr
+}
+
+// refresh token只能用一次 旧的再次使用视为泄露 整个会话撤销
+func TestAuthService_RefreshSessionRotation(t *testing.T) {
+	s, mr := newTestAuthService(t)
+	sso := &entities.OAuthToken{UID: 1}
+	if err := s.CreateJWTAccessRefreshToken(sso); err != nil {
+		t.Fatal(err)
+	}
+	sid := sso.SessionID
+	if err := s.CheckSession("1", sid, "", "127.0.0.1"); err != nil {
+		t.Fatalf("CheckSession() after login = %v", err)
+	}
+
+	rotated, err := s.RefreshSession(&entities.RefreshTokenReq{RefreshToken: sso.RefreshToken, IP: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.SessionID != sid || rotated.RefreshToken == sso.RefreshToken {
+		t.Fatalf("RefreshSession() = %+v, want same session with new refresh token", rotated)
+	}
+	if err := s.CheckSession("1", sid, "", "127.0.0.1"); err != nil {
+		t.Fatalf("CheckSession() after refresh = %v", err)
+	}
+
+	// 旧token重放
+	_, err = s.RefreshSession(&entities.RefreshTokenReq{RefreshToken: sso.RefreshToken, IP: "10.0.0.1"})
+	if !errors.IsCode(err, errors.RefreshTokenReused) {
+		t.Fatalf("RefreshSession() replay err = %v, want RefreshTokenReused", err)
+	}
+	session, err := s.SessionRepo.GetUserSessionBySID(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Status != constant.USER_SESSION_STATUS_REVOKED || session.RevokeReason != constant.USER_SESSION_REVOKE_REUSE {
+		t.Fatalf("session after replay = %+v", session)
+	}
+	if mr.Exists(fmt.Sprintf(constant.REDIS_KEY_USER_SESSION, sid)) {
+		t.Fatal("session cache not removed after replay")
+	}
+
+	// 会话撤销后 新token也不能再用
+	if _, err := s.RefreshSession(&entities.RefreshTokenReq{RefreshToken: rotated.RefreshToken}); !errors.IsCode(err, errors.SessionNotExist) {
+		t.Fatalf("RefreshSession() after revoke err = %v, want SessionNotExist", err)
+	}
+	if err := s.CheckSession("1", sid, "", "127.0.0.1"); !errors.IsCode(err, errors.SessionNotExist) {
+		t.Fatalf("CheckSession() after revoke err = %v, want SessionNotExist", err)
+	}
+}
+
+// 封禁后全部会话下线 缓存丢失时也不会从库里恢复
+func TestAuthService_BlockedUserSessions(t *testing.T) {
+	s, mr := newTestAuthService(t)
+	sids := make([]string, 0, 2)
+	for i := 0; i < 2; i++ {
+		sso := &entities.OAuthToken{UID: 1}
+		if err := s.CreateJWTAccessRefreshToken(sso); err != nil {
+			t.Fatal(err)
+		}
+		sids = append(sids, sso.SessionID)
+	}
+
+	s.Repo.DB.Model(&entities.User{}).Where("id = ?", 1).UpdateColumn("status", constant.USER_STATE_BLOCKED)
+	s.Repo.ClearUserCache(1)
+
+	// 缓存丢失 从库里恢复前检查用户状态
+	mr.Del(fmt.Sprintf(constant.REDIS_KEY_USER_SESSION, sids[0]))
+	if err := s.CheckSession("1", sids[0], "", "127.0.0.1"); !errors.IsCode(err, errors.AccountBlocked) {
+		t.Fatalf("CheckSession() blocked err = %v, want AccountBlocked", err)
+	}
+
+	if err := s.UserSrv.RevokeUserSessions(1, constant.USER_SESSION_REVOKE_BLOCKED); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.SessionRepo.GetActiveUserSessionList(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("active sessions after block = %d, want 0", len(list))
+	}
+	if err := s.CheckSession("1", sids[1], "", "127.0.0.1"); !errors.IsCode(err, errors.SessionNotExist) {
+		t.Fatalf("CheckSession() after block err = %v, want SessionNotExist", err)
+	}
+}
+
+// 更新访问时间不会把已删除的会话缓存写回来 过期时间保持登录时的设置
+func TestSessionRepository_SetUserSessionCacheSeen(t *testing.T) {
+	s, mr := newTestAuthService(t)
+	repo := s.SessionRepo
+	key := fmt.Sprintf(constant.REDIS_KEY_USER_SESSION, "sid1")
+
+	if err := repo.SetUserSessionCache("sid1", 1, 100, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	mr.SetTTL(key, time.Minute) // 模拟TTL被改掉
+	if err := repo.SetUserSessionCacheSeen("sid1", 200); err != nil {
+		t.Fatal(err)
+	}
+	if got := mr.HGet(key, "seen"); got != "200" {
+		t.Fatalf("seen = %q, want 200", got)
+	}
+	if ttl := mr.TTL(key); ttl < 59*time.Minute || ttl > time.Hour {
+		t.Fatalf("ttl = %v, want about 1h", ttl)
+	}
+
+	if err := repo.DelUserSessionCache("sid1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetUserSessionCacheSeen("sid1", 300); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := repo.RDS.Exists(context.Background(), key).Result(); n != 0 {
+		t.Fatal("session cache recreated after delete")
+	}
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/orca-zhang/ecache"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	cashbackRuleCacheKey = "cashback_rules"
+	cashbackMinClaim     = 0.01
+)
+
+var (
+	// 游戏流水类型按十位划分游戏 如201/202都是wingo
+	cashbackCategories = map[uint16]string{
+		constant.FLOW_TYPE_WINGO / 10:         "wingo",
+		constant.FLOW_TYPE_NINE / 10:          "nine",
+		constant.FLOW_TYPE_R8_WITHDRAW / 10:   "r8",
+		constant.FLOW_TYPE_ZF_BET / 10:        "zf",
+		constant.FLOW_TYPE_JHSZ_WITHDRAW / 10: "jhsz",
+		constant.FlOW_TYPE_SD / 10:            constant.GameCategoryHash,
+		constant.FLOW_TYPE_CRASH / 10:         constant.GameCategoryCrash,
+		constant.FLOW_TYPE_MINE / 10:          constant.GameCategoryMine,
+		constant.FLOW_TYPE_DICE / 10:          constant.GameCategoryDice,
+	}
+	// 退回投注 正数时扣减投注额
+	cashbackRefundTypes = map[uint16]bool{
+		constant.FLOW_TYPE_R8_ROLLBACK:    true,
+		constant.FLOW_TYPE_ZF_REFUND:      true,
+		constant.FLOW_TYPE_ZF_PAYOUT_FAIL: true,
+		constant.FLOW_TYPE_ZF_CANCEL:      true,
+		constant.FLOW_TYPE_JHSZ_ROLLBACK:  true,
+		constant.FLOW_TYPE_CRASH_CANCEL:   true,
+	}
+	// 活动奖励和资金冻结不算输赢
+	cashbackIgnoreTypes = map[uint16]bool{
+		constant.FLOW_TYPE_R8_ACTIVITY_AWARD: true,
+		constant.FLOW_TYPE_JHSZ_FREEZE:       true,
+		constant.FLOW_TYPE_JHSZ_UNFREEZE:     true,
+	}
+)
+
+var CashbackServiceSet = wire.NewSet(
+	ProvideCashbackService,
+)
+
+// 玩家自返 按自己的投注或净输返还一定比例 区别于AgentService给上级的返利
+// 游戏流水入库时按当时匹配的规则累计 玩家随时领取
+type CashbackService struct {
+	Repo      *repository.CashbackRepository
+	UserRepo  *repository.UserRepository
+	VipSrv    *VipService
+	WalletSrv *WalletService
+
+	ruleCache *ecache.Cache
+}
+
+func ProvideCashbackService(repo *repository.CashbackRepository, userRepo *repository.UserRepository, vipSrv *VipService, walletSrv *WalletService) *CashbackService {
+	return &CashbackService{
+		Repo:      repo,
+		UserRepo:  userRepo,
+		VipSrv:    vipSrv,
+		WalletSrv: walletSrv,
+		ruleCache: ecache.NewLRUCache(1, 2, time.Minute),
+	}
+}
+
+func (s *CashbackService) getCashbackRuleList() ([]*entities.CashbackRule, error) {
+	if val, ok := s.ruleCache.Get(cashbackRuleCacheKey); ok {
+		return val.([]*entities.CashbackRule), nil
+	}
+	list, err := s.Repo.GetCashbackRuleList()
+	if err != nil {
+		return nil, err
+	}
+	s.ruleCache.Put(cashbackRuleCacheKey, list)
+	return list, nil
+}
+
+// 匹配最具体的规则 渠道 > VIP等级 > 分类 没有时按VIP等级配置的比例按投注返
+func (s *CashbackService) matchCashbackRule(category string, vipLevel uint8, pc int) (*entities.CashbackRule, error) {
+	rules, err := s.getCashbackRuleList()
+	if err != nil {
+		return nil, err
+	}
+	var matched *entities.CashbackRule
+	best := -1
+	for _, rule := range rules {
+		if rule.Status != 1 || rule.Rate <= 0 {
+			continue
+		}
+		score := 0
+		if rule.Category != "" {
+			if rule.Category != category {
+				continue
+			}
+			score += 1
+		}
+		if rule.VipLevel != constant.CASHBACK_VIP_LEVEL_ANY {
+			if rule.VipLevel != int(vipLevel) {
+				continue
+			}
+			score += 2
+		}
+		if rule.PromoterCode != 0 {
+			if rule.PromoterCode != pc {
+				continue
+			}
+			score += 4
+		}
+		if score > best {
+			matched, best = rule, score
+		}
+	}
+	if matched != nil {
+		return matched, nil
+	}
+
+	tier, err := s.VipSrv.GetVipTier(vipLevel)
+	if err != nil || tier == nil || tier.RakebackRate <= 0 {
+		return nil, err
+	}
+	return &entities.CashbackRule{Category: category, Mode: constant.CASHBACK_MODE_TURNOVER, Rate: tier.RakebackRate}, nil
+}
+
+// 游戏流水入库后累计自返 下注累计投注和净输 派彩扣减净输 退回投注两者都扣减
+func (s *CashbackService) Accrue(flow *entities.Flow) error {
+	if flow.FlowType <= 200 || flow.IsRobot == 1 || cashbackIgnoreTypes[flow.FlowType] {
+		return nil
+	}
+	category, ok := cashbackCategories[flow.FlowType/10]
+	if !ok {
+		return nil
+	}
+
+	item := &entities.UserCashback{UID: flow.UID, Category: category, NetLoss: -flow.Number}
+	if refund := cashbackRefundTypes[flow.FlowType]; (flow.Number < 0 && !refund) || (flow.Number > 0 && refund) {
+		item.Turnover = -flow.Number
+	}
+
+	user, err := s.UserRepo.GetUserByID(flow.UID)
+	if err != nil || user == nil {
+		return err
+	}
+	rule, err := s.matchCashbackRule(category, user.VipLevel, flow.PromoterCode)
+	if err != nil || rule == nil {
+		return err
+	}
+	if rule.Mode == constant.CASHBACK_MODE_LOSS {
+		item.Amount = item.NetLoss * rule.Rate / 100
+	} else {
+		item.Amount = item.Turnover * rule.Rate / 100
+	}
+	return s.Repo.AccrueUserCashback(item)
+}
+
+// 分类可领取的金额 保留两位小数 余数留到下次
+func cashbackClaimable(amount float64) float64 {
+	if amount < cashbackMinClaim {
+		return 0
+	}
+	return math.Floor(amount*100) / 100
+}
+
+func (s *CashbackService) GetCashbackInfo(uid uint) (*entities.CashbackInfo, error) {
+	list, err := s.Repo.GetUserCashbackList(uid)
+	if err != nil {
+		return nil, err
+	}
+	info := &entities.CashbackInfo{List: list}
+	for _, item := range list {
+		info.Amount += cashbackClaimable(item.Amount)
+	}
+	info.Amount = math.Round(info.Amount*100) / 100
+	return info, nil
+}
+
+// 领取所有分类为正的自返 净输为负的分类不抵扣其他分类 结转到下次
+// 入账的打码量要求按FLOW_TYPE_CASHBACK的打码量规则
+func (s *CashbackService) ClaimCashback(uid uint) (float64, error) {
+	var total float64
+	err := s.WalletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
+		list, err := s.Repo.GetUserCashbackListForUpdate(tx, uid)
+		if err != nil {
+			return err
+		}
+		details := make([]*entities.UserCashback, 0, len(list))
+		for _, item := range list {
+			amount := cashbackClaimable(item.Amount)
+			if amount <= 0 {
+				continue
+			}
+			item.Amount = amount
+			if err := s.Repo.DeductUserCashbackWithTx(tx, item); err != nil {
+				return err
+			}
+			details = append(details, item)
+			total += amount
+		}
+		total = math.Round(total*100) / 100
+		if total < cashbackMinClaim {
+			return errors.WithCode(errors.CashbackNotEnough)
+		}
+
+		claim := &entities.CashbackClaim{
+			UID:       uid,
+			Amount:    total,
+			Detail:    cjson.StringifyIgnore(details),
+			ClaimTime: time.Now().Unix(),
+		}
+		if err := s.Repo.CreateCashbackClaimWithTx(tx, claim); err != nil {
+			return err
+		}
+
+		wallet.SafeAdjustCash(total)
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, uid, constant.FLOW_TYPE_CASHBACK, fmt.Sprintf("%d", claim.ID), total); err != nil {
+			return err
+		}
+		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
+			return err
+		}
+		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
+			UID:          uid,
+			FlowType:     constant.FLOW_TYPE_CASHBACK,
+			Number:       total,
+			Balance:      wallet.Cash,
+			PromoterCode: wallet.PromoterCode,
+		})
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		return event.PublishTx(tx, &event.BonusGranted{UID: uid, FlowType: constant.FLOW_TYPE_CASHBACK, SourceID: fmt.Sprintf("%d", claim.ID), Amount: total})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (s *CashbackService) GetCashbackClaimList(req *entities.GetCashbackClaimListReq) error {
+	return s.Repo.GetCashbackClaimList(req)
+}
+
+func (s *CashbackService) AdminGetCashbackRuleList() ([]*entities.CashbackRule, error) {
+	return s.Repo.GetCashbackRuleList()
+}
+
+func (s *CashbackService) SaveCashbackRule(req *entities.SaveCashbackRuleReq) (err error) {
+	defer func() {
+		s.writeCashbackLog(req.OptionID, req.IP, req, err, "自返规则保存")
+	}()
+
+	if req.Mode != constant.CASHBACK_MODE_TURNOVER && req.Mode != constant.CASHBACK_MODE_LOSS {
+		return errors.WithCode(errors.CashbackRuleInvalid)
+	}
+	if req.Rate < 0 || req.Rate > 100 || req.VipLevel < constant.CASHBACK_VIP_LEVEL_ANY || req.PromoterCode < 0 {
+		return errors.WithCode(errors.CashbackRuleInvalid)
+	}
+	if req.Category != "" && !isCashbackCategory(req.Category) {
+		return errors.WithCode(errors.CashbackRuleInvalid)
+	}
+
+	rule := new(entities.CashbackRule)
+	if req.ID > 0 {
+		if rule, err = s.Repo.GetCashbackRuleByID(req.ID); err != nil {
+			return err
+		}
+		if rule == nil {
+			return errors.WithCode(errors.CashbackRuleNotExist)
+		}
+	}
+	rule.Category = req.Category
+	rule.VipLevel = req.VipLevel
+	rule.PromoterCode = req.PromoterCode
+	rule.Mode = req.Mode
+	rule.Rate = req.Rate
+	rule.Status = req.Status
+	rule.Remark = req.Remark
+	if err = s.Repo.SaveCashbackRule(rule); err != nil {
+		return err
+	}
+	s.ruleCache.Del(cashbackRuleCacheKey)
+	return nil
+}
+
+func (s *CashbackService) DelCashbackRule(req *entities.DelCashbackRuleReq) (err error) {
+	defer func() {
+		s.writeCashbackLog(req.OptionID, req.IP, req, err, "自返规则删除")
+	}()
+
+	if err = s.Repo.DelCashbackRule(req.ID); err != nil {
+		return err
+	}
+	s.ruleCache.Del(cashbackRuleCacheKey)
+	return nil
+}
+
+func isCashbackCategory(category string) bool {
+	for _, item := range cashbackCategories {
+		if item == category {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *CashbackService) writeCashbackLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_CASHBACK_RULE,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/orca-zhang/ecache"
+	"github.com/redis/go-redis/v9"
+)
+
+// 规则和VIP配置直接放进缓存 不用建表
+func newTestCashbackService(t *testing.T, rules []*entities.CashbackRule, tiers []*entities.VipTier) *CashbackService {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	db := newTestDB(t, new(entities.User), new(entities.UserWallet), new(entities.UserCashback), new(entities.CashbackClaim),
+		new(entities.TurnoverRule), new(entities.TurnoverRequirement), new(entities.OutboxMessage))
+
+	vipSrv := &VipService{tierCache: ecache.NewLRUCache(1, 2, time.Minute)}
+	vipSrv.tierCache.Put(vipTierCacheKey, tiers)
+	walletRepo := &repository.WalletRepository{DB: db, RDS: rds}
+	s := &CashbackService{
+		Repo:     &repository.CashbackRepository{DB: db},
+		UserRepo: &repository.UserRepository{DB: db, RDS: rds},
+		VipSrv:   vipSrv,
+		WalletSrv: &WalletService{
+			Repo:              walletRepo,
+			UserLocks:         entities.NewRedisUserLock(rds),
+			turnoverRuleCache: ecache.NewLRUCache(1, 2, time.Minute),
+		},
+		ruleCache: ecache.NewLRUCache(1, 2, time.Minute),
+	}
+	s.ruleCache.Put(cashbackRuleCacheKey, rules)
+	return s
+}
+
+func TestCashbackClaimable(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   float64
+	}{
+		{amount: 0, want: 0},
+		{amount: 0.009, want: 0},
+		{amount: 0.01, want: 0.01},
+		{amount: 1.2399, want: 1.23},
+		{amount: -5, want: 0},
+	}
+	for _, tt := range tests {
+		if got := cashbackClaimable(tt.amount); got != tt.want {
+			t.Fatalf("cashbackClaimable(%v) = %v, want %v", tt.amount, got, tt.want)
+		}
+	}
+}
+
+// 渠道 > VIP等级 > 分类 关闭和比例为0的不参与 都不匹配时按VIP等级的比例
+func TestCashbackService_matchCashbackRule(t *testing.T) {
+	rules := []*entities.CashbackRule{
+		{VipLevel: constant.CASHBACK_VIP_LEVEL_ANY, Mode: constant.CASHBACK_MODE_TURNOVER, Rate: 0.5, Status: 1},
+		{Category: "wingo", VipLevel: constant.CASHBACK_VIP_LEVEL_ANY, Mode: constant.CASHBACK_MODE_TURNOVER, Rate: 1, Status: 1},
+		{VipLevel: 3, Mode: constant.CASHBACK_MODE_TURNOVER, Rate: 1.5, Status: 1},
+		{VipLevel: constant.CASHBACK_VIP_LEVEL_ANY, PromoterCode: 8, Mode: constant.CASHBACK_MODE_LOSS, Rate: 2, Status: 1},
+		{Category: "wingo", VipLevel: 3, PromoterCode: 8, Mode: constant.CASHBACK_MODE_TURNOVER, Rate: 9},
+		{Category: "zf", VipLevel: 3, PromoterCode: 8, Mode: constant.CASHBACK_MODE_TURNOVER, Rate: 0, Status: 1},
+	}
+	tiers := []*entities.VipTier{{Level: 2, RakebackRate: 0.3}}
+	s := newTestCashbackService(t, rules, tiers)
+	fallback := newTestCashbackService(t, nil, tiers)
+
+	tests := []struct {
+		name     string
+		s        *CashbackService
+		category string
+		vipLevel uint8
+		pc       int
+		wantRate float64
+	}{
+		{name: "any", s: s, category: "zf", wantRate: 0.5},
+		{name: "category", s: s, category: "wingo", wantRate: 1},
+		{name: "vip over category", s: s, category: "wingo", vipLevel: 3, wantRate: 1.5},
+		{name: "channel over vip", s: s, category: "wingo", vipLevel: 3, pc: 8, wantRate: 2},
+		{name: "vip tier", s: fallback, category: "wingo", vipLevel: 2, wantRate: 0.3},
+		{name: "no tier", s: fallback, category: "wingo", vipLevel: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := tt.s.matchCashbackRule(tt.category, tt.vipLevel, tt.pc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRate == 0 {
+				if rule != nil {
+					t.Fatalf("matchCashbackRule() = %+v, want nil", rule)
+				}
+				return
+			}
+			if rule == nil || rule.Rate != tt.wantRate {
+				t.Fatalf("matchCashbackRule() = %+v, want rate %v", rule, tt.wantRate)
+			}
+		})
+	}
+}
+
+// 按分类累计 净输为负的分类不抵扣 领取后结转
+func TestCashbackService_AccrueAndClaim(t *testing.T) {
+	rules := []*entities.CashbackRule{
+		{VipLevel: constant.CASHBACK_VIP_LEVEL_ANY, Mode: constant.CASHBACK_MODE_TURNOVER, Rate: 1, Status: 1},
+		{Category: "wingo", VipLevel: constant.CASHBACK_VIP_LEVEL_ANY, Mode: constant.CASHBACK_MODE_LOSS, Rate: 10, Status: 1},
+		{Category: "r8", VipLevel: constant.CASHBACK_VIP_LEVEL_ANY, Mode: constant.CASHBACK_MODE_LOSS, Rate: 10, Status: 1},
+	}
+	s := newTestCashbackService(t, rules, nil)
+	db := s.Repo.DB
+	const uid = 1
+	if err := db.Create(&entities.User{ID: uid, Username: "u1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&entities.UserWallet{UID: uid, Cash: 100}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	flows := []*entities.Flow{
+		{FlowType: constant.FLOW_TYPE_WINGO, Number: -100},
+		{FlowType: constant.FLOW_TYPE_WINGO + 1, Number: 30},
+		{FlowType: constant.FLOW_TYPE_ZF_BET, Number: -50},
+		{FlowType: constant.FLOW_TYPE_ZF_REFUND, Number: 20},
+		{FlowType: constant.FLOW_TYPE_R8_DEPOSIT, Number: 50},
+		// 以下不累计
+		{FlowType: constant.FLOW_TYPE_WINGO, Number: -1000, IsRobot: 1},
+		{FlowType: constant.FLOW_TYPE_R8_ACTIVITY_AWARD, Number: 100},
+		{FlowType: constant.FLOW_TYPE_RECHARGE_CASH, Number: 100},
+	}
+	for _, flow := range flows {
+		flow.UID = uid
+		if err := s.Accrue(flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := s.GetCashbackInfo(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wingo 净输70*10% zf 投注30*1% r8 赢50为负
+	if info.Amount != 7.3 || len(info.List) != 3 {
+		t.Fatalf("GetCashbackInfo() = %v %d categories, want 7.3 3", info.Amount, len(info.List))
+	}
+
+	total, err := s.ClaimCashback(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 7.3 {
+		t.Fatalf("ClaimCashback() = %v, want 7.3", total)
+	}
+	var wallet entities.UserWallet
+	db.Where("uid = ?", uid).First(&wallet)
+	if wallet.Cash != 107.3 {
+		t.Fatalf("cash = %v, want 107.3", wallet.Cash)
+	}
+	var messages int64
+	db.Model(&entities.OutboxMessage{}).Count(&messages)
+	if messages != 2 {
+		t.Fatalf("outbox messages = %d, want flow and bonus event", messages)
+	}
+
+	info, _ = s.GetCashbackInfo(uid)
+	for _, item := range info.List {
+		switch item.Category {
+		case "r8":
+			if item.Amount != -5 || item.TotalClaimed != 0 {
+				t.Fatalf("r8 after claim = %+v", item)
+			}
+		case "wingo":
+			if item.Amount != 0 || item.TotalClaimed != 7 {
+				t.Fatalf("wingo after claim = %+v", item)
+			}
+		}
+	}
+	if _, err := s.ClaimCashback(uid); !errors.IsCode(err, errors.CashbackNotEnough) {
+		t.Fatalf("ClaimCashback() again err = %v, want CashbackNotEnough", err)
+	}
+}
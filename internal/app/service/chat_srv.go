@@ -88,6 +88,28 @@ func (s *ChatService) SendMessage(msg *entities.ChatMessage) error {
 	return nil
 }
 
+// 系统消息 不校验发送人订阅 直接投放到频道
+func (s *ChatService) SendSystemMessage(channel string, msgType string, content string) error {
+	chatChannel, err := s.Repo.GetChannel(channel)
+	if err != nil {
+		logger.ZError("get channel error", zap.String("channel", channel), zap.Error(err))
+		return errors.With("channel not found")
+	}
+	if chatChannel.Active != 1 {
+		return errors.With("channel is closed")
+	}
+	msg := &entities.ChatMessage{
+		Channel: channel,
+		Content: content,
+		Type:    msgType,
+	}
+	if err := s.Repo.CreateChatMessage(msg); err != nil {
+		return err
+	}
+	s.Repo.PublishChannelMessage(channel, []byte(content))
+	return nil
+}
+
 // 统一的消息分发循环
 func (s *ChatService) StartMessageDispatcher() {
 	logger.Info("start message dispatcher")
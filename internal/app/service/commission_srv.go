@@ -0,0 +1,457 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const commissionMonthLayout = "200601"
+
+var CommissionServiceSet = wire.NewSet(
+	ProvideCommissionService,
+)
+
+// 代理佣金方案 按周/月结算团队数据生成结算单并入账 与按层级比例的游戏返利相互独立
+type CommissionService struct {
+	Repo      *repository.CommissionRepository
+	UserRepo  *repository.UserRepository
+	WalletSrv *WalletService
+}
+
+func ProvideCommissionService(repo *repository.CommissionRepository, userRepo *repository.UserRepository, walletSrv *WalletService) *CommissionService {
+	return &CommissionService{
+		Repo:      repo,
+		UserRepo:  userRepo,
+		WalletSrv: walletSrv,
+	}
+}
+
+// 周期编号 周为ISO周 2006W01 月为200601
+func commissionPeriodKey(kind string, t time.Time) string {
+	if kind == constant.COMMISSION_PERIOD_WEEK {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%dW%02d", year, week)
+	}
+	return t.Format(commissionMonthLayout)
+}
+
+// 周期编号对应的类型和时间范围 [start, end)
+func commissionPeriodRange(period string) (string, time.Time, time.Time, error) {
+	var year, week int
+	if n, _ := fmt.Sscanf(period, "%dW%d", &year, &week); n == 2 {
+		// 1月4日所在的周为第一周
+		jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, time.Local)
+		start := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+(week-1)*7)
+		if commissionPeriodKey(constant.COMMISSION_PERIOD_WEEK, start) != period {
+			return "", start, start, errors.WithCode(errors.CommissionPeriodInvalid)
+		}
+		return constant.COMMISSION_PERIOD_WEEK, start, start.AddDate(0, 0, 7), nil
+	}
+	start, err := time.ParseInLocation(commissionMonthLayout, period, time.Local)
+	if err != nil {
+		return "", start, start, errors.WithCode(errors.CommissionPeriodInvalid)
+	}
+	return constant.COMMISSION_PERIOD_MONTH, start, start.AddDate(0, 1, 0), nil
+}
+
+// 代理在一个周期内的团队数据 按层级保留 计算时按方案的层级过滤
+type commissionTeam struct {
+	turnover []*repository.AgentStatsAmount
+	revenue  []*repository.AgentStatsAmount
+	deposits []*repository.AgentStatsAmount
+}
+
+func commissionLevelMatch(plan *entities.CommissionPlan, level uint8) bool {
+	return plan.MaxLevel == 0 || level <= plan.MaxLevel
+}
+
+// 指定代理的分配优先于渠道
+func resolveCommissionPlan(uid uint, pc int, plans map[uint]*entities.CommissionPlan, assigns []*entities.CommissionPlanAssign) *entities.CommissionPlan {
+	var byPC *entities.CommissionPlan
+	for _, assign := range assigns {
+		plan, ok := plans[assign.PlanID]
+		if !ok {
+			continue
+		}
+		if assign.UID != 0 && assign.UID == uid {
+			return plan
+		}
+		if assign.UID == 0 && assign.PromoterCode == pc && byPC == nil {
+			byPC = plan
+		}
+	}
+	return byPC
+}
+
+// 结算一个周期 已有结算单的代理跳过 可重复执行
+func (s *CommissionService) SettleCommission(period string) error {
+	kind, start, end, err := commissionPeriodRange(period)
+	if err != nil {
+		return err
+	}
+	if end.After(time.Now()) {
+		return errors.WithCode(errors.CommissionPeriodInvalid)
+	}
+
+	planList, err := s.Repo.GetCommissionPlanList()
+	if err != nil {
+		return err
+	}
+	plans := make(map[uint]*entities.CommissionPlan)
+	for _, plan := range planList {
+		if plan.Status == 1 && plan.Period == kind {
+			plans[plan.ID] = plan
+		}
+	}
+	if len(plans) == 0 {
+		return nil
+	}
+	assigns, err := s.Repo.GetCommissionPlanAssignList()
+	if err != nil {
+		return err
+	}
+
+	teams := make(map[uint]*commissionTeam)
+	getTeam := func(pid uint) *commissionTeam {
+		if team, ok := teams[pid]; ok {
+			return team
+		}
+		team := new(commissionTeam)
+		teams[pid] = team
+		return team
+	}
+	// 投注取代理每日统计 最后一天在凌晨定稿后才结算
+	turnovers, err := s.Repo.SumTeamTurnover(agentStatsDate(start), agentStatsDate(end.AddDate(0, 0, -1)))
+	if err != nil {
+		return err
+	}
+	for _, item := range turnovers {
+		team := getTeam(item.PID)
+		team.turnover = append(team.turnover, item)
+	}
+	ignoreTypes := make([]uint16, 0, len(cashbackIgnoreTypes))
+	for flowType := range cashbackIgnoreTypes {
+		ignoreTypes = append(ignoreTypes, flowType)
+	}
+	revenues, err := s.Repo.SumTeamNetRevenue(start.Unix(), end.Unix(), ignoreTypes)
+	if err != nil {
+		return err
+	}
+	for _, item := range revenues {
+		team := getTeam(item.PID)
+		team.revenue = append(team.revenue, item)
+	}
+	deposits, err := s.Repo.GetTeamFirstDepositList(start.Unix(), end.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range deposits {
+		team := getTeam(item.PID)
+		team.deposits = append(team.deposits, item)
+	}
+	if len(teams) == 0 {
+		return nil
+	}
+
+	pids := make([]uint, 0, len(teams))
+	for pid := range teams {
+		pids = append(pids, pid)
+	}
+	pcMap, err := s.Repo.GetUserPromoterCodeMap(pids)
+	if err != nil {
+		return err
+	}
+	settled, err := s.Repo.GetCommissionStatementUIDs(period)
+	if err != nil {
+		return err
+	}
+
+	for pid, team := range teams {
+		if settled[pid] {
+			continue
+		}
+		plan := resolveCommissionPlan(pid, pcMap[pid], plans, assigns)
+		if plan == nil {
+			continue
+		}
+		statement, err := s.buildCommissionStatement(pid, period, start, end, plan, team)
+		if err != nil {
+			return err
+		}
+		if err := s.Repo.CreateCommissionStatement(statement); err != nil {
+			return err
+		}
+	}
+	return s.PayPendingCommission()
+}
+
+func (s *CommissionService) buildCommissionStatement(uid uint, period string, start, end time.Time, plan *entities.CommissionPlan, team *commissionTeam) (*entities.CommissionStatement, error) {
+	statement := &entities.CommissionStatement{
+		UID:       uid,
+		Period:    period,
+		PlanID:    plan.ID,
+		PlanType:  plan.Type,
+		StartTime: start.Unix(),
+		EndTime:   end.Unix(),
+		Rate:      plan.Rate,
+	}
+	for _, item := range team.turnover {
+		if commissionLevelMatch(plan, item.Level) {
+			statement.Turnover += item.Amount
+		}
+	}
+	for _, item := range team.revenue {
+		if commissionLevelMatch(plan, item.Level) {
+			statement.NetRevenue += item.Amount
+		}
+	}
+	for _, item := range team.deposits {
+		if commissionLevelMatch(plan, item.Level) && item.Amount >= plan.CpaMinDeposit {
+			statement.QualifiedFTDs++
+		}
+	}
+
+	var amount float64
+	switch plan.Type {
+	case constant.COMMISSION_TYPE_TURNOVER:
+		amount = statement.Turnover * plan.Rate / 100
+	case constant.COMMISSION_TYPE_REVSHARE:
+		// 净输为负时不发放 负数结转到下期抵扣
+		carryIn, err := s.Repo.GetLastCarryOut(uid, statement.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		statement.CarryIn = carryIn
+		if total := statement.NetRevenue + carryIn; total > 0 {
+			amount = total * plan.Rate / 100
+		} else {
+			statement.CarryOut = total
+		}
+	case constant.COMMISSION_TYPE_CPA:
+		amount = float64(statement.QualifiedFTDs) * plan.CpaAmount
+	}
+	statement.Amount = math.Floor(amount*100) / 100
+	return statement, nil
+}
+
+// 发放所有待发放的结算单 入账失败的留到下次
+func (s *CommissionService) PayPendingCommission() error {
+	list, err := s.Repo.GetPendingCommissionStatementList()
+	if err != nil {
+		return err
+	}
+	for _, statement := range list {
+		if err := s.payCommissionStatement(statement); err != nil {
+			logger.ZError("payCommissionStatement", zap.Uint("id", statement.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *CommissionService) payCommissionStatement(statement *entities.CommissionStatement) error {
+	if statement.Amount <= 0 {
+		_, err := s.Repo.MarkCommissionStatementPaidWithTx(s.Repo.DB, statement.ID)
+		return err
+	}
+	return s.WalletSrv.HandleWallet(statement.UID, func(wallet *entities.UserWallet, tx *gorm.DB) error {
+		ok, err := s.Repo.MarkCommissionStatementPaidWithTx(tx, statement.ID)
+		if err != nil || !ok {
+			return err
+		}
+		wallet.SafeAdjustCash(statement.Amount)
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, statement.UID, constant.FLOW_TYPE_COMMISSION, fmt.Sprintf("%d", statement.ID), statement.Amount); err != nil {
+			return err
+		}
+		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
+			return err
+		}
+		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
+			UID:          statement.UID,
+			FlowType:     constant.FLOW_TYPE_COMMISSION,
+			Number:       statement.Amount,
+			Balance:      wallet.Cash,
+			PromoterCode: wallet.PromoterCode,
+		})
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		return event.PublishTx(tx, &event.BonusGranted{UID: statement.UID, FlowType: constant.FLOW_TYPE_COMMISSION, SourceID: fmt.Sprintf("%d", statement.ID), Amount: statement.Amount})
+	})
+}
+
+// 定时任务 每天结算上一个已结束的周和月 已结算的会跳过
+func (s *CommissionService) SettleDueCommission() error {
+	now := time.Now()
+	lastWeek := commissionPeriodKey(constant.COMMISSION_PERIOD_WEEK, now.AddDate(0, 0, -7))
+	if err := s.SettleCommission(lastWeek); err != nil {
+		return err
+	}
+	lastMonth := commissionPeriodKey(constant.COMMISSION_PERIOD_MONTH, now.AddDate(0, 0, -now.Day()))
+	return s.SettleCommission(lastMonth)
+}
+
+func (s *CommissionService) AdminSettleCommission(req *entities.SettleCommissionReq) (err error) {
+	defer func() {
+		s.writeCommissionLog(req.OptionID, req.IP, req, err, "佣金结算")
+	}()
+	return s.SettleCommission(req.Period)
+}
+
+// 代理当前适用的方案
+func (s *CommissionService) GetCommissionPlanInfo(uid uint) (*entities.CommissionPlanInfo, error) {
+	info := new(entities.CommissionPlanInfo)
+	user, err := s.UserRepo.GetUserByID(uid)
+	if err != nil || user == nil {
+		return info, err
+	}
+	for _, source := range []struct {
+		name string
+		uid  uint
+		pc   int
+	}{{"uid", uid, 0}, {"pc", 0, user.PromoterCode}} {
+		assign, err := s.Repo.GetCommissionPlanAssign(source.uid, source.pc)
+		if err != nil {
+			return nil, err
+		}
+		if assign == nil {
+			continue
+		}
+		plan, err := s.Repo.GetCommissionPlanByID(assign.PlanID)
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil && plan.Status == 1 {
+			info.Plan, info.Source = plan, source.name
+			return info, nil
+		}
+	}
+	return info, nil
+}
+
+func (s *CommissionService) GetCommissionStatementList(req *entities.GetCommissionStatementListReq) error {
+	return s.Repo.GetCommissionStatementList(req)
+}
+
+func (s *CommissionService) AdminGetCommissionPlanList() ([]*entities.CommissionPlan, error) {
+	return s.Repo.GetCommissionPlanList()
+}
+
+func (s *CommissionService) SaveCommissionPlan(req *entities.SaveCommissionPlanReq) (err error) {
+	defer func() {
+		s.writeCommissionLog(req.OptionID, req.IP, req, err, "佣金方案保存")
+	}()
+
+	switch req.Type {
+	case constant.COMMISSION_TYPE_TURNOVER, constant.COMMISSION_TYPE_REVSHARE:
+		if req.Rate <= 0 || req.Rate > 100 {
+			return errors.WithCode(errors.CommissionPlanInvalid)
+		}
+	case constant.COMMISSION_TYPE_CPA:
+		if req.CpaAmount <= 0 || req.CpaMinDeposit < 0 {
+			return errors.WithCode(errors.CommissionPlanInvalid)
+		}
+	default:
+		return errors.WithCode(errors.CommissionPlanInvalid)
+	}
+	if req.Period != constant.COMMISSION_PERIOD_WEEK && req.Period != constant.COMMISSION_PERIOD_MONTH {
+		return errors.WithCode(errors.CommissionPlanInvalid)
+	}
+
+	plan := new(entities.CommissionPlan)
+	if req.ID > 0 {
+		if plan, err = s.Repo.GetCommissionPlanByID(req.ID); err != nil {
+			return err
+		}
+		if plan == nil {
+			return errors.WithCode(errors.CommissionPlanNotExist)
+		}
+	}
+	plan.Name = req.Name
+	plan.Type = req.Type
+	plan.Period = req.Period
+	plan.Rate = req.Rate
+	plan.CpaAmount = req.CpaAmount
+	plan.CpaMinDeposit = req.CpaMinDeposit
+	plan.MaxLevel = req.MaxLevel
+	plan.Status = req.Status
+	return s.Repo.SaveCommissionPlan(plan)
+}
+
+func (s *CommissionService) DelCommissionPlan(req *entities.DelCommissionPlanReq) (err error) {
+	defer func() {
+		s.writeCommissionLog(req.OptionID, req.IP, req, err, "佣金方案删除")
+	}()
+	return s.Repo.DelCommissionPlan(req.ID)
+}
+
+func (s *CommissionService) AdminGetCommissionPlanAssignList() ([]*entities.CommissionPlanAssign, error) {
+	return s.Repo.GetCommissionPlanAssignList()
+}
+
+// 指定代理或渠道只能二选一 已有分配时改为新方案
+func (s *CommissionService) SaveCommissionPlanAssign(req *entities.SaveCommissionPlanAssignReq) (err error) {
+	defer func() {
+		s.writeCommissionLog(req.OptionID, req.IP, req, err, "佣金方案分配")
+	}()
+
+	if (req.UID == 0) == (req.PromoterCode == 0) || req.PromoterCode < 0 {
+		return errors.WithCode(errors.InvalidParam)
+	}
+	plan, err := s.Repo.GetCommissionPlanByID(req.PlanID)
+	if err != nil {
+		return err
+	}
+	if plan == nil {
+		return errors.WithCode(errors.CommissionPlanNotExist)
+	}
+	assign, err := s.Repo.GetCommissionPlanAssign(req.UID, req.PromoterCode)
+	if err != nil {
+		return err
+	}
+	if assign == nil {
+		assign = &entities.CommissionPlanAssign{UID: req.UID, PromoterCode: req.PromoterCode}
+	}
+	assign.PlanID = req.PlanID
+	return s.Repo.SaveCommissionPlanAssign(assign)
+}
+
+func (s *CommissionService) DelCommissionPlanAssign(req *entities.DelCommissionPlanAssignReq) (err error) {
+	defer func() {
+		s.writeCommissionLog(req.OptionID, req.IP, req, err, "佣金方案分配删除")
+	}()
+	return s.Repo.DelCommissionPlanAssign(req.ID)
+}
+
+func (s *CommissionService) writeCommissionLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_COMMISSION_PLAN,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/orca-zhang/ecache"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestCommissionService(t *testing.T) *CommissionService {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	db := newTestDB(t, new(entities.CommissionStatement), new(entities.UserWallet),
+		new(entities.TurnoverRule), new(entities.TurnoverRequirement), new(entities.OutboxMessage))
+	return &CommissionService{
+		Repo: &repository.CommissionRepository{DB: db},
+		WalletSrv: &WalletService{
+			Repo:              &repository.WalletRepository{DB: db, RDS: rds},
+			UserLocks:         entities.NewRedisUserLock(rds),
+			turnoverRuleCache: ecache.NewLRUCache(1, 2, time.Minute),
+		},
+	}
+}
+
+func TestCommissionPeriodRange(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		period    string
+		wantKind  string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{period: "2024W01", wantKind: constant.COMMISSION_PERIOD_WEEK, wantStart: day(2024, 1, 1), wantEnd: day(2024, 1, 8)},
+		{period: "2021W01", wantKind: constant.COMMISSION_PERIOD_WEEK, wantStart: day(2021, 1, 4), wantEnd: day(2021, 1, 11)},
+		{period: "2020W53", wantKind: constant.COMMISSION_PERIOD_WEEK, wantStart: day(2020, 12, 28), wantEnd: day(2021, 1, 4)},
+		{period: "2021W53", wantErr: true}, // 2021年只有52周
+		{period: "202402", wantKind: constant.COMMISSION_PERIOD_MONTH, wantStart: day(2024, 2, 1), wantEnd: day(2024, 3, 1)},
+		{period: "2024-02", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			kind, start, end, err := commissionPeriodRange(tt.period)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("commissionPeriodRange() = %s %v, want error", kind, start)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != tt.wantKind || !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("commissionPeriodRange() = %s %v %v, want %s %v %v", kind, start, end, tt.wantKind, tt.wantStart, tt.wantEnd)
+			}
+			if key := commissionPeriodKey(kind, start); key != tt.period {
+				t.Fatalf("commissionPeriodKey() = %s, want %s", key, tt.period)
+			}
+		})
+	}
+}
+
+// 指定代理优先于渠道 方案关闭(不在plans中)的分配忽略
+func TestResolveCommissionPlan(t *testing.T) {
+	plans := map[uint]*entities.CommissionPlan{
+		1: {BaseModel: entities.BaseModel{ID: 1}},
+		2: {BaseModel: entities.BaseModel{ID: 2}},
+	}
+	assigns := []*entities.CommissionPlanAssign{
+		{PlanID: 1, PromoterCode: 8},
+		{PlanID: 2, UID: 10},
+		{PlanID: 3, UID: 11},
+	}
+	tests := []struct {
+		name   string
+		uid    uint
+		pc     int
+		wantID uint
+	}{
+		{name: "by uid", uid: 10, pc: 8, wantID: 2},
+		{name: "by pc", uid: 12, pc: 8, wantID: 1},
+		{name: "disabled plan falls back to pc", uid: 11, pc: 8, wantID: 1},
+		{name: "none", uid: 11, pc: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := resolveCommissionPlan(tt.uid, tt.pc, plans, assigns)
+			if tt.wantID == 0 {
+				if plan != nil {
+					t.Fatalf("resolveCommissionPlan() = %d, want nil", plan.ID)
+				}
+				return
+			}
+			if plan == nil || plan.ID != tt.wantID {
+				t.Fatalf("resolveCommissionPlan() = %+v, want %d", plan, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestCommissionService_buildCommissionStatement(t *testing.T) {
+	s := newTestCommissionService(t)
+	_, start, end, err := commissionPeriodRange("202402")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := func(level uint8, amount float64) *repository.AgentStatsAmount {
+		return &repository.AgentStatsAmount{Level: level, Amount: amount}
+	}
+	team := &commissionTeam{
+		turnover: []*repository.AgentStatsAmount{amount(1, 1000), amount(2, 500.55)},
+		revenue:  []*repository.AgentStatsAmount{amount(1, 300), amount(2, -100)},
+		deposits: []*repository.AgentStatsAmount{amount(1, 100), amount(1, 49.99), amount(2, 200)},
+	}
+
+	tests := []struct {
+		name       string
+		plan       *entities.CommissionPlan
+		wantAmount float64
+	}{
+		{name: "turnover all levels", plan: &entities.CommissionPlan{Type: constant.COMMISSION_TYPE_TURNOVER, Rate: 0.5}, wantAmount: 7.5},
+		{name: "turnover level 1", plan: &entities.CommissionPlan{Type: constant.COMMISSION_TYPE_TURNOVER, Rate: 0.5, MaxLevel: 1}, wantAmount: 5},
+		{name: "cpa", plan: &entities.CommissionPlan{Type: constant.COMMISSION_TYPE_CPA, CpaAmount: 20, CpaMinDeposit: 50}, wantAmount: 40},
+		{name: "revshare", plan: &entities.CommissionPlan{Type: constant.COMMISSION_TYPE_REVSHARE, Rate: 30}, wantAmount: 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := s.buildCommissionStatement(1, "202402", start, end, tt.plan, team)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if statement.Amount != tt.wantAmount {
+				t.Fatalf("amount = %v, want %v, statement %+v", statement.Amount, tt.wantAmount, statement)
+			}
+		})
+	}
+}
+
+// 净输为负结转到下期抵扣
+func TestCommissionService_RevshareCarry(t *testing.T) {
+	s := newTestCommissionService(t)
+	plan := &entities.CommissionPlan{Type: constant.COMMISSION_TYPE_REVSHARE, Rate: 10}
+	steps := []struct {
+		period       string
+		revenue      float64
+		wantCarryIn  float64
+		wantCarryOut float64
+		wantAmount   float64
+	}{
+		{period: "202401", revenue: -300, wantCarryOut: -300},
+		{period: "202402", revenue: 200, wantCarryIn: -300, wantCarryOut: -100},
+		{period: "202403", revenue: 500, wantCarryIn: -100, wantAmount: 40},
+		{period: "202404", revenue: 100, wantAmount: 10},
+	}
+	for _, step := range steps {
+		_, start, end, err := commissionPeriodRange(step.period)
+		if err != nil {
+			t.Fatal(err)
+		}
+		team := &commissionTeam{revenue: []*repository.AgentStatsAmount{{Level: 1, Amount: step.revenue}}}
+		statement, err := s.buildCommissionStatement(1, step.period, start, end, plan, team)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if statement.CarryIn != step.wantCarryIn || statement.CarryOut != step.wantCarryOut || statement.Amount != step.wantAmount {
+			t.Fatalf("%s statement = %+v", step.period, statement)
+		}
+		if err := s.Repo.CreateCommissionStatement(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 同一结算单只入账一次 金额为0的直接标记已发放
+func TestCommissionService_payCommissionStatement(t *testing.T) {
+	s := newTestCommissionService(t)
+	db := s.Repo.DB
+	if err := db.Create(&entities.UserWallet{UID: 1, Cash: 10}).Error; err != nil {
+		t.Fatal(err)
+	}
+	statements := []*entities.CommissionStatement{
+		{UID: 1, Period: "202401", PlanType: constant.COMMISSION_TYPE_TURNOVER, Amount: 25.5},
+		{UID: 2, Period: "202401", PlanType: constant.COMMISSION_TYPE_REVSHARE, CarryOut: -100},
+	}
+	for _, statement := range statements {
+		if err := s.Repo.CreateCommissionStatement(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.PayPendingCommission(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 重复发放同一条
+	if err := s.payCommissionStatement(statements[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var wallet entities.UserWallet
+	db.Where("uid = ?", 1).First(&wallet)
+	if wallet.Cash != 35.5 {
+		t.Fatalf("cash = %v, want 35.5", wallet.Cash)
+	}
+	list, err := s.Repo.GetPendingCommissionStatementList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("pending statements = %d, want 0", len(list))
+	}
+	var messages int64
+	db.Model(&entities.OutboxMessage{}).Count(&messages)
+	if messages != 2 {
+		t.Fatalf("outbox messages = %d, want flow and bonus event", messages)
+	}
+}
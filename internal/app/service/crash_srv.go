@@ -7,6 +7,7 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
@@ -167,6 +168,10 @@ func (s *CrashGameService) CreateCrashGameOrder(order *entities.CrashGameOrder)
 		return err
 	}
 
+	if err := s.WalletSrv.CheckBetLimit(order.UID, order.BetAmount); err != nil {
+		return err
+	}
+
 	wallet, err := s.WalletSrv.GetWallet(order.UID)
 	if err != nil {
 		return err
@@ -188,6 +193,9 @@ func (s *CrashGameService) CreateCrashGameOrder(order *entities.CrashGameOrder)
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.ConsumeTurnoverWithTx(tx, order.UID, order.BetAmount); err != nil { //扣减打码量
+			return err
+		}
 
 		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
 			UID:          order.UID,
@@ -196,8 +204,11 @@ func (s *CrashGameService) CreateCrashGameOrder(order *entities.CrashGameOrder)
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		if err := event.PublishTx(tx, &event.BetPlaced{UID: order.UID, Game: constant.GameNameCrash, OrderID: order.EventOrderID(), Amount: order.BetAmount}); err != nil {
+			return err
 		}
 		logger.ZInfo("CreateCrashGameOrder", zap.Any("order", order))
 		return nil
@@ -226,8 +237,8 @@ func (s *CrashGameService) CancelCrashGameOrder(order *entities.CrashGameOrder)
 			Balance:      wallet.Cash,
 			PromoterCode: order.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 		logger.ZInfo("CancelCrashGameOrder", zap.Any("order", order))
 		return nil
@@ -298,22 +309,28 @@ func (s *CrashGameService) SettleOrder(order *entities.CrashGameOrder) error {
 		return err
 	}
 
+	// 流水消息和结算在同一个事务写入发件箱
+	if flow != nil {
+		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := event.PublishTx(tx, &event.BetSettled{UID: order.UID, Game: constant.GameNameCrash, OrderID: order.EventOrderID(), BetAmount: order.BetAmount, RewardAmount: order.RewardAmount}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
+	mq.NotifyOutbox()
 
 	// 清除钱包缓存
 	s.WalletSrv.ClearWalletCache(order.UID)
 
-	// 事务提交成功后发送MQ消息 生成流水记录
-	if flow != nil {
-		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue failed", zap.Any("flow", flow), zap.Error(err))
-		}
-	}
-
 	return nil
 }
 
@@ -381,6 +398,7 @@ func (s *CrashGameService) settleUserBatch(batchUids []uint, userOrderMap map[ui
 	// 处理每个用户的订单
 	flows := make([]*entities.Flow, 0, len(batchUids))
 	records := make([]*entities.GameRecord, 0, len(batchUids))
+	settled := make([]*event.BetSettled, 0, len(batchUids))
 	for _, uid := range batchUids {
 		userOrders := userOrderMap[uid]
 		var totalReward float64
@@ -400,6 +418,7 @@ func (s *CrashGameService) settleUserBatch(batchUids []uint, userOrderMap map[ui
 				Currency:     constant.CurrencyCNY, // 假设使用人民币，可以根据实际情况调整
 				PromoterCode: order.PromoterCode,
 			})
+			settled = append(settled, &event.BetSettled{UID: uid, Game: constant.GameNameCrash, OrderID: order.EventOrderID(), BetAmount: order.BetAmount, RewardAmount: order.RewardAmount})
 
 			if err := tx.Model(&entities.CrashGameOrder{}).
 				Where("uid = ? and round_id = ? and bet_index = ?", order.UID, order.RoundID, order.BetIndex).
@@ -451,24 +470,32 @@ func (s *CrashGameService) settleUserBatch(batchUids []uint, userOrderMap map[ui
 		return err
 	}
 
+	// 流水消息和结算在同一个事务写入发件箱
+	for _, flow := range flows {
+		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, evt := range settled {
+		if err := event.PublishTx(tx, evt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
+	mq.NotifyOutbox()
 
 	for _, uid := range batchUids {
 		// 清除钱包缓存
 		s.WalletSrv.ClearWalletCache(uid)
 	}
 
-	// 事务提交成功后发送MQ消息
-	for _, flow := range flows {
-		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue failed", zap.Any("flow", flow), zap.Error(err))
-		}
-	}
-
 	return nil
 }
 
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/internal/app/telegram"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+)
+
+var DeadLetterServiceSet = wire.NewSet(
+	ProvideDeadLetterService,
+)
+
+// 队列死信 重试耗尽的任务留在asynq的archived里 这里提供查看/修改重放/丢弃
+// 消费端按类型每分钟计数 失败率突增时告警到telegram
+type DeadLetterService struct {
+	Repo *repository.DeadLetterRepository
+}
+
+func ProvideDeadLetterService(repo *repository.DeadLetterRepository) *DeadLetterService {
+	return &DeadLetterService{
+		Repo: repo,
+	}
+}
+
+func (s *DeadLetterService) GetDeadLetterList(req *entities.GetDeadLetterListReq) error {
+	if req.State == "" {
+		req.State = constant.DLQ_STATE_ARCHIVED
+	}
+	if req.State != constant.DLQ_STATE_ARCHIVED && req.State != constant.DLQ_STATE_RETRY {
+		return errors.WithCode(errors.InvalidParam)
+	}
+	list, count, err := mq.ListDeadTasks(req.State, req.Type, req.Page, req.PageSize)
+	if err != nil {
+		return err
+	}
+	req.List = list
+	req.Count = count
+	return nil
+}
+
+func (s *DeadLetterService) GetDeadLetter(req *entities.GetDeadLetterReq) (*entities.DeadLetterTask, error) {
+	task, err := mq.GetDeadTask(req.Queue, req.ID)
+	if err != nil {
+		return nil, s.wrapDeadTaskErr(err)
+	}
+	return task, nil
+}
+
+func (s *DeadLetterService) ReplayDeadLetter(req *entities.ReplayDeadLetterReq) (err error) {
+	defer func() {
+		s.writeDeadLetterLog(req.OptionID, req.IP, req, err, "重放队列死信")
+	}()
+	if err = mq.ReplayDeadTask(req.Queue, req.ID, []byte(req.Payload)); err != nil {
+		return s.wrapDeadTaskErr(err)
+	}
+	return nil
+}
+
+func (s *DeadLetterService) DiscardDeadLetter(req *entities.DiscardDeadLetterReq) (err error) {
+	defer func() {
+		s.writeDeadLetterLog(req.OptionID, req.IP, req, err, "丢弃队列死信")
+	}()
+	if err = mq.DiscardDeadTask(req.Queue, req.ID); err != nil {
+		return s.wrapDeadTaskErr(err)
+	}
+	return nil
+}
+
+func (s *DeadLetterService) wrapDeadTaskErr(err error) error {
+	if mq.IsDeadTaskNotFound(err) {
+		return errors.WithCode(errors.DeadLetterNotExist)
+	}
+	if err == mq.ErrDeadTaskPayload {
+		return errors.WithCode(errors.DeadLetterPayloadInvalid)
+	}
+	return err
+}
+
+// 消费端每处理一次任务调用 计数失败不影响任务
+func (s *DeadLetterService) RecordTaskResult(typename string, failed bool) {
+	if err := s.Repo.IncrTaskStats(typename, time.Now().Unix()/60, failed); err != nil {
+		logger.ZError("IncrTaskStats", zap.String("type", typename), zap.Error(err))
+	}
+}
+
+// 统计窗口内每个类型的处理次数和失败率
+func (s *DeadLetterService) GetTaskFailureStats() ([]*entities.TaskFailureStat, error) {
+	to := time.Now().Unix() / 60
+	from := to - constant.DLQ_ALERT_WINDOW + 1
+	list := make([]*entities.TaskFailureStat, 0)
+	for _, typename := range handle.QueueTypes() {
+		processed, failed, err := s.Repo.SumTaskStats(typename, from, to)
+		if err != nil {
+			return nil, err
+		}
+		stat := &entities.TaskFailureStat{Type: typename, Processed: processed, Failed: failed}
+		if processed > 0 {
+			stat.Rate = float64(failed) / float64(processed)
+		}
+		list = append(list, stat)
+	}
+	return list, nil
+}
+
+// 定时任务 失败次数和失败率都超过阈值才告警 同一类型冷却期内只发一次
+func (s *DeadLetterService) CheckQueueFailureRate() error {
+	list, err := s.GetTaskFailureStats()
+	if err != nil {
+		return err
+	}
+	alerts := make([]string, 0)
+	for _, stat := range list {
+		if stat.Failed < constant.DLQ_ALERT_MIN_FAIL || stat.Rate < constant.DLQ_ALERT_RATE {
+			continue
+		}
+		ok, err := s.Repo.TryFailureAlert(stat.Type)
+		if err != nil {
+			logger.ZError("TryFailureAlert", zap.String("type", stat.Type), zap.Error(err))
+			continue
+		}
+		if ok {
+			alerts = append(alerts, fmt.Sprintf("%s 处理%d次 失败%d次 失败率%.1f%%", stat.Type, stat.Processed, stat.Failed, stat.Rate*100))
+		}
+	}
+	if len(alerts) > 0 {
+		telegram.SendToManage(fmt.Sprintf("<b>队列失败率告警</b> 最近%d分钟\n%s", constant.DLQ_ALERT_WINDOW, strings.Join(alerts, "\n")))
+	}
+	return nil
+}
+
+func (s *DeadLetterService) writeDeadLetterLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_DEAD_LETTER,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
@@ -63,6 +64,10 @@ func (s *DiceGameService) PlaceOrder(order *entities.DiceGameOrder) error {
 		return err
 	}
 
+	if err := s.WalletSrv.CheckBetLimit(order.UID, order.BetAmount); err != nil {
+		return err
+	}
+
 	wallet, err := s.WalletSrv.GetWallet(order.UID)
 	if err != nil {
 		return err
@@ -83,6 +88,9 @@ func (s *DiceGameService) PlaceOrder(order *entities.DiceGameOrder) error {
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.ConsumeTurnoverWithTx(tx, order.UID, order.BetAmount); err != nil { //扣减打码量
+			return err
+		}
 
 		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
 			UID:          order.UID,
@@ -91,8 +99,11 @@ func (s *DiceGameService) PlaceOrder(order *entities.DiceGameOrder) error {
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		if err := event.PublishTx(tx, &event.BetPlaced{UID: order.UID, Game: constant.GameNameDice, OrderID: fmt.Sprintf("%d", order.ID), Amount: order.BetAmount}); err != nil {
+			return err
 		}
 		logger.ZInfo("DiceGameService.PlaceOrder", zap.Any("order", order))
 		return nil
@@ -164,21 +175,27 @@ func (s *DiceGameService) SettleOrder(order *entities.DiceGameOrder) error {
 		return err
 	}
 
+	// 流水消息和结算在同一个事务写入发件箱
+	if flow != nil {
+		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := event.PublishTx(tx, &event.BetSettled{UID: order.UID, Game: constant.GameNameDice, OrderID: fmt.Sprintf("%d", order.ID), BetAmount: order.BetAmount, RewardAmount: order.RewardAmount}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
+	mq.NotifyOutbox()
 
 	// 清除钱包缓存
 	s.WalletSrv.ClearWalletCache(order.UID)
 
-	// 事务提交成功后发送MQ消息 生成流水记录
-	if flow != nil {
-		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue failed", zap.Any("flow", flow), zap.Error(err))
-		}
-	}
-
 	return nil
 }
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var EventBusServiceSet = wire.NewSet(
+	ProvideEventBusService,
+)
+
+// 返回错误的事件不确认 超过认领时间后重新投递
+type EventHandler func(evt *entities.EventEnvelope) error
+
+type eventSubscription struct {
+	group   string
+	names   []string
+	handler EventHandler
+}
+
+// 领域事件总线 事件经发件箱中继写入redis stream 每个事件一个stream
+// 每个订阅者一个消费组 各自确认各自重放 一个订阅者出错不影响其他订阅者
+// 多个节点用同一个消费组时事件只会被其中一个节点处理
+type EventBusService struct {
+	Repo *repository.EventRepository
+
+	node string
+	mu   sync.Mutex
+	subs []*eventSubscription
+}
+
+func ProvideEventBusService(repo *repository.EventRepository) *EventBusService {
+	hostname, _ := os.Hostname()
+	return &EventBusService{
+		Repo: repo,
+		node: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// 发件箱中继调用 id是发件箱去重键 重复写入会被忽略
+func (s *EventBusService) Append(name, id, payload string, t int64) error {
+	_, err := s.Repo.AppendEvent(id, name, t, payload)
+	return err
+}
+
+// 在Start之前订阅
+func (s *EventBusService) Subscribe(group string, handler EventHandler, names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs = append(s.subs, &eventSubscription{group: group, names: names, handler: handler})
+}
+
+// 新建的消费组从当前位置开始 不处理订阅之前的历史事件 需要时通过重放补处理
+func (s *EventBusService) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subs {
+		for _, name := range sub.names {
+			if err := s.Repo.CreateEventGroup(name, sub.group, "$"); err != nil {
+				return fmt.Errorf("event group %s %s: %w", sub.group, name, err)
+			}
+		}
+	}
+	for _, sub := range s.subs {
+		go s.consume(sub)
+	}
+	logger.ZInfo("EventBusService Start", zap.String("node", s.node), zap.Int("subs", len(s.subs)))
+	return nil
+}
+
+func (s *EventBusService) consume(sub *eventSubscription) {
+	var lastClaim time.Time
+	for {
+		if time.Since(lastClaim) >= constant.EVENT_CLAIM_INTERVAL*time.Second {
+			s.claim(sub)
+			lastClaim = time.Now()
+		}
+		streams, err := s.Repo.ReadEventGroup(sub.group, s.node, sub.names, constant.EVENT_BATCH_SIZE, constant.EVENT_BLOCK*time.Millisecond)
+		if err != nil {
+			logger.ZError("ReadEventGroup", zap.String("group", sub.group), zap.Error(err))
+			time.Sleep(time.Second)
+			continue
+		}
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				s.handle(sub, msg)
+			}
+		}
+	}
+}
+
+// 认领其他节点崩溃或处理失败遗留的事件 投递次数过多的确认丢弃
+func (s *EventBusService) claim(sub *eventSubscription) {
+	idle := constant.EVENT_CLAIM_IDLE * time.Second
+	for _, name := range sub.names {
+		pending, err := s.Repo.GetStalePendingEvents(name, sub.group, idle, constant.EVENT_BATCH_SIZE)
+		if err != nil {
+			logger.ZError("GetStalePendingEvents", zap.String("group", sub.group), zap.String("name", name), zap.Error(err))
+			continue
+		}
+		ids := make([]string, 0, len(pending))
+		for _, item := range pending {
+			if item.RetryCount < constant.EVENT_MAX_DELIVERY {
+				ids = append(ids, item.ID)
+				continue
+			}
+			logger.ZError("EventBusService drop event", zap.String("group", sub.group), zap.String("name", name),
+				zap.String("stream_id", item.ID), zap.Int64("delivery", item.RetryCount))
+			if err := s.Repo.AckEvent(name, sub.group, item.ID); err != nil {
+				logger.ZError("AckEvent", zap.String("group", sub.group), zap.String("name", name), zap.Error(err))
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		list, err := s.Repo.ClaimEvents(name, sub.group, s.node, idle, ids)
+		if err != nil {
+			logger.ZError("ClaimEvents", zap.String("group", sub.group), zap.String("name", name), zap.Error(err))
+			continue
+		}
+		for _, msg := range list {
+			s.handle(sub, msg)
+		}
+	}
+}
+
+func (s *EventBusService) handle(sub *eventSubscription, msg redis.XMessage) {
+	evt := toEventEnvelope(msg)
+	err := func() (err error) {
+		defer func() {
+			if x := recover(); x != nil {
+				err = fmt.Errorf("panic: %v", x)
+			}
+		}()
+		return sub.handler(evt)
+	}()
+	if err != nil {
+		logger.ZError("EventBusService handle", zap.String("group", sub.group), zap.String("name", evt.Name),
+			zap.String("stream_id", evt.StreamID), zap.Error(err))
+		return
+	}
+	if err := s.Repo.AckEvent(evt.Name, sub.group, msg.ID); err != nil {
+		logger.ZError("AckEvent", zap.String("group", sub.group), zap.String("name", evt.Name), zap.Error(err))
+	}
+}
+
+func toEventEnvelope(msg redis.XMessage) *entities.EventEnvelope {
+	evt := &entities.EventEnvelope{StreamID: msg.ID}
+	evt.ID, _ = msg.Values["id"].(string)
+	evt.Name, _ = msg.Values["name"].(string)
+	if t, ok := msg.Values["time"].(string); ok {
+		evt.Time, _ = strconv.ParseInt(t, 10, 64)
+	}
+	if payload, ok := msg.Values["payload"].(string); ok {
+		evt.Payload = json.RawMessage(payload)
+	}
+	return evt
+}
+
+// 所有事件上的消费组和积压情况
+func (s *EventBusService) GetEventGroupList() ([]*entities.EventGroupInfo, error) {
+	list := make([]*entities.EventGroupInfo, 0)
+	for _, name := range event.Names {
+		groups, err := s.Repo.GetEventGroups(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			list = append(list, &entities.EventGroupInfo{
+				Group:           group.Name,
+				Name:            name,
+				Pending:         group.Pending,
+				Lag:             group.Lag,
+				LastDeliveredID: group.LastDeliveredID,
+			})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Group < list[j].Group })
+	return list, nil
+}
+
+// 从新到旧查看事件 EndID不包含在结果里
+func (s *EventBusService) GetEventList(req *entities.GetEventListReq) ([]*entities.EventEnvelope, error) {
+	if req.Count <= 0 || req.Count > constant.EVENT_BATCH_SIZE {
+		req.Count = constant.EVENT_BATCH_SIZE
+	}
+	end := "+"
+	if req.EndID != "" {
+		end = "(" + req.EndID
+	}
+	msgs, err := s.Repo.GetEventList(req.Name, end, req.Count)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*entities.EventEnvelope, 0, len(msgs))
+	for _, msg := range msgs {
+		list = append(list, toEventEnvelope(msg))
+	}
+	return list, nil
+}
+
+// 把消费组的读取位置移到StartTime 之后的事件会重新投递给该订阅者
+// 订阅者需要能接受重复处理 只影响指定的消费组和事件
+func (s *EventBusService) ReplayEventGroup(req *entities.ReplayEventGroupReq) (err error) {
+	defer func() {
+		s.writeEventLog(req.OptionID, req.IP, req, err, "重放领域事件")
+	}()
+	groups, err := s.Repo.GetEventGroups(req.Name)
+	if err != nil {
+		return err
+	}
+	exist := false
+	for _, group := range groups {
+		if group.Name == req.Group {
+			exist = true
+			break
+		}
+	}
+	if !exist {
+		return errors.WithCode(errors.EventGroupNotExist)
+	}
+	return s.Repo.SetEventGroupID(req.Name, req.Group, fmt.Sprintf("%d-0", req.StartTime*1000))
+}
+
+func (s *EventBusService) GetEventDailyStats(req *entities.GetEventDailyStatsReq) ([]*entities.EventDailyStat, error) {
+	if req.Date == "" {
+		req.Date = time.Now().Format("20060102")
+	}
+	values, err := s.Repo.GetEventDailyStats(req.Date)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*entities.EventDailyStat, 0, len(event.Names))
+	for _, name := range event.Names {
+		stat := &entities.EventDailyStat{Name: name}
+		stat.Count, _ = strconv.ParseInt(values[name+":count"], 10, 64)
+		stat.Amount, _ = strconv.ParseFloat(values[name+":amount"], 64)
+		list = append(list, stat)
+	}
+	return list, nil
+}
+
+func (s *EventBusService) writeEventLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_EVENT_REPLAY,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
+
+// 订阅者解析事件内容
+func decodeEvent(evt *entities.EventEnvelope, v interface{}) error {
+	if err := cjson.Cjson.Unmarshal(evt.Payload, v); err != nil {
+		return fmt.Errorf("decode event %s %s: %w", evt.Name, evt.StreamID, err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/event"
+	"rk-api/internal/app/telegram"
+	"time"
+
+	"github.com/google/wire"
+)
+
+// 消费组名 重放和后台查看时使用
+const (
+	eventGroupAgentInvite       = "agent-invite"
+	eventGroupRechargePromotion = "recharge-promotion"
+	eventGroupVip               = "vip"
+	eventGroupNotification      = "notification"
+	eventGroupTelegramAlert     = "telegram-alert"
+	eventGroupStats             = "stats"
+)
+
+var EventSubscriberServiceSet = wire.NewSet(
+	ProvideEventSubscriberService,
+)
+
+// 领域事件的订阅者 每个订阅者一个消费组 互不影响
+// 事件至少投递一次 处理逻辑需要能接受重复
+type EventSubscriberService struct {
+	Bus         *EventBusService
+	AgentSrv    *AgentService
+	RechargeSrv *RechargeService
+	VipSrv      *VipService
+}
+
+func ProvideEventSubscriberService(bus *EventBusService,
+	agentSrv *AgentService,
+	rechargeSrv *RechargeService,
+	vipSrv *VipService,
+) *EventSubscriberService {
+	return &EventSubscriberService{
+		Bus:         bus,
+		AgentSrv:    agentSrv,
+		RechargeSrv: rechargeSrv,
+		VipSrv:      vipSrv,
+	}
+}
+
+func (s *EventSubscriberService) Start() error {
+	// 先写入默认充值优惠 再消费充值事件
+	if err := s.RechargeSrv.InitDefaultRechargePromotions(); err != nil {
+		return err
+	}
+	s.Bus.Subscribe(eventGroupAgentInvite, s.onUserRegistered, event.NameUserRegistered)
+	s.Bus.Subscribe(eventGroupRechargePromotion, s.onDepositPromotion, event.NameDepositSucceeded)
+	s.Bus.Subscribe(eventGroupVip, s.onDepositVip, event.NameDepositSucceeded)
+	s.Bus.Subscribe(eventGroupNotification, s.onNotification,
+		event.NameDepositSucceeded, event.NameWithdrawalPaid, event.NameBonusGranted)
+	s.Bus.Subscribe(eventGroupTelegramAlert, s.onTelegramAlert,
+		event.NameDepositSucceeded, event.NameWithdrawalRequested)
+	s.Bus.Subscribe(eventGroupStats, s.onStats, event.Names...)
+	return s.Bus.Start()
+}
+
+// 建立代理关系 已经有上级的跳过
+func (s *EventSubscriberService) onUserRegistered(evt *entities.EventEnvelope) error {
+	var e event.UserRegistered
+	if err := decodeEvent(evt, &e); err != nil {
+		return err
+	}
+	if e.InviterID == 0 {
+		return nil
+	}
+	parent, err := s.AgentSrv.Repo.GetParentRelation(e.UID)
+	if err != nil {
+		return err
+	}
+	if parent != nil {
+		return nil
+	}
+	return s.AgentSrv.FillInviteRelation(&entities.HallInviteRelation{
+		UID:    e.UID,
+		Mobile: e.Mobile,
+		PID:    e.InviterID,
+		Level:  1,
+	})
+}
+
+// 充值优惠 包括上级的代理充值返利
+func (s *EventSubscriberService) onDepositPromotion(evt *entities.EventEnvelope) error {
+	var e event.DepositSucceeded
+	if err := decodeEvent(evt, &e); err != nil {
+		return err
+	}
+	return s.RechargeSrv.ApplyRechargePromotions(&e)
+}
+
+func (s *EventSubscriberService) onDepositVip(evt *entities.EventEnvelope) error {
+	var e event.DepositSucceeded
+	if err := decodeEvent(evt, &e); err != nil {
+		return err
+	}
+	return s.VipSrv.RecalculateUserVip(e.UID)
+}
+
+func (s *EventSubscriberService) onNotification(evt *entities.EventEnvelope) error {
+	switch evt.Name {
+	case event.NameDepositSucceeded:
+		var e event.DepositSucceeded
+		if err := decodeEvent(evt, &e); err != nil {
+			return err
+		}
+		SendNotification(e.UID, fmt.Sprintf("your deposit of %.2f has been credited to your wallet.", e.Amount), "Deposit Successful")
+	case event.NameWithdrawalPaid:
+		var e event.WithdrawalPaid
+		if err := decodeEvent(evt, &e); err != nil {
+			return err
+		}
+		SendNotification(e.UID, fmt.Sprintf("your withdrawal of %.2f has been paid, please check your account.", e.Amount), "Withdrawal Paid")
+	case event.NameBonusGranted:
+		var e event.BonusGranted
+		if err := decodeEvent(evt, &e); err != nil {
+			return err
+		}
+		SendNotification(e.UID, fmt.Sprintf("you have received a bonus of %.2f.", e.Amount), "Bonus Received")
+	}
+	return nil
+}
+
+// 大额充值和提现申请通知到管理群
+func (s *EventSubscriberService) onTelegramAlert(evt *entities.EventEnvelope) error {
+	switch evt.Name {
+	case event.NameDepositSucceeded:
+		var e event.DepositSucceeded
+		if err := decodeEvent(evt, &e); err != nil {
+			return err
+		}
+		if e.Amount >= constant.EVENT_ALERT_DEPOSIT {
+			telegram.SendToManage(fmt.Sprintf("<b>大额充值</b>\nUID: %d\n订单: %s\n金额: %.2f\n渠道: %s", e.UID, e.OrderID, e.Amount, e.Channel))
+		}
+	case event.NameWithdrawalRequested:
+		var e event.WithdrawalRequested
+		if err := decodeEvent(evt, &e); err != nil {
+			return err
+		}
+		if e.Amount >= constant.EVENT_ALERT_WITHDRAWAL {
+			telegram.SendToManage(fmt.Sprintf("<b>大额提现申请</b>\nUID: %d\n订单: %s\n金额: %.2f", e.UID, e.OrderID, e.Amount))
+		}
+	}
+	return nil
+}
+
+// 按事件发生的日期统计次数和金额 结算统计的是中奖金额
+func (s *EventSubscriberService) onStats(evt *entities.EventEnvelope) error {
+	var amount struct {
+		Amount       float64 `json:"amount"`
+		RewardAmount float64 `json:"reward_amount"`
+	}
+	if err := decodeEvent(evt, &amount); err != nil {
+		return err
+	}
+	value := amount.Amount
+	if evt.Name == event.NameBetSettled {
+		value = amount.RewardAmount
+	}
+	date := time.Unix(evt.Time, 0).Format("20060102")
+	return s.Bus.Repo.IncrEventDailyStats(date, evt.Name, value)
+}
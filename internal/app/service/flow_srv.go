@@ -48,6 +48,7 @@ var TypeRemarks = map[uint16]string{
 	constant.FLOW_TYPE_APPLY_FOR_WITHDRAW_CASH: "apply for withdraw cash",
 	constant.FLOW_TYPE_GM_CASH:                 "gm send",
 	constant.FLOW_TYPE_WITHDRAW_LOCK_CASH:      "withdraw lock cash return",
+	constant.FLOW_TYPE_RECHARGE_PROMOTION:      "recharge bonus",
 
 	// constant.FLOW_TYPE_R8_WITHDRAW:        "Rich88 提款",
 	// constant.FLOW_TYPE_R8_WITHDRAW_POKDEN: "Rich88 POKDEN提走",
@@ -66,6 +67,8 @@ type FlowService struct {
 	UserSrv      *UserService
 	AgentSrv     *AgentService
 	StateSrv     *StateService
+	WalletSrv    *WalletService
+	CashbackSrv  *CashbackService
 	InGameReturn bool
 }
 
@@ -74,12 +77,16 @@ func ProvideFlowService(
 	userSrv *UserService,
 	agentSrv *AgentService,
 	stateSrv *StateService,
+	walletSrv *WalletService,
+	cashbackSrv *CashbackService,
 ) *FlowService {
 	service := &FlowService{
-		Repo:     repo,
-		UserSrv:  userSrv,
-		AgentSrv: agentSrv,
-		StateSrv: stateSrv,
+		Repo:        repo,
+		UserSrv:     userSrv,
+		AgentSrv:    agentSrv,
+		StateSrv:    stateSrv,
+		WalletSrv:   walletSrv,
+		CashbackSrv: cashbackSrv,
 	}
 	return service
 }
@@ -107,6 +114,14 @@ func (s *FlowService) CreateFlow(flow *entities.Flow) (err error) {
 		return
 	}
 
+	if err := s.WalletSrv.RecordGamingUsage(flow); err != nil { //负责任博彩 周期统计
+		logger.ZError("RecordGamingUsage", zap.Uint("uid", flow.UID), zap.Float64("number", flow.Number), zap.Error(err))
+	}
+
+	if err := s.CashbackSrv.Accrue(flow); err != nil { //玩家自返累计
+		logger.ZError("AccrueCashback", zap.Uint("uid", flow.UID), zap.Float64("number", flow.Number), zap.Error(err))
+	}
+
 	if flow.FlowType > 200 { //表示游戏
 		if flow.FlowType < 300 { //内部游戏
 			refundFlow := new(entities.RefundGameFlow)
@@ -1,10 +1,20 @@
 package service
 
 import (
+	"math"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
 	"rk-api/internal/app/utils"
+	"rk-api/pkg/cjson"
 	"rk-api/pkg/logger"
+	"rk-api/pkg/search"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/wire"
@@ -21,18 +31,49 @@ type IFetchOnlineCount interface {
 	FetchOnlineCount() ([]map[string]interface{}, error)
 }
 
+// 三方游戏列表 用于导入/更新游戏目录
+type IFetchGameList interface {
+	FetchGameList() ([]*entities.Game, error)
+}
+
+const (
+	gameLobbyRankDays     = 7   // 大厅排序统计近几天的投注
+	gameLobbyTurnoverRate = 0.6 // 投注额权重 其余为投注人数权重
+
+	gameSearchCheckInterval = 30 * time.Second // 检查目录版本号的间隔 其他实例修改目录后据此重建索引
+
+	favoriteGameMax = 100 // 每个用户最多收藏的游戏数
+)
+
+// 游戏搜索的分面字段
+var gameSearchFacets = []string{"category", "provider", "tag"}
+
+// 搜索索引和对应的游戏快照 整体替换
+type gameSearchIndex struct {
+	index   *search.Index
+	games   map[uint]*entities.Game
+	version int64
+}
+
 var GameServiceSet = wire.NewSet(
 	ProvideGameService,
 )
 
 type GameService struct {
-	Repo     *repository.GameRepository
-	adapters map[string]IFetchOnlineCount
-	UserSrv  *UserService
+	Repo         *repository.GameRepository
+	adapters     map[string]IFetchOnlineCount
+	listAdapters map[string]IFetchGameList
+	UserSrv      *UserService
 
 	GameCategoryCache *ecache.Cache // 新建的缓存，用来存储 gameCode 和 category
 
 	cacheTTL time.Duration
+
+	searchMu       sync.RWMutex
+	searchIndex    *gameSearchIndex
+	searchBuildMu  sync.Mutex
+	searchCheckAt  atomic.Int64
+	searchChecking atomic.Bool
 }
 
 // ProvideGameService 创建 GameService 实例
@@ -43,8 +84,12 @@ func ProvideGameService(
 ) *GameService {
 	adapters := make(map[string]IFetchOnlineCount)
 	adapters["jhsz"] = JhszSrv
+	listAdapters := make(map[string]IFetchGameList)
+	listAdapters["jhsz"] = JhszSrv
 	service := &GameService{
 		Repo:              repo,
+		adapters:          adapters,
+		listAdapters:      listAdapters,
 		UserSrv:           userSrv,
 		GameCategoryCache: ecache.NewLRUCache(3, 20, 60*time.Minute), // 初始化新缓存
 	}
@@ -105,11 +150,199 @@ func (s *GameService) GetGameIdentification(gameCode string) *entities.GameIdent
 }
 
 func (s *GameService) GetGameList(req *entities.GetGameListReq) error {
-	return s.Repo.GetGameList(req)
+	if err := s.Repo.GetGameList(req); err != nil {
+		return err
+	}
+	if list, ok := req.List.([]*entities.Game); ok {
+		applyGameMaintenance(list)
+	}
+	return nil
 }
 
+// 优先走本地搜索索引 索引不可用时退回数据库模糊查询
 func (s *GameService) SearchGame(req *entities.SearchGameReq) error {
-	return s.Repo.SearchGameList(req)
+	idx := s.getGameSearchIndex()
+	if idx == nil {
+		if err := s.Repo.SearchGameList(req); err != nil {
+			return err
+		}
+		if list, ok := req.List.([]*entities.Game); ok {
+			applyGameMaintenance(list)
+		}
+		return nil
+	}
+
+	filters := make(map[string][]string)
+	if req.Category != "" {
+		filters["category"] = []string{req.Category}
+	}
+	if req.Provider != "" {
+		filters["provider"] = []string{req.Provider}
+	}
+	if req.Tag != "" {
+		filters["tag"] = []string{req.Tag}
+	}
+	if req.Region != "" {
+		filters["region"] = []string{req.Region, gameSearchAll}
+	}
+	if req.Language != "" {
+		filters["language"] = []string{req.Language, gameSearchAll}
+	}
+	query := search.Query{
+		Text:    req.Name,
+		Filters: filters,
+		Facets:  gameSearchFacets,
+		Limit:   req.PageSize,
+	}
+	if req.PageSize > 0 {
+		if req.Page <= 0 {
+			req.Page = 1
+		}
+		query.Offset = (req.Page - 1) * req.PageSize
+	}
+	result := idx.index.Search(query)
+
+	list := make([]*entities.Game, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		if game, ok := idx.games[hit.ID]; ok {
+			item := *game //快照只读 复制一份再修改状态
+			list = append(list, &item)
+		}
+	}
+	applyGameMaintenance(list)
+	req.Count = int64(result.Total)
+	req.List = list
+	req.Facets = result.Facets
+	return nil
+}
+
+const gameSearchAll = "*" // 地区/语言为空时表示全部可见
+
+func (s *GameService) getGameSearchIndex() *gameSearchIndex {
+	s.searchMu.RLock()
+	idx := s.searchIndex
+	s.searchMu.RUnlock()
+	if idx == nil {
+		if err := s.RebuildGameSearchIndex(); err != nil {
+			logger.ZError("RebuildGameSearchIndex", zap.Error(err))
+			return nil
+		}
+		s.searchMu.RLock()
+		idx = s.searchIndex
+		s.searchMu.RUnlock()
+		return idx
+	}
+
+	// 定期比对目录版本号 有变动时后台重建 本次仍使用旧索引
+	now := time.Now().Unix()
+	if now-s.searchCheckAt.Load() >= int64(gameSearchCheckInterval/time.Second) && s.searchChecking.CompareAndSwap(false, true) {
+		s.searchCheckAt.Store(now)
+		go func() {
+			defer utils.PrintPanicStack()
+			defer s.searchChecking.Store(false)
+			version, err := s.Repo.GetGameCatalogVersion()
+			if err != nil {
+				logger.ZError("GetGameCatalogVersion", zap.Error(err))
+				return
+			}
+			if version != idx.version {
+				if err := s.RebuildGameSearchIndex(); err != nil {
+					logger.ZError("RebuildGameSearchIndex", zap.Error(err))
+				}
+			}
+		}()
+	}
+	return idx
+}
+
+// 重新加载可见游戏并构建搜索索引
+func (s *GameService) RebuildGameSearchIndex() error {
+	s.searchBuildMu.Lock()
+	defer s.searchBuildMu.Unlock()
+
+	// 先取版本号再取数据 构建期间的变动会在下次检查时再重建
+	version, err := s.Repo.GetGameCatalogVersion()
+	if err != nil {
+		return err
+	}
+	games, err := s.Repo.GetVisibleGameList()
+	if err != nil {
+		return err
+	}
+
+	docs := make([]*search.Document, 0, len(games))
+	gameMap := make(map[uint]*entities.Game, len(games))
+	for _, game := range games {
+		docs = append(docs, gameSearchDocument(game))
+		gameMap[game.ID] = game
+	}
+	idx := &gameSearchIndex{
+		index:   search.New(docs),
+		games:   gameMap,
+		version: version,
+	}
+
+	s.searchMu.Lock()
+	s.searchIndex = idx
+	s.searchMu.Unlock()
+	s.searchCheckAt.Store(time.Now().Unix())
+	logger.ZInfo("RebuildGameSearchIndex", zap.Int("games", len(games)), zap.Int64("version", version))
+	return nil
+}
+
+// 名称/多语言名称权重最高 其次是游戏编码 三方/分类/标签也参与检索
+func gameSearchDocument(game *entities.Game) *search.Document {
+	fields := []search.Field{
+		{Text: game.Name, Boost: 3},
+		{Text: game.GameCode, Boost: 2},
+		{Text: game.Provider, Boost: 1.5},
+		{Text: game.Category, Boost: 1.5},
+		{Text: game.GameplayType},
+		{Text: strings.ReplaceAll(game.Tags, ",", " ")},
+	}
+	if game.LocalizedNames != "" {
+		names := make(map[string]string)
+		if err := cjson.Parse(game.LocalizedNames, &names); err == nil {
+			for _, name := range names {
+				fields = append(fields, search.Field{Text: name, Boost: 3})
+			}
+		}
+	}
+	return &search.Document{
+		ID:     game.ID,
+		Fields: fields,
+		Facets: map[string][]string{
+			"category": {game.Category},
+			"provider": {game.Provider},
+			"tag":      splitGameList(game.Tags, ""),
+			"region":   splitGameList(game.Regions, gameSearchAll),
+			"language": splitGameList(game.Languages, gameSearchAll),
+		},
+	}
+}
+
+// 逗号分隔的列表 为空时返回默认值
+func splitGameList(value string, empty string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 && empty != "" {
+		list = append(list, empty)
+	}
+	return list
+}
+
+// 处于计划维护时间内的游戏 返回维护状态
+func applyGameMaintenance(list []*entities.Game) {
+	now := time.Now().Unix()
+	for _, game := range list {
+		if game.MaintainEnd > 0 && game.MaintainStart <= now && now < game.MaintainEnd {
+			game.ServiceStatus = constant.ServiceStatusMaintenance
+		}
+	}
 }
 
 func (s *GameService) GetRefreshGameList() ([]*entities.GameRefresh, error) {
@@ -161,3 +394,426 @@ func (s *GameService) SyncOnlineCount(source string, adapter IFetchOnlineCount)
 
 	return nil
 }
+
+// 三方游戏列表同步 新游戏默认下架 需要后台配置后上架
+func (s *GameService) SyncProviderGameList(req *entities.SyncProviderGameListReq) (err error) {
+	defer func() {
+		s.writeCatalogLog(req.OptionID, req.IP, req, err, "同步三方游戏列表")
+	}()
+
+	if req.Provider != "" {
+		adapter, ok := s.listAdapters[req.Provider]
+		if !ok {
+			return errors.WithCode(errors.GameProviderNotExist)
+		}
+		return s.syncProviderGameList(req.Provider, adapter)
+	}
+	for provider, adapter := range s.listAdapters {
+		if err = s.syncProviderGameList(provider, adapter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 定时同步全部三方游戏列表
+func (s *GameService) SyncAllProviderGameList() {
+	defer utils.PrintPanicStack()
+	for provider, adapter := range s.listAdapters {
+		if err := s.syncProviderGameList(provider, adapter); err != nil {
+			logger.ZError("SyncAllProviderGameList", zap.String("provider", provider), zap.Error(err))
+		}
+	}
+}
+
+func (s *GameService) syncProviderGameList(provider string, adapter IFetchGameList) error {
+	list, err := adapter.FetchGameList()
+	if err != nil {
+		return err
+	}
+	return s.saveProviderGameList(provider, list)
+}
+
+// 按provider+game_code合并 已存在的只更新三方给出的非空字段
+func (s *GameService) saveProviderGameList(provider string, list []*entities.Game) error {
+	existing, err := s.Repo.GetGameListByProvider(provider)
+	if err != nil {
+		return err
+	}
+	existingMap := make(map[string]*entities.Game, len(existing))
+	for _, game := range existing {
+		existingMap[game.GameCode] = game
+	}
+
+	createList := make([]*entities.Game, 0)
+	updateList := make([]*entities.Game, 0)
+	for _, item := range list {
+		if item.GameCode == "" {
+			continue
+		}
+		game, ok := existingMap[item.GameCode]
+		if !ok {
+			newGame := *item
+			newGame.ID = 0
+			newGame.Provider = provider
+			newGame.IsActive = false
+			newGame.IsDeleted = false
+			newGame.ServiceStatus = constant.ServiceStatusNormal
+			if newGame.Name == "" {
+				newGame.Name = item.GameCode
+			}
+			if newGame.Category == "" {
+				newGame.Category = constant.GameCategoryExternal
+			}
+			createList = append(createList, &newGame)
+			existingMap[item.GameCode] = &newGame
+			continue
+		}
+		mergeProviderGame(game, item)
+		updateList = append(updateList, game)
+	}
+
+	if err := s.Repo.SaveGameList(createList, updateList); err != nil {
+		return err
+	}
+	logger.ZInfo("saveProviderGameList", zap.String("provider", provider),
+		zap.Int("create", len(createList)), zap.Int("update", len(updateList)))
+	s.onCatalogChanged()
+	return nil
+}
+
+func mergeProviderGame(game, item *entities.Game) {
+	if item.Name != "" {
+		game.Name = item.Name
+	}
+	if item.Category != "" {
+		game.Category = item.Category
+	}
+	if item.IconURL != "" {
+		game.IconURL = item.IconURL
+	}
+	if item.CompanyLogoURL != "" {
+		game.CompanyLogoURL = item.CompanyLogoURL
+	}
+	if item.GameplayType != "" {
+		game.GameplayType = item.GameplayType
+	}
+	if item.Description != "" {
+		game.Description = item.Description
+	}
+	if item.Language != "" {
+		game.Language = item.Language
+	}
+}
+
+// 后台批量导入游戏列表
+func (s *GameService) ImportGameList(req *entities.ImportGameListReq) (err error) {
+	defer func() {
+		s.writeCatalogLog(req.OptionID, req.IP, req, err, "导入游戏列表")
+	}()
+	return s.saveProviderGameList(req.Provider, req.List)
+}
+
+func (s *GameService) AdminGetGameList(req *entities.AdminGetGameListReq) error {
+	return s.Repo.AdminGetGameList(req)
+}
+
+func (s *GameService) SaveGame(req *entities.SaveGameReq) (err error) {
+	defer func() {
+		s.writeCatalogLog(req.OptionID, req.IP, req, err, "保存游戏")
+	}()
+
+	if req.MaintainEnd > 0 && req.MaintainEnd <= req.MaintainStart {
+		return errors.With("maintain end must be after start")
+	}
+	if req.LocalizedNames != "" {
+		names := make(map[string]string)
+		if err = cjson.Parse(req.LocalizedNames, &names); err != nil {
+			return errors.With("localized names must be a json object")
+		}
+	}
+	game := &entities.Game{
+		Name:           req.Name,
+		Category:       req.Category,
+		GameCode:       req.GameCode,
+		Provider:       req.Provider,
+		IconURL:        req.IconURL,
+		CompanyLogoURL: req.CompanyLogoURL,
+		GameplayType:   req.GameplayType,
+		Description:    req.Description,
+		IsActive:       req.IsActive,
+		ServiceStatus:  req.ServiceStatus,
+		Language:       req.Language,
+		Priority:       req.Priority,
+		Tags:           req.Tags,
+		Regions:        req.Regions,
+		Languages:      req.Languages,
+		MaintainStart:  req.MaintainStart,
+		MaintainEnd:    req.MaintainEnd,
+		LocalizedNames: req.LocalizedNames,
+	}
+	if req.ID == 0 {
+		err = s.Repo.CreateGame(game)
+	} else {
+		var old *entities.Game
+		if old, err = s.Repo.GetGameByID(req.ID); err != nil {
+			return err
+		}
+		if old == nil {
+			return errors.WithCode(errors.GameNotExist)
+		}
+		game.ID = req.ID
+		err = s.Repo.UpdateGame(game)
+	}
+	if err != nil {
+		return err
+	}
+	s.onCatalogChanged()
+	return nil
+}
+
+func (s *GameService) DelGame(req *entities.DelGameReq) (err error) {
+	defer func() {
+		s.writeCatalogLog(req.OptionID, req.IP, req, err, "删除游戏")
+	}()
+
+	game, err := s.Repo.GetGameByID(req.ID)
+	if err != nil {
+		return err
+	}
+	if game == nil {
+		return errors.WithCode(errors.GameNotExist)
+	}
+	if err = s.Repo.DelGame(req.ID); err != nil {
+		return err
+	}
+	s.onCatalogChanged()
+	return nil
+}
+
+// 合集列表 每个合集带上当前地区/语言可见的游戏
+func (s *GameService) GetGameCollectionList(req *entities.GetGameCollectionListReq) ([]*entities.GameCollection, error) {
+	list, err := s.Repo.GetGameCollectionList(true)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+	ids := make([]uint, 0, len(list))
+	for _, collection := range list {
+		ids = append(ids, collection.ID)
+	}
+	gameMap, err := s.Repo.GetCollectionGameList(ids, req.Region, req.Language)
+	if err != nil {
+		return nil, err
+	}
+	for _, collection := range list {
+		collection.Games = gameMap[collection.ID]
+		if collection.Games == nil {
+			collection.Games = make([]*entities.Game, 0)
+		}
+		applyGameMaintenance(collection.Games)
+	}
+	return list, nil
+}
+
+func (s *GameService) AdminGetGameCollectionList() ([]*entities.GameCollection, error) {
+	return s.Repo.GetGameCollectionList(false)
+}
+
+func (s *GameService) SaveGameCollection(req *entities.SaveGameCollectionReq) (err error) {
+	defer func() {
+		s.writeCatalogLog(req.OptionID, req.IP, req, err, "保存游戏合集")
+	}()
+
+	collection := &entities.GameCollection{
+		Code:   req.Code,
+		Name:   req.Name,
+		Sort:   req.Sort,
+		Status: req.Status,
+	}
+	if req.ID == 0 {
+		return s.Repo.CreateGameCollection(collection)
+	}
+	old, err := s.Repo.GetGameCollectionByID(req.ID)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return errors.WithCode(errors.GameCollectionNotExist)
+	}
+	collection.ID = req.ID
+	return s.Repo.UpdateGameCollection(collection)
+}
+
+func (s *GameService) DelGameCollection(req *entities.DelGameCollectionReq) (err error) {
+	defer func() {
+		s.writeCatalogLog(req.OptionID, req.IP, req, err, "删除游戏合集")
+	}()
+	return s.Repo.DelGameCollection(req.ID)
+}
+
+func (s *GameService) SetGameCollectionItems(req *entities.SetGameCollectionItemsReq) (err error) {
+	defer func() {
+		s.writeCatalogLog(req.OptionID, req.IP, req, err, "设置合集游戏")
+	}()
+
+	collection, err := s.Repo.GetGameCollectionByID(req.CollectionID)
+	if err != nil {
+		return err
+	}
+	if collection == nil {
+		return errors.WithCode(errors.GameCollectionNotExist)
+	}
+	items := make([]*entities.GameCollectionItem, 0, len(req.GameIDs))
+	seen := make(map[uint]bool, len(req.GameIDs))
+	for i, gameID := range req.GameIDs {
+		if seen[gameID] {
+			continue
+		}
+		seen[gameID] = true
+		items = append(items, &entities.GameCollectionItem{
+			CollectionID: req.CollectionID,
+			GameID:       gameID,
+			Sort:         len(req.GameIDs) - i, //数组越靠前排序越大
+		})
+	}
+	return s.Repo.SetGameCollectionItems(req.CollectionID, items)
+}
+
+// 根据近期真实投注计算大厅排序分
+// 投注额和投注人数分别取对数后按最大值归一 避免个别大额玩家拉高排序
+func (s *GameService) RefreshGameLobbyRank() error {
+	defer utils.PrintPanicStack()
+	games, err := s.Repo.GetWholeGameList()
+	if err != nil {
+		return err
+	}
+	stats, err := s.Repo.GetGamePlayStats(time.Now().AddDate(0, 0, -gameLobbyRankDays))
+	if err != nil {
+		return err
+	}
+
+	// 游戏记录里可能是游戏名也可能是游戏编码
+	statsMap := make(map[string]*entities.GamePlayStats, len(stats))
+	var maxTurnover float64
+	var maxPlayers int
+	for _, item := range stats {
+		statsMap[item.Game] = item
+		if item.Turnover > maxTurnover {
+			maxTurnover = item.Turnover
+		}
+		if item.Players > maxPlayers {
+			maxPlayers = item.Players
+		}
+	}
+
+	changed := 0
+	for _, game := range games {
+		if game.IsDeleted {
+			continue
+		}
+		item, ok := statsMap[game.GameCode]
+		if !ok {
+			item, ok = statsMap[game.Name]
+		}
+		var turnover float64
+		var players int
+		if ok {
+			turnover, players = item.Turnover, item.Players
+		}
+		score := gameLobbyScore(turnover, players, maxTurnover, maxPlayers)
+		if score == game.LobbyScore && turnover == game.RecentTurnover && players == game.RecentPlayers {
+			continue
+		}
+		if err := s.Repo.UpdateGameLobbyScore(game.ID, turnover, players, score); err != nil {
+			logger.ZError("UpdateGameLobbyScore", zap.Uint("id", game.ID), zap.Error(err))
+			continue
+		}
+		changed++
+	}
+	// 排序分变化后搜索结果的默认顺序也要更新
+	if changed > 0 {
+		s.onCatalogChanged()
+	}
+	return nil
+}
+
+func gameLobbyScore(turnover float64, players int, maxTurnover float64, maxPlayers int) float64 {
+	var score float64
+	if maxTurnover > 0 && turnover > 0 {
+		score += gameLobbyTurnoverRate * math.Log1p(turnover) / math.Log1p(maxTurnover)
+	}
+	if maxPlayers > 0 && players > 0 {
+		score += (1 - gameLobbyTurnoverRate) * math.Log1p(float64(players)) / math.Log1p(float64(maxPlayers))
+	}
+	return math.Round(score*10000) / 100
+}
+
+// 游戏目录变动后刷新缓存 递增版本号通知其他实例 并重建本实例的搜索索引
+func (s *GameService) onCatalogChanged() {
+	if err := s.CacheGameIdentification(); err != nil {
+		logger.ZError("CacheGameIdentification", zap.Error(err))
+	}
+	if _, err := s.Repo.IncrGameCatalogVersion(); err != nil {
+		logger.ZError("IncrGameCatalogVersion", zap.Error(err))
+	}
+	go func() {
+		defer utils.PrintPanicStack()
+		if err := s.RebuildGameSearchIndex(); err != nil {
+			logger.ZError("RebuildGameSearchIndex", zap.Error(err))
+		}
+	}()
+}
+
+func (s *GameService) writeCatalogLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_GAME_CATALOG,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
+
+func (s *GameService) AddFavoriteGame(req *entities.FavoriteGameReq) error {
+	game, err := s.Repo.GetGameByID(req.GameID)
+	if err != nil {
+		return err
+	}
+	if game == nil || !game.IsActive {
+		return errors.WithCode(errors.GameNotExist)
+	}
+	count, err := s.Repo.CountUserFavoriteGame(req.UID)
+	if err != nil {
+		return err
+	}
+	if count >= favoriteGameMax {
+		return errors.WithCode(errors.FavoriteGameLimit)
+	}
+	return s.Repo.CreateUserFavoriteGame(&entities.UserFavoriteGame{UID: req.UID, GameID: req.GameID})
+}
+
+func (s *GameService) DelFavoriteGame(req *entities.FavoriteGameReq) error {
+	return s.Repo.DelUserFavoriteGame(req.UID, req.GameID)
+}
+
+func (s *GameService) GetFavoriteGameList(uid uint) ([]*entities.Game, error) {
+	list, err := s.Repo.GetUserFavoriteGameList(uid)
+	if err != nil {
+		return nil, err
+	}
+	applyGameMaintenance(list)
+	return list, nil
+}
@@ -0,0 +1,472 @@
+package service
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+)
+
+const (
+	gamingLimitCacheExpire   = 10 * time.Minute
+	gamingLimitIncreaseDelay = 24 * time.Hour // 提高/取消限额需要等待的时间
+	gamingExcludeForever     = 253402271999   // 永久排除 9999-12-31
+	gamingSessionReminderMax = 24 * 60
+)
+
+var (
+	gamingLimitTypes = map[string]int{
+		constant.GAMING_LIMIT_TYPE_DEPOSIT: errors.GamingDepositLimit,
+		constant.GAMING_LIMIT_TYPE_LOSS:    errors.GamingLossLimit,
+		constant.GAMING_LIMIT_TYPE_WAGER:   errors.GamingWagerLimit,
+	}
+	gamingLimitPeriods = []string{
+		constant.GAMING_LIMIT_PERIOD_DAY,
+		constant.GAMING_LIMIT_PERIOD_WEEK,
+		constant.GAMING_LIMIT_PERIOD_MONTH,
+	}
+	gamingCoolOffDays       = map[int]bool{1: true, 7: true, 30: true}
+	gamingSelfExcludeMonths = map[int]bool{0: true, 6: true, 12: true, 60: true}
+)
+
+var GamingServiceSet = wire.NewSet(
+	ProvideGamingService,
+)
+
+// 负责任博彩 充值/输钱/投注限额 冷静期 自我排除 会话时长提醒
+// 下注和充值前由WalletService统一检查 周期统计由流水更新
+type GamingService struct {
+	Repo        *repository.GamingRepository
+	SessionRepo *repository.SessionRepository
+}
+
+func ProvideGamingService(repo *repository.GamingRepository, sessionRepo *repository.SessionRepository) *GamingService {
+	return &GamingService{
+		Repo:        repo,
+		SessionRepo: sessionRepo,
+	}
+}
+
+type gamingLimitData struct {
+	Limits      []*entities.GamingLimit     `json:"limits"`
+	Restriction *entities.GamingRestriction `json:"restriction"`
+}
+
+// 读取用户的限额和限制 到期的待生效修改在这里应用
+func (s *GamingService) getGamingLimitData(uid uint) (*gamingLimitData, error) {
+	if cache, err := s.Repo.GetGamingLimitCache(uid); err == nil && cache != "" {
+		data := new(gamingLimitData)
+		if err := cjson.Parse(cache, data); err == nil {
+			return data, nil
+		}
+	}
+
+	limits, err := s.Repo.GetGamingLimitList(uid)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	expire := gamingLimitCacheExpire
+	for _, limit := range limits {
+		if limit.PendingTime == 0 {
+			continue
+		}
+		if limit.PendingTime <= now {
+			limit.Amount = limit.PendingAmount
+			limit.PendingAmount = 0
+			limit.PendingTime = 0
+			if err := s.Repo.SaveGamingLimit(limit); err != nil {
+				return nil, err
+			}
+		} else if d := time.Duration(limit.PendingTime-now) * time.Second; d < expire {
+			expire = d //缓存不能跨过生效时间
+		}
+	}
+	restriction, err := s.Repo.GetGamingRestriction(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &gamingLimitData{Limits: limits, Restriction: restriction}
+	if str, err := cjson.Stringify(data); err == nil {
+		if err := s.Repo.SetGamingLimitCache(uid, str, expire); err != nil {
+			logger.ZError("SetGamingLimitCache", zap.Uint("uid", uid), zap.Error(err))
+		}
+	}
+	return data, nil
+}
+
+func (s *GamingService) clearGamingLimitCache(uid uint) {
+	if err := s.Repo.DelGamingLimitCache(uid); err != nil {
+		logger.ZError("DelGamingLimitCache", zap.Uint("uid", uid), zap.Error(err))
+	}
+}
+
+func checkGamingRestriction(restriction *entities.GamingRestriction, now int64) error {
+	if restriction == nil {
+		return nil
+	}
+	if restriction.SelfExcludeUntil > now {
+		return errors.WithCode(errors.GamingSelfExcluded)
+	}
+	if restriction.CoolOffUntil > now {
+		return errors.WithCode(errors.GamingCoolOff)
+	}
+	return nil
+}
+
+// 周期编号和统计key的过期时间
+func gamingPeriodBucket(period string, now time.Time) (string, time.Duration) {
+	switch period {
+	case constant.GAMING_LIMIT_PERIOD_WEEK:
+		year, week := now.ISOWeek()
+		return fmt.Sprintf("%dW%02d", year, week), 8 * 24 * time.Hour
+	case constant.GAMING_LIMIT_PERIOD_MONTH:
+		return now.Format("200601"), 32 * 24 * time.Hour
+	default:
+		return now.Format("20060102"), 2 * 24 * time.Hour
+	}
+}
+
+// 同一类型同一周期取用户和运营设置中较小的额度
+func effectiveGamingLimits(limits []*entities.GamingLimit, limitType string) map[string]float64 {
+	result := make(map[string]float64)
+	for _, limit := range limits {
+		if limit.Type != limitType || limit.Amount <= 0 {
+			continue
+		}
+		if amount, ok := result[limit.Period]; !ok || limit.Amount < amount {
+			result[limit.Period] = limit.Amount
+		}
+	}
+	return result
+}
+
+// 本次金额加上周期内已使用的不能超过限额
+func (s *GamingService) checkGamingLimits(uid uint, limits []*entities.GamingLimit, amount float64, limitTypes ...string) error {
+	now := time.Now()
+	keys := make([]repository.GamingUsageKey, 0)
+	amounts := make([]float64, 0)
+	for _, limitType := range limitTypes {
+		for period, limit := range effectiveGamingLimits(limits, limitType) {
+			bucket, _ := gamingPeriodBucket(period, now)
+			keys = append(keys, repository.GamingUsageKey{Type: limitType, Bucket: bucket})
+			amounts = append(amounts, limit)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	usage, err := s.Repo.GetGamingUsage(uid, keys)
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		if usage[i]+amount > amounts[i] {
+			return errors.WithCode(gamingLimitTypes[key.Type])
+		}
+	}
+	return nil
+}
+
+// 下注前检查 输钱限额按本次全输计算
+func (s *GamingService) CheckBet(uid uint, amount float64) error {
+	data, err := s.getGamingLimitData(uid)
+	if err != nil {
+		return err
+	}
+	if err := checkGamingRestriction(data.Restriction, time.Now().Unix()); err != nil {
+		return err
+	}
+	return s.checkGamingLimits(uid, data.Limits, amount, constant.GAMING_LIMIT_TYPE_WAGER, constant.GAMING_LIMIT_TYPE_LOSS)
+}
+
+// 充值前检查
+func (s *GamingService) CheckDeposit(uid uint, amount float64) error {
+	data, err := s.getGamingLimitData(uid)
+	if err != nil {
+		return err
+	}
+	if err := checkGamingRestriction(data.Restriction, time.Now().Unix()); err != nil {
+		return err
+	}
+	return s.checkGamingLimits(uid, data.Limits, amount, constant.GAMING_LIMIT_TYPE_DEPOSIT)
+}
+
+// 按流水更新周期统计 充值计入充值额 游戏扣款计入投注和输钱 派彩冲减输钱 退款同时冲减投注
+func (s *GamingService) RecordUsage(flow *entities.Flow) error {
+	deltas := make(map[string]float64)
+	switch {
+	case flow.FlowType == constant.FLOW_TYPE_RECHARGE_CASH && flow.Number > 0:
+		deltas[constant.GAMING_LIMIT_TYPE_DEPOSIT] = flow.Number
+	case flow.FlowType > 200 && flow.Number < 0:
+		deltas[constant.GAMING_LIMIT_TYPE_WAGER] = -flow.Number
+		deltas[constant.GAMING_LIMIT_TYPE_LOSS] = -flow.Number
+	case flow.FlowType > 200 && flow.Number > 0:
+		deltas[constant.GAMING_LIMIT_TYPE_LOSS] = -flow.Number
+		if withdrawRiskRefundFlowTypes[flow.FlowType] {
+			deltas[constant.GAMING_LIMIT_TYPE_WAGER] = -flow.Number
+		}
+	default:
+		return nil
+	}
+
+	now := time.Now()
+	items := make([]repository.GamingUsageIncr, 0, len(deltas)*len(gamingLimitPeriods))
+	for limitType, delta := range deltas {
+		for _, period := range gamingLimitPeriods {
+			bucket, expire := gamingPeriodBucket(period, now)
+			items = append(items, repository.GamingUsageIncr{
+				GamingUsageKey: repository.GamingUsageKey{Type: limitType, Bucket: bucket},
+				Amount:         delta,
+				Expire:         expire,
+			})
+		}
+	}
+	return s.Repo.IncrGamingUsage(flow.UID, items)
+}
+
+func validateGamingLimit(limitType, period string, amount float64) error {
+	if _, ok := gamingLimitTypes[limitType]; !ok || amount < 0 {
+		return errors.WithCode(errors.GamingInvalidSetting)
+	}
+	for _, item := range gamingLimitPeriods {
+		if item == period {
+			return nil
+		}
+	}
+	return errors.WithCode(errors.GamingInvalidSetting)
+}
+
+// 用户设置限额 降低立即生效 提高或取消要等待一段时间 期间再改回原额度则撤销
+func (s *GamingService) SetGamingLimit(req *entities.SetGamingLimitReq) error {
+	if err := validateGamingLimit(req.Type, req.Period, req.Amount); err != nil {
+		return err
+	}
+	limit, err := s.Repo.GetGamingLimit(req.UID, req.Type, req.Period, constant.GAMING_LIMIT_SOURCE_USER)
+	if err != nil {
+		return err
+	}
+	if limit == nil {
+		if req.Amount == 0 {
+			return nil
+		}
+		limit = &entities.GamingLimit{
+			UID:    req.UID,
+			Type:   req.Type,
+			Period: req.Period,
+			Source: constant.GAMING_LIMIT_SOURCE_USER,
+		}
+	}
+
+	switch {
+	case req.Amount == limit.Amount:
+		limit.PendingAmount = 0
+		limit.PendingTime = 0
+	case req.Amount > 0 && (limit.Amount == 0 || req.Amount < limit.Amount):
+		limit.Amount = req.Amount
+		limit.PendingAmount = 0
+		limit.PendingTime = 0
+	case limit.PendingTime == 0 || limit.PendingAmount != req.Amount:
+		limit.PendingAmount = req.Amount
+		limit.PendingTime = time.Now().Add(gamingLimitIncreaseDelay).Unix()
+	}
+	if err := s.Repo.SaveGamingLimit(limit); err != nil {
+		return err
+	}
+	s.clearGamingLimitCache(req.UID)
+	return nil
+}
+
+// 运营设置限额 立即生效
+func (s *GamingService) AdminSetGamingLimit(req *entities.AdminSetGamingLimitReq) (err error) {
+	defer func() {
+		s.writeGamingLog(req.OptionID, req.IP, req, err, "设置用户限额")
+	}()
+	if err = validateGamingLimit(req.Type, req.Period, req.Amount); err != nil {
+		return
+	}
+	limit, err := s.Repo.GetGamingLimit(req.UID, req.Type, req.Period, constant.GAMING_LIMIT_SOURCE_OPERATOR)
+	if err != nil {
+		return
+	}
+	if limit == nil {
+		limit = &entities.GamingLimit{
+			UID:    req.UID,
+			Type:   req.Type,
+			Period: req.Period,
+			Source: constant.GAMING_LIMIT_SOURCE_OPERATOR,
+		}
+	}
+	limit.Amount = req.Amount
+	limit.PendingAmount = 0
+	limit.PendingTime = 0
+	limit.Remark = req.Remark
+	if err = s.Repo.SaveGamingLimit(limit); err != nil {
+		return
+	}
+	s.clearGamingLimitCache(req.UID)
+	return
+}
+
+func (s *GamingService) getOrNewRestriction(uid uint) (*entities.GamingRestriction, error) {
+	restriction, err := s.Repo.GetGamingRestriction(uid)
+	if err != nil {
+		return nil, err
+	}
+	if restriction == nil {
+		restriction = &entities.GamingRestriction{UID: uid}
+	}
+	return restriction, nil
+}
+
+func (s *GamingService) saveRestriction(restriction *entities.GamingRestriction) error {
+	if err := s.Repo.SaveGamingRestriction(restriction); err != nil {
+		return err
+	}
+	s.clearGamingLimitCache(restriction.UID)
+	return nil
+}
+
+func (s *GamingService) SetSessionReminder(req *entities.SetSessionReminderReq) error {
+	if req.Minutes < 0 || req.Minutes > gamingSessionReminderMax {
+		return errors.WithCode(errors.GamingInvalidSetting)
+	}
+	restriction, err := s.getOrNewRestriction(req.UID)
+	if err != nil {
+		return err
+	}
+	restriction.SessionReminder = req.Minutes
+	return s.saveRestriction(restriction)
+}
+
+// 冷静期 用户自己只能延长不能缩短
+func (s *GamingService) CoolOff(req *entities.GamingCoolOffReq) error {
+	if !gamingCoolOffDays[req.Days] {
+		return errors.WithCode(errors.GamingInvalidSetting)
+	}
+	restriction, err := s.getOrNewRestriction(req.UID)
+	if err != nil {
+		return err
+	}
+	until := time.Now().AddDate(0, 0, req.Days).Unix()
+	if until > restriction.CoolOffUntil {
+		restriction.CoolOffUntil = until
+	}
+	return s.saveRestriction(restriction)
+}
+
+// 自我排除 用户自己只能延长不能缩短 提前解除需要运营处理
+func (s *GamingService) SelfExclude(req *entities.GamingSelfExcludeReq) error {
+	if !gamingSelfExcludeMonths[req.Months] {
+		return errors.WithCode(errors.GamingInvalidSetting)
+	}
+	restriction, err := s.getOrNewRestriction(req.UID)
+	if err != nil {
+		return err
+	}
+	var until int64 = gamingExcludeForever
+	if req.Months > 0 {
+		until = time.Now().AddDate(0, req.Months, 0).Unix()
+	}
+	if until > restriction.SelfExcludeUntil {
+		restriction.SelfExcludeUntil = until
+		restriction.ExcludeSource = constant.GAMING_LIMIT_SOURCE_USER
+	}
+	return s.saveRestriction(restriction)
+}
+
+// 运营设置冷静期/自我排除 传0表示解除
+func (s *GamingService) AdminSetGamingRestriction(req *entities.AdminSetGamingRestrictionReq) (err error) {
+	defer func() {
+		s.writeGamingLog(req.OptionID, req.IP, req, err, "设置用户冷静期/自我排除")
+	}()
+	restriction, err := s.getOrNewRestriction(req.UID)
+	if err != nil {
+		return
+	}
+	if req.SelfExcludeUntil != restriction.SelfExcludeUntil {
+		restriction.ExcludeSource = ""
+		if req.SelfExcludeUntil > 0 {
+			restriction.ExcludeSource = constant.GAMING_LIMIT_SOURCE_OPERATOR
+		}
+	}
+	restriction.CoolOffUntil = req.CoolOffUntil
+	restriction.SelfExcludeUntil = req.SelfExcludeUntil
+	restriction.Remark = req.Remark
+	err = s.saveRestriction(restriction)
+	return
+}
+
+// 当前限额及周期内已使用 当前会话时长和下次提醒时间
+func (s *GamingService) GetGamingStatus(req *entities.GetGamingStatusReq) (*entities.GamingStatus, error) {
+	data, err := s.getGamingLimitData(req.UID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	keys := make([]repository.GamingUsageKey, 0, len(data.Limits))
+	for _, limit := range data.Limits {
+		bucket, _ := gamingPeriodBucket(limit.Period, now)
+		keys = append(keys, repository.GamingUsageKey{Type: limit.Type, Bucket: bucket})
+	}
+	usage, err := s.Repo.GetGamingUsage(req.UID, keys)
+	if err != nil {
+		return nil, err
+	}
+	for i, limit := range data.Limits {
+		limit.Usage = usage[i]
+	}
+
+	status := &entities.GamingStatus{
+		Limits:      data.Limits,
+		Restriction: data.Restriction,
+	}
+	if req.SessionID == "" {
+		return status, nil
+	}
+	session, err := s.SessionRepo.GetUserSessionBySID(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.UID != req.UID {
+		return status, nil
+	}
+	status.SessionStart = session.CreatedAt
+	elapsed := now.Unix() - session.CreatedAt
+	status.SessionMinutes = elapsed / 60
+	if data.Restriction != nil && data.Restriction.SessionReminder > 0 {
+		interval := int64(data.Restriction.SessionReminder) * 60
+		status.NextReminderAt = session.CreatedAt + (elapsed/interval+1)*interval
+	}
+	return status, nil
+}
+
+func (s *GamingService) writeGamingLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_GAMING_LIMIT,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
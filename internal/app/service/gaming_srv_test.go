@@ -0,0 +1,209 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestGamingService(t *testing.T) *GamingService {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	db := newTestDB(t, new(entities.GamingLimit), new(entities.GamingRestriction))
+	return &GamingService{Repo: &repository.GamingRepository{DB: db, RDS: rds}}
+}
+
+func TestGamingPeriodBucket(t *testing.T) {
+	now := time.Date(2024, 12, 30, 23, 59, 0, 0, time.UTC)
+	tests := []struct {
+		period string
+		want   string
+	}{
+		{period: constant.GAMING_LIMIT_PERIOD_DAY, want: "20241230"},
+		{period: constant.GAMING_LIMIT_PERIOD_WEEK, want: "2025W01"}, // ISO周跨年
+		{period: constant.GAMING_LIMIT_PERIOD_MONTH, want: "202412"},
+		{period: "unknown", want: "20241230"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			if got, _ := gamingPeriodBucket(tt.period, now); got != tt.want {
+				t.Fatalf("gamingPeriodBucket() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 同一周期取用户和运营中较小的 0为不限制
+func TestEffectiveGamingLimits(t *testing.T) {
+	limits := []*entities.GamingLimit{
+		{Type: constant.GAMING_LIMIT_TYPE_LOSS, Period: constant.GAMING_LIMIT_PERIOD_DAY, Source: constant.GAMING_LIMIT_SOURCE_USER, Amount: 100},
+		{Type: constant.GAMING_LIMIT_TYPE_LOSS, Period: constant.GAMING_LIMIT_PERIOD_DAY, Source: constant.GAMING_LIMIT_SOURCE_OPERATOR, Amount: 50},
+		{Type: constant.GAMING_LIMIT_TYPE_LOSS, Period: constant.GAMING_LIMIT_PERIOD_WEEK, Source: constant.GAMING_LIMIT_SOURCE_USER, Amount: 0},
+		{Type: constant.GAMING_LIMIT_TYPE_LOSS, Period: constant.GAMING_LIMIT_PERIOD_MONTH, Source: constant.GAMING_LIMIT_SOURCE_USER, Amount: 300},
+		{Type: constant.GAMING_LIMIT_TYPE_WAGER, Period: constant.GAMING_LIMIT_PERIOD_DAY, Source: constant.GAMING_LIMIT_SOURCE_USER, Amount: 10},
+	}
+	got := effectiveGamingLimits(limits, constant.GAMING_LIMIT_TYPE_LOSS)
+	if len(got) != 2 || got[constant.GAMING_LIMIT_PERIOD_DAY] != 50 || got[constant.GAMING_LIMIT_PERIOD_MONTH] != 300 {
+		t.Fatalf("effectiveGamingLimits() = %v", got)
+	}
+}
+
+// 下注按全输计算输钱额 派彩冲减输钱 退款同时冲减投注
+func TestGamingService_CheckBet(t *testing.T) {
+	s := newTestGamingService(t)
+	const uid = 1
+	if err := s.SetGamingLimit(&entities.SetGamingLimitReq{UID: uid, Type: constant.GAMING_LIMIT_TYPE_WAGER, Period: constant.GAMING_LIMIT_PERIOD_DAY, Amount: 100}); err != nil {
+		t.Fatal(err)
+	}
+	operator := &entities.GamingLimit{UID: uid, Type: constant.GAMING_LIMIT_TYPE_LOSS, Period: constant.GAMING_LIMIT_PERIOD_WEEK, Source: constant.GAMING_LIMIT_SOURCE_OPERATOR, Amount: 50}
+	if err := s.Repo.SaveGamingLimit(operator); err != nil {
+		t.Fatal(err)
+	}
+	s.clearGamingLimitCache(uid)
+
+	record := func(flowType uint16, number float64) {
+		if err := s.RecordUsage(&entities.Flow{UID: uid, FlowType: flowType, Number: number}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(amount float64, want int) {
+		t.Helper()
+		err := s.CheckBet(uid, amount)
+		if want == 0 && err != nil || want != 0 && !errors.IsCode(err, want) {
+			t.Fatalf("CheckBet(%v) err = %v, want %d", amount, err, want)
+		}
+	}
+
+	record(201, -30)
+	check(20, 0)
+	check(21, errors.GamingLossLimit)
+	record(202, 10) // 输钱20 投注30
+	check(30, 0)
+	record(201, -60) // 输钱80 投注90
+	check(10, errors.GamingLossLimit)
+	record(202, 45) // 输钱35
+	check(10, 0)
+	check(11, errors.GamingWagerLimit)
+	record(constant.FLOW_TYPE_ZF_REFUND, 20) // 输钱15 投注70
+	check(30, 0)
+	check(31, errors.GamingWagerLimit)
+}
+
+func TestGamingService_CheckDeposit(t *testing.T) {
+	s := newTestGamingService(t)
+	const uid = 1
+	if err := s.SetGamingLimit(&entities.SetGamingLimitReq{UID: uid, Type: constant.GAMING_LIMIT_TYPE_DEPOSIT, Period: constant.GAMING_LIMIT_PERIOD_MONTH, Amount: 200}); err != nil {
+		t.Fatal(err)
+	}
+	// 充值以外的正数流水不计入
+	for _, flow := range []*entities.Flow{
+		{UID: uid, FlowType: constant.FLOW_TYPE_RECHARGE_CASH, Number: 150},
+		{UID: uid, FlowType: constant.FLOW_TYPE_RECHARGE_PROMOTION, Number: 100},
+	} {
+		if err := s.RecordUsage(flow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CheckDeposit(uid, 50); err != nil {
+		t.Fatalf("CheckDeposit(50) = %v", err)
+	}
+	if err := s.CheckDeposit(uid, 50.01); !errors.IsCode(err, errors.GamingDepositLimit) {
+		t.Fatalf("CheckDeposit(50.01) err = %v, want GamingDepositLimit", err)
+	}
+	// 下注不受充值限额影响
+	if err := s.CheckBet(uid, 1000); err != nil {
+		t.Fatalf("CheckBet() = %v", err)
+	}
+}
+
+// 降低立即生效 提高或取消等待生效 期间改回原额度撤销
+func TestGamingService_SetGamingLimit(t *testing.T) {
+	s := newTestGamingService(t)
+	const uid = 1
+	set := func(amount float64) *entities.GamingLimit {
+		t.Helper()
+		req := &entities.SetGamingLimitReq{UID: uid, Type: constant.GAMING_LIMIT_TYPE_WAGER, Period: constant.GAMING_LIMIT_PERIOD_DAY, Amount: amount}
+		if err := s.SetGamingLimit(req); err != nil {
+			t.Fatal(err)
+		}
+		limit, err := s.Repo.GetGamingLimit(uid, req.Type, req.Period, constant.GAMING_LIMIT_SOURCE_USER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return limit
+	}
+
+	if limit := set(100); limit.Amount != 100 || limit.PendingTime != 0 {
+		t.Fatalf("new limit = %+v", limit)
+	}
+	if limit := set(200); limit.Amount != 100 || limit.PendingAmount != 200 || limit.PendingTime <= time.Now().Unix() {
+		t.Fatalf("raised limit = %+v", limit)
+	}
+	if limit := set(100); limit.Amount != 100 || limit.PendingTime != 0 {
+		t.Fatalf("reverted limit = %+v", limit)
+	}
+	if limit := set(50); limit.Amount != 50 || limit.PendingTime != 0 {
+		t.Fatalf("lowered limit = %+v", limit)
+	}
+	limit := set(0)
+	if limit.Amount != 50 || limit.PendingAmount != 0 || limit.PendingTime == 0 {
+		t.Fatalf("cancelled limit = %+v", limit)
+	}
+	if err := s.CheckBet(uid, 51); !errors.IsCode(err, errors.GamingWagerLimit) {
+		t.Fatalf("CheckBet() before pending err = %v, want GamingWagerLimit", err)
+	}
+
+	// 到期后读取时应用
+	s.Repo.DB.Model(limit).UpdateColumn("pending_time", time.Now().Unix()-1)
+	s.clearGamingLimitCache(uid)
+	if err := s.CheckBet(uid, 51); err != nil {
+		t.Fatalf("CheckBet() after pending = %v", err)
+	}
+	if limit, _ = s.Repo.GetGamingLimit(uid, limit.Type, limit.Period, limit.Source); limit.Amount != 0 || limit.PendingTime != 0 {
+		t.Fatalf("applied limit = %+v", limit)
+	}
+}
+
+// 冷静期和自我排除只能延长 期间不能下注和充值
+func TestGamingService_Restriction(t *testing.T) {
+	s := newTestGamingService(t)
+	const uid = 1
+	if err := s.CoolOff(&entities.GamingCoolOffReq{UID: uid, Days: 2}); !errors.IsCode(err, errors.GamingInvalidSetting) {
+		t.Fatalf("CoolOff(2) err = %v, want GamingInvalidSetting", err)
+	}
+	if err := s.CoolOff(&entities.GamingCoolOffReq{UID: uid, Days: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CoolOff(&entities.GamingCoolOffReq{UID: uid, Days: 1}); err != nil {
+		t.Fatal(err)
+	}
+	restriction, err := s.Repo.GetGamingRestriction(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restriction.CoolOffUntil < time.Now().AddDate(0, 0, 6).Unix() {
+		t.Fatalf("cool off shortened to %d", restriction.CoolOffUntil)
+	}
+	if err := s.CheckBet(uid, 1); !errors.IsCode(err, errors.GamingCoolOff) {
+		t.Fatalf("CheckBet() err = %v, want GamingCoolOff", err)
+	}
+
+	if err := s.SelfExclude(&entities.GamingSelfExcludeReq{UID: uid, Months: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SelfExclude(&entities.GamingSelfExcludeReq{UID: uid, Months: 6}); err != nil {
+		t.Fatal(err)
+	}
+	if restriction, _ = s.Repo.GetGamingRestriction(uid); restriction.SelfExcludeUntil != gamingExcludeForever {
+		t.Fatalf("self exclude shortened to %d", restriction.SelfExcludeUntil)
+	}
+	if err := s.CheckDeposit(uid, 1); !errors.IsCode(err, errors.GamingSelfExcluded) {
+		t.Fatalf("CheckDeposit() err = %v, want GamingSelfExcluded", err)
+	}
+}
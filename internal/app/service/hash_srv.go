@@ -5,6 +5,7 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
@@ -66,6 +67,10 @@ func (s *HashGameService) CreateHashGameOrder(order entities.IHashGameOrder) err
 		return err
 	}
 
+	if err := s.WalletSrv.CheckBetLimit(order.GetUID(), order.GetBetAmount()); err != nil {
+		return err
+	}
+
 	wallet, err := s.WalletSrv.GetWallet(order.GetUID())
 	if err != nil {
 		return err
@@ -86,6 +91,9 @@ func (s *HashGameService) CreateHashGameOrder(order entities.IHashGameOrder) err
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.ConsumeTurnoverWithTx(tx, order.GetUID(), order.GetBetAmount()); err != nil { //扣减打码量
+			return err
+		}
 
 		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
 			UID:          order.GetUID(),
@@ -94,8 +102,11 @@ func (s *HashGameService) CreateHashGameOrder(order entities.IHashGameOrder) err
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		if err := event.PublishTx(tx, &event.BetPlaced{UID: order.GetUID(), Game: constant.GameNameHash, OrderID: order.GetOrderID(), Amount: order.GetBetAmount()}); err != nil {
+			return err
 		}
 		logger.ZInfo("CreateWingoOrder", zap.Any("order", order))
 		return nil
@@ -165,6 +176,7 @@ func (s *HashGameService) settleUserBatch(batchUids []uint, userOrderMap map[uin
 
 	// 处理每个用户的订单
 	flows := make([]*entities.Flow, 0)
+	settled := make([]*event.BetSettled, 0, len(batchUids))
 	for _, uid := range batchUids {
 		userOrders := userOrderMap[uid]
 		var totalReward float64
@@ -173,6 +185,7 @@ func (s *HashGameService) settleUserBatch(batchUids []uint, userOrderMap map[uin
 		for _, order := range userOrders {
 			orderIDs = append(orderIDs, order.GetID())
 			totalReward += order.GetRewardAmount()
+			settled = append(settled, &event.BetSettled{UID: uid, Game: constant.GameNameHash, OrderID: order.GetOrderID(), BetAmount: order.GetBetAmount(), RewardAmount: order.GetRewardAmount()})
 		}
 
 		// 批量更新订单状态
@@ -217,18 +230,26 @@ func (s *HashGameService) settleUserBatch(batchUids []uint, userOrderMap map[uin
 		}
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return err
-	}
-
-	// 事务提交成功后发送MQ消息
+	// 流水消息和结算在同一个事务写入发件箱
 	for _, flow := range flows {
 		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue failed", zap.Any("flow", flow), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	for _, evt := range settled {
+		if err := event.PublishTx(tx, evt); err != nil {
+			tx.Rollback()
+			return err
 		}
 	}
 
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	mq.NotifyOutbox()
+
 	return nil
 }
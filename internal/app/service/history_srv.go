@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/service/repository"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+)
+
+const (
+	betHistoryDefaultLimit = 20
+	betHistoryMaxLimit     = 100
+	betHistoryExportMax    = 10000          // 导出最多条数
+	betHistoryExportRange  = 90 * 24 * 3600 // 导出最大时间范围
+	recentGameDays         = 30             // 最近玩过统计的天数
+	recentGameDefaultLimit = 10
+)
+
+var HistoryServiceSet = wire.NewSet(
+	ProvideHistoryService,
+)
+
+// 我的投注记录 合并自研游戏订单和三方游戏记录
+type HistoryService struct {
+	Repo     *repository.HistoryRepository
+	GameRepo *repository.GameRepository
+}
+
+func ProvideHistoryService(repo *repository.HistoryRepository, gameRepo *repository.GameRepository) *HistoryService {
+	return &HistoryService{
+		Repo:     repo,
+		GameRepo: gameRepo,
+	}
+}
+
+func (s *HistoryService) GetBetHistory(req *entities.GetBetHistoryReq) (*entities.GetBetHistoryResp, error) {
+	if req.Limit <= 0 {
+		req.Limit = betHistoryDefaultLimit
+	}
+	if req.Limit > betHistoryMaxLimit {
+		req.Limit = betHistoryMaxLimit
+	}
+	var cursor *entities.BetHistoryCursor
+	if req.Cursor != "" {
+		var err error
+		if cursor, err = decodeBetHistoryCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	list, next, err := s.queryBetHistory(&req.BetHistoryFilter, cursor, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &entities.GetBetHistoryResp{List: list}
+	if next != nil {
+		resp.NextCursor = encodeBetHistoryCursor(next)
+	}
+	return resp, nil
+}
+
+// 导出CSV 不传时间时默认最近30天
+func (s *HistoryService) ExportBetHistory(req *entities.ExportBetHistoryReq) ([]byte, error) {
+	if req.EndTime == 0 {
+		req.EndTime = time.Now().Unix()
+	}
+	if req.StartTime == 0 {
+		req.StartTime = req.EndTime - recentGameDays*24*3600
+	}
+	if req.StartTime >= req.EndTime || req.EndTime-req.StartTime > betHistoryExportRange {
+		return nil, errors.WithCode(errors.BetHistoryInvalidRange)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("\xEF\xBB\xBF") //BOM 方便excel直接打开
+	writer := csv.NewWriter(buf)
+	writer.Write([]string{"id", "bet_time", "game", "category", "round_id", "bet_amount", "payout", "profit", "result"})
+
+	var cursor *entities.BetHistoryCursor
+	total := 0
+	for total < betHistoryExportMax {
+		list, next, err := s.queryBetHistory(&req.BetHistoryFilter, cursor, betHistoryMaxLimit)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list {
+			writer.Write([]string{
+				item.ID,
+				time.Unix(item.BetTime, 0).Format(time.DateTime),
+				item.Game,
+				item.Category,
+				item.RoundID,
+				strconv.FormatFloat(item.BetAmount, 'f', 2, 64),
+				strconv.FormatFloat(item.Payout, 'f', 2, 64),
+				strconv.FormatFloat(item.Profit, 'f', 2, 64),
+				item.Result,
+			})
+		}
+		total += len(list)
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type betHistoryItem struct {
+	source int
+	row    *repository.BetHistoryRow
+}
+
+// 每个来源按游标各取limit+1条 合并排序后取前limit条
+func (s *HistoryService) queryBetHistory(filter *entities.BetHistoryFilter, cursor *entities.BetHistoryCursor, limit int) ([]*entities.BetHistory, *entities.BetHistoryCursor, error) {
+	items := make([]*betHistoryItem, 0)
+	for i, source := range repository.BetHistorySources {
+		if !betHistorySourceMatch(source, filter.Game) {
+			continue
+		}
+		rows, err := s.Repo.GetBetHistoryRows(i, filter, cursor, limit+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, row := range rows {
+			items = append(items, &betHistoryItem{source: i, row: row})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.row.BetTime != b.row.BetTime {
+			return a.row.BetTime > b.row.BetTime
+		}
+		if a.source != b.source {
+			return a.source < b.source
+		}
+		return a.row.ID > b.row.ID
+	})
+
+	var next *entities.BetHistoryCursor
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		next = &entities.BetHistoryCursor{BetTime: last.row.BetTime, Source: last.source, ID: last.row.ID}
+	}
+
+	list := make([]*entities.BetHistory, 0, len(items))
+	for _, item := range items {
+		list = append(list, toBetHistory(repository.BetHistorySources[item.source], item.row))
+	}
+	return list, next, nil
+}
+
+// 游戏筛选 自研游戏按标识匹配来源 其他按三方游戏名筛选
+func betHistorySourceMatch(source *repository.BetHistorySource, game string) bool {
+	if game == "" {
+		return true
+	}
+	for _, item := range repository.BetHistorySources {
+		if item.Key == game && item.Key != constant.BET_SOURCE_PROVIDER {
+			return source.Key == game
+		}
+	}
+	return source.Key == constant.BET_SOURCE_PROVIDER
+}
+
+func toBetHistory(source *repository.BetHistorySource, row *repository.BetHistoryRow) *entities.BetHistory {
+	item := &entities.BetHistory{
+		ID:        fmt.Sprintf("%s:%d", source.Key, row.ID),
+		Source:    source.Key,
+		Game:      row.Game,
+		Category:  row.Category,
+		RoundID:   row.RoundID,
+		BetTime:   row.BetTime,
+		BetAmount: row.BetAmount,
+	}
+	switch {
+	case row.Canceled:
+		item.Result = constant.BET_RESULT_CANCEL
+	case !row.Settled:
+		item.Result = constant.BET_RESULT_PENDING
+	default:
+		item.Payout = row.Payout
+		item.Profit = row.Payout - row.BetAmount
+		if item.Profit > 0 {
+			item.Result = constant.BET_RESULT_WIN
+		} else {
+			item.Result = constant.BET_RESULT_LOSE
+		}
+	}
+	return item
+}
+
+// 游标格式 时间:来源:ID
+func encodeBetHistoryCursor(cursor *entities.BetHistoryCursor) string {
+	return fmt.Sprintf("%d:%s:%d", cursor.BetTime, repository.BetHistorySources[cursor.Source].Key, cursor.ID)
+}
+
+func decodeBetHistoryCursor(value string) (*entities.BetHistoryCursor, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return nil, errors.WithCode(errors.BetHistoryInvalidCursor)
+	}
+	betTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errors.WithCode(errors.BetHistoryInvalidCursor)
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, errors.WithCode(errors.BetHistoryInvalidCursor)
+	}
+	for i, source := range repository.BetHistorySources {
+		if source.Key == parts[1] {
+			return &entities.BetHistoryCursor{BetTime: betTime, Source: i, ID: uint(id)}, nil
+		}
+	}
+	return nil, errors.WithCode(errors.BetHistoryInvalidCursor)
+}
+
+// 最近玩过的游戏 按最后投注时间倒序 并带上游戏目录信息
+func (s *HistoryService) GetRecentGameList(req *entities.GetRecentGameListReq) ([]*entities.RecentGame, error) {
+	if req.Limit <= 0 || req.Limit > betHistoryMaxLimit {
+		req.Limit = recentGameDefaultLimit
+	}
+	since := time.Now().AddDate(0, 0, -recentGameDays).Unix()
+	list, err := s.Repo.GetRecentGameList(req.UID, since, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastPlayTime > list[j].LastPlayTime
+	})
+	if len(list) > req.Limit {
+		list = list[:req.Limit]
+	}
+
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		names = append(names, item.Game)
+	}
+	games, err := s.GameRepo.GetVisibleGameListByNames(names)
+	if err != nil {
+		return nil, err
+	}
+	gameMap := make(map[string]*entities.Game, len(games)*2)
+	for _, game := range games {
+		gameMap[game.Name] = game
+		gameMap[game.GameCode] = game
+	}
+	for _, item := range list {
+		item.Info = gameMap[item.Game]
+	}
+	return list, nil
+}
@@ -7,8 +7,6 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
-	"rk-api/internal/app/mq"
-	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
 	"rk-api/internal/app/utils"
 	resty "rk-api/pkg/http"
@@ -19,7 +17,6 @@ import (
 
 	"github.com/google/wire"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 var JhszServiceSet = wire.NewSet(
@@ -34,26 +31,29 @@ type JhszService struct {
 	appSecret  string
 	signSecret string
 
-	walletSrv *WalletService
-	authSrv   *AuthService
+	walletSrv   *WalletService
+	seamlessSrv *SeamlessWalletService
+	authSrv     *AuthService
 }
 
 func ProvideJhszService(repo *repository.JhszRepository,
 	userSrv *UserService,
 	walletSrv *WalletService,
 	authSrv *AuthService,
+	seamlessSrv *SeamlessWalletService,
 ) *JhszService {
 	setting := config.Get().JhszSetting
 	logger.ZInfo("ProvideJhszService", zap.Any("setting", setting))
 	return &JhszService{
-		Repo:       repo,
-		UserSrv:    userSrv,
-		apiUrl:     setting.ApiUrl,
-		appID:      setting.AppID,
-		appSecret:  setting.AppSecret,
-		signSecret: setting.SignSecret,
-		walletSrv:  walletSrv,
-		authSrv:    authSrv,
+		Repo:        repo,
+		UserSrv:     userSrv,
+		apiUrl:      setting.ApiUrl,
+		appID:       setting.AppID,
+		appSecret:   setting.AppSecret,
+		signSecret:  setting.SignSecret,
+		walletSrv:   walletSrv,
+		seamlessSrv: seamlessSrv,
+		authSrv:     authSrv,
 	}
 }
 
@@ -208,145 +208,73 @@ func (s *JhszService) Transfer(req *entities.JhszTransferReq) (*entities.JhszTra
 	if err != nil {
 		return nil, errors.With("Invalid account")
 	}
-	user, err := s.UserSrv.GetUserByUID(uid)
-	if user == nil || err != nil {
-		return nil, errors.With("user not exist")
-	}
-
-	wallet, err := s.walletSrv.GetUserWallet(user.ID)
-	if err != nil {
-		return nil, err
-	}
-	if wallet == nil {
-		return nil, errors.With("user wallet not exist")
+	if req.Currency != constant.CURRENCY_CASH {
+		return nil, errors.With("Invalid currency")
 	}
 	logger.ZInfo("JhszService-Transfer", zap.Any("req", req))
 
-	err = s.walletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
-		var action = req.Action
-
-		switch action {
-		case "withdraw":
-			req.FlowType = constant.FLOW_TYPE_JHSZ_WITHDRAW
-			transferAmount, err := s.Withdraw(wallet, req.Currency, req.Amount)
-			if err != nil {
-				return err
-			}
-			req.Amount = -transferAmount //提现-金额
-		case "rollback":
-
-		case "deposit":
-			req.FlowType = constant.FLOW_TYPE_JHSZ_DEPOSIT
-
-			transferAmount, err := s.Deposit(wallet, req.Currency, req.Amount)
-			if err != nil {
-				return err
-			}
-			req.Amount = transferAmount
-		case "freeze":
-			req.FlowType = constant.FLOW_TYPE_JHSZ_FREEZE
-			transferAmount, err := s.Freeze(wallet, req.Currency, req.Amount)
-			if err != nil {
-				return err
-			}
-			req.Amount = -transferAmount
-
-		case "unfreeze":
-			req.FlowType = constant.FLOW_TYPE_JHSZ_UNFREEZE
-			transferAmount, err := s.Unfreeze(wallet, req.Currency, req.Amount)
-			if err != nil {
-				return err
-			}
-			req.Amount = transferAmount
-		default:
-			return errors.With("Invalid action")
+	externalID := req.UniqueID
+	if externalID == "" {
+		externalID = fmt.Sprintf("%s:%s:%s", req.Action, req.RecordId, req.RoundId)
+	}
+	txReq := &entities.SeamlessTxReq{
+		Provider:   constant.SEAMLESS_PROVIDER_JHSZ,
+		ExternalID: externalID,
+		UID:        uid,
+		Amount:     req.Amount,
+		GameCode:   req.GameCode,
+		RoundID:    req.RoundId,
+		RecordID:   req.RecordId,
+		Remark:     fmt.Sprintf("JHSZ %s %s %s %s", req.Action, req.GameCode, req.RecordId, req.RoundId),
+	}
+
+	var result *entities.SeamlessTxResult
+	switch req.Action {
+	case "withdraw":
+		txReq.FlowType = constant.FLOW_TYPE_JHSZ_WITHDRAW
+		result, err = s.seamlessSrv.Debit(txReq)
+	case "freeze":
+		txReq.FlowType = constant.FLOW_TYPE_JHSZ_FREEZE
+		txReq.Partial = true //不够就冻结所有
+		result, err = s.seamlessSrv.Debit(txReq)
+	case "deposit": //派彩关联本局的下注
+		txReq.FlowType = constant.FLOW_TYPE_JHSZ_DEPOSIT
+		if txReq.RefExternalID, err = s.seamlessSrv.OpenDebitExternalID(txReq.Provider, uid, req.RoundId, req.RecordId, constant.FLOW_TYPE_JHSZ_WITHDRAW); err != nil {
+			return nil, err
 		}
-
-		if err := s.walletSrv.UpdateCashWithTx(tx, wallet); err != nil {
-			return nil
+		result, err = s.seamlessSrv.Credit(txReq)
+	case "unfreeze": //解冻关联本局的冻结
+		txReq.FlowType = constant.FLOW_TYPE_JHSZ_UNFREEZE
+		if txReq.RefExternalID, err = s.seamlessSrv.OpenDebitExternalID(txReq.Provider, uid, req.RoundId, req.RecordId, constant.FLOW_TYPE_JHSZ_FREEZE); err != nil {
+			return nil, err
 		}
-
-		remark := fmt.Sprintf("JHSZ %s %s %s %s", req.Action, req.GameCode, req.RecordId, req.RoundId)
-
-		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
-			UID:          user.ID,
-			FlowType:     uint16(req.FlowType),
-			Number:       req.Amount,
-			Balance:      wallet.Cash,
-			Remark:       remark,
-			PromoterCode: user.PromoterCode,
-		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		result, err = s.seamlessSrv.Credit(txReq)
+	case "rollback": //按局号/注单号找到原扣款 退回原扣款金额
+		txReq.FlowType = constant.FLOW_TYPE_JHSZ_ROLLBACK
+		result, err = s.seamlessSrv.Rollback(txReq)
+	default:
+		return nil, errors.With("Invalid action")
+	}
+	if err != nil {
+		if errors.IsCode(err, errors.WalletNotExist) {
+			return nil, errors.With("user wallet not exist")
 		}
-
-		return nil
-	})
-
-	return &entities.JhszTransferResp{Balance: wallet.Cash, TransferAmount: -req.Amount}, err
-}
-
-func (s *JhszService) Freeze(wallet *entities.UserWallet, currency string, amount float64) (transferAmount float64, err error) {
-	logger.ZInfo("Freeze", zap.Float64("amount", amount), zap.Float64("wallet.cash", wallet.Cash))
-	switch currency {
-	case constant.CURRENCY_CASH:
-		if wallet.Cash <= 0 {
-			err = errors.With("insufficient cash")
-			return
+		if errors.IsCode(err, errors.InsufficientBalance) {
+			return nil, errors.With("insufficient cash")
 		}
-		if wallet.Cash < amount { //不够就所有
-			amount = wallet.Cash
+		if errors.IsCode(err, errors.SeamlessTxNotExist) {
+			return nil, errors.With("original transaction not exist")
 		}
-		wallet.SafeAdjustCash(-amount)
-		transferAmount = amount
-	default:
-		err = errors.With("Invalid currency")
-		return
-	}
-	return
-}
-
-func (s *JhszService) Unfreeze(wallet *entities.UserWallet, currency string, amount float64) (transferAmount float64, err error) {
-	logger.ZInfo("Unfreeze", zap.Float64("amount", amount))
-	switch currency {
-	case constant.CURRENCY_CASH:
-		wallet.SafeAdjustCash(amount)
-		transferAmount = amount
-	default:
-		err = errors.With("Invalid currency")
-		return
-	}
-	return
-}
-
-func (s *JhszService) Withdraw(wallet *entities.UserWallet, currency string, amount float64) (transferAmount float64, err error) {
-	logger.ZInfo("Withdraw", zap.Float64("amount", amount))
-	switch currency {
-	case constant.CURRENCY_CASH:
-		if wallet.Cash < amount {
-			err = errors.With("insufficient cash")
-			return
+		if errors.IsCode(err, errors.SeamlessTxRolledBack) || errors.IsCode(err, errors.SeamlessTxSettled) {
+			return nil, errors.With("original transaction already rolled back or settled")
 		}
-		wallet.SafeAdjustCash(-amount)
-
-		transferAmount = amount
-	default:
-		err = errors.With("Invalid currency")
+		if errors.IsCode(err, errors.SeamlessTxAmbiguous) {
+			return nil, errors.With("multiple open transactions in round, original transaction id required")
+		}
+		return nil, err
 	}
-	return
-}
 
-func (s *JhszService) Deposit(wallet *entities.UserWallet, currency string, amount float64) (transferAmount float64, err error) {
-	logger.ZInfo("Deposit", zap.Float64("amount", amount))
-	switch currency {
-	case constant.CURRENCY_CASH:
-		wallet.SafeAdjustCash(amount)
-		transferAmount = amount
-	default:
-		err = errors.With("Invalid currency")
-		return
-	}
-	return
+	return &entities.JhszTransferResp{Balance: result.Balance, TransferAmount: -result.Amount}, nil
 }
 
 func (s *JhszService) FetchWallet(req *entities.JhszBalanceReq) (*entities.JhszTransferResp, error) {
@@ -362,13 +290,12 @@ func (s *JhszService) FetchWallet(req *entities.JhszBalanceReq) (*entities.JhszT
 		return nil, errors.With("Invalid username")
 	}
 
-	wallet, err := s.walletSrv.GetUserWallet(uid)
-
-	if wallet == nil || err != nil {
+	balance, err := s.seamlessSrv.Balance(uid)
+	if err != nil {
 		return nil, errors.With("user not exist")
 	}
-	logger.ZInfo("fetchbalance", zap.Float64("balance", wallet.Cash))
-	return &entities.JhszTransferResp{Balance: wallet.Cash}, nil
+	logger.ZInfo("fetchbalance", zap.Float64("balance", balance))
+	return &entities.JhszTransferResp{Balance: balance}, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -463,6 +390,31 @@ func (s *JhszService) FetchRecords(startTime, endTime time.Time) ([]*entities.Ga
 	return gameRecords, nil
 }
 
+// 三方返回的count为该时间段的总条数 只取一条记录
+func (s *JhszService) CountRecords(startTime, endTime time.Time) (int, error) {
+	client := resty.GetHttpClient()
+
+	var result struct {
+		Code  int    `json:"code"`
+		Msg   string `json:"msg"`
+		Count int    `json:"count"`
+	}
+	_, err := client.R().
+		SetQueryParam("limit", "1").
+		SetQueryParam("merchantCode", s.appID).
+		SetQueryParam("startDate", fmt.Sprintf("%d", startTime.UnixMilli())).
+		SetQueryParam("endDate", fmt.Sprintf("%d", endTime.UnixMilli())).
+		SetResult(&result).
+		Get(s.apiUrl + "/LogPlayerGameTable")
+	if err != nil {
+		return 0, err
+	}
+	if result.Code != 200 {
+		return 0, fmt.Errorf("error fetching count: %s,%d", result.Msg, result.Code)
+	}
+	return result.Count, nil
+}
+
 func (s *JhszService) FetchOnlineCount() ([]map[string]interface{}, error) {
 	// 使用 resty 发送 HTTP 请求
 	client := resty.GetHttpClient()
@@ -499,5 +451,26 @@ func (s *JhszService) FetchOnlineCount() ([]map[string]interface{}, error) {
 	return result.Data, nil
 }
 
+// 三方没有单独的游戏列表接口 以在线人数接口返回的游戏为准
+func (s *JhszService) FetchGameList() ([]*entities.Game, error) {
+	data, err := s.FetchOnlineCount()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*entities.Game, 0, len(data))
+	for _, item := range data {
+		gameName, _ := item["gameName"].(string)
+		if gameName == "" {
+			continue
+		}
+		list = append(list, &entities.Game{
+			Name:     gameName,
+			GameCode: gameName,
+			Category: constant.GameCategoryExternal,
+		})
+	}
+	return list, nil
+}
+
 // 1740731328000
 // 1740738428016
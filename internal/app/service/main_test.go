@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"rk-api/pkg/logger"
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.ReplaceLogger(zap.NewNop())
+	os.Exit(m.Run())
+}
+
+// 每个测试一个独立的内存库 连接池里的连接共用同一个库 事务内外都能查到
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
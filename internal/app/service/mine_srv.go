@@ -5,6 +5,7 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
@@ -67,6 +68,10 @@ func (s *MineGameService) PlaceOrder(order *entities.MineGameOrder) error {
 		return err
 	}
 
+	if err := s.WalletSrv.CheckBetLimit(order.UID, order.BetAmount); err != nil {
+		return err
+	}
+
 	wallet, err := s.WalletSrv.GetWallet(order.UID)
 	if err != nil {
 		return err
@@ -87,6 +92,9 @@ func (s *MineGameService) PlaceOrder(order *entities.MineGameOrder) error {
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.ConsumeTurnoverWithTx(tx, order.UID, order.BetAmount); err != nil { //扣减打码量
+			return err
+		}
 
 		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
 			UID:          order.UID,
@@ -95,8 +103,11 @@ func (s *MineGameService) PlaceOrder(order *entities.MineGameOrder) error {
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		if err := event.PublishTx(tx, &event.BetPlaced{UID: order.UID, Game: constant.GameNameMine, OrderID: fmt.Sprintf("%d", order.ID), Amount: order.BetAmount}); err != nil {
+			return err
 		}
 		logger.ZInfo("MineGameService.PlaceOrder", zap.Any("order", order))
 		return nil
@@ -168,21 +179,27 @@ func (s *MineGameService) SettleOrder(order *entities.MineGameOrder) error {
 		return err
 	}
 
+	// 流水消息和结算在同一个事务写入发件箱
+	if flow != nil {
+		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := event.PublishTx(tx, &event.BetSettled{UID: order.UID, Game: constant.GameNameMine, OrderID: fmt.Sprintf("%d", order.ID), BetAmount: order.BetAmount, RewardAmount: order.RewardAmount}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
+	mq.NotifyOutbox()
 
 	// 清除钱包缓存
 	s.WalletSrv.ClearWalletCache(order.UID)
 
-	// 事务提交成功后发送MQ消息 生成流水记录
-	if flow != nil {
-		createFlowQueue, _ := handle.NewCreateFlowQueue(flow)
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue failed", zap.Any("flow", flow), zap.Error(err))
-		}
-	}
-
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
@@ -125,6 +126,10 @@ func (s *NineService) CreateNineOrder(order *entities.NineOrder) error {
 	// 	}
 	// }
 
+	if err := s.WalletSrv.CheckBetLimit(order.UID, order.BetAmount); err != nil {
+		return err
+	}
+
 	wallet, err := s.WalletSrv.GetWallet(order.UID)
 	if err != nil {
 		return err
@@ -148,6 +153,9 @@ func (s *NineService) CreateNineOrder(order *entities.NineOrder) error {
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.ConsumeTurnoverWithTx(tx, order.UID, order.BetAmount); err != nil { //扣减打码量
+			return err
+		}
 
 		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
 			UID:          order.UID,
@@ -156,8 +164,11 @@ func (s *NineService) CreateNineOrder(order *entities.NineOrder) error {
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		if err := event.PublishTx(tx, &event.BetPlaced{UID: order.UID, Game: constant.GameNameNine, OrderID: fmt.Sprintf("%d", order.ID), Amount: order.BetAmount}); err != nil {
+			return err
 		}
 		logger.ZInfo("CreateWingoOrder", zap.Any("order", order))
 		return nil
@@ -346,6 +357,7 @@ func (s *NineService) BatchSettlePlayerOrder(batchOrders []*entities.NineOrder)
 			logger.ZError("SettlePlayerOrderWithTx", zap.Any("order", order), zap.Any("Error", err))
 		}
 	}
+	mq.NotifyOutbox()
 
 	return nil
 }
@@ -393,10 +405,13 @@ func (s *NineService) SettlePlayerOrderWithTx(tx *gorm.DB, order *entities.NineO
 			Balance:      wallet.Cash,
 			PromoterCode: wallet.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 	}
+	if err := event.PublishTx(tx, &event.BetSettled{UID: order.UID, Game: constant.GameNameNine, OrderID: fmt.Sprintf("%d", order.ID), BetAmount: order.BetAmount, RewardAmount: order.RewardAmount}); err != nil {
+		return err
+	}
 
 	logger.ZInfo("SettlePlayerOrder", zap.Any("order", order))
 
@@ -62,7 +62,7 @@ func (m *NotificationService) HandleNotification(notification *entities.Notifica
 
 func SendNotification(uid uint, message string, title string) { //发送通知
 	notificationQueue, _ := handle.NewNotificationQueue(&entities.Notification{UID: uid, Message: message, Title: title})
-	if _, err := mq.MClient.Enqueue(notificationQueue); err != nil {
+	if err := mq.Publish(notificationQueue); err != nil {
 		logger.ZInfo("notificationQueue fail", zap.Error(err))
 	}
 }
@@ -77,7 +77,7 @@ func (s *NotificationService) SendNotification(req *entities.SendNotificationReq
 		Read:       0,
 	}
 	notificationQueue, _ := handle.NewNotificationQueue(&notification)
-	if _, err := mq.MClient.Enqueue(notificationQueue); err != nil {
+	if err := mq.Publish(notificationQueue); err != nil {
 		logger.ZInfo("notificationQueue fail", zap.Error(err))
 	}
 
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/event"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/internal/app/utils"
+	"rk-api/pkg/logger"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+var OutboxServiceSet = wire.NewSet(
+	ProvideOutboxService,
+)
+
+// 队列发件箱中继 业务事务里通过mq.EnqueueTx写入的消息在提交后投递到asynq
+// 同一时间只有抢到redis锁的节点投递 投递成功前崩溃会重投 靠TaskID去重
+type OutboxService struct {
+	Repo     *repository.OutboxRepository
+	EventSrv *EventBusService
+
+	node string
+}
+
+func ProvideOutboxService(repo *repository.OutboxRepository, eventSrv *EventBusService) *OutboxService {
+	hostname, _ := os.Hostname()
+	return &OutboxService{
+		Repo:     repo,
+		EventSrv: eventSrv,
+		node:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// 定时轮询 本节点有事务提交时立即唤醒
+func (s *OutboxService) Start() {
+	go func() {
+		ticker := time.NewTicker(constant.OUTBOX_RELAY_INTERVAL * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-mq.OutboxNotified():
+			}
+			func() {
+				defer utils.PrintPanicStack()
+				s.relay()
+			}()
+		}
+	}()
+	logger.ZInfo("OutboxService Start", zap.String("node", s.node))
+}
+
+func (s *OutboxService) relay() {
+	if mq.MClient == nil {
+		return
+	}
+	ok, err := s.Repo.AcquireOutboxRelayLock(s.node, constant.OUTBOX_LOCK_EXPIRE*time.Second)
+	if err != nil {
+		logger.ZError("AcquireOutboxRelayLock", zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := s.Repo.ReleaseOutboxRelayLock(s.node); err != nil {
+			logger.ZError("ReleaseOutboxRelayLock", zap.Error(err))
+		}
+	}()
+
+	for {
+		list, err := s.Repo.GetPendingOutboxMessages(time.Now().Unix(), constant.OUTBOX_BATCH_SIZE)
+		if err != nil {
+			logger.ZError("GetPendingOutboxMessages", zap.Error(err))
+			return
+		}
+		for _, msg := range list {
+			s.publish(msg)
+		}
+		if len(list) < constant.OUTBOX_BATCH_SIZE {
+			return
+		}
+		// 积压时连续投递 每批续一次锁
+		if ok, err := s.Repo.RenewOutboxRelayLock(s.node, constant.OUTBOX_LOCK_EXPIRE*time.Second); err != nil || !ok {
+			logger.ZError("RenewOutboxRelayLock", zap.Bool("ok", ok), zap.Error(err))
+			return
+		}
+	}
+}
+
+// 用去重键作为TaskID入队 已存在说明之前投递过 同样视为成功
+// 领域事件写入redis stream 同样按去重键去重
+// 失败按次数退避 超过最大次数标记失败等待人工处理
+func (s *OutboxService) publish(msg *entities.OutboxMessage) {
+	var err error
+	if name, ok := event.ParseTaskType(msg.Type); ok {
+		err = s.EventSrv.Append(name, msg.DedupKey, msg.Payload, msg.CreatedAt)
+	} else {
+		task := asynq.NewTask(msg.Type, []byte(msg.Payload), handle.TaskOptions(msg.Type)...)
+		_, err = mq.Enqueue(task, asynq.TaskID(msg.DedupKey), asynq.Retention(constant.OUTBOX_RETENTION*time.Second))
+	}
+	if err == nil || errors.Is(err, asynq.ErrTaskIDConflict) {
+		if err := s.Repo.UpdateOutboxMessage(msg.ID, map[string]interface{}{
+			"status":       constant.OUTBOX_STATUS_PUBLISHED,
+			"attempts":     msg.Attempts + 1,
+			"publish_time": time.Now().Unix(),
+		}); err != nil {
+			logger.ZError("UpdateOutboxMessage", zap.Uint("id", msg.ID), zap.Error(err))
+		}
+		return
+	}
+
+	attempts := msg.Attempts + 1
+	backoff := attempts * attempts
+	if backoff > constant.OUTBOX_MAX_BACKOFF {
+		backoff = constant.OUTBOX_MAX_BACKOFF
+	}
+	values := map[string]interface{}{
+		"attempts":  attempts,
+		"next_time": time.Now().Unix() + int64(backoff),
+		"error":     err.Error(),
+	}
+	if attempts >= constant.OUTBOX_MAX_ATTEMPTS {
+		values["status"] = constant.OUTBOX_STATUS_FAILED
+	}
+	logger.ZError("OutboxService publish", zap.Uint("id", msg.ID), zap.String("type", msg.Type), zap.Int("attempts", attempts), zap.Error(err))
+	if err := s.Repo.UpdateOutboxMessage(msg.ID, values); err != nil {
+		logger.ZError("UpdateOutboxMessage", zap.Uint("id", msg.ID), zap.Error(err))
+	}
+}
+
+// 清理保留期之前已投递的消息 失败的消息保留
+func (s *OutboxService) CleanPublishedOutbox() error {
+	before := time.Now().AddDate(0, 0, -constant.OUTBOX_KEEP_DAYS).Unix()
+	for {
+		n, err := s.Repo.DeletePublishedOutboxMessages(before, constant.ARCHIVE_BATCH_SIZE)
+		if err != nil {
+			return err
+		}
+		if n < constant.ARCHIVE_BATCH_SIZE {
+			return nil
+		}
+		time.Sleep(constant.ARCHIVE_BATCH_INTERVAL * time.Millisecond)
+	}
+}
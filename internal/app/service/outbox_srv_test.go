@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/event"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// asynq和事件流都写到miniredis
+func newTestOutboxService(t *testing.T) (*OutboxService, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	db := newTestDB(t, new(entities.OutboxMessage))
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: mr.Addr()})
+	mq.MClient = client
+	t.Cleanup(func() {
+		mq.MClient = nil
+		client.Close()
+	})
+	return &OutboxService{
+		Repo:     &repository.OutboxRepository{DB: db, RDS: rds},
+		EventSrv: &EventBusService{Repo: &repository.EventRepository{RDS: rds}},
+		node:     "a",
+	}, mr
+}
+
+func getOutboxMessage(t *testing.T, s *OutboxService, dedupKey string) *entities.OutboxMessage {
+	t.Helper()
+	msg := new(entities.OutboxMessage)
+	if err := s.Repo.DB.Where("dedup_key = ?", dedupKey).First(msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// 同一业务标识只写一次 投递后按去重键入队 已入队的视为成功 未到时间的不投递
+func TestOutboxService_relay(t *testing.T) {
+	s, mr := newTestOutboxService(t)
+	db := s.Repo.DB
+
+	flowTask, _ := handle.NewCreateFlowQueue(&entities.Flow{UID: 1, FlowType: constant.FLOW_TYPE_CASHBACK, Number: 10})
+	for i := 0; i < 2; i++ {
+		if err := mq.EnqueueTxWithKey(db, flowTask, "order-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bonus := &event.BonusGranted{UID: 1, FlowType: constant.FLOW_TYPE_CASHBACK, SourceID: "1", Amount: 10}
+	if err := event.PublishTx(db, bonus); err != nil {
+		t.Fatal(err)
+	}
+	// 上次投递后崩溃 消息已在asynq中
+	if err := mq.EnqueueTxWithKey(db, flowTask, "order-2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mq.Enqueue(flowTask, asynq.TaskID(flowTask.Type()+":order-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := mq.EnqueueTxWithKey(db, flowTask, "order-3"); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&entities.OutboxMessage{}).Where("dedup_key = ?", flowTask.Type()+":order-3").UpdateColumn("next_time", time.Now().Unix()+60)
+
+	var count int64
+	db.Model(&entities.OutboxMessage{}).Count(&count)
+	if count != 4 {
+		t.Fatalf("outbox messages = %d, want 4", count)
+	}
+
+	// 其他节点持有中继锁时不投递
+	mr.Set(constant.REDIS_KEY_OUTBOX_RELAY_LOCK, "b")
+	s.relay()
+	if msg := getOutboxMessage(t, s, flowTask.Type()+":order-1"); msg.Status != constant.OUTBOX_STATUS_PENDING {
+		t.Fatalf("relayed without lock: %+v", msg)
+	}
+	mr.Del(constant.REDIS_KEY_OUTBOX_RELAY_LOCK)
+
+	s.relay()
+	for _, key := range []string{"order-1", "order-2"} {
+		msg := getOutboxMessage(t, s, flowTask.Type()+":"+key)
+		if msg.Status != constant.OUTBOX_STATUS_PUBLISHED || msg.Attempts != 1 || msg.PublishTime == 0 {
+			t.Fatalf("%s after relay = %+v", key, msg)
+		}
+	}
+	if msg := getOutboxMessage(t, s, flowTask.Type()+":order-3"); msg.Status != constant.OUTBOX_STATUS_PENDING {
+		t.Fatalf("future message relayed: %+v", msg)
+	}
+	if _, err := mq.Enqueue(flowTask, asynq.TaskID(flowTask.Type()+":order-1")); !errors.Is(err, asynq.ErrTaskIDConflict) {
+		t.Fatalf("enqueue order-1 again err = %v, want ErrTaskIDConflict", err)
+	}
+	entries, err := mr.Stream(repository.EventStreamKey(event.NameBonusGranted))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("bonus stream entries = %d %v, want 1", len(entries), err)
+	}
+	if mr.Exists(constant.REDIS_KEY_OUTBOX_RELAY_LOCK) {
+		t.Fatal("relay lock not released")
+	}
+}
+
+// 投递失败按次数退避 超过最大次数标记失败
+func TestOutboxService_publishRetry(t *testing.T) {
+	s, _ := newTestOutboxService(t)
+	db := s.Repo.DB
+	// 事件流的redis不可用
+	down := miniredis.RunT(t)
+	s.EventSrv.Repo.RDS = redis.NewClient(&redis.Options{Addr: down.Addr(), MaxRetries: -1})
+	down.Close()
+
+	bonus := &event.BonusGranted{UID: 1, FlowType: constant.FLOW_TYPE_CASHBACK, SourceID: "1", Amount: 10}
+	if err := event.PublishTx(db, bonus); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.Repo.GetPendingOutboxMessages(time.Now().Unix(), 10)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("pending = %d %v, want 1", len(list), err)
+	}
+	msg := list[0]
+
+	now := time.Now().Unix()
+	s.publish(msg)
+	saved := getOutboxMessage(t, s, msg.DedupKey)
+	if saved.Status != constant.OUTBOX_STATUS_PENDING || saved.Attempts != 1 || saved.NextTime < now+1 || saved.Error == "" {
+		t.Fatalf("after first failure = %+v", saved)
+	}
+
+	saved.Attempts = constant.OUTBOX_MAX_ATTEMPTS - 1
+	s.publish(saved)
+	saved = getOutboxMessage(t, s, msg.DedupKey)
+	if saved.Status != constant.OUTBOX_STATUS_FAILED || saved.Attempts != constant.OUTBOX_MAX_ATTEMPTS || saved.NextTime > now+constant.OUTBOX_MAX_BACKOFF+1 {
+		t.Fatalf("after last failure = %+v", saved)
+	}
+}
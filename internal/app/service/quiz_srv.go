@@ -157,6 +157,10 @@ func (s *QuizService) CreateCrashGameOrder(order *entities.QuizBuyRecord) error
 		return err
 	}
 
+	if err := s.WalletSrv.CheckBetLimit(order.UID, order.PayMoney); err != nil {
+		return err
+	}
+
 	wallet, err := s.WalletSrv.GetWallet(order.UID)
 	if err != nil {
 		return err
@@ -179,6 +183,9 @@ func (s *QuizService) CreateCrashGameOrder(order *entities.QuizBuyRecord) error
 		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
 			return err
 		}
+		if err := s.WalletSrv.ConsumeTurnoverWithTx(tx, order.UID, order.PayMoney); err != nil { //扣减打码量
+			return err
+		}
 
 		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
 			UID:          order.UID,
@@ -187,8 +194,8 @@ func (s *QuizService) CreateCrashGameOrder(order *entities.QuizBuyRecord) error
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 		logger.ZInfo("CreateCrashGameOrder", zap.Any("order", order))
 		return nil
@@ -11,19 +11,15 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
-	"rk-api/internal/app/mq"
-	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
 	resty "rk-api/pkg/http"
 	"rk-api/pkg/logger"
-	"rk-api/pkg/structure"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 var R8ServiceSet = wire.NewSet(
@@ -31,28 +27,31 @@ var R8ServiceSet = wire.NewSet(
 )
 
 type R8Service struct {
-	Repo      *repository.R8Repository
-	UserSrv   *UserService
-	WalletSrv *WalletService
-	appID     string
-	apiUrl    string
-	appKey    string
+	Repo        *repository.R8Repository
+	UserSrv     *UserService
+	WalletSrv   *WalletService
+	SeamlessSrv *SeamlessWalletService
+	appID       string
+	apiUrl      string
+	appKey      string
 }
 
 func ProvideR8Service(repo *repository.R8Repository,
 	userSrv *UserService,
 	walletSrv *WalletService,
+	seamlessSrv *SeamlessWalletService,
 ) *R8Service {
 	setting := config.Get().R8Setting
 	logger.ZInfo("ProvideR8Service", zap.Any("setting", setting))
 	// 返回你的RechargeService实例
 	return &R8Service{
-		Repo:      repo,
-		UserSrv:   userSrv,
-		WalletSrv: walletSrv,
-		appID:     setting.AppID,
-		appKey:    setting.AppKey,
-		apiUrl:    setting.ApiUrl,
+		Repo:        repo,
+		UserSrv:     userSrv,
+		WalletSrv:   walletSrv,
+		SeamlessSrv: seamlessSrv,
+		appID:       setting.AppID,
+		appKey:      setting.AppKey,
+		apiUrl:      setting.ApiUrl,
 	}
 }
 
@@ -150,8 +149,8 @@ func (s *R8Service) FetchBalance(account string) gin.H {
 			"msg":  "无效的用户ID",
 		}
 	}
-	wallet, err := s.WalletSrv.GetWallet(uid)
-	if wallet == nil || err != nil {
+	balance, err := s.SeamlessSrv.Balance(uid)
+	if err != nil {
 		return gin.H{
 			"code": 22006,
 			"msg":  "單一錢包玩家不存在",
@@ -161,7 +160,7 @@ func (s *R8Service) FetchBalance(account string) gin.H {
 		"code": 0,
 		"msg":  "Success",
 		"data": gin.H{
-			"balance": wallet.Cash,
+			"balance": balance,
 		},
 	}
 }
@@ -194,51 +193,25 @@ func (s *R8Service) AwardActivity(req *entities.R8Activity) gin.H {
 		return gin.H{"code": 22007, "msg": "Invalid username"}
 	}
 
-	wallet, err := s.WalletSrv.GetWallet(uid)
-	if wallet == nil || err != nil {
-		return gin.H{"code": 22006, "msg": "单一钱包玩家不存在"}
-	}
-	order, err := s.Repo.GetActivityOrderByAwardID(req.AwardID)
+	result, err := s.SeamlessSrv.Credit(&entities.SeamlessTxReq{
+		Provider:   constant.SEAMLESS_PROVIDER_R8,
+		ExternalID: "award:" + req.AwardID,
+		UID:        uid,
+		Amount:     req.Money,
+		RecordID:   req.EventID,
+		FlowType:   constant.FLOW_TYPE_R8_ACTIVITY_AWARD,
+		Remark:     fmt.Sprintf("Rich88 %s %s", req.ActivityType, req.Action),
+	})
 	if err != nil {
+		if errors.IsCode(err, errors.WalletNotExist) {
+			return gin.H{"code": 22006, "msg": "单一钱包玩家不存在"}
+		}
 		return gin.H{"code": 22008, "msg": "單⼀錢包平台發⽣錯誤"}
 	}
-	if order != nil {
+	if result.Duplicate {
 		return gin.H{"code": 22008, "msg": "奖励单已经存在"}
 	}
 
-	orderForUpdate := new(entities.R8ActivityOrder)
-	structure.Copy(req, orderForUpdate)
-
-	err = s.WalletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
-		if err := s.Repo.CreateActivityOrderWithTx(tx, orderForUpdate); err != nil {
-			return err
-		}
-		wallet.SafeAdjustCash(req.Money)
-
-		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
-			tx.Rollback()
-			return err
-		}
-
-		remark := fmt.Sprintf("Rich88 %s %s", req.ActivityType, req.Action)
-
-		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
-			UID:          wallet.ID,
-			FlowType:     constant.FLOW_TYPE_R8_ACTIVITY_AWARD,
-			Number:       req.Money,
-			Balance:      wallet.Cash,
-			Remark:       remark,
-			PromoterCode: wallet.PromoterCode,
-		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
-		}
-		return nil
-	})
-	if err != nil {
-		return gin.H{"code": 22008, "msg": "單⼀錢包平台發⽣錯誤"}
-	}
-
 	return gin.H{"code": 0, "msg": "Success"}
 
 }
@@ -250,79 +223,60 @@ func (s *R8Service) Transfer(req *entities.R8Transfer) gin.H {
 		return gin.H{"code": 22007, "msg": "Invalid username"}
 	}
 
-	wallet, err := s.WalletSrv.GetUserWallet(uid)
-	if wallet == nil || err != nil {
-		return gin.H{"code": 22006, "msg": "单一钱包玩家不存在"}
-	}
-
 	logger.ZInfo("r8 transfer", zap.Any("req", req))
 
-	order, err := s.Repo.GetOrderByTransferNo(req.TransferNo, req.Action)
-	if err != nil {
-		logger.Error("----------------------------------err-----------------")
-		return gin.H{"code": 22008, "msg": "單⼀錢包平台發⽣錯誤"}
-	}
-	if order != nil {
-		logger.Error("----------------------------------exist-----------------")
-		return gin.H{"code": 22008, "msg": "transferNo+action exist"}
+	txReq := &entities.SeamlessTxReq{
+		Provider:   constant.SEAMLESS_PROVIDER_R8,
+		ExternalID: req.TransferNo + ":" + req.Action,
+		UID:        uid,
+		Amount:     math.Abs(req.Money),
+		GameCode:   req.GameCode,
+		RoundID:    req.RoundID,
+		RecordID:   req.RecordID,
+		Remark:     fmt.Sprintf("Rich88 %s %s", req.GameCode, req.Action),
 	}
 
-	if req.Action == "withdraw" {
-		req.FlowType = constant.FLOW_TYPE_R8_WITHDRAW
+	var result *entities.SeamlessTxResult
+	switch req.Action {
+	case "withdraw":
+		txReq.FlowType = constant.FLOW_TYPE_R8_WITHDRAW
 		if req.GameCode == "PokDeng" {
-			req.FlowType = constant.FLOW_TYPE_R8_WITHDRAW_POKDEN
+			txReq.FlowType = constant.FLOW_TYPE_R8_WITHDRAW_POKDEN
 		}
-		if wallet.Cash-math.Abs(req.Money) < 0 {
-			return gin.H{"code": 22007, "msg": "單⼀錢包玩家⾦錢不⾜"}
+		result, err = s.SeamlessSrv.Debit(txReq)
+	case "deposit": //派彩关联本局的下注 下注标记为已结算
+		txReq.FlowType = constant.FLOW_TYPE_R8_DEPOSIT
+		if txReq.RefExternalID, err = s.SeamlessSrv.OpenDebitExternalID(txReq.Provider, uid, req.RoundID, req.RecordID, 0); err != nil {
+			return gin.H{"code": 22008, "msg": "單⼀錢包平台發⽣錯誤"}
 		}
-		req.Money = -req.Money
-	} else if req.Action == "rollback" {
-		req.FlowType = constant.FLOW_TYPE_R8_ROLLBACK
-	} else if req.Action == "deposit" {
-		req.FlowType = constant.FLOW_TYPE_R8_DEPOSIT
-	}
-
-	orderForUpdate := entities.R8TransferOrder{
-		UID:        wallet.UID,
-		Amount:     req.Money,
-		RoundID:    req.RoundID,
-		RecordID:   req.RecordID,
-		TransferNo: req.TransferNo,
-		GameCode:   req.GameCode,
-		Action:     req.Action,
+		result, err = s.SeamlessSrv.Credit(txReq)
+	case "rollback": //按局号/注单号找到原扣款 退回原扣款金额
+		txReq.FlowType = constant.FLOW_TYPE_R8_ROLLBACK
+		result, err = s.SeamlessSrv.Rollback(txReq)
+	default:
+		return gin.H{"code": 22008, "msg": "Invalid action"}
 	}
-
-	err = s.WalletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
-
-		if err := s.Repo.CreateOrderWithTx(tx, &orderForUpdate); err != nil {
-			return err
+	if err != nil {
+		if errors.IsCode(err, errors.WalletNotExist) {
+			return gin.H{"code": 22006, "msg": "单一钱包玩家不存在"}
 		}
-
-		wallet.SafeAdjustCash(req.Money)
-
-		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
-			return err
+		if errors.IsCode(err, errors.InsufficientBalance) {
+			return gin.H{"code": 22007, "msg": "單⼀錢包玩家⾦錢不⾜"}
 		}
-
-		remark := fmt.Sprintf("Rich88 %s %s", req.GameCode, req.Action)
-
-		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
-			UID:          wallet.ID,
-			FlowType:     uint16(req.FlowType),
-			Number:       req.Money,
-			Balance:      wallet.Cash,
-			Remark:       remark,
-			PromoterCode: wallet.PromoterCode,
-		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if errors.IsCode(err, errors.SeamlessTxNotExist) {
+			return gin.H{"code": 22008, "msg": "rollback original transfer not found"}
+		}
+		if errors.IsCode(err, errors.SeamlessTxRolledBack) || errors.IsCode(err, errors.SeamlessTxSettled) {
+			return gin.H{"code": 22008, "msg": "original transfer already rolled back or settled"}
+		}
+		if errors.IsCode(err, errors.SeamlessTxAmbiguous) {
+			return gin.H{"code": 22008, "msg": "multiple open transfers in round, original transferNo required"}
 		}
-		return nil
-	})
-
-	if err != nil {
 		return gin.H{"code": 22008, "msg": "單⼀錢包平台發⽣錯誤"}
 	}
+	if result.Duplicate {
+		return gin.H{"code": 22008, "msg": "transferNo+action exist"}
+	}
 
 	return gin.H{"code": 0, "msg": "Success"}
 }
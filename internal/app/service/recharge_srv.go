@@ -7,10 +7,12 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/pay"
 	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
 	"rk-api/pkg/logger"
 	"sync"
 	"time"
@@ -76,6 +78,7 @@ func ProvideRechargeService(repo *repository.RechargeRepository,
 		FlowSrv:             flowSrv,
 		AgentSrv:            agentSrv,
 		walletSrv:           walletSrv,
+		WalletSrv:           walletSrv,
 		rechargeImplMap:     rechargeImplMap,
 		channelSettingCache: channelSettingCache,
 	}
@@ -177,6 +180,9 @@ func (s *RechargeService) GetRechargeUrlInfo(param *entities.GetRechargeUrlReq)
 	if param.Cash < RECHARGE_CASH_MIN {
 		return nil, errors.WithCode(errors.MinRechargeCashLimit)
 	}
+	if err := s.WalletSrv.CheckDepositLimit(param.UID, param.Cash); err != nil {
+		return nil, err
+	}
 	config, err := s.Repo.GetRechargeSetting(&entities.RechargeSetting{RechargeState: 1, Name: param.Name})
 
 	if err != nil {
@@ -377,6 +383,17 @@ func (s *RechargeService) HandleBusiAfterTradeSucc(order *entities.RechargeOrder
 		if err := s.Repo.CreateCompletedRechargeWithTx(tx, &completedRecharge); err != nil { //加入已完成充值表
 			return err
 		}
+		depositCount, err := s.Repo.GetCompletedRechargeCountWithTx(tx, order.UID)
+		if err != nil {
+			return err
+		}
+		todayCount, err := s.Repo.GetTodayCompletedRechargeCountWithTx(tx, order.UID, completedRecharge.TodayTime)
+		if err != nil {
+			return err
+		}
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, order.UID, constant.FLOW_TYPE_RECHARGE_CASH, order.OrderID, order.TotalAmount); err != nil {
+			return err
+		}
 
 		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
 			UID:          order.UID,
@@ -386,11 +403,19 @@ func (s *RechargeService) HandleBusiAfterTradeSucc(order *entities.RechargeOrder
 			PromoterCode: wallet.PromoterCode,
 		})
 
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 
-		return nil
+		return event.PublishTx(tx, &event.DepositSucceeded{ //充值优惠/VIP/通知等由订阅者处理
+			UID:          order.UID,
+			OrderID:      order.OrderID,
+			Amount:       order.TotalAmount,
+			Channel:      order.Channel,
+			PromoterCode: wallet.PromoterCode,
+			DepositCount: depositCount,
+			TodayCount:   todayCount,
+		})
 	})
 	if err != nil {
 		return err
@@ -433,3 +458,272 @@ func (s *RechargeService) HandleBusiAfterTradeSucc(order *entities.RechargeOrder
 
 	return nil
 }
+
+// 默认规则 来自原先写死的代理充值返利档位, 默认开启 保持原有返利 由后台关闭或调整
+func defaultRechargePromotions() []*entities.RechargePromotion {
+	tiers := []struct {
+		min   float64
+		bonus float64
+	}{
+		{100000, 20000}, {50000, 5500}, {10000, 1500}, {5000, 1000},
+		{3000, 600}, {1000, 300}, {500, 150}, {200, 20},
+	}
+	list := make([]*entities.RechargePromotion, 0, len(tiers))
+	for i, tier := range tiers {
+		list = append(list, &entities.RechargePromotion{
+			Name:        fmt.Sprintf("代理首充返利%.0f", tier.min),
+			Trigger:     constant.RECHARGE_PROMOTION_TRIGGER_FIRST,
+			Beneficiary: constant.RECHARGE_PROMOTION_BENEFICIARY_INVITER,
+			BonusType:   constant.RECHARGE_PROMOTION_BONUS_FIXED,
+			BonusValue:  tier.bonus,
+			MinAmount:   tier.min,
+			Priority:    len(tiers) - i,
+			Exclusive:   1, //只取命中的最高档
+			Status:      1,
+		})
+	}
+	return list
+}
+
+// 订阅充值到账事件 计算并发放充值优惠
+// 事件和充值在同一事务写入发件箱 发放失败时返回错误 事件不确认 稍后重新投递
+// 充值次数来自事件 重复投递时已发放的规则直接跳过
+func (s *RechargeService) ApplyRechargePromotions(evt *event.DepositSucceeded) error {
+	now := time.Now()
+	list, err := s.Repo.GetActiveRechargePromotionList(now.Unix())
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	user, err := s.UserSrv.GetUserByUID(evt.UID)
+	if err != nil {
+		return err
+	}
+	order := &entities.RechargeOrder{
+		UID:         evt.UID,
+		OrderID:     evt.OrderID,
+		TotalAmount: evt.Amount,
+	}
+	totalCount, todayCount := evt.DepositCount, evt.TodayCount
+
+	for _, promotion := range list {
+		granted, err := s.Repo.ExistRechargePromotionRecord(promotion.ID, order.OrderID)
+		if err != nil {
+			return err
+		}
+		if granted {
+			if promotion.Exclusive == 1 {
+				break
+			}
+			continue
+		}
+		matched, err := s.matchRechargePromotion(promotion, user, order, totalCount, todayCount, now)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+		bonus := promotion.CalculateBonus(order.TotalAmount)
+		if bonus <= 0 {
+			continue
+		}
+		if err := s.grantRechargePromotion(promotion, user, order, bonus); err != nil {
+			logger.ZError("grantRechargePromotion", zap.Uint("promotion", promotion.ID), zap.String("orderID", order.OrderID), zap.Error(err))
+			if errors.IsCode(err, errors.RechargePromotionBudgetExceeded) { //预算用完 重试也不会成功
+				continue
+			}
+			return err
+		}
+		logger.ZInfo("grantRechargePromotion", zap.Uint("promotion", promotion.ID), zap.String("orderID", order.OrderID), zap.Float64("bonus", bonus))
+		if promotion.Exclusive == 1 { //不再计算优先级更低的规则
+			break
+		}
+	}
+	return nil
+}
+
+func (s *RechargeService) matchRechargePromotion(promotion *entities.RechargePromotion, user *entities.User, order *entities.RechargeOrder, totalCount, todayCount int64, now time.Time) (bool, error) {
+	switch promotion.Trigger {
+	case constant.RECHARGE_PROMOTION_TRIGGER_FIRST:
+		if totalCount != 1 {
+			return false, nil
+		}
+	case constant.RECHARGE_PROMOTION_TRIGGER_NTH:
+		if totalCount != int64(promotion.NthDeposit) {
+			return false, nil
+		}
+	case constant.RECHARGE_PROMOTION_TRIGGER_DAILY:
+		if todayCount != 1 {
+			return false, nil
+		}
+	case constant.RECHARGE_PROMOTION_TRIGGER_EVERY:
+	default:
+		return false, nil
+	}
+
+	if order.TotalAmount < promotion.MinAmount {
+		return false, nil
+	}
+	if promotion.MaxAmount > 0 && order.TotalAmount > promotion.MaxAmount {
+		return false, nil
+	}
+	if promotion.PromoterCode != 0 && promotion.PromoterCode != user.PromoterCode {
+		return false, nil
+	}
+	if promotion.Channel != "" && promotion.Channel != user.Channel {
+		return false, nil
+	}
+	if promotion.NewUserDays > 0 && now.Unix()-user.CreatedAt > int64(promotion.NewUserDays)*24*3600 {
+		return false, nil
+	}
+	if promotion.UserLimit > 0 {
+		count, err := s.Repo.GetRechargePromotionRecordCount(promotion.ID, user.ID)
+		if err != nil {
+			return false, err
+		}
+		if count >= int64(promotion.UserLimit) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// 发放充值优惠 发放记录按(规则,订单)唯一, 重复回调不会重复发放
+func (s *RechargeService) grantRechargePromotion(promotion *entities.RechargePromotion, user *entities.User, order *entities.RechargeOrder, bonus float64) error {
+	record := &entities.RechargePromotionRecord{
+		PromotionID:  promotion.ID,
+		OrderID:      order.OrderID,
+		UID:          user.ID,
+		Amount:       order.TotalAmount,
+		Bonus:        bonus,
+		PromoterCode: user.PromoterCode,
+	}
+
+	createRecord := func(tx *gorm.DB) error {
+		ok, err := s.Repo.IncrRechargePromotionGrantedWithTx(tx, promotion.ID, bonus)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.WithCode(errors.RechargePromotionBudgetExceeded)
+		}
+		return s.Repo.CreateRechargePromotionRecordWithTx(tx, record)
+	}
+
+	if promotion.Beneficiary == constant.RECHARGE_PROMOTION_BENEFICIARY_INVITER { //上级返利 走代理充值返利
+		pid, err := s.AgentSrv.GetInviterUID(user.ID)
+		if err != nil {
+			return err
+		}
+		if pid == 0 {
+			return nil
+		}
+		record.BeneficiaryUID = pid
+		// 发放记录和上级入账同一事务 失败时都不落库 重试时可以重新发放
+		return s.WalletSrv.HandleWallet(pid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
+			if err := createRecord(tx); err != nil {
+				return err
+			}
+			return s.AgentSrv.CreateRechargeReturnWithTx(tx, wallet, user, order.TotalAmount, bonus, order.OrderID)
+		})
+	}
+
+	record.BeneficiaryUID = user.ID
+	return s.WalletSrv.HandleWallet(user.ID, func(wallet *entities.UserWallet, tx *gorm.DB) error {
+		if err := createRecord(tx); err != nil {
+			return err
+		}
+		wallet.SafeAdjustCash(bonus)
+		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
+			return err
+		}
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, user.ID, constant.FLOW_TYPE_RECHARGE_PROMOTION, order.OrderID, bonus); err != nil {
+			return err
+		}
+		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
+			UID:          user.ID,
+			FlowType:     constant.FLOW_TYPE_RECHARGE_PROMOTION,
+			Number:       bonus,
+			Balance:      wallet.Cash,
+			PromoterCode: wallet.PromoterCode,
+		})
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		// 同一笔充值可能命中多条规则 用发放记录区分
+		return event.PublishTx(tx, &event.BonusGranted{UID: user.ID, FlowType: constant.FLOW_TYPE_RECHARGE_PROMOTION, SourceID: fmt.Sprintf("%d", record.ID), Amount: bonus})
+	})
+}
+
+// 规则表为空时写入默认规则 启动时调用 保证上线后原有的代理返利不中断
+func (s *RechargeService) InitDefaultRechargePromotions() error {
+	count, err := s.Repo.GetRechargePromotionCount()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return s.Repo.CreateRechargePromotionList(defaultRechargePromotions())
+}
+
+func (s *RechargeService) GetRechargePromotionList(req *entities.GetRechargePromotionListReq) error {
+	return s.Repo.GetRechargePromotionList(req)
+}
+
+func (s *RechargeService) GetRechargePromotionRecordList(req *entities.GetRechargePromotionRecordListReq) error {
+	return s.Repo.GetRechargePromotionRecordList(req)
+}
+
+// 创建或修改充值优惠 ID为0时创建
+func (s *RechargeService) SaveRechargePromotion(req *entities.SaveRechargePromotionReq) (err error) {
+	promotion := &req.RechargePromotion
+
+	defer func() {
+		log := entities.SystemOptionLog{
+			Type:     constant.SYS_OPTION_TYPE_RECHARGE_PROMOTION,
+			OptionID: req.OptionID,
+			IP:       req.IP,
+			Content:  cjson.StringifyIgnore(promotion),
+		}
+		if err != nil {
+			log.Result = "false"
+			log.Remark = "充值优惠规则保存失败"
+		} else {
+			log.Result = "true"
+			log.Remark = "充值优惠规则保存成功"
+		}
+		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+		if err := mq.Publish(optionLogQueue); err != nil {
+			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+		}
+	}()
+
+	if promotion.Trigger < constant.RECHARGE_PROMOTION_TRIGGER_FIRST || promotion.Trigger > constant.RECHARGE_PROMOTION_TRIGGER_EVERY ||
+		(promotion.Trigger == constant.RECHARGE_PROMOTION_TRIGGER_NTH && promotion.NthDeposit <= 0) ||
+		(promotion.Beneficiary != constant.RECHARGE_PROMOTION_BENEFICIARY_SELF && promotion.Beneficiary != constant.RECHARGE_PROMOTION_BENEFICIARY_INVITER) ||
+		(promotion.BonusType != constant.RECHARGE_PROMOTION_BONUS_PERCENT && promotion.BonusType != constant.RECHARGE_PROMOTION_BONUS_FIXED) ||
+		promotion.BonusValue <= 0 ||
+		(promotion.EndTime > 0 && promotion.EndTime < promotion.StartTime) {
+		return errors.WithCode(errors.InvalidParam)
+	}
+
+	if promotion.ID == 0 {
+		promotion.GrantedAmount = 0
+		return s.Repo.CreateRechargePromotion(promotion)
+	}
+
+	exist, err := s.Repo.GetRechargePromotionByID(promotion.ID)
+	if err != nil {
+		return err
+	}
+	if exist == nil {
+		return errors.WithCode(errors.RechargePromotionNotExist)
+	}
+	promotion.CreatedAt = exist.CreatedAt
+	return s.Repo.UpdateRechargePromotion(promotion)
+}
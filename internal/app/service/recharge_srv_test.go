@@ -0,0 +1,107 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+)
+
+func newTestRechargeService(t *testing.T) *RechargeService {
+	db := newTestDB(t, new(entities.RechargePromotion), new(entities.RechargePromotionRecord))
+	return &RechargeService{Repo: &repository.RechargeRepository{DB: db}}
+}
+
+func TestRechargeService_matchRechargePromotion(t *testing.T) {
+	s := newTestRechargeService(t)
+	now := time.Now()
+	user := &entities.User{ID: 1, PromoterCode: 8, Channel: "google", CreatedAt: now.Unix() - 2*24*3600}
+	if err := s.Repo.DB.Create(&entities.RechargePromotionRecord{PromotionID: 9, OrderID: "old", UID: user.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	first := func(p *entities.RechargePromotion) *entities.RechargePromotion {
+		p.Trigger = constant.RECHARGE_PROMOTION_TRIGGER_FIRST
+		return p
+	}
+	tests := []struct {
+		name       string
+		promotion  *entities.RechargePromotion
+		amount     float64
+		totalCount int64
+		todayCount int64
+		want       bool
+	}{
+		{name: "first deposit", promotion: first(&entities.RechargePromotion{}), amount: 100, totalCount: 1, todayCount: 1, want: true},
+		{name: "second deposit not first", promotion: first(&entities.RechargePromotion{}), amount: 100, totalCount: 2, todayCount: 1, want: false},
+		{name: "nth deposit", promotion: &entities.RechargePromotion{Trigger: constant.RECHARGE_PROMOTION_TRIGGER_NTH, NthDeposit: 3}, amount: 100, totalCount: 3, todayCount: 2, want: true},
+		{name: "nth deposit miss", promotion: &entities.RechargePromotion{Trigger: constant.RECHARGE_PROMOTION_TRIGGER_NTH, NthDeposit: 3}, amount: 100, totalCount: 4, todayCount: 2, want: false},
+		{name: "daily first", promotion: &entities.RechargePromotion{Trigger: constant.RECHARGE_PROMOTION_TRIGGER_DAILY}, amount: 100, totalCount: 5, todayCount: 1, want: true},
+		{name: "daily second", promotion: &entities.RechargePromotion{Trigger: constant.RECHARGE_PROMOTION_TRIGGER_DAILY}, amount: 100, totalCount: 6, todayCount: 2, want: false},
+		{name: "every deposit", promotion: &entities.RechargePromotion{Trigger: constant.RECHARGE_PROMOTION_TRIGGER_EVERY}, amount: 100, totalCount: 7, todayCount: 3, want: true},
+		{name: "unknown trigger", promotion: &entities.RechargePromotion{Trigger: 99}, amount: 100, totalCount: 1, todayCount: 1, want: false},
+		{name: "below min", promotion: first(&entities.RechargePromotion{MinAmount: 200}), amount: 199.99, totalCount: 1, todayCount: 1, want: false},
+		{name: "above max", promotion: first(&entities.RechargePromotion{MaxAmount: 500}), amount: 500.01, totalCount: 1, todayCount: 1, want: false},
+		{name: "promoter code", promotion: first(&entities.RechargePromotion{PromoterCode: 7}), amount: 100, totalCount: 1, todayCount: 1, want: false},
+		{name: "channel", promotion: first(&entities.RechargePromotion{Channel: "facebook"}), amount: 100, totalCount: 1, todayCount: 1, want: false},
+		{name: "new user in days", promotion: first(&entities.RechargePromotion{NewUserDays: 3}), amount: 100, totalCount: 1, todayCount: 1, want: true},
+		{name: "new user expired", promotion: first(&entities.RechargePromotion{NewUserDays: 1}), amount: 100, totalCount: 1, todayCount: 1, want: false},
+		{name: "user limit reached", promotion: &entities.RechargePromotion{BaseModel: entities.BaseModel{ID: 9}, Trigger: constant.RECHARGE_PROMOTION_TRIGGER_EVERY, UserLimit: 1}, amount: 100, totalCount: 2, todayCount: 1, want: false},
+		{name: "user limit left", promotion: &entities.RechargePromotion{BaseModel: entities.BaseModel{ID: 9}, Trigger: constant.RECHARGE_PROMOTION_TRIGGER_EVERY, UserLimit: 2}, amount: 100, totalCount: 2, todayCount: 1, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &entities.RechargeOrder{UID: user.ID, OrderID: "o1", TotalAmount: tt.amount}
+			got, err := s.matchRechargePromotion(tt.promotion, user, order, tt.totalCount, tt.todayCount, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("matchRechargePromotion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRechargePromotion_CalculateBonus(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion *entities.RechargePromotion
+		amount    float64
+		want      float64
+	}{
+		{name: "percent", promotion: &entities.RechargePromotion{BonusType: constant.RECHARGE_PROMOTION_BONUS_PERCENT, BonusValue: 10}, amount: 333.33, want: 33.33},
+		{name: "percent capped", promotion: &entities.RechargePromotion{BonusType: constant.RECHARGE_PROMOTION_BONUS_PERCENT, BonusValue: 50, MaxBonus: 100}, amount: 1000, want: 100},
+		{name: "fixed", promotion: &entities.RechargePromotion{BonusType: constant.RECHARGE_PROMOTION_BONUS_FIXED, BonusValue: 20}, amount: 1000, want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.promotion.CalculateBonus(tt.amount); got != tt.want {
+				t.Fatalf("CalculateBonus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 默认规则启动时写入 开启状态 只写一次
+func TestRechargeService_InitDefaultRechargePromotions(t *testing.T) {
+	s := newTestRechargeService(t)
+	for i := 0; i < 2; i++ {
+		if err := s.InitDefaultRechargePromotions(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := s.Repo.GetActiveRechargePromotionList(time.Now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := defaultRechargePromotions()
+	if len(list) != len(want) {
+		t.Fatalf("active promotions = %d, want %d", len(list), len(want))
+	}
+	// 按优先级排列 命中的最高档独占
+	if list[0].MinAmount != 100000 || list[0].Exclusive != 1 {
+		t.Fatalf("first promotion = %+v", list[0])
+	}
+}
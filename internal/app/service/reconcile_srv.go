@@ -0,0 +1,263 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/service/repository"
+	"rk-api/internal/app/telegram"
+	"rk-api/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+)
+
+const (
+	reconcileWindow       = time.Hour
+	reconcileLag          = time.Hour        // 等三方投注记录同步完成后再对账
+	reconcileMargin       = 10 * time.Minute // 转账和投注记录的时间可能跨窗口
+	reconcileTolerance    = 0.01
+	reconcileNotifyDetail = 10 // telegram消息里最多列出的差异条数
+)
+
+var ReconcileServiceSet = wire.NewSet(
+	ProvideReconcileService,
+)
+
+// 各三方的投注记录来源 以及能核对的字段
+type reconcileSource struct {
+	provider    string
+	checkBet    bool // 核对投注额: 扣款-回滚
+	checkProfit bool // 核对输赢: 加款+回滚-扣款
+	load        func(startTime, endTime int64) ([]*entities.ReconcileBetRecord, error)
+}
+
+// 同一注单的转账合计
+type reconcileGroup struct {
+	uid      uint
+	recordID string
+	roundID  string
+	debit    float64
+	credit   float64
+	rollback float64
+	first    int64
+}
+
+type ReconcileService struct {
+	Repo    *repository.ReconcileRepository
+	sources []*reconcileSource
+}
+
+func ProvideReconcileService(repo *repository.ReconcileRepository) *ReconcileService {
+	return &ReconcileService{
+		Repo: repo,
+		sources: []*reconcileSource{
+			{provider: constant.SEAMLESS_PROVIDER_ZF, checkBet: true, load: repo.GetZfBetRecordsInRange},
+			{provider: constant.SEAMLESS_PROVIDER_R8, checkBet: true, load: repo.GetR8BetRecordsInRange},
+			{provider: constant.SEAMLESS_PROVIDER_JHSZ, checkProfit: true, load: gameRecordLoader(repo, constant.SEAMLESS_PROVIDER_JHSZ)},
+		},
+	}
+}
+
+// 统计同步导入到game_record的投注记录
+func gameRecordLoader(repo *repository.ReconcileRepository, provider string) func(startTime, endTime int64) ([]*entities.ReconcileBetRecord, error) {
+	return func(startTime, endTime int64) ([]*entities.ReconcileBetRecord, error) {
+		return repo.GetGameRecordsInRange(provider, startTime, endTime)
+	}
+}
+
+func (s *ReconcileService) GetReconcileReportList(req *entities.GetReconcileReportListReq) error {
+	return s.Repo.GetReconcileReportList(req)
+}
+
+func (s *ReconcileService) GetReconcileDiscrepancyList(req *entities.GetReconcileDiscrepancyListReq) error {
+	return s.Repo.GetReconcileDiscrepancyList(req)
+}
+
+// 定时任务 对账一个小时前的完整小时
+func (s *ReconcileService) ReconcileProviderTransfers() {
+	endTime := time.Now().Add(-reconcileLag).Truncate(reconcileWindow)
+	startTime := endTime.Add(-reconcileWindow)
+	for _, source := range s.sources {
+		report, list, err := s.reconcile(source, startTime.Unix(), endTime.Unix())
+		if err != nil {
+			logger.ZError("ReconcileProviderTransfers", zap.String("provider", source.provider), zap.Error(err))
+			continue
+		}
+		if report != nil && report.Status != constant.RECONCILE_STATUS_OK {
+			telegram.SendToManage(s.reportMessage(report, list))
+		}
+	}
+}
+
+func (s *ReconcileService) reconcile(source *reconcileSource, startTime, endTime int64) (*entities.ReconcileReport, []*entities.ReconcileDiscrepancy, error) {
+	report, err := s.Repo.GetReconcileReport(source.provider, startTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	if report != nil { //已经对过
+		return nil, nil, nil
+	}
+
+	report = &entities.ReconcileReport{
+		Provider:  source.provider,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+	if err := s.Repo.CreateReconcileReport(report); err != nil {
+		return nil, nil, err
+	}
+
+	list, err := s.compare(source, report)
+	if err != nil {
+		report.Status = constant.RECONCILE_STATUS_FAILED
+		report.Error = errors.TruncateError(err, 255)
+	} else {
+		report.Discrepancies = len(list)
+		report.Status = constant.RECONCILE_STATUS_OK
+		if len(list) > 0 {
+			report.Status = constant.RECONCILE_STATUS_DISCREPANCY
+		}
+		for _, item := range list {
+			item.ReportID = report.ID
+		}
+		if err := s.Repo.CreateReconcileDiscrepancyList(list); err != nil {
+			logger.ZError("CreateReconcileDiscrepancyList", zap.Uint("reportID", report.ID), zap.Error(err))
+		}
+	}
+	if err := s.Repo.UpdateReconcileReport(report); err != nil {
+		logger.ZError("UpdateReconcileReport", zap.Uint("reportID", report.ID), zap.Error(err))
+	}
+	logger.ZInfo("reconcile", zap.String("provider", source.provider), zap.Int64("start", startTime),
+		zap.Int("transfers", report.Transfers), zap.Int("records", report.Records), zap.Int("discrepancies", report.Discrepancies))
+	return report, list, nil
+}
+
+// 按 uid+注单号 合并转账和投注记录后逐条比较
+// 窗口前后各多取一段 只统计首笔转账/投注时间落在窗口内的注单
+func (s *ReconcileService) compare(source *reconcileSource, report *entities.ReconcileReport) ([]*entities.ReconcileDiscrepancy, error) {
+	margin := int64(reconcileMargin / time.Second)
+	transactions, err := s.Repo.GetSeamlessTransactionsInRange(source.provider, report.StartTime-margin, report.EndTime+margin)
+	if err != nil {
+		return nil, err
+	}
+	records, err := source.load(report.StartTime-margin, report.EndTime+margin)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*reconcileGroup)
+	for _, transaction := range transactions {
+		key := fmt.Sprintf("%d:%s", transaction.UID, transaction.RecordID)
+		group, ok := groups[key]
+		if !ok {
+			group = &reconcileGroup{uid: transaction.UID, recordID: transaction.RecordID, roundID: transaction.RoundID, first: transaction.CreatedAt}
+			groups[key] = group
+		}
+		switch transaction.Action {
+		case constant.SEAMLESS_ACTION_DEBIT:
+			group.debit -= transaction.Amount
+		case constant.SEAMLESS_ACTION_CREDIT:
+			group.credit += transaction.Amount
+		case constant.SEAMLESS_ACTION_ROLLBACK:
+			group.rollback += transaction.Amount
+		}
+	}
+
+	recordMap := make(map[string]*entities.ReconcileBetRecord)
+	for _, record := range records {
+		key := fmt.Sprintf("%d:%s", record.UID, record.RecordID)
+		if exist, ok := recordMap[key]; ok {
+			exist.BetAmount += record.BetAmount
+			exist.Profit += record.Profit
+			continue
+		}
+		recordMap[key] = &entities.ReconcileBetRecord{UID: record.UID, RecordID: record.RecordID, BetAmount: record.BetAmount, Profit: record.Profit, Time: record.Time}
+	}
+
+	list := make([]*entities.ReconcileDiscrepancy, 0)
+	newDiscrepancy := func(group *reconcileGroup, record *entities.ReconcileBetRecord, typ, remark string) {
+		item := &entities.ReconcileDiscrepancy{Provider: source.provider, Type: typ, Remark: remark}
+		if group != nil {
+			item.UID, item.RecordID, item.RoundID = group.uid, group.recordID, group.roundID
+			item.DebitAmount, item.CreditAmount, item.RollbackAmount = group.debit, group.credit, group.rollback
+		}
+		if record != nil {
+			item.UID, item.RecordID = record.UID, record.RecordID
+			item.RecordBetAmount, item.RecordProfit = record.BetAmount, record.Profit
+		}
+		list = append(list, item)
+	}
+
+	for key, group := range groups {
+		if group.first < report.StartTime || group.first >= report.EndTime {
+			continue
+		}
+		report.Transfers++
+		bet := group.debit - group.rollback
+		record, ok := recordMap[key]
+		if !ok {
+			if bet > reconcileTolerance {
+				newDiscrepancy(group, nil, constant.RECONCILE_TYPE_ORPHAN_DEBIT, "")
+			} else if group.credit > reconcileTolerance {
+				newDiscrepancy(group, nil, constant.RECONCILE_TYPE_ORPHAN_CREDIT, "")
+			} else {
+				report.Matched++ //全部回滚
+			}
+			continue
+		}
+		if group.debit <= reconcileTolerance && group.credit > reconcileTolerance {
+			newDiscrepancy(group, record, constant.RECONCILE_TYPE_ORPHAN_CREDIT, "没有扣款")
+			continue
+		}
+		remarks := make([]string, 0, 2)
+		if source.checkBet && math.Abs(bet-record.BetAmount) > reconcileTolerance {
+			remarks = append(remarks, fmt.Sprintf("投注额 转账%.2f 记录%.2f", bet, record.BetAmount))
+		}
+		if profit := group.credit + group.rollback - group.debit; source.checkProfit && math.Abs(profit-record.Profit) > reconcileTolerance {
+			remarks = append(remarks, fmt.Sprintf("输赢 转账%.2f 记录%.2f", profit, record.Profit))
+		}
+		if len(remarks) > 0 {
+			newDiscrepancy(group, record, constant.RECONCILE_TYPE_AMOUNT_MISMATCH, strings.Join(remarks, ";"))
+			continue
+		}
+		report.Matched++
+	}
+
+	for key, record := range recordMap {
+		if record.Time < report.StartTime || record.Time >= report.EndTime {
+			continue
+		}
+		report.Records++
+		if _, ok := groups[key]; !ok {
+			newDiscrepancy(nil, record, constant.RECONCILE_TYPE_MISSING_TRANSFER, "")
+		}
+	}
+	return list, nil
+}
+
+func (s *ReconcileService) reportMessage(report *entities.ReconcileReport, list []*entities.ReconcileDiscrepancy) string {
+	var b strings.Builder
+	b.WriteString("<b>三方转账对账异常</b>\n")
+	b.WriteString(fmt.Sprintf("<pre>三方：%s</pre>\n", report.Provider))
+	b.WriteString(fmt.Sprintf("<pre>时间：%s ~ %s</pre>\n",
+		time.Unix(report.StartTime, 0).Format(constant.DateLayout), time.Unix(report.EndTime, 0).Format(constant.DateLayout)))
+	if report.Status == constant.RECONCILE_STATUS_FAILED {
+		b.WriteString(fmt.Sprintf("<pre>对账失败：%s</pre>\n", report.Error))
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("<pre>注单：%d 记录：%d 一致：%d 差异：%d</pre>\n", report.Transfers, report.Records, report.Matched, report.Discrepancies))
+	for i, item := range list {
+		if i >= reconcileNotifyDetail {
+			b.WriteString(fmt.Sprintf("<pre>... 还有%d条</pre>\n", len(list)-i))
+			break
+		}
+		b.WriteString(fmt.Sprintf("<pre>%s uid:%d 注单:%s 扣款:%.2f 加款:%.2f 回滚:%.2f %s</pre>\n",
+			item.Type, item.UID, item.RecordID, item.DebitAmount, item.CreditAmount, item.RollbackAmount, item.Remark))
+	}
+	return b.String()
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/plugin/dbresolver"
 )
 
@@ -102,6 +103,67 @@ func (r *ActivityRepository) CreateHongbaoRecordWithTx(tx *gorm.DB, entity *enti
 	return tx.Create(entity).Error
 }
 
+// 创建红包设置以及预分配的份额
+func (r *ActivityRepository) CreateHongbaoSettingWithShares(setting *entities.HongbaoSetting, shares []*entities.HongbaoShare) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(setting).Error; err != nil {
+			return err
+		}
+		if len(shares) == 0 {
+			return nil
+		}
+		for _, share := range shares {
+			share.HongID = setting.ID
+		}
+		return tx.CreateInBatches(shares, 200).Error
+	})
+}
+
+// 领取时锁定红包设置行 同一个红包的领取在所有节点间串行
+func (r *ActivityRepository) GetHongbaoSettingForUpdate(tx *gorm.DB, id uint) (*entities.HongbaoSetting, error) {
+	entity := new(entities.HongbaoSetting)
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? and status = 0", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *ActivityRepository) IncrHongbaoReceiveNumberWithTx(tx *gorm.DB, id uint) (bool, error) {
+	result := tx.Model(&entities.HongbaoSetting{}).Where("id = ? AND receive_number < number", id).
+		UpdateColumn("receive_number", gorm.Expr("receive_number + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *ActivityRepository) CountHongbaoRecordWithTx(tx *gorm.DB, hongID uint, uid uint) (int64, error) {
+	var count int64
+	err := tx.Model(&entities.HongbaoRecord{}).Where("hong_id = ? AND uid = ?", hongID, uid).Count(&count).Error
+	return count, err
+}
+
+func (r *ActivityRepository) GetHongbaoShareWithTx(tx *gorm.DB, hongID uint, seq uint) (*entities.HongbaoShare, error) {
+	entity := new(entities.HongbaoShare)
+	result := tx.Where("hong_id = ? AND seq = ?", hongID, seq).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// 用户累计充值金额
+func (r *ActivityRepository) GetRechargeTotal(uid uint) (float64, error) {
+	var total float64
+	err := r.DB.Model(&entities.CompletedRecharge{}).Where("uid = ?", uid).
+		Select("IFNULL(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
+
 func (r *ActivityRepository) CreatePinduoRecord(pinduoRecord *entities.PinduoRecord) error {
 	return r.DB.Create(pinduoRecord).Error
 }
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var AdminApprovalRepositorySet = wire.NewSet(wire.Struct(new(AdminApprovalRepository), "*"))
+
+type AdminApprovalRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+func (r *AdminApprovalRepository) CreateAdminApproval(entity *entities.AdminApproval) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *AdminApprovalRepository) GetAdminApprovalByID(id uint) (*entities.AdminApproval, error) {
+	entity := new(entities.AdminApproval)
+	result := r.DB.Clauses(dbresolver.Write).Where("id = ?", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// 待复核改为复核结果 返回false表示已被处理或已超时
+func (r *AdminApprovalRepository) CheckAdminApproval(id uint, status uint8, checkerID uint, ip, remark string) (bool, error) {
+	now := time.Now().Unix()
+	result := r.DB.Model(&entities.AdminApproval{}).
+		Where("id = ? AND status = ? AND expire_time > ?", id, constant.ADMIN_APPROVAL_STATUS_PENDING, now).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"checker_id": checkerID,
+			"checker_ip": ip,
+			"check_time": now,
+			"remark":     remark,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *AdminApprovalRepository) UpdateAdminApprovalResult(id uint, status uint8, result string) error {
+	return r.DB.Model(&entities.AdminApproval{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "result": result}).Error
+}
+
+// 超时的待复核单改为超时
+func (r *AdminApprovalRepository) ExpireAdminApprovals() error {
+	return r.DB.Model(&entities.AdminApproval{}).
+		Where("status = ? AND expire_time <= ?", constant.ADMIN_APPROVAL_STATUS_PENDING, time.Now().Unix()).
+		Update("status", constant.ADMIN_APPROVAL_STATUS_EXPIRED).Error
+}
+
+func (r *AdminApprovalRepository) GetAdminApprovalList(req *entities.GetAdminApprovalListReq) error {
+	tx := r.DB.Model(&entities.AdminApproval{})
+	if req.Status > 0 {
+		tx = tx.Where("status = ?", req.Status)
+	}
+	if req.Action != "" {
+		tx = tx.Where("action = ?", req.Action)
+	}
+	if req.MakerID > 0 {
+		tx = tx.Where("maker_id = ?", req.MakerID)
+	}
+	tx = tx.Order("id desc")
+	req.List = make([]*entities.AdminApproval, 0)
+	return req.Paginate(tx)
+}
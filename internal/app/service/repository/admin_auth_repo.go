@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var AdminAuthRepositorySet = wire.NewSet(wire.Struct(new(AdminAuthRepository), "*"))
+
+type AdminAuthRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+func (r *AdminAuthRepository) GetAdminRoleList() ([]*entities.AdminRole, error) {
+	list := make([]*entities.AdminRole, 0)
+	err := r.DB.Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *AdminAuthRepository) GetAdminRoleByID(id uint) (*entities.AdminRole, error) {
+	entity := new(entities.AdminRole)
+	result := r.DB.Clauses(dbresolver.Write).Where("id = ?", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *AdminAuthRepository) SaveAdminRole(entity *entities.AdminRole) error {
+	return r.DB.Save(entity).Error
+}
+
+func (r *AdminAuthRepository) DelAdminRole(id uint) error {
+	return r.DB.Where("id = ?", id).Delete(&entities.AdminRole{}).Error
+}
+
+func (r *AdminAuthRepository) CountAdminUserByRole(roleID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&entities.AdminUser{}).Where("role_id = ?", roleID).Count(&count).Error
+	return count, err
+}
+
+func (r *AdminAuthRepository) CountAdminUser() (int64, error) {
+	var count int64
+	err := r.DB.Clauses(dbresolver.Write).Model(&entities.AdminUser{}).Count(&count).Error
+	return count, err
+}
+
+func (r *AdminAuthRepository) GetAdminUserList() ([]*entities.AdminUser, error) {
+	list := make([]*entities.AdminUser, 0)
+	err := r.DB.Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *AdminAuthRepository) GetAdminUserByUsername(username string) (*entities.AdminUser, error) {
+	entity := new(entities.AdminUser)
+	result := r.DB.Clauses(dbresolver.Write).Where("username = ?", username).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *AdminAuthRepository) GetAdminUserByUID(uid uint) (*entities.AdminUser, error) {
+	entity := new(entities.AdminUser)
+	result := r.DB.Clauses(dbresolver.Write).Where("uid = ?", uid).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *AdminAuthRepository) GetAdminUserByID(id uint) (*entities.AdminUser, error) {
+	entity := new(entities.AdminUser)
+	result := r.DB.Clauses(dbresolver.Write).Where("id = ?", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *AdminAuthRepository) SaveAdminUser(entity *entities.AdminUser) error {
+	return r.DB.Save(entity).Error
+}
+
+// 首个账号和超级管理员角色一起创建
+func (r *AdminAuthRepository) CreateAdminUserWithRole(user *entities.AdminUser, role *entities.AdminRole) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		user.RoleID = role.ID
+		return tx.Create(user).Error
+	})
+}
+
+func (r *AdminAuthRepository) UpdateAdminUserLogin(id uint, ip string) error {
+	return r.DB.Model(&entities.AdminUser{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_login_time": time.Now().Unix(), "last_login_ip": ip}).Error
+}
+
+func (r *AdminAuthRepository) CreateAdminSession(entity *entities.AdminSession) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *AdminAuthRepository) GetActiveAdminSessionList(uid uint) ([]*entities.AdminSession, error) {
+	list := make([]*entities.AdminSession, 0)
+	err := r.DB.Clauses(dbresolver.Write).
+		Where("uid = ? AND status = ? AND expire_time > ?", uid, constant.USER_SESSION_STATUS_ACTIVE, time.Now().Unix()).
+		Order("id desc").Find(&list).Error
+	return list, err
+}
+
+func (r *AdminAuthRepository) GetAdminSessionList(req *entities.GetAdminSessionListReq) error {
+	tx := r.DB.Model(&entities.AdminSession{})
+	if req.UID > 0 {
+		tx = tx.Where("uid = ?", req.UID)
+	}
+	tx = tx.Order("id desc")
+	req.List = make([]*entities.AdminSession, 0)
+	return req.Paginate(tx)
+}
+
+func (r *AdminAuthRepository) RevokeAdminSession(uid uint, sid string, reason string) error {
+	err := r.DB.Model(&entities.AdminSession{}).
+		Where("session_id = ? AND uid = ? AND status = ?", sid, uid, constant.USER_SESSION_STATUS_ACTIVE).
+		Updates(map[string]interface{}{
+			"status":        constant.USER_SESSION_STATUS_REVOKED,
+			"revoke_reason": reason,
+			"revoke_time":   time.Now().Unix(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	pipe := r.RDS.TxPipeline()
+	pipe.Del(context.Background(), fmt.Sprintf(constant.REDIS_KEY_ADMIN_SESSION, sid))
+	pipe.SRem(context.Background(), fmt.Sprintf(constant.REDIS_KEY_ADMIN_SESSION_SET, uid), sid)
+	_, err = pipe.Exec(context.Background())
+	return err
+}
+
+// 会话缓存 AdminMiddleware每次请求都会校验 不存在即视为失效
+func (r *AdminAuthRepository) SetAdminSessionCache(sid string, uid uint, expiration time.Duration) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_ADMIN_SESSION, sid)
+	setKey := fmt.Sprintf(constant.REDIS_KEY_ADMIN_SESSION_SET, uid)
+	pipe := r.RDS.TxPipeline()
+	pipe.Set(context.Background(), key, uid, expiration)
+	pipe.SAdd(context.Background(), setKey, sid)
+	pipe.Expire(context.Background(), setKey, expiration)
+	_, err := pipe.Exec(context.Background())
+	return err
+}
+
+func (r *AdminAuthRepository) GetAdminSessionCache(sid string) (string, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_ADMIN_SESSION, sid)
+	val, err := r.RDS.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return val, err
+}
+
+func (r *AdminAuthRepository) GetAdminSessionIDs(uid uint) ([]string, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_ADMIN_SESSION_SET, uid)
+	return r.RDS.SMembers(context.Background(), key).Result()
+}
+
+// 登录失败计数 第一次失败时开始计时
+func (r *AdminAuthRepository) IncrAdminLoginFail(username string) (int64, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_ADMIN_LOGIN_FAIL, username)
+	count, err := r.RDS.Incr(context.Background(), key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		r.RDS.Expire(context.Background(), key, constant.ADMIN_LOGIN_FAIL_EXPIRE*time.Second)
+	}
+	return count, nil
+}
+
+func (r *AdminAuthRepository) GetAdminLoginFail(username string) (int64, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_ADMIN_LOGIN_FAIL, username)
+	count, err := r.RDS.Get(context.Background(), key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+func (r *AdminAuthRepository) DelAdminLoginFail(username string) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_ADMIN_LOGIN_FAIL, username)
+	return r.RDS.Del(context.Background(), key).Err()
+}
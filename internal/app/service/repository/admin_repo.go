@@ -64,47 +64,6 @@ func (r *AdminRepository) GetSysUserAdmin(id uint) (*entities.SysUserAdmin, erro
 	return &entity, nil
 }
 
-func (r *AdminRepository) CallMonthBackupAndCleanV3(backupMonth string, tableNames string) error {
-	// 创建一个结构体来存储可能的输出
-	type Result struct {
-		Message string
-	}
-	var results []Result
-
-	// 执行存储过程
-	err := r.DB.Raw("CALL MonthBackupAndCleanV3(?, ?)", backupMonth, tableNames).Scan(&results).Error
-	if err != nil {
-		return err
-	}
-
-	// 处理结果（如果有的话）
-	for _, result := range results {
-		logger.ZInfo(result.Message)
-	}
-
-	return nil
-}
-
-func (r *AdminRepository) CallMonthBackupAndCleanV4(tableNames string) error {
-	// 创建一个结构体来存储可能的输出
-	type Result struct {
-		Message string
-	}
-	var results []Result
-
-	// 执行存储过程
-	err := r.DB.Raw("CALL MonthBackupAndCleanV4(?)", tableNames).Scan(&results).Error
-	if err != nil {
-		return err
-	}
-
-	// 处理结果（如果有的话）
-	for _, result := range results {
-		logger.ZInfo(result.Message)
-	}
-
-	return nil
-}
 func (r *AdminRepository) CallChangePC(pcSRC uint, pcDST uint) error {
 	// 创建一个结构体来存储可能的输出
 	type Result struct {
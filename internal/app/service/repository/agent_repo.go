@@ -71,6 +71,41 @@ func (r *AgentRepository) AddRelationList(list []*entities.HallInviteRelation) e
 	return r.DB.CreateInBatches(list, len(list)).Error
 }
 
+// 直属上级关系 没有上级时返回nil
+func (r *AgentRepository) GetParentRelation(uid uint) (*entities.HallInviteRelation, error) {
+	entity := new(entities.HallInviteRelation)
+	result := r.DB.Clauses(dbresolver.Write).Where("uid = ? AND level = ?", uid, 1).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// 下级关系 只能查到AGENT_LEVEL_MAX以内的
+func (r *AgentRepository) GetDescendantRelationList(pid uint) ([]*entities.HallInviteRelation, error) {
+	list := make([]*entities.HallInviteRelation, 0)
+	err := r.DB.Clauses(dbresolver.Write).Where("pid = ?", pid).Select("id", "uid", "level", "mobile").Find(&list).Error
+	return list, err
+}
+
+// 删除用户level以上的上级关系
+func (r *AgentRepository) DelAncestorRelationWithTx(tx *gorm.DB, uid uint, level uint8) error {
+	return tx.Where("uid = ? AND level > ?", uid, level).Delete(&entities.HallInviteRelation{}).Error
+}
+
+func (r *AgentRepository) AddRelationListWithTx(tx *gorm.DB, list []*entities.HallInviteRelation) error {
+	return tx.CreateInBatches(list, 100).Error
+}
+
+// 按一级关系重新统计直属人数
+func (r *AgentRepository) UpdateInviteCountWithTx(tx *gorm.DB, pid uint) error {
+	count := tx.Model(&entities.HallInviteRelation{}).Select("COUNT(1)").Where("pid = ? AND level = ?", pid, 1)
+	return tx.Model(&entities.User{}).Where("id = ?", pid).Update("invite_count", count).Error
+}
+
 func (r *AgentRepository) GetRakeBackList(game int) ([]*entities.RakeBack, error) {
 	list := make([]*entities.RakeBack, 0)
 	err := r.DB.Where("status = ? and game = ?", 1, game).Find(&list).Error
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var AgentStatsRepositorySet = wire.NewSet(wire.Struct(new(AgentStatsRepository), "*"))
+
+type AgentStatsRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 按上级和层级(或分类)汇总的数据
+type AgentStatsAmount struct {
+	PID      uint `gorm:"column:pid"`
+	UID      uint
+	Level    uint8
+	Category string
+	Count    int64
+	Amount   float64
+}
+
+const relationJoin = "JOIN hall_invite_relation ON hall_invite_relation.uid = "
+
+// 新注册
+func (r *AgentStatsRepository) SumTeamRegister(start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.User{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, COUNT(1) AS count").
+		Joins(relationJoin+"user.id").
+		Where("user.created_at >= ? AND user.created_at < ?", start, end).
+		Group("hall_invite_relation.pid, hall_invite_relation.level").Scan(&list).Error
+	return list, err
+}
+
+// 首充 当天之前没有成功的充值订单
+func (r *AgentStatsRepository) SumTeamFirstDeposit(start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.RechargeOrder{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, COUNT(DISTINCT recharge_order.uid) AS count, SUM(recharge_order.paymoney) AS amount").
+		Joins(relationJoin+"recharge_order.uid").
+		Where("recharge_order.status = ? AND recharge_order.finish_time >= ? AND recharge_order.finish_time < ?", 1, start, end).
+		Where("NOT EXISTS (SELECT 1 FROM recharge_order p WHERE p.uid = recharge_order.uid AND p.status = ? AND p.finish_time < ?)", 1, start).
+		Group("hall_invite_relation.pid, hall_invite_relation.level").Scan(&list).Error
+	return list, err
+}
+
+func (r *AgentStatsRepository) SumTeamDeposit(start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.RechargeOrder{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, SUM(recharge_order.paymoney) AS amount").
+		Joins(relationJoin+"recharge_order.uid").
+		Where("recharge_order.status = ? AND recharge_order.finish_time >= ? AND recharge_order.finish_time < ?", 1, start, end).
+		Group("hall_invite_relation.pid, hall_invite_relation.level").Scan(&list).Error
+	return list, err
+}
+
+// 打款成功的提现
+func (r *AgentStatsRepository) SumTeamWithdraw(start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.HallWithdrawRecord{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, SUM(hall_withdraw_record.cash) AS amount").
+		Joins(relationJoin+"hall_withdraw_record.uid").
+		Where("hall_withdraw_record.status = ? AND hall_withdraw_record.give_time >= ? AND hall_withdraw_record.give_time < ?", 3, start, end).
+		Group("hall_invite_relation.pid, hall_invite_relation.level").Scan(&list).Error
+	return list, err
+}
+
+// game_record的有效流水 按分类
+func (r *AgentStatsRepository) SumTeamGameRecordTurnover(start, end time.Time) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.GameRecord{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, game_record.category, SUM(game_record.amount) AS amount").
+		Joins(relationJoin+"game_record.uid").
+		Where("game_record.bet_time >= ? AND game_record.bet_time < ?", start, end).
+		Group("hall_invite_relation.pid, hall_invite_relation.level, game_record.category").Scan(&list).Error
+	return list, err
+}
+
+// 自研游戏(wingo/nine等)的投注 不写game_record 分类为流水类型的十位
+func (r *AgentStatsRepository) SumTeamGameFlowBet(start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.Flow{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, CAST(FLOOR(flow.type / 10) AS CHAR) AS category, -SUM(flow.number) AS amount").
+		Joins(relationJoin+"flow.uid").
+		Where("flow.type > ? AND flow.type < ? AND flow.number < 0 AND flow.created_at >= ? AND flow.created_at < ?", 200, 300, start, end).
+		Group("hall_invite_relation.pid, hall_invite_relation.level, category").Scan(&list).Error
+	return list, err
+}
+
+// 实际产生的游戏返利
+func (r *AgentStatsRepository) SumTeamCommission(start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.GameReturn{}).
+		Select("pid, level, SUM(return_cash) AS amount").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Group("pid, level").Scan(&list).Error
+	return list, err
+}
+
+// 重新汇总某一天 先删后插
+func (r *AgentStatsRepository) ReplaceAgentDailyStats(date int, levels []*entities.AgentDailyStats, categories []*entities.AgentDailyCategoryStats) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("date = ?", date).Delete(&entities.AgentDailyStats{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("date = ?", date).Delete(&entities.AgentDailyCategoryStats{}).Error; err != nil {
+			return err
+		}
+		if len(levels) > 0 {
+			if err := tx.CreateInBatches(levels, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(categories) > 0 {
+			if err := tx.CreateInBatches(categories, 500).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *AgentStatsRepository) GetAgentLevelStats(pid uint, startDate, endDate int) ([]*entities.AgentLevelStats, error) {
+	list := make([]*entities.AgentLevelStats, 0)
+	err := r.DB.Model(&entities.AgentDailyStats{}).
+		Select("level, SUM(new_members) AS new_members, SUM(first_deposits) AS first_deposits, SUM(first_deposit_amount) AS first_deposit_amount, "+
+			"SUM(deposit_amount) AS deposit_amount, SUM(withdraw_amount) AS withdraw_amount, SUM(turnover) AS turnover, SUM(commission) AS commission").
+		Where("pid = ? AND date >= ? AND date <= ?", pid, startDate, endDate).
+		Group("level").Order("level asc").Scan(&list).Error
+	return list, err
+}
+
+func (r *AgentStatsRepository) GetAgentCategoryStats(pid uint, startDate, endDate int) ([]*entities.AgentCategoryStats, error) {
+	list := make([]*entities.AgentCategoryStats, 0)
+	err := r.DB.Model(&entities.AgentDailyCategoryStats{}).
+		Select("category, SUM(turnover) AS turnover, SUM(commission) AS commission").
+		Where("pid = ? AND date >= ? AND date <= ?", pid, startDate, endDate).
+		Group("category").Order("turnover desc").Scan(&list).Error
+	return list, err
+}
+
+// 当前各层级人数
+func (r *AgentStatsRepository) GetAgentTeamSize(pid uint) ([]*entities.AgentTeamSize, error) {
+	list := make([]*entities.AgentTeamSize, 0)
+	err := r.DB.Model(&entities.HallInviteRelation{}).
+		Select("level, COUNT(1) AS count").
+		Where("pid = ?", pid).
+		Group("level").Order("level asc").Scan(&list).Error
+	return list, err
+}
+
+func (r *AgentStatsRepository) GetAgentDownlineList(req *entities.GetAgentDownlineListReq) error {
+	tx := r.DB.Table("hall_invite_relation").
+		Select("hall_invite_relation.uid, hall_invite_relation.level, hall_invite_relation.mobile, user.nickname, user.created_at AS register_time").
+		Joins("JOIN user ON user.id = hall_invite_relation.uid").
+		Where("hall_invite_relation.pid = ?", req.UID)
+	if req.Level > 0 {
+		tx = tx.Where("hall_invite_relation.level = ?", req.Level)
+	}
+	tx = tx.Order("hall_invite_relation.level asc, hall_invite_relation.id desc")
+	req.List = make([]*entities.AgentDownline, 0)
+	return req.Paginate(tx)
+}
+
+// 以下按成员汇总 用于下级列表的当前页
+func (r *AgentStatsRepository) SumMemberDeposit(uids []uint, start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.RechargeOrder{}).
+		Select("uid, SUM(paymoney) AS amount").
+		Where("uid IN ? AND status = ? AND finish_time >= ? AND finish_time < ?", uids, 1, start, end).
+		Group("uid").Scan(&list).Error
+	return list, err
+}
+
+func (r *AgentStatsRepository) SumMemberTurnover(uids []uint, start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.GameRecord{}).
+		Select("uid, SUM(amount) AS amount").
+		Where("uid IN ? AND bet_time >= ? AND bet_time < ?", uids, time.Unix(start, 0), time.Unix(end, 0)).
+		Group("uid").Scan(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	flows := make([]*AgentStatsAmount, 0)
+	err = r.DB.Model(&entities.Flow{}).
+		Select("uid, -SUM(number) AS amount").
+		Where("uid IN ? AND type > ? AND type < ? AND number < 0 AND created_at >= ? AND created_at < ?", uids, 200, 300, start, end).
+		Group("uid").Scan(&flows).Error
+	return append(list, flows...), err
+}
+
+func (r *AgentStatsRepository) SumMemberCommission(pid uint, uids []uint, start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.GameReturn{}).
+		Select("uid, SUM(return_cash) AS amount").
+		Where("pid = ? AND uid IN ? AND created_at >= ? AND created_at < ?", pid, uids, start, end).
+		Group("uid").Scan(&list).Error
+	return list, err
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var ArchiveRepositorySet = wire.NewSet(wire.Struct(new(ArchiveRepository), "*"))
+
+// 表名都来自service里的白名单 这里直接拼接
+type ArchiveRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+type ArchiveStat struct {
+	Count  int64
+	Amount float64
+}
+
+func (r *ArchiveRepository) GetArchiveJob(table, month string) (*entities.ArchiveJob, error) {
+	entity := new(entities.ArchiveJob)
+	result := r.DB.Clauses(dbresolver.Write).Where("source_table = ? AND month = ?", table, month).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *ArchiveRepository) CreateArchiveJob(entity *entities.ArchiveJob) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *ArchiveRepository) UpdateArchiveJob(id uint, values map[string]interface{}) error {
+	return r.DB.Model(&entities.ArchiveJob{}).Where("id = ?", id).Updates(values).Error
+}
+
+// 未完成的任务 按月份先后续跑
+func (r *ArchiveRepository) GetUnfinishedArchiveJobs(tables []string) ([]*entities.ArchiveJob, error) {
+	list := make([]*entities.ArchiveJob, 0)
+	err := r.DB.Clauses(dbresolver.Write).
+		Where("source_table IN ? AND status <> ?", tables, constant.ARCHIVE_STATUS_DONE).
+		Order("month asc, id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *ArchiveRepository) GetArchiveJobList(req *entities.GetArchiveJobListReq) error {
+	tx := r.DB.Model(&entities.ArchiveJob{})
+	if req.SourceTable != "" {
+		tx = tx.Where("source_table = ?", req.SourceTable)
+	}
+	if req.Month != "" {
+		tx = tx.Where("month = ?", req.Month)
+	}
+	if req.Status > 0 {
+		tx = tx.Where("status = ?", req.Status)
+	}
+	tx = tx.Order("id desc")
+	req.List = make([]*entities.ArchiveJob, 0)
+	return req.Paginate(tx)
+}
+
+func (r *ArchiveRepository) CreateArchiveTable(src, dst string) error {
+	return r.DB.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` LIKE `%s`", dst, src)).Error
+}
+
+func (r *ArchiveRepository) GetRangeMaxID(table string, start, end int64) (uint, error) {
+	var maxID uint
+	err := r.DB.Clauses(dbresolver.Write).
+		Raw(fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM `%s` WHERE created_at >= ? AND created_at < ?", table), start, end).
+		Scan(&maxID).Error
+	return maxID, err
+}
+
+// 范围内的行数和金额合计 用于归档前后对比
+func (r *ArchiveRepository) StatRange(table, sumColumn string, start, end int64, maxID uint) (*ArchiveStat, error) {
+	stat := new(ArchiveStat)
+	err := r.DB.Clauses(dbresolver.Write).
+		Raw(fmt.Sprintf("SELECT COUNT(*) AS count, COALESCE(SUM(`%s`), 0) AS amount FROM `%s` WHERE created_at >= ? AND created_at < ? AND id <= ?", sumColumn, table), start, end, maxID).
+		Scan(stat).Error
+	return stat, err
+}
+
+// 下一批的结束ID 返回0表示没有待复制的数据
+func (r *ArchiveRepository) GetNextBatchID(table string, start, end int64, lastID, maxID uint, limit int) (uint, error) {
+	var nextID uint
+	err := r.DB.Clauses(dbresolver.Write).
+		Raw(fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM (SELECT id FROM `%s` WHERE id > ? AND id <= ? AND created_at >= ? AND created_at < ? ORDER BY id LIMIT ?) b", table),
+			lastID, maxID, start, end, limit).
+		Scan(&nextID).Error
+	return nextID, err
+}
+
+// 按ID区间复制 重复执行时已存在的行忽略
+func (r *ArchiveRepository) CopyArchiveBatch(src, dst string, start, end int64, fromID, toID uint) (int64, error) {
+	result := r.DB.Exec(fmt.Sprintf("INSERT IGNORE INTO `%s` SELECT * FROM `%s` WHERE id > ? AND id <= ? AND created_at >= ? AND created_at < ?", dst, src),
+		fromID, toID, start, end)
+	return result.RowsAffected, result.Error
+}
+
+func (r *ArchiveRepository) DeleteArchivedBatch(table string, start, end int64, maxID uint, limit int) (int64, error) {
+	result := r.DB.Exec(fmt.Sprintf("DELETE FROM `%s` WHERE created_at >= ? AND created_at < ? AND id <= ? ORDER BY id LIMIT ?", table),
+		start, end, maxID, limit)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+var CashbackRepositorySet = wire.NewSet(wire.Struct(new(CashbackRepository), "*"))
+
+type CashbackRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+func (r *CashbackRepository) GetCashbackRuleList() ([]*entities.CashbackRule, error) {
+	list := make([]*entities.CashbackRule, 0)
+	err := r.DB.Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *CashbackRepository) GetCashbackRuleByID(id uint) (*entities.CashbackRule, error) {
+	entity := new(entities.CashbackRule)
+	result := r.DB.Clauses(dbresolver.Write).Where("id = ?", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *CashbackRepository) SaveCashbackRule(entity *entities.CashbackRule) error {
+	return r.DB.Save(entity).Error
+}
+
+func (r *CashbackRepository) DelCashbackRule(id uint) error {
+	return r.DB.Where("id = ?", id).Delete(&entities.CashbackRule{}).Error
+}
+
+// 累加到用户分类的自返 不存在时创建
+func (r *CashbackRepository) AccrueUserCashback(entity *entities.UserCashback) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "uid"}, {Name: "category"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"turnover":   gorm.Expr("turnover + ?", entity.Turnover),
+			"net_loss":   gorm.Expr("net_loss + ?", entity.NetLoss),
+			"amount":     gorm.Expr("amount + ?", entity.Amount),
+			"updated_at": time.Now().Unix(),
+		}),
+	}).Create(entity).Error
+}
+
+func (r *CashbackRepository) GetUserCashbackList(uid uint) ([]*entities.UserCashback, error) {
+	list := make([]*entities.UserCashback, 0)
+	err := r.DB.Clauses(dbresolver.Write).Where("uid = ?", uid).Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *CashbackRepository) GetUserCashbackListForUpdate(tx *gorm.DB, uid uint) ([]*entities.UserCashback, error) {
+	list := make([]*entities.UserCashback, 0)
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uid = ?", uid).Order("id asc").Find(&list).Error
+	return list, err
+}
+
+// 领取后按快照扣减 Amount为实际领取的金额 领取期间新增的累计不受影响
+func (r *CashbackRepository) DeductUserCashbackWithTx(tx *gorm.DB, entity *entities.UserCashback) error {
+	return tx.Model(&entities.UserCashback{}).Where("id = ?", entity.ID).Updates(map[string]interface{}{
+		"turnover":      gorm.Expr("turnover - ?", entity.Turnover),
+		"net_loss":      gorm.Expr("net_loss - ?", entity.NetLoss),
+		"amount":        gorm.Expr("amount - ?", entity.Amount),
+		"total_claimed": gorm.Expr("total_claimed + ?", entity.Amount),
+	}).Error
+}
+
+func (r *CashbackRepository) CreateCashbackClaimWithTx(tx *gorm.DB, entity *entities.CashbackClaim) error {
+	return tx.Create(entity).Error
+}
+
+func (r *CashbackRepository) GetCashbackClaimList(req *entities.GetCashbackClaimListReq) error {
+	tx := r.DB.Where("uid = ?", req.UID).Order("id desc")
+	req.List = make([]*entities.CashbackClaim, 0)
+	return req.Paginate(tx)
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"errors"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var CommissionRepositorySet = wire.NewSet(wire.Struct(new(CommissionRepository), "*"))
+
+type CommissionRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+func (r *CommissionRepository) GetCommissionPlanList() ([]*entities.CommissionPlan, error) {
+	list := make([]*entities.CommissionPlan, 0)
+	err := r.DB.Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *CommissionRepository) GetCommissionPlanByID(id uint) (*entities.CommissionPlan, error) {
+	entity := new(entities.CommissionPlan)
+	result := r.DB.Clauses(dbresolver.Write).Where("id = ?", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *CommissionRepository) SaveCommissionPlan(entity *entities.CommissionPlan) error {
+	return r.DB.Save(entity).Error
+}
+
+// 删除方案同时删除它的分配
+func (r *CommissionRepository) DelCommissionPlan(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("plan_id = ?", id).Delete(&entities.CommissionPlanAssign{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&entities.CommissionPlan{}).Error
+	})
+}
+
+func (r *CommissionRepository) GetCommissionPlanAssignList() ([]*entities.CommissionPlanAssign, error) {
+	list := make([]*entities.CommissionPlanAssign, 0)
+	err := r.DB.Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *CommissionRepository) GetCommissionPlanAssign(uid uint, pc int) (*entities.CommissionPlanAssign, error) {
+	entity := new(entities.CommissionPlanAssign)
+	result := r.DB.Clauses(dbresolver.Write).Where("uid = ? AND pc = ?", uid, pc).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *CommissionRepository) SaveCommissionPlanAssign(entity *entities.CommissionPlanAssign) error {
+	return r.DB.Save(entity).Error
+}
+
+func (r *CommissionRepository) DelCommissionPlanAssign(id uint) error {
+	return r.DB.Where("id = ?", id).Delete(&entities.CommissionPlanAssign{}).Error
+}
+
+// 结算用的团队有效投注 取代理每日统计 日期包含两端
+func (r *CommissionRepository) SumTeamTurnover(startDate, endDate int) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.AgentDailyStats{}).
+		Select("pid, level, SUM(turnover) AS amount").
+		Where("date >= ? AND date <= ?", startDate, endDate).
+		Group("pid, level").Scan(&list).Error
+	return list, err
+}
+
+// 团队净输 游戏流水的负数合计 不含活动奖励和资金冻结
+func (r *CommissionRepository) SumTeamNetRevenue(start, end int64, ignoreTypes []uint16) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	tx := r.DB.Model(&entities.Flow{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, -SUM(flow.number) AS amount").
+		Joins(relationJoin+"flow.uid").
+		Where("flow.type > ? AND flow.is_robot = ? AND flow.created_at >= ? AND flow.created_at < ?", 200, 0, start, end)
+	if len(ignoreTypes) > 0 {
+		tx = tx.Where("flow.type NOT IN ?", ignoreTypes)
+	}
+	err := tx.Group("hall_invite_relation.pid, hall_invite_relation.level").Scan(&list).Error
+	return list, err
+}
+
+// 期内首充的成员及其期内累计充值 期初之前没有成功的充值订单
+func (r *CommissionRepository) GetTeamFirstDepositList(start, end int64) ([]*AgentStatsAmount, error) {
+	list := make([]*AgentStatsAmount, 0)
+	err := r.DB.Model(&entities.RechargeOrder{}).
+		Select("hall_invite_relation.pid, hall_invite_relation.level, recharge_order.uid, SUM(recharge_order.paymoney) AS amount").
+		Joins(relationJoin+"recharge_order.uid").
+		Where("recharge_order.status = ? AND recharge_order.finish_time >= ? AND recharge_order.finish_time < ?", 1, start, end).
+		Where("NOT EXISTS (SELECT 1 FROM recharge_order p WHERE p.uid = recharge_order.uid AND p.status = ? AND p.finish_time < ?)", 1, start).
+		Group("hall_invite_relation.pid, hall_invite_relation.level, recharge_order.uid").Scan(&list).Error
+	return list, err
+}
+
+// 代理所属渠道
+func (r *CommissionRepository) GetUserPromoterCodeMap(uids []uint) (map[uint]int, error) {
+	list := make([]*entities.User, 0)
+	err := r.DB.Select("id, pc").Where("id IN ?", uids).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[uint]int, len(list))
+	for _, item := range list {
+		m[item.ID] = item.PromoterCode
+	}
+	return m, nil
+}
+
+func (r *CommissionRepository) GetCommissionStatementUIDs(period string) (map[uint]bool, error) {
+	uids := make([]uint, 0)
+	err := r.DB.Clauses(dbresolver.Write).Model(&entities.CommissionStatement{}).Where("period = ?", period).Pluck("uid", &uids).Error
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[uint]bool, len(uids))
+	for _, uid := range uids {
+		m[uid] = true
+	}
+	return m, nil
+}
+
+// 代理上一期净输分成结转的负数
+func (r *CommissionRepository) GetLastCarryOut(uid uint, before int64) (float64, error) {
+	entity := new(entities.CommissionStatement)
+	result := r.DB.Clauses(dbresolver.Write).
+		Where("uid = ? AND plan_type = ? AND end_time <= ?", uid, constant.COMMISSION_TYPE_REVSHARE, before).
+		Order("end_time desc").First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, result.Error
+	}
+	return entity.CarryOut, nil
+}
+
+func (r *CommissionRepository) CreateCommissionStatement(entity *entities.CommissionStatement) error {
+	return r.DB.Create(entity).Error
+}
+
+// 待发放改为已发放 返回false表示已被处理
+func (r *CommissionRepository) MarkCommissionStatementPaidWithTx(tx *gorm.DB, id uint) (bool, error) {
+	result := tx.Model(&entities.CommissionStatement{}).
+		Where("id = ? AND status = ?", id, constant.COMMISSION_STATEMENT_PENDING).
+		Updates(map[string]interface{}{
+			"status":    constant.COMMISSION_STATEMENT_PAID,
+			"paid_time": time.Now().Unix(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *CommissionRepository) GetPendingCommissionStatementList() ([]*entities.CommissionStatement, error) {
+	list := make([]*entities.CommissionStatement, 0)
+	err := r.DB.Clauses(dbresolver.Write).Where("status = ?", constant.COMMISSION_STATEMENT_PENDING).Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *CommissionRepository) GetCommissionStatementList(req *entities.GetCommissionStatementListReq) error {
+	tx := r.DB.Model(&entities.CommissionStatement{})
+	if req.UID > 0 {
+		tx = tx.Where("uid = ?", req.UID)
+	}
+	if req.Period != "" {
+		tx = tx.Where("period = ?", req.Period)
+	}
+	tx = tx.Order("id desc")
+	req.List = make([]*entities.CommissionStatement, 0)
+	return req.Paginate(tx)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"strconv"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+)
+
+var DeadLetterRepositorySet = wire.NewSet(wire.Struct(new(DeadLetterRepository), "*"))
+
+// 队列任务按类型和分钟计数 用于失败率告警
+type DeadLetterRepository struct {
+	RDS redis.UniversalClient
+}
+
+func (r *DeadLetterRepository) IncrTaskStats(typename string, minute int64, failed bool) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_MQ_TASK_STATS, typename, minute)
+	pipe := r.RDS.Pipeline()
+	pipe.HIncrBy(context.Background(), key, "processed", 1)
+	if failed {
+		pipe.HIncrBy(context.Background(), key, "failed", 1)
+	}
+	pipe.Expire(context.Background(), key, constant.DLQ_STATS_EXPIRE*time.Second)
+	_, err := pipe.Exec(context.Background())
+	return err
+}
+
+// 汇总[from, to]分钟内的处理和失败次数
+func (r *DeadLetterRepository) SumTaskStats(typename string, from, to int64) (processed, failed int64, err error) {
+	for minute := from; minute <= to; minute++ {
+		key := fmt.Sprintf(constant.REDIS_KEY_MQ_TASK_STATS, typename, minute)
+		values, err := r.RDS.HMGet(context.Background(), key, "processed", "failed").Result()
+		if err != nil {
+			return 0, 0, err
+		}
+		processed += parseRedisInt(values[0])
+		failed += parseRedisInt(values[1])
+	}
+	return processed, failed, nil
+}
+
+// 冷却期内同一类型只告警一次
+func (r *DeadLetterRepository) TryFailureAlert(typename string) (bool, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_MQ_FAILURE_ALERT, typename)
+	return r.RDS.SetNX(context.Background(), key, time.Now().Unix(), constant.DLQ_ALERT_COOLDOWN*time.Second).Result()
+}
+
+func parseRedisInt(value interface{}) int64 {
+	s, ok := value.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
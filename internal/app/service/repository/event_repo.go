@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+)
+
+var EventRepositorySet = wire.NewSet(wire.Struct(new(EventRepository), "*"))
+
+type EventRepository struct {
+	RDS redis.UniversalClient
+}
+
+// 事件ID第一次出现才写入stream 发件箱重投不会重复
+var appendEventScript = redis.NewScript(`
+if redis.call("SET", KEYS[2], 1, "NX", "EX", ARGV[1]) then
+	return redis.call("XADD", KEYS[1], "MAXLEN", "~", ARGV[2], "*", "id", ARGV[3], "name", ARGV[4], "time", ARGV[5], "payload", ARGV[6])
+end
+return false`)
+
+func EventStreamKey(name string) string {
+	return fmt.Sprintf(constant.REDIS_KEY_EVENT_STREAM, name)
+}
+
+// 返回写入的StreamID 重复的事件返回空
+func (r *EventRepository) AppendEvent(id, name string, t int64, payload string) (string, error) {
+	keys := []string{EventStreamKey(name), fmt.Sprintf(constant.REDIS_KEY_EVENT_APPENDED, id)}
+	streamID, err := appendEventScript.Run(context.Background(), r.RDS, keys,
+		constant.EVENT_APPENDED_EXPIRE, constant.EVENT_STREAM_MAXLEN, id, name, t, payload).Text()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return streamID, err
+}
+
+// 消费组已存在时忽略
+func (r *EventRepository) CreateEventGroup(name, group, start string) error {
+	err := r.RDS.XGroupCreateMkStream(context.Background(), EventStreamKey(name), group, start).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// 读取新事件 超时没有新事件返回空
+func (r *EventRepository) ReadEventGroup(group, consumer string, names []string, count int64, block time.Duration) ([]redis.XStream, error) {
+	streams := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		streams = append(streams, EventStreamKey(name))
+	}
+	for range names {
+		streams = append(streams, ">")
+	}
+	list, err := r.RDS.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  streams,
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return list, err
+}
+
+func (r *EventRepository) AckEvent(name, group string, ids ...string) error {
+	return r.RDS.XAck(context.Background(), EventStreamKey(name), group, ids...).Err()
+}
+
+// 已读取但超过idle未确认的事件 包含投递次数
+func (r *EventRepository) GetStalePendingEvents(name, group string, idle time.Duration, count int64) ([]redis.XPendingExt, error) {
+	return r.RDS.XPendingExt(context.Background(), &redis.XPendingExtArgs{
+		Stream: EventStreamKey(name),
+		Group:  group,
+		Idle:   idle,
+		Start:  "-",
+		End:    "+",
+		Count:  count,
+	}).Result()
+}
+
+func (r *EventRepository) ClaimEvents(name, group, consumer string, idle time.Duration, ids []string) ([]redis.XMessage, error) {
+	return r.RDS.XClaim(context.Background(), &redis.XClaimArgs{
+		Stream:   EventStreamKey(name),
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  idle,
+		Messages: ids,
+	}).Result()
+}
+
+func (r *EventRepository) SetEventGroupID(name, group, id string) error {
+	return r.RDS.XGroupSetID(context.Background(), EventStreamKey(name), group, id).Err()
+}
+
+// stream还不存在时返回空
+func (r *EventRepository) GetEventGroups(name string) ([]redis.XInfoGroup, error) {
+	list, err := r.RDS.XInfoGroups(context.Background(), EventStreamKey(name)).Result()
+	if err != nil && strings.Contains(err.Error(), "no such key") {
+		return nil, nil
+	}
+	return list, err
+}
+
+func (r *EventRepository) GetEventList(name, end string, count int64) ([]redis.XMessage, error) {
+	return r.RDS.XRevRangeN(context.Background(), EventStreamKey(name), end, "-", count).Result()
+}
+
+func (r *EventRepository) IncrEventDailyStats(date, name string, amount float64) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_EVENT_DAILY_STATS, date)
+	pipe := r.RDS.Pipeline()
+	pipe.HIncrBy(context.Background(), key, name+":count", 1)
+	if amount != 0 {
+		pipe.HIncrByFloat(context.Background(), key, name+":amount", amount)
+	}
+	pipe.Expire(context.Background(), key, constant.EVENT_STATS_EXPIRE*24*time.Hour)
+	_, err := pipe.Exec(context.Background())
+	return err
+}
+
+func (r *EventRepository) GetEventDailyStats(date string) (map[string]string, error) {
+	return r.RDS.HGetAll(context.Background(), fmt.Sprintf(constant.REDIS_KEY_EVENT_DAILY_STATS, date)).Result()
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"rk-api/internal/app/entities"
 	"strconv"
@@ -11,10 +12,12 @@ import (
 	"github.com/google/wire"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
-	gameOnlineCacheKey = "game:online" // 单游戏在线人数缓存键
+	gameOnlineCacheKey = "game:online"          // 单游戏在线人数缓存键
+	gameCatalogVersion = "game:catalog:version" // 游戏目录版本号 变动时递增 各实例据此重建搜索索引
 )
 
 var GameRepositorySet = wire.NewSet(wire.Struct(new(GameRepository), "*"))
@@ -105,12 +108,44 @@ func (r *GameRepository) SyncOnlineCount2DB(list []*entities.GameRefresh) error
 	})
 }
 
+// 前台可见的游戏 未删除/已上架 并且地区/语言可见
+func gameVisibleScope(region, language string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("game.is_deleted = ? and game.is_active = ?", 0, 1)
+		if region != "" {
+			tx = tx.Where("(game.regions = '' OR FIND_IN_SET(?, game.regions))", region)
+		}
+		if language != "" {
+			tx = tx.Where("(game.languages = '' OR FIND_IN_SET(?, game.languages))", language)
+		}
+		return tx
+	}
+}
+
 func (r *GameRepository) GetGameList(param *entities.GetGameListReq) error {
-	var tx *gorm.DB = r.DB
+	var tx *gorm.DB = r.DB.Model(&entities.Game{}).Scopes(gameVisibleScope(param.Region, param.Language))
 	if param.Category != "" {
-		tx = tx.Where("category = ?", param.Category)
+		tx = tx.Where("game.category = ?", param.Category)
+	}
+	if param.Provider != "" {
+		tx = tx.Where("game.provider = ?", param.Provider)
 	}
-	tx = tx.Where("is_deleted = ? and is_active = ?", 0, 1).Order("priority desc")
+	if param.Tag != "" {
+		tx = tx.Where("FIND_IN_SET(?, game.tags)", param.Tag)
+	}
+	if param.Collection != "" {
+		collection, err := r.GetGameCollectionByCode(param.Collection)
+		if err != nil {
+			return err
+		}
+		if collection == nil || collection.Status != 1 {
+			param.List = make([]*entities.Game, 0)
+			return nil
+		}
+		tx = tx.Where("game.id IN (?)", r.DB.Model(&entities.GameCollectionItem{}).Select("game_id").Where("collection_id = ?", collection.ID)).
+			Order(fmt.Sprintf("(SELECT sort FROM game_collection_item WHERE game_collection_item.game_id = game.id AND game_collection_item.collection_id = %d) desc", collection.ID))
+	}
+	tx = tx.Order("game.lobby_score desc, game.priority desc, game.id asc")
 	param.List = make([]*entities.Game, 0)
 	return param.Paginate(tx)
 }
@@ -125,15 +160,255 @@ func (r *GameRepository) GetWholeGameList() ([]*entities.Game, error) {
 	return games, nil
 }
 
+// 前台可见的全部游戏 按大厅默认排序 用于构建搜索索引
+func (r *GameRepository) GetVisibleGameList() ([]*entities.Game, error) {
+	list := make([]*entities.Game, 0)
+	err := r.DB.Scopes(gameVisibleScope("", "")).
+		Order("game.lobby_score desc, game.priority desc, game.id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *GameRepository) IncrGameCatalogVersion() (int64, error) {
+	return r.RDS.Incr(context.Background(), gameCatalogVersion).Result()
+}
+
+func (r *GameRepository) GetGameCatalogVersion() (int64, error) {
+	version, err := r.RDS.Get(context.Background(), gameCatalogVersion).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
 func (r *GameRepository) SearchGameList(param *entities.SearchGameReq) error {
-	var tx *gorm.DB = r.DB
+	var tx *gorm.DB = r.DB.Model(&entities.Game{}).Scopes(gameVisibleScope(param.Region, param.Language))
 	if param.Category != "" {
 		tx = tx.Where("category = ?", param.Category)
 	}
-	tx = tx.Where("is_deleted = ? and is_active = ?", 0, 1)
+	if param.Tag != "" {
+		tx = tx.Where("FIND_IN_SET(?, tags)", param.Tag)
+	}
+	if param.Provider != "" {
+		tx = tx.Where("provider = ?", param.Provider)
+	}
 	if param.Name != "" {
 		tx = tx.Where("name LIKE ?", "%"+param.Name+"%")
 	}
+	tx = tx.Order("lobby_score desc, priority desc")
 	param.List = make([]*entities.Game, 0)
 	return param.Paginate(tx)
 }
+
+func (r *GameRepository) AdminGetGameList(param *entities.AdminGetGameListReq) error {
+	var tx *gorm.DB = r.DB.Where("is_deleted = ?", 0)
+	if param.Category != "" {
+		tx = tx.Where("category = ?", param.Category)
+	}
+	if param.Provider != "" {
+		tx = tx.Where("provider = ?", param.Provider)
+	}
+	if param.Name != "" {
+		tx = tx.Where("name LIKE ?", "%"+param.Name+"%")
+	}
+	if param.IsActive != nil {
+		tx = tx.Where("is_active = ?", *param.IsActive)
+	}
+	tx = tx.Order("id desc")
+	param.List = make([]*entities.Game, 0)
+	return param.Paginate(tx)
+}
+
+func (r *GameRepository) GetGameByID(id uint) (*entities.Game, error) {
+	entity := new(entities.Game)
+	result := r.DB.Where("id = ? AND is_deleted = ?", id, 0).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *GameRepository) GetGameListByProvider(provider string) ([]*entities.Game, error) {
+	list := make([]*entities.Game, 0)
+	err := r.DB.Where("provider = ?", provider).Find(&list).Error
+	return list, err
+}
+
+func (r *GameRepository) CreateGame(entity *entities.Game) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *GameRepository) UpdateGame(entity *entities.Game) error {
+	return r.DB.Select("name", "category", "game_code", "provider", "icon_url", "company_logo_url", "gameplay_type",
+		"description", "is_active", "service_status", "language", "priority", "tags", "regions", "languages",
+		"maintain_start", "maintain_end", "localized_names").Updates(entity).Error
+}
+
+func (r *GameRepository) DelGame(id uint) error {
+	return r.DB.Model(&entities.Game{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"is_deleted": true, "is_active": false}).Error
+}
+
+// 批量导入 已存在的按id更新 新的创建
+func (r *GameRepository) SaveGameList(createList []*entities.Game, updateList []*entities.Game) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if len(createList) > 0 {
+			if err := tx.CreateInBatches(createList, 100).Error; err != nil {
+				return err
+			}
+		}
+		for _, game := range updateList {
+			if err := tx.Select("name", "category", "icon_url", "company_logo_url", "gameplay_type", "description", "language").
+				Updates(game).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 近期各游戏的投注额和投注人数 游戏记录里的game为游戏名
+func (r *GameRepository) GetGamePlayStats(since time.Time) ([]*entities.GamePlayStats, error) {
+	list := make([]*entities.GamePlayStats, 0)
+	err := r.DB.Model(&entities.GameRecord{}).
+		Select("game, IFNULL(SUM(bet_amount), 0) AS turnover, COUNT(DISTINCT uid) AS players").
+		Where("bet_time >= ?", since).
+		Group("game").Scan(&list).Error
+	return list, err
+}
+
+func (r *GameRepository) UpdateGameLobbyScore(id uint, turnover float64, players int, score float64) error {
+	return r.DB.Model(&entities.Game{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"recent_turnover": turnover,
+		"recent_players":  players,
+		"lobby_score":     score,
+	}).Error
+}
+
+func (r *GameRepository) GetGameCollectionList(onlyShow bool) ([]*entities.GameCollection, error) {
+	list := make([]*entities.GameCollection, 0)
+	tx := r.DB.Order("sort desc, id asc")
+	if onlyShow {
+		tx = tx.Where("status = ?", 1)
+	}
+	err := tx.Find(&list).Error
+	return list, err
+}
+
+func (r *GameRepository) GetGameCollectionByID(id uint) (*entities.GameCollection, error) {
+	entity := new(entities.GameCollection)
+	result := r.DB.Where("id = ?", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *GameRepository) GetGameCollectionByCode(code string) (*entities.GameCollection, error) {
+	entity := new(entities.GameCollection)
+	result := r.DB.Where("code = ?", code).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *GameRepository) CreateGameCollection(entity *entities.GameCollection) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *GameRepository) UpdateGameCollection(entity *entities.GameCollection) error {
+	return r.DB.Select("code", "name", "sort", "status").Updates(entity).Error
+}
+
+func (r *GameRepository) DelGameCollection(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", id).Delete(&entities.GameCollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entities.GameCollection{}, id).Error
+	})
+}
+
+// 整体替换合集内的游戏
+func (r *GameRepository) SetGameCollectionItems(collectionID uint, items []*entities.GameCollectionItem) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collectionID).Delete(&entities.GameCollectionItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Create(&items).Error
+	})
+}
+
+// 多个合集内的可见游戏 按合集内排序
+func (r *GameRepository) GetCollectionGameList(collectionIDs []uint, region, language string) (map[uint][]*entities.Game, error) {
+	type row struct {
+		entities.Game
+		CollectionID uint
+	}
+	rows := make([]*row, 0)
+	err := r.DB.Model(&entities.Game{}).Scopes(gameVisibleScope(region, language)).
+		Select("game.*, game_collection_item.collection_id").
+		Joins("JOIN game_collection_item ON game_collection_item.game_id = game.id").
+		Where("game_collection_item.collection_id IN ?", collectionIDs).
+		Order("game_collection_item.sort desc, game.lobby_score desc").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uint][]*entities.Game, len(collectionIDs))
+	for _, item := range rows {
+		game := item.Game
+		result[item.CollectionID] = append(result[item.CollectionID], &game)
+	}
+	return result, nil
+}
+
+// 按游戏编码或游戏名查找可见的游戏
+func (r *GameRepository) GetVisibleGameListByNames(names []string) ([]*entities.Game, error) {
+	list := make([]*entities.Game, 0)
+	if len(names) == 0 {
+		return list, nil
+	}
+	err := r.DB.Scopes(gameVisibleScope("", "")).
+		Where("game.game_code IN ? OR game.name IN ?", names, names).Find(&list).Error
+	return list, err
+}
+
+// 重复收藏忽略
+func (r *GameRepository) CreateUserFavoriteGame(entity *entities.UserFavoriteGame) error {
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(entity).Error
+}
+
+func (r *GameRepository) DelUserFavoriteGame(uid uint, gameID uint) error {
+	return r.DB.Where("uid = ? AND game_id = ?", uid, gameID).Delete(&entities.UserFavoriteGame{}).Error
+}
+
+func (r *GameRepository) CountUserFavoriteGame(uid uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&entities.UserFavoriteGame{}).Where("uid = ?", uid).Count(&count).Error
+	return count, err
+}
+
+// 用户收藏的可见游戏 最近收藏的在前
+func (r *GameRepository) GetUserFavoriteGameList(uid uint) ([]*entities.Game, error) {
+	list := make([]*entities.Game, 0)
+	err := r.DB.Model(&entities.Game{}).Scopes(gameVisibleScope("", "")).
+		Select("game.*").
+		Joins("JOIN user_favorite_game ON user_favorite_game.game_id = game.id").
+		Where("user_favorite_game.uid = ?", uid).
+		Order("user_favorite_game.id desc").
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var GamingRepositorySet = wire.NewSet(wire.Struct(new(GamingRepository), "*"))
+
+type GamingRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 周期统计的key 类型+周期编号
+type GamingUsageKey struct {
+	Type   string
+	Bucket string
+}
+
+type GamingUsageIncr struct {
+	GamingUsageKey
+	Amount float64
+	Expire time.Duration
+}
+
+func (r *GamingRepository) GetGamingLimitList(uid uint) ([]*entities.GamingLimit, error) {
+	list := make([]*entities.GamingLimit, 0)
+	err := r.DB.Clauses(dbresolver.Write).Where("uid = ?", uid).Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *GamingRepository) GetGamingLimit(uid uint, limitType, period, source string) (*entities.GamingLimit, error) {
+	entity := new(entities.GamingLimit)
+	result := r.DB.Clauses(dbresolver.Write).Where("uid = ? AND type = ? AND period = ? AND source = ?", uid, limitType, period, source).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *GamingRepository) SaveGamingLimit(entity *entities.GamingLimit) error {
+	return r.DB.Save(entity).Error
+}
+
+func (r *GamingRepository) GetGamingRestriction(uid uint) (*entities.GamingRestriction, error) {
+	entity := new(entities.GamingRestriction)
+	result := r.DB.Clauses(dbresolver.Write).Where("uid = ?", uid).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *GamingRepository) SaveGamingRestriction(entity *entities.GamingRestriction) error {
+	return r.DB.Save(entity).Error
+}
+
+// 限额缓存 下注/充值前都要检查
+func (r *GamingRepository) GetGamingLimitCache(uid uint) (string, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_GAMING_LIMIT, uid)
+	data, err := r.RDS.Get(context.Background(), key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return data, err
+}
+
+func (r *GamingRepository) SetGamingLimitCache(uid uint, data string, expiration time.Duration) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_GAMING_LIMIT, uid)
+	return r.RDS.Set(context.Background(), key, data, expiration).Err()
+}
+
+func (r *GamingRepository) DelGamingLimitCache(uid uint) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_GAMING_LIMIT, uid)
+	return r.RDS.Del(context.Background(), key).Err()
+}
+
+func (r *GamingRepository) GetGamingUsage(uid uint, keys []GamingUsageKey) ([]float64, error) {
+	list := make([]float64, len(keys))
+	if len(keys) == 0 {
+		return list, nil
+	}
+	// 集群模式下key不在同一个slot 用pipeline代替MGET
+	pipe := r.RDS.Pipeline()
+	cmds := make([]*redis.StringCmd, 0, len(keys))
+	for _, item := range keys {
+		key := fmt.Sprintf(constant.REDIS_KEY_GAMING_USAGE, uid, item.Type, item.Bucket)
+		cmds = append(cmds, pipe.Get(context.Background(), key))
+	}
+	if _, err := pipe.Exec(context.Background()); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	for i, cmd := range cmds {
+		if value, err := cmd.Float64(); err == nil {
+			list[i] = value
+		}
+	}
+	return list, nil
+}
+
+func (r *GamingRepository) IncrGamingUsage(uid uint, items []GamingUsageIncr) error {
+	if len(items) == 0 {
+		return nil
+	}
+	pipe := r.RDS.Pipeline()
+	for _, item := range items {
+		key := fmt.Sprintf(constant.REDIS_KEY_GAMING_USAGE, uid, item.Type, item.Bucket)
+		pipe.IncrByFloat(context.Background(), key, item.Amount)
+		pipe.Expire(context.Background(), key, item.Expire)
+	}
+	_, err := pipe.Exec(context.Background())
+	return err
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var HistoryRepositorySet = wire.NewSet(wire.Struct(new(HistoryRepository), "*"))
+
+type HistoryRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 投注记录来源 各订单表字段不一致 查询时统一成相同的列
+type BetHistorySource struct {
+	Key         string
+	Game        string
+	Category    string
+	Table       string
+	RoundCol    string // 局号/期号列
+	SettledCond string // 已结算条件
+	CancelCond  string // 已取消条件
+}
+
+// 顺序即游标中同一时间的排序顺序 只能在末尾追加
+var BetHistorySources = []*BetHistorySource{
+	{Key: "dice", Game: constant.GameNameDice, Category: constant.GameCategoryDice, Table: "dice_game_order", RoundCol: "round_id", SettledCond: "settled = 1", CancelCond: "0"},
+	{Key: "crash", Game: constant.GameNameCrash, Category: constant.GameCategoryCrash, Table: "crash_game_order", RoundCol: "round_id", SettledCond: "status = 1", CancelCond: "status = 2"},
+	{Key: "mine", Game: constant.GameNameMine, Category: constant.GameCategoryMine, Table: "mine_game_order", RoundCol: "round_id", SettledCond: "settled = 1", CancelCond: "0"},
+	{Key: "limbo", Game: "Limbo", Category: constant.GameCategoryCompanyChess, Table: "limbo_game_order", RoundCol: "round_id", SettledCond: "settled = 1", CancelCond: "0"},
+	{Key: "wingo", Game: "Wingo", Category: constant.GameCategoryCompanyChess, Table: "wingo_order", RoundCol: "period", SettledCond: "status = 1", CancelCond: "0"},
+	{Key: "nine", Game: "Nine", Category: constant.GameCategoryCompanyChess, Table: "nine_order", RoundCol: "period", SettledCond: "status = 1", CancelCond: "0"},
+	{Key: "hash_sd", Game: "Hash SD", Category: constant.GameCategoryHash, Table: "hash_sd_game_order", RoundCol: "roundId", SettledCond: "status = 1", CancelCond: "0"},
+	{Key: constant.BET_SOURCE_PROVIDER, Table: "game_record"},
+}
+
+// 自研游戏结算时也会写game_record 三方来源里排除这些分类避免重复
+var betHistoryHouseCategories = []string{constant.GameCategoryCrash, constant.GameCategoryDice, constant.GameCategoryMine}
+
+type BetHistoryRow struct {
+	ID        uint
+	BetTime   int64
+	BetAmount float64
+	Payout    float64
+	RoundID   string
+	Game      string
+	Category  string
+	Settled   bool
+	Canceled  bool
+}
+
+// 按游标查询单个来源的投注记录 按投注时间和ID倒序
+// 合并后的顺序为 时间倒序 > 来源顺序 > ID倒序 游标条件需要和它保持一致
+func (r *HistoryRepository) GetBetHistoryRows(sourceIndex int, filter *entities.BetHistoryFilter, cursor *entities.BetHistoryCursor, limit int) ([]*BetHistoryRow, error) {
+	source := BetHistorySources[sourceIndex]
+	provider := source.Key == constant.BET_SOURCE_PROVIDER
+
+	var tx *gorm.DB
+	var payoutExpr, settledCond, cancelCond string
+	var timeValue func(int64) interface{}
+	if provider {
+		payoutExpr, settledCond, cancelCond = "bet_amount + profit", "status >= 1", "0"
+		timeValue = func(t int64) interface{} { return time.Unix(t, 0) }
+		tx = r.DB.Table(source.Table).
+			Select("id, UNIX_TIMESTAMP(bet_time) AS bet_time, bet_amount, bet_amount + profit AS payout, record_id AS round_id, game, category, status >= 1 AS settled, 0 AS canceled").
+			Where("(category IS NULL OR category NOT IN ?)", betHistoryHouseCategories)
+		if filter.Game != "" {
+			tx = tx.Where("game = ?", filter.Game)
+		}
+	} else {
+		payoutExpr, settledCond, cancelCond = "reward_amount", source.SettledCond, source.CancelCond
+		timeValue = func(t int64) interface{} { return t }
+		tx = r.DB.Table(source.Table).
+			Select(fmt.Sprintf("id, bet_time, bet_amount, reward_amount AS payout, CAST(%s AS CHAR) AS round_id, (%s) AS settled, (%s) AS canceled",
+				source.RoundCol, settledCond, cancelCond))
+	}
+
+	tx = tx.Where("uid = ?", filter.UID)
+	if filter.StartTime > 0 {
+		tx = tx.Where("bet_time >= ?", timeValue(filter.StartTime))
+	}
+	if filter.EndTime > 0 {
+		tx = tx.Where("bet_time < ?", timeValue(filter.EndTime))
+	}
+
+	switch filter.Result {
+	case constant.BET_RESULT_WIN:
+		tx = tx.Where(fmt.Sprintf("(%s) AND NOT (%s) AND %s > bet_amount", settledCond, cancelCond, payoutExpr))
+	case constant.BET_RESULT_LOSE:
+		tx = tx.Where(fmt.Sprintf("(%s) AND NOT (%s) AND %s <= bet_amount", settledCond, cancelCond, payoutExpr))
+	case constant.BET_RESULT_PENDING:
+		tx = tx.Where(fmt.Sprintf("NOT (%s) AND NOT (%s)", settledCond, cancelCond))
+	case constant.BET_RESULT_CANCEL:
+		tx = tx.Where(cancelCond)
+	}
+
+	if cursor != nil {
+		t := timeValue(cursor.BetTime)
+		switch {
+		case sourceIndex < cursor.Source:
+			tx = tx.Where("bet_time < ?", t)
+		case sourceIndex == cursor.Source:
+			tx = tx.Where("(bet_time < ? OR (bet_time = ? AND id < ?))", t, t, cursor.ID)
+		default:
+			tx = tx.Where("bet_time <= ?", t)
+		}
+	}
+
+	list := make([]*BetHistoryRow, 0)
+	err := tx.Order("bet_time desc, id desc").Limit(limit).Scan(&list).Error
+	if !provider {
+		for _, row := range list {
+			row.Game = source.Game
+			row.Category = source.Category
+		}
+	}
+	return list, err
+}
+
+// 各来源最近一次投注时间 三方按游戏名分组
+func (r *HistoryRepository) GetRecentGameList(uid uint, since int64, limit int) ([]*entities.RecentGame, error) {
+	list := make([]*entities.RecentGame, 0)
+	for _, source := range BetHistorySources {
+		if source.Key == constant.BET_SOURCE_PROVIDER {
+			type row struct {
+				Game string
+				Last int64
+			}
+			rows := make([]*row, 0)
+			if err := r.DB.Table(source.Table).
+				Select("game, UNIX_TIMESTAMP(MAX(bet_time)) AS last").
+				Where("uid = ? AND bet_time >= ?", uid, time.Unix(since, 0)).
+				Where("(category IS NULL OR category NOT IN ?)", betHistoryHouseCategories).
+				Group("game").Order("last desc").Limit(limit).Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+			for _, item := range rows {
+				list = append(list, &entities.RecentGame{Source: source.Key, Game: item.Game, LastPlayTime: item.Last})
+			}
+			continue
+		}
+
+		var last int64
+		if err := r.DB.Table(source.Table).Select("IFNULL(MAX(bet_time), 0)").
+			Where("uid = ? AND bet_time >= ?", uid, since).Scan(&last).Error; err != nil {
+			return nil, err
+		}
+		if last > 0 {
+			list = append(list, &entities.RecentGame{Source: source.Key, Game: source.Game, LastPlayTime: last})
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var OutboxRepositorySet = wire.NewSet(wire.Struct(new(OutboxRepository), "*"))
+
+type OutboxRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 到期待投递的消息 按写入顺序 刚提交的事务要读主库
+func (r *OutboxRepository) GetPendingOutboxMessages(now int64, limit int) ([]*entities.OutboxMessage, error) {
+	list := make([]*entities.OutboxMessage, 0)
+	err := r.DB.Clauses(dbresolver.Write).
+		Where("status = ? AND next_time <= ?", constant.OUTBOX_STATUS_PENDING, now).
+		Order("id asc").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (r *OutboxRepository) UpdateOutboxMessage(id uint, values map[string]interface{}) error {
+	return r.DB.Model(&entities.OutboxMessage{}).Where("id = ?", id).Updates(values).Error
+}
+
+// 分批删除已投递的旧消息 返回删除条数
+func (r *OutboxRepository) DeletePublishedOutboxMessages(before int64, limit int) (int64, error) {
+	result := r.DB.Where("status = ? AND publish_time < ?", constant.OUTBOX_STATUS_PUBLISHED, before).
+		Limit(limit).Delete(&entities.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *OutboxRepository) AcquireOutboxRelayLock(node string, expiration time.Duration) (bool, error) {
+	return r.RDS.SetNX(context.Background(), constant.REDIS_KEY_OUTBOX_RELAY_LOCK, node, expiration).Result()
+}
+
+func (r *OutboxRepository) RenewOutboxRelayLock(node string, expiration time.Duration) (bool, error) {
+	n, err := renewLeaseScript.Run(context.Background(), r.RDS, []string{constant.REDIS_KEY_OUTBOX_RELAY_LOCK}, node, expiration.Milliseconds()).Int64()
+	return n > 0, err
+}
+
+func (r *OutboxRepository) ReleaseOutboxRelayLock(node string) error {
+	return releaseLeaseScript.Run(context.Background(), r.RDS, []string{constant.REDIS_KEY_OUTBOX_RELAY_LOCK}, node).Err()
+}
@@ -137,3 +137,107 @@ func (r *RechargeRepository) GetMinRecharge() (float64, error) {
 	}
 	return gm.MinRecharge, result.Error
 }
+
+// 用户已完成的充值次数
+func (r *RechargeRepository) GetCompletedRechargeCountWithTx(tx *gorm.DB, uid uint) (int64, error) {
+	var count int64
+	err := tx.Model(&entities.CompletedRecharge{}).Where("uid = ?", uid).Count(&count).Error
+	return count, err
+}
+
+// 用户当日已完成的充值次数
+func (r *RechargeRepository) GetTodayCompletedRechargeCountWithTx(tx *gorm.DB, uid uint, todayTime int64) (int64, error) {
+	var count int64
+	err := tx.Model(&entities.CompletedRecharge{}).Where("uid = ? AND today_time = ?", uid, todayTime).Count(&count).Error
+	return count, err
+}
+
+// 当前生效中的充值优惠
+func (r *RechargeRepository) GetActiveRechargePromotionList(now int64) ([]*entities.RechargePromotion, error) {
+	list := make([]*entities.RechargePromotion, 0)
+	err := r.DB.Where("status = 1 AND (start_time = 0 OR start_time <= ?) AND (end_time = 0 OR end_time >= ?)", now, now).
+		Order("priority desc, id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *RechargeRepository) GetRechargePromotionList(param *entities.GetRechargePromotionListReq) error {
+	var tx *gorm.DB = r.DB
+	if param.Status != nil {
+		tx = tx.Where("status = ?", *param.Status)
+	}
+	tx = tx.Order("id desc")
+	param.List = make([]*entities.RechargePromotion, 0)
+	return param.Paginate(tx)
+}
+
+func (r *RechargeRepository) GetRechargePromotionByID(id uint) (*entities.RechargePromotion, error) {
+	var entity entities.RechargePromotion
+	result := r.DB.Where("id = ?", id).First(&entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &entity, nil
+}
+
+func (r *RechargeRepository) CreateRechargePromotion(entity *entities.RechargePromotion) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *RechargeRepository) CreateRechargePromotionList(list []*entities.RechargePromotion) error {
+	return r.DB.Create(&list).Error
+}
+
+func (r *RechargeRepository) GetRechargePromotionCount() (int64, error) {
+	var count int64
+	err := r.DB.Model(&entities.RechargePromotion{}).Count(&count).Error
+	return count, err
+}
+
+// 更新规则 已发放金额不允许修改
+func (r *RechargeRepository) UpdateRechargePromotion(entity *entities.RechargePromotion) error {
+	return r.DB.Model(entity).Select("*").Omit("id", "created_at", "granted_amount").Updates(entity).Error
+}
+
+// 累加已发放金额 超出预算返回false
+func (r *RechargeRepository) IncrRechargePromotionGrantedWithTx(tx *gorm.DB, id uint, bonus float64) (bool, error) {
+	result := tx.Model(&entities.RechargePromotion{}).
+		Where("id = ? AND (budget = 0 OR granted_amount + ? <= budget)", id, bonus).
+		Update("granted_amount", gorm.Expr("granted_amount + ?", bonus))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *RechargeRepository) CreateRechargePromotionRecordWithTx(tx *gorm.DB, entity *entities.RechargePromotionRecord) error {
+	return tx.Create(entity).Error
+}
+
+// 订单是否已经发放过该优惠
+func (r *RechargeRepository) ExistRechargePromotionRecord(promotionID uint, orderID string) (bool, error) {
+	var count int64
+	err := r.DB.Clauses(dbresolver.Write).Model(&entities.RechargePromotionRecord{}).
+		Where("promotion_id = ? AND order_id = ?", promotionID, orderID).Count(&count).Error
+	return count > 0, err
+}
+
+// 用户在某个优惠下已获得的次数
+func (r *RechargeRepository) GetRechargePromotionRecordCount(promotionID uint, uid uint) (int64, error) {
+	var count int64
+	err := r.DB.Clauses(dbresolver.Write).Model(&entities.RechargePromotionRecord{}).
+		Where("promotion_id = ? AND uid = ?", promotionID, uid).Count(&count).Error
+	return count, err
+}
+
+func (r *RechargeRepository) GetRechargePromotionRecordList(param *entities.GetRechargePromotionRecordListReq) error {
+	var tx *gorm.DB = r.DB
+	if param.PromotionID != 0 {
+		tx = tx.Where("promotion_id = ?", param.PromotionID)
+	}
+	if param.UID != 0 {
+		tx = tx.Where("uid = ?", param.UID)
+	}
+	tx = tx.Order("id desc")
+	param.List = make([]*entities.RechargePromotionRecord, 0)
+	return param.Paginate(tx)
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"errors"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var ReconcileRepositorySet = wire.NewSet(wire.Struct(new(ReconcileRepository), "*"))
+
+type ReconcileRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 同一三方同一时间段只能有一条报告 多实例同时执行时只有一个能创建成功
+func (r *ReconcileRepository) CreateReconcileReport(entity *entities.ReconcileReport) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *ReconcileRepository) UpdateReconcileReport(entity *entities.ReconcileReport) error {
+	return r.DB.Model(entity).
+		Select("transfers", "records", "matched", "discrepancies", "status", "error").
+		Updates(entity).Error
+}
+
+func (r *ReconcileRepository) CreateReconcileDiscrepancyList(list []*entities.ReconcileDiscrepancy) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return r.DB.CreateInBatches(list, 200).Error
+}
+
+func (r *ReconcileRepository) GetReconcileReportList(param *entities.GetReconcileReportListReq) error {
+	var tx *gorm.DB = r.DB
+	if param.Provider != "" {
+		tx = tx.Where("provider = ?", param.Provider)
+	}
+	if param.Status != 0 {
+		tx = tx.Where("status = ?", param.Status)
+	}
+	tx = tx.Order("id desc")
+	param.List = make([]*entities.ReconcileReport, 0)
+	return param.Paginate(tx)
+}
+
+func (r *ReconcileRepository) GetReconcileDiscrepancyList(param *entities.GetReconcileDiscrepancyListReq) error {
+	var tx *gorm.DB = r.DB
+	if param.ReportID != 0 {
+		tx = tx.Where("report_id = ?", param.ReportID)
+	}
+	if param.Provider != "" {
+		tx = tx.Where("provider = ?", param.Provider)
+	}
+	if param.Type != "" {
+		tx = tx.Where("type = ?", param.Type)
+	}
+	if param.UID != 0 {
+		tx = tx.Where("uid = ?", param.UID)
+	}
+	tx = tx.Order("id desc")
+	param.List = make([]*entities.ReconcileDiscrepancy, 0)
+	return param.Paginate(tx)
+}
+
+// 时间段内的单一钱包交易 不含活动奖励
+func (r *ReconcileRepository) GetSeamlessTransactionsInRange(provider string, startTime, endTime int64) ([]*entities.SeamlessTransaction, error) {
+	list := make([]*entities.SeamlessTransaction, 0)
+	err := r.DB.Where("provider = ? AND created_at >= ? AND created_at < ? AND flow_type <> ?",
+		provider, startTime, endTime, constant.FLOW_TYPE_R8_ACTIVITY_AWARD).
+		Order("id asc").Find(&list).Error
+	return list, err
+}
+
+// 统计同步导入的三方游戏记录 record_id格式为 三方-三方注单号-uid
+func (r *ReconcileRepository) GetGameRecordsInRange(provider string, startTime, endTime int64) ([]*entities.ReconcileBetRecord, error) {
+	list := make([]*entities.GameRecord, 0)
+	err := r.DB.Select("record_id", "uid", "bet_time", "bet_amount", "profit").
+		Where("record_id LIKE ? AND bet_time >= ? AND bet_time < ?", provider+"-%",
+			time.Unix(startTime, 0).UTC(), time.Unix(endTime, 0).UTC()).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*entities.ReconcileBetRecord, 0, len(list))
+	for _, record := range list {
+		recordID := strings.TrimPrefix(record.RecordId, provider+"-")
+		recordID = strings.TrimSuffix(recordID, "-"+strconv.FormatUint(uint64(record.UID), 10))
+		records = append(records, &entities.ReconcileBetRecord{
+			UID:       record.UID,
+			RecordID:  recordID,
+			BetAmount: record.BetAmount,
+			Profit:    record.Profit,
+			Time:      record.BetTime.Unix(),
+		})
+	}
+	return records, nil
+}
+
+func (r *ReconcileRepository) GetR8BetRecordsInRange(startTime, endTime int64) ([]*entities.ReconcileBetRecord, error) {
+	list := make([]*entities.R8BetRecord, 0)
+	if err := r.DB.Where("created_at >= ? AND created_at < ?", startTime, endTime).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	records := make([]*entities.ReconcileBetRecord, 0, len(list))
+	for _, record := range list {
+		uid, err := strconv.ParseUint(record.Account, 10, 64)
+		if err != nil {
+			continue
+		}
+		records = append(records, &entities.ReconcileBetRecord{
+			UID:       uint(uid),
+			RecordID:  record.RecordID,
+			BetAmount: record.BetAmount,
+			Time:      record.CreatedAt,
+		})
+	}
+	return records, nil
+}
+
+// zf的uniqueid即下注回调的bet_id
+func (r *ReconcileRepository) GetZfBetRecordsInRange(startTime, endTime int64) ([]*entities.ReconcileBetRecord, error) {
+	list := make([]*entities.ZfBetRecord, 0)
+	if err := r.DB.Where("created_at >= ? AND created_at < ?", startTime, endTime).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	records := make([]*entities.ReconcileBetRecord, 0, len(list))
+	for _, record := range list {
+		uid, err := strconv.ParseUint(record.Account, 10, 64)
+		if err != nil {
+			continue
+		}
+		records = append(records, &entities.ReconcileBetRecord{
+			UID:       uint(uid),
+			RecordID:  record.RecordID,
+			BetAmount: record.BetAmount,
+			Time:      record.CreatedAt,
+		})
+	}
+	return records, nil
+}
+
+func (r *ReconcileRepository) GetReconcileReport(provider string, startTime int64) (*entities.ReconcileReport, error) {
+	entity := new(entities.ReconcileReport)
+	result := r.DB.Where("provider = ? AND start_time = ?", provider, startTime).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
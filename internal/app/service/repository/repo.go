@@ -43,6 +43,23 @@ var RepoSet = wire.NewSet(
 	MineGameRepositorySet,
 	DiceGameRepositorySet,
 	LimboGameRepositorySet,
+	RiskRepositorySet,
+	SessionRepositorySet,
+	SeamlessRepositorySet,
+	ReconcileRepositorySet,
+	HistoryRepositorySet,
+	GamingRepositorySet,
+	VipRepositorySet,
+	CashbackRepositorySet,
+	AgentStatsRepositorySet,
+	CommissionRepositorySet,
+	AdminAuthRepositorySet,
+	AdminApprovalRepositorySet,
+	ArchiveRepositorySet,
+	SchedulerRepositorySet,
+	OutboxRepositorySet,
+	DeadLetterRepositorySet,
+	EventRepositorySet,
 ) // end
 
 // Auto migration for given models
@@ -69,9 +86,14 @@ func AutoMigrate(db *gorm.DB) error {
 		new(entities.CompletedRecharge),
 
 		new(entities.User),
+		new(entities.UserSession),
+		new(entities.SeamlessTransaction),
+		new(entities.ReconcileReport),
+		new(entities.ReconcileDiscrepancy),
 		new(entities.VerifyCode),
 		new(entities.HongbaoSetting),
 		new(entities.HongbaoRecord),
+		new(entities.HongbaoShare),
 
 		new(entities.WingoRoomSetting),
 		new(entities.WingoPeriod),
@@ -96,6 +118,29 @@ func AutoMigrate(db *gorm.DB) error {
 
 		new(entities.RankStats),
 		new(entities.Game),
+		new(entities.GameCollection),
+		new(entities.GameCollectionItem),
+		new(entities.UserFavoriteGame),
+		new(entities.GamingLimit),
+		new(entities.GamingRestriction),
+		new(entities.VipTier),
+		new(entities.UserVip),
+		new(entities.CashbackRule),
+		new(entities.UserCashback),
+		new(entities.CashbackClaim),
+		new(entities.AgentDailyStats),
+		new(entities.AgentDailyCategoryStats),
+		new(entities.CommissionPlan),
+		new(entities.CommissionPlanAssign),
+		new(entities.CommissionStatement),
+		new(entities.AdminRole),
+		new(entities.AdminUser),
+		new(entities.AdminSession),
+		new(entities.AdminApproval),
+		new(entities.ArchiveJob),
+		new(entities.ScheduledJob),
+		new(entities.ScheduledJobRun),
+		new(entities.OutboxMessage),
 
 		new(entities.QuizFetchRule),
 		new(entities.QuizEvent),
@@ -118,6 +163,7 @@ func AutoMigrate(db *gorm.DB) error {
 		new(entities.Logo),
 		new(entities.GamerDailyStats),
 		new(entities.GlobalSyncStatus),
+		new(entities.GameRecordSyncWindow),
 		new(entities.GameRecord),
 
 		new(entities.HashSDGameRound),
@@ -127,6 +173,13 @@ func AutoMigrate(db *gorm.DB) error {
 		new(entities.CrashAutoBet),
 		new(entities.MineGameOrder),
 		new(entities.DiceGameOrder),
+
+		new(entities.WithdrawRiskRule),
+		new(entities.WithdrawRiskLog),
+		new(entities.TurnoverRule),
+		new(entities.TurnoverRequirement),
+		new(entities.RechargePromotion),
+		new(entities.RechargePromotionRecord),
 	) // end
 
 	AutoIncrement(db)
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"rk-api/internal/app/entities"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var RiskRepositorySet = wire.NewSet(wire.Struct(new(RiskRepository), "*"))
+
+type RiskRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+func (r *RiskRepository) GetWithdrawRiskRuleList() ([]*entities.WithdrawRiskRule, error) {
+	list := make([]*entities.WithdrawRiskRule, 0)
+	err := r.DB.Find(&list).Error
+	return list, err
+}
+
+func (r *RiskRepository) CreateWithdrawRiskRuleList(list []*entities.WithdrawRiskRule) error {
+	return r.DB.Create(&list).Error
+}
+
+func (r *RiskRepository) UpdateWithdrawRiskRule(entity *entities.WithdrawRiskRule) error {
+	return r.DB.Model(entity).Select("threshold", "window", "status").Updates(entity).Error
+}
+
+func (r *RiskRepository) CreateWithdrawRiskLog(entity *entities.WithdrawRiskLog) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *RiskRepository) UpdateWithdrawRiskLog(entity *entities.WithdrawRiskLog) error {
+	return r.DB.Updates(entity).Error
+}
+
+func (r *RiskRepository) GetWithdrawRiskLogList(param *entities.GetWithdrawRiskLogListReq) error {
+	var tx *gorm.DB = r.DB
+	if param.UID != 0 {
+		tx = tx.Where("uid = ?", param.UID)
+	}
+	if param.Decision != 0 {
+		tx = tx.Where("decision = ?", param.Decision)
+	}
+	tx = tx.Order("id desc")
+	param.List = make([]*entities.WithdrawRiskLog, 0)
+	return param.Paginate(tx)
+}
+
+// 更新提现记录的风控状态
+func (r *RiskRepository) UpdateHallWithdrawRecordRisk(id uint, riskState uint8, riskReason string) error {
+	return r.DB.Model(&entities.HallWithdrawRecord{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"risk_state": riskState, "risk_reason": riskReason}).Error
+}
+
+// 窗口内充值总额以及最早一笔充值时间
+func (r *RiskRepository) GetRechargeSince(uid uint, since int64) (float64, int64, error) {
+	type Result struct {
+		Total float64
+		First int64
+	}
+	var result Result
+	err := r.DB.Model(&entities.CompletedRecharge{}).
+		Where("uid = ? AND create_time >= ?", uid, since).
+		Select("IFNULL(SUM(amount), 0) AS total, IFNULL(MIN(create_time), 0) AS first").
+		Scan(&result).Error
+	return result.Total, result.First, err
+}
+
+// 某时间点之后的游戏投注额 (游戏流水中的扣款部分)
+func (r *RiskRepository) GetBetAmountSince(uid uint, since int64) (float64, error) {
+	var total float64
+	err := r.DB.Model(&entities.Flow{}).
+		Where("uid = ? AND type > ? AND number < 0 AND created_at >= ?", uid, 200, since).
+		Select("IFNULL(-SUM(number), 0)").Scan(&total).Error
+	return total, err
+}
+
+// 窗口内的游戏流水 按时间顺序
+func (r *RiskRepository) GetGameFlowListSince(uid uint, since int64, limit int) ([]*entities.Flow, error) {
+	list := make([]*entities.Flow, 0)
+	err := r.DB.Where("uid = ? AND type > ? AND created_at >= ?", uid, 200, since).
+		Select("id", "type", "number").Order("id asc").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// 与该用户共用注册IP/登录IP/提现设备的其他账号数量
+func (r *RiskRepository) CountSharedAccounts(uid uint, ips []string, deviceID string) (int64, error) {
+	uids := make([]uint, 0)
+	if len(ips) > 0 {
+		var ipUIDs []uint
+		if err := r.DB.Model(&entities.User{}).
+			Where("id <> ? AND (ip IN ? OR login_ip IN ?)", uid, ips, ips).
+			Limit(100).Pluck("id", &ipUIDs).Error; err != nil {
+			return 0, err
+		}
+		uids = append(uids, ipUIDs...)
+	}
+	if deviceID != "" {
+		var deviceUIDs []uint
+		if err := r.DB.Model(&entities.WithdrawRiskLog{}).
+			Where("uid <> ? AND device_id = ?", uid, deviceID).
+			Distinct("uid").Limit(100).Pluck("uid", &deviceUIDs).Error; err != nil {
+			return 0, err
+		}
+		uids = append(uids, deviceUIDs...)
+	}
+	set := make(map[uint]struct{}, len(uids))
+	for _, id := range uids {
+		set[id] = struct{}{}
+	}
+	return int64(len(set)), nil
+}
+
+// 使用过同一银行卡提现的其他UID数量
+func (r *RiskRepository) CountCardSharedUIDs(uid uint, accountNumber string) (int64, error) {
+	var count int64
+	err := r.DB.Clauses(dbresolver.Write).Model(&entities.HallWithdrawRecord{}).
+		Where("account_number = ? AND uid <> ?", accountNumber, uid).
+		Distinct("uid").Count(&count).Error
+	return count, err
+}
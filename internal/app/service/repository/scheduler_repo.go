@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+var SchedulerRepositorySet = wire.NewSet(wire.Struct(new(SchedulerRepository), "*"))
+
+type SchedulerRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 租约的值为节点标识 只有持有者可以续期和释放
+var (
+	renewLeaseScript   = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`)
+	releaseLeaseScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`)
+)
+
+func (r *SchedulerRepository) GetScheduledJobList() ([]*entities.ScheduledJob, error) {
+	list := make([]*entities.ScheduledJob, 0)
+	err := r.DB.Clauses(dbresolver.Write).Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *SchedulerRepository) GetScheduledJobByName(name string) (*entities.ScheduledJob, error) {
+	entity := new(entities.ScheduledJob)
+	result := r.DB.Clauses(dbresolver.Write).Where("name = ?", name).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// 多个节点同时启动时只有一个能创建成功
+func (r *SchedulerRepository) CreateScheduledJobIfNotExist(entity *entities.ScheduledJob) error {
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(entity).Error
+}
+
+func (r *SchedulerRepository) UpdateScheduledJob(name string, values map[string]interface{}) error {
+	return r.DB.Model(&entities.ScheduledJob{}).Where("name = ?", name).Updates(values).Error
+}
+
+// 推进触发游标 返回false表示这次触发已被其他节点处理
+func (r *SchedulerRepository) AdvanceScheduledJob(name string, lastFireTime, fireTime, nextFireTime int64) (bool, error) {
+	result := r.DB.Model(&entities.ScheduledJob{}).
+		Where("name = ? AND last_fire_time = ?", name, lastFireTime).
+		Updates(map[string]interface{}{"last_fire_time": fireTime, "next_fire_time": nextFireTime})
+	return result.RowsAffected > 0, result.Error
+}
+
+// 领取手动触发 返回false表示已被其他节点领取
+func (r *SchedulerRepository) ClaimScheduledJobTrigger(name string, triggerTime int64) (bool, error) {
+	result := r.DB.Model(&entities.ScheduledJob{}).
+		Where("name = ? AND trigger_time = ?", name, triggerTime).
+		Update("trigger_time", 0)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *SchedulerRepository) CreateScheduledJobRun(entity *entities.ScheduledJobRun) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *SchedulerRepository) UpdateScheduledJobRun(id uint, values map[string]interface{}) error {
+	return r.DB.Model(&entities.ScheduledJobRun{}).Where("id = ?", id).Updates(values).Error
+}
+
+func (r *SchedulerRepository) GetScheduledJobRunList(req *entities.GetScheduledJobRunListReq) error {
+	tx := r.DB.Model(&entities.ScheduledJobRun{})
+	if req.JobName != "" {
+		tx = tx.Where("job_name = ?", req.JobName)
+	}
+	if req.Status > 0 {
+		tx = tx.Where("status = ?", req.Status)
+	}
+	tx = tx.Order("id desc")
+	req.List = make([]*entities.ScheduledJobRun, 0)
+	return req.Paginate(tx)
+}
+
+func (r *SchedulerRepository) AcquireSchedulerLease(name, node string, expiration time.Duration) (bool, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_SCHEDULER_LEASE, name)
+	return r.RDS.SetNX(context.Background(), key, node, expiration).Result()
+}
+
+func (r *SchedulerRepository) RenewSchedulerLease(name, node string, expiration time.Duration) (bool, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_SCHEDULER_LEASE, name)
+	n, err := renewLeaseScript.Run(context.Background(), r.RDS, []string{key}, node, expiration.Milliseconds()).Int64()
+	return n > 0, err
+}
+
+func (r *SchedulerRepository) ReleaseSchedulerLease(name, node string) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_SCHEDULER_LEASE, name)
+	return releaseLeaseScript.Run(context.Background(), r.RDS, []string{key}, node).Err()
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"errors"
+	"rk-api/internal/app/entities"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+var SeamlessRepositorySet = wire.NewSet(wire.Struct(new(SeamlessRepository), "*"))
+
+type SeamlessRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+func (r *SeamlessRepository) GetTransaction(provider, externalID string) (*entities.SeamlessTransaction, error) {
+	entity := new(entities.SeamlessTransaction)
+	result := r.DB.Clauses(dbresolver.Write).Where("provider = ? AND external_id = ?", provider, externalID).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// 事务内锁定原交易 防止同一笔扣款被并发派彩/回滚
+func (r *SeamlessRepository) GetTransactionForUpdate(tx *gorm.DB, provider, externalID string) (*entities.SeamlessTransaction, error) {
+	entity := new(entities.SeamlessTransaction)
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("provider = ? AND external_id = ?", provider, externalID).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *SeamlessRepository) debitQuery(tx *gorm.DB, provider string, uid uint, roundID, recordID, debitAction string) *gorm.DB {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("provider = ? AND uid = ? AND action = ?", provider, uid, debitAction)
+	if roundID != "" {
+		query = query.Where("round_id = ?", roundID)
+	}
+	if recordID != "" {
+		query = query.Where("record_id = ?", recordID)
+	}
+	return query
+}
+
+// 按局号/注单号锁定用户未结算的扣款 最多返回limit笔 调用方据此判断是否唯一
+func (r *SeamlessRepository) GetOpenDebitTransactionListForUpdate(tx *gorm.DB, provider string, uid uint, roundID, recordID, debitAction string, openStatus uint8, limit int) ([]*entities.SeamlessTransaction, error) {
+	list := make([]*entities.SeamlessTransaction, 0, limit)
+	err := r.debitQuery(tx, provider, uid, roundID, recordID, debitAction).
+		Where("status = ?", openStatus).Order("id desc").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// 按局号/注单号锁定用户最近一笔扣款 不限状态
+func (r *SeamlessRepository) GetDebitTransactionForUpdate(tx *gorm.DB, provider string, uid uint, roundID, recordID, debitAction string) (*entities.SeamlessTransaction, error) {
+	entity := new(entities.SeamlessTransaction)
+	result := r.debitQuery(tx, provider, uid, roundID, recordID, debitAction).Order("id desc").First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// 按局号/注单号查找用户未结算的扣款 flowType为0时不限类型
+func (r *SeamlessRepository) GetOpenDebitTransaction(provider string, uid uint, roundID, recordID string, flowType uint16, debitAction string, openStatus uint8) (*entities.SeamlessTransaction, error) {
+	entity := new(entities.SeamlessTransaction)
+	query := r.DB.Clauses(dbresolver.Write).Where("provider = ? AND uid = ? AND action = ? AND status = ?", provider, uid, debitAction, openStatus)
+	if roundID != "" {
+		query = query.Where("round_id = ?", roundID)
+	}
+	if recordID != "" {
+		query = query.Where("record_id = ?", recordID)
+	}
+	if flowType != 0 {
+		query = query.Where("flow_type = ?", flowType)
+	}
+	result := query.Order("id desc").First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *SeamlessRepository) CreateTransactionWithTx(tx *gorm.DB, entity *entities.SeamlessTransaction) error {
+	return tx.Create(entity).Error
+}
+
+func (r *SeamlessRepository) UpdateTransactionStatusWithTx(tx *gorm.DB, id uint, status uint8) error {
+	return tx.Model(&entities.SeamlessTransaction{}).Where("id = ?", id).UpdateColumn("status", status).Error
+}
+
+// 一局内未结算的扣款全部标记为已结算
+func (r *SeamlessRepository) SettleRoundTransactions(provider, gameCode, roundID string, debitAction string, fromStatus, toStatus uint8) (int64, error) {
+	result := r.DB.Model(&entities.SeamlessTransaction{}).
+		Where("provider = ? AND game_code = ? AND round_id = ? AND action = ? AND status = ?", provider, gameCode, roundID, debitAction, fromStatus).
+		UpdateColumn("status", toStatus)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var SessionRepositorySet = wire.NewSet(wire.Struct(new(SessionRepository), "*"))
+
+type SessionRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 只更新还存在的会话缓存 撤销/退出删除后不会被重新写回 并按写入时的过期时间重新设置TTL
+var setUserSessionSeenScript = redis.NewScript(`if redis.call("EXISTS", KEYS[1]) == 0 then return 0 end
+redis.call("HSET", KEYS[1], "seen", ARGV[1])
+local exp = redis.call("HGET", KEYS[1], "exp")
+if exp then redis.call("EXPIREAT", KEYS[1], exp) end
+return 1`)
+
+func (r *SessionRepository) CreateUserSession(entity *entities.UserSession) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *SessionRepository) GetUserSessionBySID(sid string) (*entities.UserSession, error) {
+	entity := new(entities.UserSession)
+	result := r.DB.Clauses(dbresolver.Write).Where("session_id = ?", sid).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+// 有效会话 最新登录的在前
+func (r *SessionRepository) GetActiveUserSessionList(uid uint) ([]*entities.UserSession, error) {
+	list := make([]*entities.UserSession, 0)
+	err := r.DB.Clauses(dbresolver.Write).
+		Where("uid = ? AND status = ? AND expire_time > ?", uid, constant.USER_SESSION_STATUS_ACTIVE, time.Now().Unix()).
+		Order("id desc").Find(&list).Error
+	return list, err
+}
+
+// refresh token 轮换 只有携带当前编号的请求能成功 并发/重放的请求影响行数为0
+func (r *SessionRepository) RotateUserSessionRefresh(sid, oldJti, newJti string, expireTime int64, ip string) (bool, error) {
+	result := r.DB.Model(&entities.UserSession{}).
+		Where("session_id = ? AND refresh_jti = ? AND status = ?", sid, oldJti, constant.USER_SESSION_STATUS_ACTIVE).
+		Updates(map[string]interface{}{
+			"refresh_jti": newJti,
+			"expire_time": expireTime,
+			"last_ip":     ip,
+			"last_seen":   time.Now().Unix(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *SessionRepository) RevokeUserSession(sid string, reason string) error {
+	err := r.DB.Model(&entities.UserSession{}).
+		Where("session_id = ? AND status = ?", sid, constant.USER_SESSION_STATUS_ACTIVE).
+		Updates(map[string]interface{}{
+			"status":        constant.USER_SESSION_STATUS_REVOKED,
+			"revoke_reason": reason,
+			"revoke_time":   time.Now().Unix(),
+		}).Error
+	if err != nil {
+		return err
+	}
+	return r.DelUserSessionCache(sid)
+}
+
+func (r *SessionRepository) UpdateUserSessionSeen(sid string, ip string, seen int64) error {
+	return r.DB.Model(&entities.UserSession{}).Where("session_id = ?", sid).
+		Updates(map[string]interface{}{"last_ip": ip, "last_seen": seen}).Error
+}
+
+// 会话缓存 JWTMiddleware每次请求都会校验
+func (r *SessionRepository) SetUserSessionCache(sid string, uid uint, seen int64, expiration time.Duration) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_USER_SESSION, sid)
+	pipe := r.RDS.TxPipeline()
+	pipe.HSet(context.Background(), key, "uid", uid, "seen", seen, "exp", time.Now().Add(expiration).Unix())
+	pipe.Expire(context.Background(), key, expiration)
+	_, err := pipe.Exec(context.Background())
+	return err
+}
+
+func (r *SessionRepository) GetUserSessionCache(sid string) (map[string]string, error) {
+	key := fmt.Sprintf(constant.REDIS_KEY_USER_SESSION, sid)
+	return r.RDS.HGetAll(context.Background(), key).Result()
+}
+
+func (r *SessionRepository) SetUserSessionCacheSeen(sid string, seen int64) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_USER_SESSION, sid)
+	return setUserSessionSeenScript.Run(context.Background(), r.RDS, []string{key}, seen).Err()
+}
+
+func (r *SessionRepository) DelUserSessionCache(sid string) error {
+	key := fmt.Sprintf(constant.REDIS_KEY_USER_SESSION, sid)
+	return r.RDS.Del(context.Background(), key).Err()
+}
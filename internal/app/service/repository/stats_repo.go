@@ -271,3 +271,45 @@ func (r *StatsRepository) GetProfitLeaderboard() ([]*entities.GameProfitStats, e
 		Find(&results).Error
 	return results, err
 }
+
+func (r *StatsRepository) CreateGameRecordSyncWindow(entity *entities.GameRecordSyncWindow) error {
+	return r.DB.Create(entity).Error
+}
+
+func (r *StatsRepository) UpdateGameRecordSyncWindow(entity *entities.GameRecordSyncWindow) error {
+	return r.DB.Model(entity).
+		Select("pages", "fetched", "inserted", "updated", "provider_total", "status", "error").
+		Updates(entity).Error
+}
+
+func (r *StatsRepository) GetGameRecordSyncWindowList(param *entities.GetGameRecordSyncWindowListReq) error {
+	var tx *gorm.DB = r.DB
+	if param.Source != "" {
+		tx = tx.Where("source = ?", param.Source)
+	}
+	if param.Status != nil {
+		tx = tx.Where("status = ?", *param.Status)
+	}
+	tx = tx.Order("id desc")
+	param.List = make([]*entities.GameRecordSyncWindow, 0)
+	return param.Paginate(tx)
+}
+
+// 与时间段有交集的同步窗口 按开始时间排序
+func (r *StatsRepository) GetGameRecordSyncWindowsInRange(source string, startTime, endTime int64, status []uint8) ([]*entities.GameRecordSyncWindow, error) {
+	list := make([]*entities.GameRecordSyncWindow, 0)
+	err := r.DB.Where("source = ? AND start_time < ? AND end_time > ? AND status IN ?", source, endTime, startTime, status).
+		Order("start_time asc").Find(&list).Error
+	return list, err
+}
+
+// 事务内查询已存在的游戏记录 用于区分新增/更新
+func (r *StatsRepository) GetGameRecordsByRecordIDWithTx(tx *gorm.DB, recordIDs []string) ([]*entities.GameRecord, error) {
+	list := make([]*entities.GameRecord, 0)
+	if len(recordIDs) == 0 {
+		return list, nil
+	}
+	err := tx.Select("id", "record_id", "bet_time", "bet_amount", "amount", "profit", "uid", "currency").
+		Where("record_id IN ?", recordIDs).Find(&list).Error
+	return list, err
+}
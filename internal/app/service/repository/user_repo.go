@@ -67,6 +67,15 @@ func (r *UserRepository) UpdateUserWithTx(tx *gorm.DB, user *entities.User) erro
 	// return tx.Debug().Updates(user).Error
 }
 
+// 邀请关系迁移 邀请人可能被置空 不能用Updates(struct)
+func (r *UserRepository) UpdateUserInviterWithTx(tx *gorm.DB, uid uint, promoter string, pc int, inviter string) error {
+	return tx.Model(&entities.User{}).Where("id = ?", uid).Updates(map[string]interface{}{
+		"promoter": promoter,
+		"pc":       pc,
+		"inviter":  inviter,
+	}).Error
+}
+
 func (r *UserRepository) UpdateUserWithSelects(selects []string, user *entities.User) error {
 	return r.DB.Model(user).Select(selects).Updates(user).Error
 }
@@ -262,6 +271,17 @@ func (r *UserRepository) UpdateUser(user *entities.User) error {
 	return r.RDS.Del(context.Background(), key).Err() // 删除旧的缓存
 }
 
+// VIP等级可能降为0 不能用Updates(struct)
+func (r *UserRepository) UpdateUserVipLevel(uid uint, level uint8) error {
+
+	key := fmt.Sprintf("user:base:%d", uid)
+
+	if err := r.DB.Model(&entities.User{}).Where("id = ?", uid).Update("vip_level", level).Error; err != nil {
+		return err
+	}
+	return r.RDS.Del(context.Background(), key).Err() // 删除旧的缓存
+}
+
 func (r *UserRepository) UpdateUserNameAndGender(uid uint, nickname string, gender uint8) error {
 
 	key := fmt.Sprintf("user:base:%d", uid)
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"errors"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+var VipRepositorySet = wire.NewSet(wire.Struct(new(VipRepository), "*"))
+
+type VipRepository struct {
+	DB  *gorm.DB
+	RDS redis.UniversalClient
+}
+
+// 按用户汇总的金额
+type VipAmount struct {
+	UID    uint
+	Amount float64
+}
+
+func (r *VipRepository) GetVipTierList() ([]*entities.VipTier, error) {
+	list := make([]*entities.VipTier, 0)
+	err := r.DB.Order("level asc").Find(&list).Error
+	return list, err
+}
+
+func (r *VipRepository) GetVipTierByID(id uint) (*entities.VipTier, error) {
+	entity := new(entities.VipTier)
+	result := r.DB.Clauses(dbresolver.Write).Where("id = ?", id).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *VipRepository) SaveVipTier(entity *entities.VipTier) error {
+	return r.DB.Save(entity).Error
+}
+
+func (r *VipRepository) DelVipTier(id uint) error {
+	return r.DB.Where("id = ?", id).Delete(&entities.VipTier{}).Error
+}
+
+func (r *VipRepository) GetUserVip(uid uint) (*entities.UserVip, error) {
+	entity := new(entities.UserVip)
+	result := r.DB.Clauses(dbresolver.Write).Where("uid = ?", uid).First(entity)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return entity, nil
+}
+
+func (r *VipRepository) GetUserVipListByUIDs(uids []uint) ([]*entities.UserVip, error) {
+	list := make([]*entities.UserVip, 0, len(uids))
+	err := r.DB.Clauses(dbresolver.Write).Where("uid IN ?", uids).Find(&list).Error
+	return list, err
+}
+
+// 当前有等级的用户 没有活跃的也需要重新评定
+func (r *VipRepository) GetLeveledUIDList() ([]uint, error) {
+	uids := make([]uint, 0)
+	err := r.DB.Model(&entities.UserVip{}).Where("level > 0").Pluck("uid", &uids).Error
+	return uids, err
+}
+
+func (r *VipRepository) SaveUserVip(entity *entities.UserVip) error {
+	return r.DB.Save(entity).Error
+}
+
+// 发放升级奖励前更新最高等级 返回false表示已经被更新过
+func (r *VipRepository) UpdateUserVipMaxLevelWithTx(tx *gorm.DB, uid uint, from, to uint8) (bool, error) {
+	result := tx.Model(&entities.UserVip{}).Where("uid = ? AND max_level = ?", uid, from).Update("max_level", to)
+	return result.RowsAffected > 0, result.Error
+}
+
+// 发放生日奖励前更新年份 返回false表示今年已经发过
+func (r *VipRepository) UpdateUserVipBirthdayYearWithTx(tx *gorm.DB, uid uint, year int) (bool, error) {
+	result := tx.Model(&entities.UserVip{}).Where("uid = ? AND birthday_year < ?", uid, year).Update("birthday_year", year)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *VipRepository) GetBirthdayUserVipList(birthdays []string, year int, setBefore int64) ([]*entities.UserVip, error) {
+	list := make([]*entities.UserVip, 0)
+	err := r.DB.Where("birthday IN ? AND birthday_year < ? AND birthday_time <= ?", birthdays, year, setBefore).Find(&list).Error
+	return list, err
+}
+
+// 周期内自研游戏(wingo/nine等)的投注 这些游戏不写game_record
+func (r *VipRepository) SumGameFlowBet(since int64) ([]*VipAmount, error) {
+	list := make([]*VipAmount, 0)
+	err := r.DB.Model(&entities.Flow{}).
+		Select("uid, -SUM(number) AS amount").
+		Where("type > ? AND type < ? AND number < 0 AND created_at >= ?", 200, 300, since).
+		Group("uid").Scan(&list).Error
+	return list, err
+}
+
+// 周期内game_record的有效流水 包括三方游戏和crash/mine/dice
+func (r *VipRepository) SumGameRecordTurnover(since time.Time) ([]*VipAmount, error) {
+	list := make([]*VipAmount, 0)
+	err := r.DB.Model(&entities.GameRecord{}).
+		Select("uid, SUM(amount) AS amount").
+		Where("bet_time >= ?", since).
+		Group("uid").Scan(&list).Error
+	return list, err
+}
+
+func (r *VipRepository) SumRecharge(since int64) ([]*VipAmount, error) {
+	list := make([]*VipAmount, 0)
+	err := r.DB.Model(&entities.Flow{}).
+		Select("uid, SUM(number) AS amount").
+		Where("type = ? AND created_at >= ?", constant.FLOW_TYPE_RECHARGE_CASH, since).
+		Group("uid").Scan(&list).Error
+	return list, err
+}
+
+// 单个用户周期内自研游戏的投注
+func (r *VipRepository) SumUserGameFlowBet(uid uint, since int64) (float64, error) {
+	var amount float64
+	err := r.DB.Model(&entities.Flow{}).
+		Select("COALESCE(-SUM(number), 0)").
+		Where("uid = ? AND type > ? AND type < ? AND number < 0 AND created_at >= ?", uid, 200, 300, since).
+		Scan(&amount).Error
+	return amount, err
+}
+
+func (r *VipRepository) SumUserGameRecordTurnover(uid uint, since time.Time) (float64, error) {
+	var amount float64
+	err := r.DB.Model(&entities.GameRecord{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("uid = ? AND bet_time >= ?", uid, since).
+		Scan(&amount).Error
+	return amount, err
+}
+
+// 单个用户周期内的充值 按已完成充值表统计 充值流水是异步写入的
+func (r *VipRepository) SumUserCompletedRecharge(uid uint, since int64) (float64, error) {
+	var amount float64
+	err := r.DB.Clauses(dbresolver.Write).Model(&entities.CompletedRecharge{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("uid = ? AND create_time >= ?", uid, since).
+		Scan(&amount).Error
+	return amount, err
+}
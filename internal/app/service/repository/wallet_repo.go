@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/pkg/logger"
 	"rk-api/pkg/rds"
@@ -13,6 +14,8 @@ import (
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
 )
 
 var WalletRepositorySet = wire.NewSet(wire.Struct(new(WalletRepository), "*"))
@@ -134,3 +137,48 @@ func (r *WalletRepository) GetWallet(uid uint) (*entities.UserWallet, error) {
 	}
 	return &wallet, nil
 }
+
+func (r *WalletRepository) GetTurnoverRuleList() ([]*entities.TurnoverRule, error) {
+	list := make([]*entities.TurnoverRule, 0)
+	err := r.DB.Find(&list).Error
+	return list, err
+}
+
+func (r *WalletRepository) SaveTurnoverRule(entity *entities.TurnoverRule) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "flow_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"multiplier", "status", "updated_at"}),
+	}).Create(entity).Error
+}
+
+func (r *WalletRepository) CreateTurnoverRequirementWithTx(tx *gorm.DB, entity *entities.TurnoverRequirement) error {
+	return tx.Create(entity).Error
+}
+
+func (r *WalletRepository) UpdateTurnoverRequirementWithTx(tx *gorm.DB, entity *entities.TurnoverRequirement) error {
+	return tx.Model(entity).Select("wagered", "status", "finish_time").Updates(entity).Error
+}
+
+// 进行中的打码量要求 加行锁 按入账顺序
+func (r *WalletRepository) GetActiveTurnoverRequirementListForUpdate(tx *gorm.DB, uid uint) ([]*entities.TurnoverRequirement, error) {
+	list := make([]*entities.TurnoverRequirement, 0)
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("uid = ? AND status = ?", uid, constant.TURNOVER_STATUS_ACTIVE).
+		Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *WalletRepository) GetActiveTurnoverRequirementList(uid uint) ([]*entities.TurnoverRequirement, error) {
+	list := make([]*entities.TurnoverRequirement, 0)
+	err := r.DB.Clauses(dbresolver.Write).
+		Where("uid = ? AND status = ?", uid, constant.TURNOVER_STATUS_ACTIVE).
+		Order("id asc").Find(&list).Error
+	return list, err
+}
+
+func (r *WalletRepository) GetTurnoverRequirementList(param *entities.GetTurnoverRequirementListReq) error {
+	var tx *gorm.DB = r.DB
+	tx = tx.Where("uid = ? AND status = ?", param.UID, param.Status).Order("id desc")
+	param.List = make([]*entities.TurnoverRequirement, 0)
+	return param.Paginate(tx)
+}
@@ -95,6 +95,15 @@ func (r *WithdrawRepository) GetTodayWithdrawCount(uid uint) (int64, error) {
 	return count, nil
 }
 
+func (r *WithdrawRepository) GetTodayWithdrawCash(uid uint) (float64, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var total float64
+	err := r.DB.Model(&entities.HallWithdrawRecord{}).Where("uid = ? and status in(0,1,3,4)", uid).Where("created_at >= ?", today.Unix()).
+		Select("IFNULL(SUM(cash), 0)").Scan(&total).Error
+	return total, err
+}
+
 func (r *WithdrawRepository) GetHallWithdrawRecord(entity *entities.HallWithdrawRecord) (*entities.HallWithdrawRecord, error) {
 	// result := r.DB.Last(&entity, entity)
 
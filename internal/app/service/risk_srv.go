@@ -0,0 +1,284 @@
+package service
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/internal/app/utils"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/orca-zhang/ecache"
+	"go.uber.org/zap"
+)
+
+const (
+	withdrawRiskRuleCacheKey = "withdraw_risk_rules"
+	withdrawRiskFlowLimit    = 2000 //连赢统计最多取的流水条数
+)
+
+var RiskServiceSet = wire.NewSet(
+	ProvideRiskService,
+)
+
+type RiskService struct {
+	Repo      *repository.RiskRepository
+	ruleCache *ecache.Cache
+}
+
+func ProvideRiskService(repo *repository.RiskRepository) *RiskService {
+	ruleCache := ecache.NewLRUCache(1, 2, time.Minute) //初始化缓存
+	return &RiskService{
+		Repo:      repo,
+		ruleCache: ruleCache,
+	}
+}
+
+// 默认规则 表为空时写入
+func defaultWithdrawRiskRules() []*entities.WithdrawRiskRule {
+	return []*entities.WithdrawRiskRule{
+		{Code: constant.WITHDRAW_RISK_RULE_TURNOVER, Name: "充值后投注倍数", Threshold: 1, Window: 7 * 24 * 3600, Status: 1},
+		{Code: constant.WITHDRAW_RISK_RULE_ACCOUNT_AGE, Name: "新注册账号(小时)", Threshold: 24, Status: 1},
+		{Code: constant.WITHDRAW_RISK_RULE_SHARED_IP, Name: "IP/设备共用账号数", Threshold: 3, Status: 1},
+		{Code: constant.WITHDRAW_RISK_RULE_WIN_STREAK, Name: "连赢局数", Threshold: 10, Window: 24 * 3600, Status: 1},
+		{Code: constant.WITHDRAW_RISK_RULE_CARD_REUSE, Name: "银行卡多账号使用", Threshold: 1, Status: 1},
+		{Code: constant.WITHDRAW_RISK_RULE_AUTO_APPROVE, Name: "自动通过最大金额", Threshold: 1000, Status: 1},
+	}
+}
+
+func (s *RiskService) GetWithdrawRiskRuleList() ([]*entities.WithdrawRiskRule, error) {
+	if val, ok := s.ruleCache.Get(withdrawRiskRuleCacheKey); ok {
+		return val.([]*entities.WithdrawRiskRule), nil
+	}
+	list, err := s.Repo.GetWithdrawRiskRuleList()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		list = defaultWithdrawRiskRules()
+		if err := s.Repo.CreateWithdrawRiskRuleList(list); err != nil {
+			logger.ZError("CreateWithdrawRiskRuleList", zap.Error(err))
+		}
+	}
+	s.ruleCache.Put(withdrawRiskRuleCacheKey, list)
+	return list, nil
+}
+
+func (s *RiskService) UpdateWithdrawRiskRule(req *entities.UpdateWithdrawRiskRuleReq) (err error) {
+	var rule *entities.WithdrawRiskRule
+
+	defer func() {
+		log := entities.SystemOptionLog{
+			Type:     constant.SYS_OPTION_TYPE_WITHDRAW_RISK_RULE,
+			OptionID: req.OptionID,
+			IP:       req.IP,
+			Content:  cjson.StringifyIgnore(req),
+			Data:     cjson.StringifyIgnore(rule),
+		}
+		if err != nil {
+			log.Result = "false"
+			log.Remark = "提现风控规则修改失败"
+		} else {
+			log.Result = "true"
+			log.Remark = "提现风控规则修改成功"
+		}
+		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+		if err := mq.Publish(optionLogQueue); err != nil {
+			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+		}
+	}()
+
+	list, err := s.GetWithdrawRiskRuleList()
+	if err != nil {
+		return err
+	}
+	for _, r := range list {
+		if r.Code == req.Code {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		return errors.WithCode(errors.WithdrawRiskRuleNotExist)
+	}
+	// 大额提现需要两个管理员复核 自动通过不能绕过
+	if rule.Code == constant.WITHDRAW_RISK_RULE_AUTO_APPROVE && req.Threshold >= constant.ADMIN_APPROVAL_WITHDRAW_THRESHOLD {
+		return errors.WithCode(errors.WithdrawAutoApproveTooHigh)
+	}
+
+	ruleForUpdate := *rule
+	ruleForUpdate.Threshold = req.Threshold
+	ruleForUpdate.Window = req.Window
+	ruleForUpdate.Status = req.Status
+	if err = s.Repo.UpdateWithdrawRiskRule(&ruleForUpdate); err != nil {
+		return err
+	}
+	s.ruleCache.Del(withdrawRiskRuleCacheKey)
+	return nil
+}
+
+func (s *RiskService) GetWithdrawRiskLogList(req *entities.GetWithdrawRiskLogListReq) error {
+	return s.Repo.GetWithdrawRiskLogList(req)
+}
+
+// 提现风控评估 记录决策日志并回写提现记录的风控状态
+func (s *RiskService) EvaluateWithdrawal(user *entities.User, record *entities.HallWithdrawRecord, param *entities.ApplyForWithdrawalReq) (*entities.WithdrawRiskResult, *entities.WithdrawRiskLog, error) {
+	rules, err := s.GetWithdrawRiskRuleList()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().Unix()
+	result := &entities.WithdrawRiskResult{Reasons: make([]string, 0)}
+	metrics := &result.Metrics
+	var autoApprove *entities.WithdrawRiskRule
+
+	for _, rule := range rules {
+		if rule.Status != 1 {
+			continue
+		}
+		switch rule.Code {
+		case constant.WITHDRAW_RISK_RULE_TURNOVER:
+			var first int64
+			metrics.RecentRecharge, first, err = s.Repo.GetRechargeSince(user.ID, now-rule.Window)
+			if err != nil {
+				return nil, nil, err
+			}
+			if metrics.RecentRecharge <= 0 {
+				continue
+			}
+			if metrics.Turnover, err = s.Repo.GetBetAmountSince(user.ID, first); err != nil {
+				return nil, nil, err
+			}
+			if metrics.Turnover < metrics.RecentRecharge*rule.Threshold {
+				result.Reasons = append(result.Reasons, fmt.Sprintf("充值后投注不足: 投注%.2f 充值%.2f 要求%.1f倍", metrics.Turnover, metrics.RecentRecharge, rule.Threshold))
+			}
+
+		case constant.WITHDRAW_RISK_RULE_ACCOUNT_AGE:
+			metrics.AccountAge = now - user.CreatedAt
+			if float64(metrics.AccountAge) < rule.Threshold*3600 {
+				result.Reasons = append(result.Reasons, fmt.Sprintf("新注册账号: 注册%d小时", metrics.AccountAge/3600))
+			}
+
+		case constant.WITHDRAW_RISK_RULE_SHARED_IP:
+			ips := make([]string, 0, 3)
+			for _, ip := range []string{user.IP, user.LoginIP, param.IP} {
+				if ip != "" {
+					ips = append(ips, ip)
+				}
+			}
+			if metrics.SharedAccounts, err = s.Repo.CountSharedAccounts(user.ID, ips, param.DeviceID); err != nil {
+				return nil, nil, err
+			}
+			if float64(metrics.SharedAccounts) >= rule.Threshold {
+				result.Reasons = append(result.Reasons, fmt.Sprintf("IP/设备共用: 其他账号%d个", metrics.SharedAccounts))
+			}
+
+		case constant.WITHDRAW_RISK_RULE_WIN_STREAK:
+			var flows []*entities.Flow
+			if flows, err = s.Repo.GetGameFlowListSince(user.ID, now-rule.Window, withdrawRiskFlowLimit); err != nil {
+				return nil, nil, err
+			}
+			metrics.WinStreak = longestWinStreak(flows)
+			if float64(metrics.WinStreak) >= rule.Threshold {
+				result.Reasons = append(result.Reasons, fmt.Sprintf("连赢异常: 最长连赢%d局", metrics.WinStreak))
+			}
+
+		case constant.WITHDRAW_RISK_RULE_CARD_REUSE:
+			if metrics.CardSharedUIDs, err = s.Repo.CountCardSharedUIDs(user.ID, record.AccountNumber); err != nil {
+				return nil, nil, err
+			}
+			if float64(metrics.CardSharedUIDs) >= rule.Threshold {
+				result.Reasons = append(result.Reasons, fmt.Sprintf("银行卡复用: 其他UID%d个", metrics.CardSharedUIDs))
+			}
+
+		case constant.WITHDRAW_RISK_RULE_AUTO_APPROVE:
+			autoApprove = rule
+		}
+	}
+
+	if len(result.Reasons) > 0 {
+		result.Decision = constant.WITHDRAW_RISK_STATE_HOLD
+	} else if autoApprove != nil && record.Cash <= autoApprove.Threshold && record.Cash < constant.ADMIN_APPROVAL_WITHDRAW_THRESHOLD { //达到复核金额的走人工审核
+		result.Decision = constant.WITHDRAW_RISK_STATE_PASS
+	} else {
+		result.Decision = constant.WITHDRAW_RISK_STATE_MANUAL
+	}
+
+	reason := strings.Join(result.Reasons, ";")
+	log := &entities.WithdrawRiskLog{
+		UID:      user.ID,
+		RecordID: record.ID,
+		OrderID:  record.OrderID,
+		Cash:     record.Cash,
+		IP:       param.IP,
+		DeviceID: param.DeviceID,
+		Decision: result.Decision,
+		Reasons:  utils.TruncateRunes(reason, 512),
+		Snapshot: cjson.StringifyIgnore(metrics),
+		Time:     now,
+	}
+	if err := s.Repo.CreateWithdrawRiskLog(log); err != nil {
+		logger.ZError("CreateWithdrawRiskLog", zap.Any("log", log), zap.Error(err))
+	}
+
+	if err := s.Repo.UpdateHallWithdrawRecordRisk(record.ID, result.Decision, utils.TruncateRunes(reason, 255)); err != nil {
+		logger.ZError("UpdateHallWithdrawRecordRisk", zap.Uint("id", record.ID), zap.Error(err))
+	}
+
+	logger.ZInfo("EvaluateWithdrawal", zap.Uint("uid", user.ID), zap.String("orderID", record.OrderID),
+		zap.Uint8("decision", result.Decision), zap.String("reasons", reason))
+	return result, log, nil
+}
+
+// 记录自动通过的执行结果
+func (s *RiskService) UpdateWithdrawRiskLogResult(log *entities.WithdrawRiskLog, result string) {
+	logForUpdate := entities.WithdrawRiskLog{Result: result}
+	logForUpdate.ID = log.ID
+	if err := s.Repo.UpdateWithdrawRiskLog(&logForUpdate); err != nil {
+		logger.ZError("UpdateWithdrawRiskLog", zap.Uint("id", log.ID), zap.Error(err))
+	}
+}
+
+// 退款/取消类流水 不算输赢
+var withdrawRiskRefundFlowTypes = map[uint16]bool{
+	constant.FLOW_TYPE_R8_ROLLBACK:    true,
+	constant.FLOW_TYPE_ZF_REFUND:      true,
+	constant.FLOW_TYPE_ZF_PAYOUT_FAIL: true,
+	constant.FLOW_TYPE_ZF_CANCEL:      true,
+	constant.FLOW_TYPE_JHSZ_ROLLBACK:  true,
+	constant.FLOW_TYPE_JHSZ_UNFREEZE:  true,
+	constant.FLOW_TYPE_CRASH_CANCEL:   true,
+}
+
+// 根据按时间排序的游戏流水计算最长连赢局数
+// 下注流水为负数, 其后出现派奖流水则视为赢, 紧接着再次下注视为输
+func longestWinStreak(flows []*entities.Flow) int {
+	longest, current := 0, 0
+	pending := false
+	for _, flow := range flows {
+		switch {
+		case flow.Number < 0:
+			if pending { //上一局没有派奖
+				current = 0
+			}
+			pending = true
+		case withdrawRiskRefundFlowTypes[flow.FlowType]:
+			pending = false
+		case flow.Number > 0 && pending:
+			pending = false
+			current++
+			if current > longest {
+				longest = current
+			}
+		}
+	}
+	return longest
+}
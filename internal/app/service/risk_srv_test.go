@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestLongestWinStreak(t *testing.T) {
+	bet := func() *entities.Flow { return &entities.Flow{FlowType: 201, Number: -10} }
+	win := func() *entities.Flow { return &entities.Flow{FlowType: 202, Number: 20} }
+	refund := func() *entities.Flow { return &entities.Flow{FlowType: constant.FLOW_TYPE_ZF_REFUND, Number: 10} }
+	tests := []struct {
+		name  string
+		flows []*entities.Flow
+		want  int
+	}{
+		{name: "empty", want: 0},
+		{name: "all lose", flows: []*entities.Flow{bet(), bet(), bet()}, want: 0},
+		{name: "three wins", flows: []*entities.Flow{bet(), win(), bet(), win(), bet(), win()}, want: 3},
+		{name: "lose resets", flows: []*entities.Flow{bet(), win(), bet(), win(), bet(), bet(), win()}, want: 2},
+		{name: "refund not win", flows: []*entities.Flow{bet(), win(), bet(), refund(), bet(), win()}, want: 2},
+		{name: "payout without bet", flows: []*entities.Flow{win(), bet(), win()}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := longestWinStreak(tt.flows); got != tt.want {
+				t.Fatalf("longestWinStreak() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestRiskService(t *testing.T) *RiskService {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(new(entities.WithdrawRiskRule), new(entities.WithdrawRiskLog), new(entities.HallWithdrawRecord),
+		new(entities.CompletedRecharge), new(entities.Flow), new(entities.User))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ProvideRiskService(&repository.RiskRepository{DB: db})
+}
+
+// 默认规则下的决策 每个用例用不同的用户互不影响
+func TestRiskService_EvaluateWithdrawal(t *testing.T) {
+	s := newTestRiskService(t)
+	db := s.Repo.DB
+	now := time.Now().Unix()
+	old := now - 30*24*3600
+	// BeforeCreate会把注册时间改成当前时间 写入后再改回来
+	createUser := func(user *entities.User) {
+		createdAt := user.CreatedAt
+		user.Username = fmt.Sprintf("u%d", user.ID)
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+		db.Model(user).UpdateColumn("created_at", createdAt)
+		user.CreatedAt = createdAt
+	}
+
+	tests := []struct {
+		name    string
+		user    *entities.User
+		cash    float64
+		card    string
+		prepare func(uid uint)
+		want    uint8
+	}{
+		{name: "small amount pass", user: &entities.User{ID: 1, CreatedAt: old, IP: "10.0.0.1"}, cash: 100, card: "c1",
+			want: constant.WITHDRAW_RISK_STATE_PASS},
+		{name: "over auto approve", user: &entities.User{ID: 2, CreatedAt: old, IP: "10.0.0.2"}, cash: 2000, card: "c2",
+			want: constant.WITHDRAW_RISK_STATE_MANUAL},
+		{name: "new account", user: &entities.User{ID: 3, CreatedAt: now - 3600, IP: "10.0.0.3"}, cash: 100, card: "c3",
+			want: constant.WITHDRAW_RISK_STATE_HOLD},
+		{name: "turnover short", user: &entities.User{ID: 4, CreatedAt: old, IP: "10.0.0.4"}, cash: 100, card: "c4",
+			prepare: func(uid uint) {
+				db.Create(&entities.CompletedRecharge{UID: uid, Amount: 500, CreateTime: now - 3600})
+				db.Create(&entities.Flow{UID: uid, FlowType: 201, Number: -100, BaseModel: entities.BaseModel{CreatedAt: now - 60}})
+			},
+			want: constant.WITHDRAW_RISK_STATE_HOLD},
+		{name: "turnover enough", user: &entities.User{ID: 5, CreatedAt: old, IP: "10.0.0.5"}, cash: 100, card: "c5",
+			prepare: func(uid uint) {
+				db.Create(&entities.CompletedRecharge{UID: uid, Amount: 500, CreateTime: now - 3600})
+				db.Create(&entities.Flow{UID: uid, FlowType: 201, Number: -600, BaseModel: entities.BaseModel{CreatedAt: now - 60}})
+			},
+			want: constant.WITHDRAW_RISK_STATE_PASS},
+		{name: "card reused", user: &entities.User{ID: 6, CreatedAt: old, IP: "10.0.0.6"}, cash: 100, card: "shared",
+			prepare: func(uid uint) {
+				db.Create(&entities.HallWithdrawRecord{OrderID: "other-shared", UID: 99, AccountNumber: "shared"})
+			},
+			want: constant.WITHDRAW_RISK_STATE_HOLD},
+		{name: "shared ip", user: &entities.User{ID: 7, CreatedAt: old, IP: "10.0.0.7"}, cash: 100, card: "c7",
+			prepare: func(uid uint) {
+				for i := 0; i < 3; i++ {
+					createUser(&entities.User{ID: uint(70 + i), CreatedAt: old, IP: "10.0.0.7"})
+				}
+			},
+			want: constant.WITHDRAW_RISK_STATE_HOLD},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createUser(tt.user)
+			if tt.prepare != nil {
+				tt.prepare(tt.user.ID)
+			}
+			record := &entities.HallWithdrawRecord{OrderID: tt.name, UID: tt.user.ID, Cash: tt.cash, AccountNumber: tt.card}
+			if err := db.Create(record).Error; err != nil {
+				t.Fatal(err)
+			}
+			result, log, err := s.EvaluateWithdrawal(tt.user, record, &entities.ApplyForWithdrawalReq{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Decision != tt.want {
+				t.Fatalf("EvaluateWithdrawal() decision = %d, want %d, reasons %v", result.Decision, tt.want, result.Reasons)
+			}
+			if log.ID == 0 || log.Decision != tt.want {
+				t.Fatalf("risk log = %+v, want decision %d", log, tt.want)
+			}
+			var saved entities.HallWithdrawRecord
+			db.First(&saved, record.ID)
+			if saved.RiskState != tt.want {
+				t.Fatalf("record risk state = %d, want %d", saved.RiskState, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/internal/app/utils"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+var SchedulerServiceSet = wire.NewSet(
+	ProvideSchedulerService,
+)
+
+type schedulerJob struct {
+	name    string
+	spec    string
+	catchUp bool
+	remark  string
+	paused  bool
+	job     cron.Job
+}
+
+// 集群定时任务 任务逻辑由task包注册 时间表和启停存数据库
+// 每个开启任务的节点定时检查 抢到redis租约并推进数据库里的触发游标的节点执行 执行记录写入scheduled_job_run
+type SchedulerService struct {
+	Repo *repository.SchedulerRepository
+
+	node string
+	mu   sync.RWMutex
+	jobs map[string]*schedulerJob
+}
+
+func ProvideSchedulerService(repo *repository.SchedulerRepository) *SchedulerService {
+	hostname, _ := os.Hostname()
+	return &SchedulerService{
+		Repo: repo,
+		node: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		jobs: make(map[string]*schedulerJob),
+	}
+}
+
+// 注册任务 spec和catchUp只是首次建表时的默认值 之后以数据库为准
+func (s *SchedulerService) Register(name, spec string, catchUp bool, remark string, job cron.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[name] = &schedulerJob{name: name, spec: spec, catchUp: catchUp, remark: remark, job: job}
+}
+
+// 注册默认暂停的任务 需要在后台手动启用
+func (s *SchedulerService) RegisterPaused(name, spec string, catchUp bool, remark string, job cron.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[name] = &schedulerJob{name: name, spec: spec, catchUp: catchUp, remark: remark, paused: true, job: job}
+}
+
+func (s *SchedulerService) getJob(name string) *schedulerJob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jobs[name]
+}
+
+// 补齐数据库里没有的任务 然后开始调度 新任务从现在开始计时 不补跑历史
+func (s *SchedulerService) Start() error {
+	s.mu.RLock()
+	for _, item := range s.jobs {
+		if _, err := cron.ParseStandard(item.spec); err != nil {
+			s.mu.RUnlock()
+			return fmt.Errorf("scheduler job %s: %w", item.name, err)
+		}
+		job := &entities.ScheduledJob{
+			Name:         item.name,
+			Spec:         item.spec,
+			Status:       constant.SCHEDULER_JOB_STATUS_ENABLED,
+			CatchUp:      item.catchUp,
+			Remark:       item.remark,
+			LastFireTime: time.Now().Unix(),
+		}
+		if item.paused {
+			job.Status = constant.SCHEDULER_JOB_STATUS_PAUSED
+		}
+		if err := s.Repo.CreateScheduledJobIfNotExist(job); err != nil {
+			s.mu.RUnlock()
+			return err
+		}
+	}
+	s.mu.RUnlock()
+
+	go func() {
+		ticker := time.NewTicker(constant.SCHEDULER_TICK * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			func() {
+				defer utils.PrintPanicStack()
+				s.tick()
+			}()
+		}
+	}()
+	logger.ZInfo("SchedulerService Start", zap.String("node", s.node))
+	return nil
+}
+
+func (s *SchedulerService) tick() {
+	list, err := s.Repo.GetScheduledJobList()
+	if err != nil {
+		logger.ZError("SchedulerService tick", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	for _, row := range list {
+		job := s.getJob(row.Name)
+		if job == nil { //本节点的版本没有这个任务
+			continue
+		}
+		if row.TriggerTime > 0 {
+			s.fireManual(job, row)
+			continue
+		}
+		if row.Status != constant.SCHEDULER_JOB_STATUS_ENABLED {
+			continue
+		}
+		schedule, err := cron.ParseStandard(row.Spec)
+		if err != nil {
+			logger.ZError("SchedulerService parse spec", zap.String("name", row.Name), zap.String("spec", row.Spec), zap.Error(err))
+			continue
+		}
+		// 取已到期的最后一次触发 中间错过的合并成一次
+		fire := schedule.Next(time.Unix(row.LastFireTime, 0))
+		if fire.After(now) {
+			continue
+		}
+		for next := schedule.Next(fire); !next.After(now); next = schedule.Next(fire) {
+			fire = next
+		}
+		trigger := constant.SCHEDULER_TRIGGER_CRON
+		if now.Sub(fire) > constant.SCHEDULER_MISFIRE*time.Second {
+			trigger = constant.SCHEDULER_TRIGGER_CATCHUP
+		}
+		s.fire(job, row, fire.Unix(), schedule.Next(fire).Unix(), trigger)
+	}
+}
+
+// 租约抢不到说明任务还在其他节点执行 游标不动 等执行完再触发
+func (s *SchedulerService) fire(job *schedulerJob, row *entities.ScheduledJob, fireTime, nextFireTime int64, trigger string) {
+	ok, err := s.Repo.AcquireSchedulerLease(job.name, s.node, constant.SCHEDULER_LEASE_EXPIRE*time.Second)
+	if err != nil || !ok {
+		return
+	}
+	advanced, err := s.Repo.AdvanceScheduledJob(job.name, row.LastFireTime, fireTime, nextFireTime)
+	if err != nil || !advanced {
+		s.releaseLease(job.name)
+		return
+	}
+	if trigger == constant.SCHEDULER_TRIGGER_CATCHUP && !row.CatchUp {
+		logger.ZInfo("SchedulerService skip missed", zap.String("name", job.name), zap.Int64("fireTime", fireTime))
+		s.releaseLease(job.name)
+		return
+	}
+	go s.execute(job, trigger, fireTime)
+}
+
+func (s *SchedulerService) fireManual(job *schedulerJob, row *entities.ScheduledJob) {
+	ok, err := s.Repo.AcquireSchedulerLease(job.name, s.node, constant.SCHEDULER_LEASE_EXPIRE*time.Second)
+	if err != nil || !ok {
+		return
+	}
+	claimed, err := s.Repo.ClaimScheduledJobTrigger(job.name, row.TriggerTime)
+	if err != nil || !claimed {
+		s.releaseLease(job.name)
+		return
+	}
+	go s.execute(job, constant.SCHEDULER_TRIGGER_MANUAL, row.TriggerTime)
+}
+
+func (s *SchedulerService) releaseLease(name string) {
+	if err := s.Repo.ReleaseSchedulerLease(name, s.node); err != nil {
+		logger.ZError("ReleaseSchedulerLease", zap.String("name", name), zap.Error(err))
+	}
+}
+
+// 执行期间续租 结束后记录耗时和结果
+func (s *SchedulerService) execute(job *schedulerJob, trigger string, fireTime int64) {
+	defer s.releaseLease(job.name)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(constant.SCHEDULER_LEASE_EXPIRE * time.Second / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ok, err := s.Repo.RenewSchedulerLease(job.name, s.node, constant.SCHEDULER_LEASE_EXPIRE*time.Second)
+				if err != nil || !ok {
+					logger.ZError("RenewSchedulerLease", zap.String("name", job.name), zap.Bool("ok", ok), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	start := time.Now()
+	run := &entities.ScheduledJobRun{
+		JobName:   job.name,
+		Trigger:   trigger,
+		FireTime:  fireTime,
+		Node:      s.node,
+		StartTime: start.Unix(),
+		Status:    constant.SCHEDULER_RUN_STATUS_RUNNING,
+	}
+	if err := s.Repo.CreateScheduledJobRun(run); err != nil {
+		logger.ZError("CreateScheduledJobRun", zap.String("name", job.name), zap.Error(err))
+	}
+
+	status, errMsg := constant.SCHEDULER_RUN_STATUS_SUCCESS, ""
+	func() {
+		defer func() {
+			if x := recover(); x != nil {
+				status, errMsg = constant.SCHEDULER_RUN_STATUS_FAILED, fmt.Sprint(x)
+				logger.ZError("SchedulerService job panic", zap.String("name", job.name), zap.Any("panic", x), zap.String("stack", string(debug.Stack())))
+			}
+		}()
+		job.job.Run()
+	}()
+
+	duration := time.Since(start).Milliseconds()
+	if run.ID > 0 {
+		if err := s.Repo.UpdateScheduledJobRun(run.ID, map[string]interface{}{
+			"end_time": time.Now().Unix(),
+			"duration": duration,
+			"status":   status,
+			"error":    errMsg,
+		}); err != nil {
+			logger.ZError("UpdateScheduledJobRun", zap.String("name", job.name), zap.Error(err))
+		}
+	}
+	if err := s.Repo.UpdateScheduledJob(job.name, map[string]interface{}{
+		"last_run_time": start.Unix(),
+		"last_duration": duration,
+		"last_status":   status,
+		"last_node":     s.node,
+	}); err != nil {
+		logger.ZError("UpdateScheduledJob", zap.String("name", job.name), zap.Error(err))
+	}
+}
+
+func (s *SchedulerService) GetScheduledJobList() ([]*entities.ScheduledJob, error) {
+	return s.Repo.GetScheduledJobList()
+}
+
+// 修改时间表或从暂停恢复时从现在开始计时 不补跑暂停期间的触发
+func (s *SchedulerService) SaveScheduledJob(req *entities.SaveScheduledJobReq) (err error) {
+	defer func() {
+		s.writeSchedulerLog(req.OptionID, req.IP, req, err, "修改定时任务")
+	}()
+
+	if req.Status != constant.SCHEDULER_JOB_STATUS_ENABLED && req.Status != constant.SCHEDULER_JOB_STATUS_PAUSED {
+		return errors.WithCode(errors.InvalidParam)
+	}
+	schedule, err := cron.ParseStandard(req.Spec)
+	if err != nil {
+		return errors.WithCode(errors.SchedulerSpecInvalid)
+	}
+	row, err := s.Repo.GetScheduledJobByName(req.Name)
+	if err != nil {
+		return
+	}
+	if row == nil {
+		return errors.WithCode(errors.SchedulerJobNotExist)
+	}
+	values := map[string]interface{}{
+		"spec":     req.Spec,
+		"status":   req.Status,
+		"catch_up": req.CatchUp,
+	}
+	if req.Spec != row.Spec || (row.Status == constant.SCHEDULER_JOB_STATUS_PAUSED && req.Status == constant.SCHEDULER_JOB_STATUS_ENABLED) {
+		now := time.Now()
+		values["last_fire_time"] = now.Unix()
+		values["next_fire_time"] = schedule.Next(now).Unix()
+	}
+	return s.Repo.UpdateScheduledJob(req.Name, values)
+}
+
+// 手动触发 由开启任务的节点在下次检查时执行 暂停的任务也可以手动执行
+func (s *SchedulerService) TriggerScheduledJob(req *entities.TriggerScheduledJobReq) (err error) {
+	defer func() {
+		s.writeSchedulerLog(req.OptionID, req.IP, req, err, "手动执行定时任务")
+	}()
+
+	row, err := s.Repo.GetScheduledJobByName(req.Name)
+	if err != nil {
+		return
+	}
+	if row == nil {
+		return errors.WithCode(errors.SchedulerJobNotExist)
+	}
+	return s.Repo.UpdateScheduledJob(req.Name, map[string]interface{}{"trigger_time": time.Now().Unix()})
+}
+
+func (s *SchedulerService) GetScheduledJobRunList(req *entities.GetScheduledJobRunListReq) error {
+	return s.Repo.GetScheduledJobRunList(req)
+}
+
+func (s *SchedulerService) writeSchedulerLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_SCHEDULER,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+)
+
+// 两个节点共用同一个库和redis
+func newTestSchedulerNodes(t *testing.T) (*SchedulerService, *SchedulerService, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	rds := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	db := newTestDB(t, new(entities.ScheduledJob), new(entities.ScheduledJobRun))
+	repo := &repository.SchedulerRepository{DB: db, RDS: rds}
+	newNode := func(node string) *SchedulerService {
+		return &SchedulerService{Repo: repo, node: node, jobs: make(map[string]*schedulerJob)}
+	}
+	return newNode("a"), newNode("b"), mr
+}
+
+// 只有持有者可以续期和释放
+func TestSchedulerRepository_Lease(t *testing.T) {
+	a, _, mr := newTestSchedulerNodes(t)
+	repo := a.Repo
+	key := fmt.Sprintf(constant.REDIS_KEY_SCHEDULER_LEASE, "job")
+
+	if ok, err := repo.AcquireSchedulerLease("job", "a", time.Minute); err != nil || !ok {
+		t.Fatalf("a acquire = %v %v, want true", ok, err)
+	}
+	if ok, _ := repo.AcquireSchedulerLease("job", "b", time.Minute); ok {
+		t.Fatal("b acquired a held lease")
+	}
+	if ok, _ := repo.RenewSchedulerLease("job", "b", time.Hour); ok {
+		t.Fatal("b renewed a's lease")
+	}
+	if err := repo.ReleaseSchedulerLease("job", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := mr.Get(key); got != "a" {
+		t.Fatalf("lease owner after b release = %q, want a", got)
+	}
+
+	if ok, err := repo.RenewSchedulerLease("job", "a", time.Hour); err != nil || !ok {
+		t.Fatalf("a renew = %v %v, want true", ok, err)
+	}
+	if ttl := mr.TTL(key); ttl != time.Hour {
+		t.Fatalf("ttl after renew = %v, want 1h", ttl)
+	}
+	if err := repo.ReleaseSchedulerLease("job", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := repo.AcquireSchedulerLease("job", "b", time.Minute); !ok {
+		t.Fatal("b could not acquire released lease")
+	}
+
+	// 过期后其他节点可以抢到 原持有者不能再续期
+	mr.FastForward(2 * time.Minute)
+	if ok, _ := repo.AcquireSchedulerLease("job", "a", time.Minute); !ok {
+		t.Fatal("a could not acquire expired lease")
+	}
+	if ok, _ := repo.RenewSchedulerLease("job", "b", time.Minute); ok {
+		t.Fatal("b renewed after its lease expired")
+	}
+}
+
+// 开始时通知测试 阻塞到测试放行
+type blockingJob struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (j *blockingJob) Run() {
+	j.started <- struct{}{}
+	<-j.release
+}
+
+func waitLeaseReleased(t *testing.T, mr *miniredis.Miniredis, name string) {
+	t.Helper()
+	key := fmt.Sprintf(constant.REDIS_KEY_SCHEDULER_LEASE, name)
+	for i := 0; i < 200; i++ {
+		if !mr.Exists(key) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("lease not released")
+}
+
+// 同一次触发只在一个节点执行 执行中其他节点抢不到租约
+func TestSchedulerService_tick(t *testing.T) {
+	a, b, mr := newTestSchedulerNodes(t)
+	job := &blockingJob{started: make(chan struct{}, 2), release: make(chan struct{})}
+	for _, node := range []*SchedulerService{a, b} {
+		node.Register("hourly", "0 * * * *", true, "", job)
+	}
+	row := &entities.ScheduledJob{Name: "hourly", Spec: "0 * * * *", CatchUp: true, LastFireTime: time.Now().Add(-3 * time.Hour).Unix()}
+	if err := a.Repo.CreateScheduledJobIfNotExist(row); err != nil {
+		t.Fatal(err)
+	}
+
+	a.tick()
+	select {
+	case <-job.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job not started")
+	}
+	// 同一次触发和执行中的任务都不会在b上执行
+	b.tick()
+	a.tick()
+	close(job.release)
+	waitLeaseReleased(t, mr, "hourly")
+	if len(job.started) != 0 {
+		t.Fatal("job ran twice")
+	}
+
+	saved, err := a.Repo.GetScheduledJobByName("hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.LastFireTime <= row.LastFireTime || saved.NextFireTime <= saved.LastFireTime || saved.LastNode != "a" || saved.LastStatus != constant.SCHEDULER_RUN_STATUS_SUCCESS {
+		t.Fatalf("job after run = %+v", saved)
+	}
+	// 游标已推进 下个整点前不会再触发
+	b.tick()
+	if len(job.started) != 0 {
+		t.Fatal("job fired again before next fire time")
+	}
+	var runs []*entities.ScheduledJobRun
+	a.Repo.DB.Where("job_name = ?", "hourly").Find(&runs)
+	if len(runs) != 1 || runs[0].Node != "a" || runs[0].Status != constant.SCHEDULER_RUN_STATUS_SUCCESS {
+		t.Fatalf("runs = %+v", runs)
+	}
+}
+
+// 错过太久且不补跑的只推进游标 手动触发暂停的任务也会执行
+func TestSchedulerService_tickMissedAndManual(t *testing.T) {
+	a, _, mr := newTestSchedulerNodes(t)
+	runs := make(chan string, 4)
+	a.Register("yearly", "0 0 1 1 *", false, "", cron.FuncJob(func() { runs <- "yearly" }))
+	a.Register("paused", "* * * * *", false, "", cron.FuncJob(func() { runs <- "paused" }))
+
+	old := time.Now().AddDate(-2, 0, 0).Unix()
+	rows := []*entities.ScheduledJob{
+		{Name: "yearly", Spec: "0 0 1 1 *", LastFireTime: old},
+		{Name: "paused", Spec: "* * * * *", Status: constant.SCHEDULER_JOB_STATUS_PAUSED, LastFireTime: old},
+	}
+	for _, row := range rows {
+		if err := a.Repo.CreateScheduledJobIfNotExist(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.tick()
+	waitLeaseReleased(t, mr, "yearly")
+	saved, _ := a.Repo.GetScheduledJobByName("yearly")
+	if saved.LastFireTime == old || saved.LastRunTime != 0 {
+		t.Fatalf("missed job = %+v, want cursor moved without run", saved)
+	}
+
+	if err := a.Repo.UpdateScheduledJob("paused", map[string]interface{}{"trigger_time": time.Now().Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	a.tick()
+	select {
+	case name := <-runs:
+		if name != "paused" {
+			t.Fatalf("ran %s, want paused", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("manual trigger not executed")
+	}
+	waitLeaseReleased(t, mr, "paused")
+	saved, _ = a.Repo.GetScheduledJobByName("paused")
+	if saved.TriggerTime != 0 || saved.LastFireTime != old {
+		t.Fatalf("paused job after manual run = %+v", saved)
+	}
+	if len(runs) != 0 {
+		t.Fatalf("unexpected runs %d", len(runs))
+	}
+}
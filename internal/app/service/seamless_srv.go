@@ -0,0 +1,260 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/logger"
+
+	"github.com/google/wire"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var SeamlessWalletServiceSet = wire.NewSet(
+	ProvideSeamlessWalletService,
+)
+
+// 三方游戏单一钱包 余额校验/重复回调/钱包变动/流水统一在这里处理
+// 各三方的service只负责协议转换和签名
+type SeamlessWalletService struct {
+	Repo      *repository.SeamlessRepository
+	WalletSrv *WalletService
+}
+
+func ProvideSeamlessWalletService(repo *repository.SeamlessRepository, walletSrv *WalletService) *SeamlessWalletService {
+	return &SeamlessWalletService{
+		Repo:      repo,
+		WalletSrv: walletSrv,
+	}
+}
+
+func (s *SeamlessWalletService) Balance(uid uint) (float64, error) {
+	wallet, err := s.WalletSrv.GetUserWallet(uid)
+	if err != nil {
+		return 0, err
+	}
+	if wallet == nil {
+		return 0, errors.WithCode(errors.WalletNotExist)
+	}
+	return wallet.Cash, nil
+}
+
+func (s *SeamlessWalletService) GetTransaction(provider, externalID string) (*entities.SeamlessTransaction, error) {
+	return s.Repo.GetTransaction(provider, externalID)
+}
+
+// 三方派彩没有带原交易号时 按局号/注单号找到未结算的原扣款交易号 没有返回空
+func (s *SeamlessWalletService) OpenDebitExternalID(provider string, uid uint, roundID, recordID string, flowType uint16) (string, error) {
+	if roundID == "" && recordID == "" {
+		return "", nil
+	}
+	debit, err := s.Repo.GetOpenDebitTransaction(provider, uid, roundID, recordID, flowType,
+		constant.SEAMLESS_ACTION_DEBIT, constant.SEAMLESS_STATUS_OPEN)
+	if err != nil || debit == nil {
+		return "", err
+	}
+	return debit.ExternalID, nil
+}
+
+// 扣款 下注/冻结
+func (s *SeamlessWalletService) Debit(req *entities.SeamlessTxReq) (*entities.SeamlessTxResult, error) {
+	return s.apply(constant.SEAMLESS_ACTION_DEBIT, req)
+}
+
+// 加款 派彩/活动奖励 关联了原扣款时将其标记为已结算
+func (s *SeamlessWalletService) Credit(req *entities.SeamlessTxReq) (*entities.SeamlessTxResult, error) {
+	return s.apply(constant.SEAMLESS_ACTION_CREDIT, req)
+}
+
+// 回滚 退回原扣款的金额 并将其标记为已回滚
+func (s *SeamlessWalletService) Rollback(req *entities.SeamlessTxReq) (*entities.SeamlessTxResult, error) {
+	return s.apply(constant.SEAMLESS_ACTION_ROLLBACK, req)
+}
+
+// 结算 一局内未结算的扣款标记为已结算 不变动余额
+func (s *SeamlessWalletService) Settle(provider, gameCode, roundID string) (int64, error) {
+	return s.Repo.SettleRoundTransactions(provider, gameCode, roundID, constant.SEAMLESS_ACTION_DEBIT,
+		constant.SEAMLESS_STATUS_OPEN, constant.SEAMLESS_STATUS_SETTLED)
+}
+
+func (s *SeamlessWalletService) duplicateResult(transaction *entities.SeamlessTransaction) (*entities.SeamlessTxResult, error) {
+	balance, err := s.Balance(transaction.UID)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.SeamlessTxResult{
+		Balance:     balance,
+		Amount:      transaction.Amount,
+		Duplicate:   true,
+		Transaction: transaction,
+	}, nil
+}
+
+// 查找并锁定关联的原扣款
+// 回滚必须找到原扣款 按交易号关联 或者按局号/注单号查找 已回滚/已结算的不能再回滚
+func (s *SeamlessWalletService) resolveRef(tx *gorm.DB, action string, uid uint, req *entities.SeamlessTxReq) (*entities.SeamlessTransaction, error) {
+	var ref *entities.SeamlessTransaction
+	var err error
+	if req.RefExternalID != "" {
+		ref, err = s.Repo.GetTransactionForUpdate(tx, req.Provider, req.RefExternalID)
+	} else if action == constant.SEAMLESS_ACTION_ROLLBACK && (req.RoundID != "" || req.RecordID != "") {
+		ref, err = s.lockDebitByRound(tx, uid, req)
+	} else if action != constant.SEAMLESS_ACTION_ROLLBACK {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ref == nil || ref.UID != uid {
+		return nil, errors.WithCode(errors.SeamlessTxNotExist)
+	}
+	if ref.Action != constant.SEAMLESS_ACTION_DEBIT {
+		if action == constant.SEAMLESS_ACTION_ROLLBACK {
+			return nil, errors.WithCode(errors.SeamlessTxNotExist)
+		}
+		return ref, nil
+	}
+	if ref.Status == constant.SEAMLESS_STATUS_ROLLED_BACK {
+		return nil, errors.WithCode(errors.SeamlessTxRolledBack)
+	}
+	if ref.Status == constant.SEAMLESS_STATUS_SETTLED && action == constant.SEAMLESS_ACTION_ROLLBACK {
+		return nil, errors.WithCode(errors.SeamlessTxSettled)
+	}
+	return ref, nil
+}
+
+// 没有原交易号时按局号/注单号锁定原扣款
+// 未结算的只能有一笔 多笔时无法确定回滚哪一笔 需要三方带上原交易号
+// 没有未结算的取最近一笔 由调用方返回已回滚/已结算
+func (s *SeamlessWalletService) lockDebitByRound(tx *gorm.DB, uid uint, req *entities.SeamlessTxReq) (*entities.SeamlessTransaction, error) {
+	list, err := s.Repo.GetOpenDebitTransactionListForUpdate(tx, req.Provider, uid, req.RoundID, req.RecordID,
+		constant.SEAMLESS_ACTION_DEBIT, constant.SEAMLESS_STATUS_OPEN, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) > 1 {
+		return nil, errors.WithCode(errors.SeamlessTxAmbiguous)
+	}
+	if len(list) == 1 {
+		return list[0], nil
+	}
+	return s.Repo.GetDebitTransactionForUpdate(tx, req.Provider, uid, req.RoundID, req.RecordID, constant.SEAMLESS_ACTION_DEBIT)
+}
+
+func (s *SeamlessWalletService) apply(action string, req *entities.SeamlessTxReq) (*entities.SeamlessTxResult, error) {
+	if req.Amount < 0 {
+		return nil, errors.WithCode(errors.SeamlessInvalidAmount)
+	}
+
+	existing, err := s.Repo.GetTransaction(req.Provider, req.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return s.duplicateResult(existing)
+	}
+	if action == constant.SEAMLESS_ACTION_DEBIT {
+		if err := s.WalletSrv.CheckBetLimit(req.UID, req.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	var result *entities.SeamlessTxResult
+	err = s.WalletSrv.HandleWallet(req.UID, func(wallet *entities.UserWallet, tx *gorm.DB) error {
+		ref, err := s.resolveRef(tx, action, wallet.UID, req)
+		if err != nil {
+			return err
+		}
+
+		amount := req.Amount
+		var refStatus uint8
+		switch action {
+		case constant.SEAMLESS_ACTION_DEBIT:
+			if wallet.Cash < amount {
+				if !req.Partial || wallet.Cash <= 0 {
+					return errors.WithCode(errors.InsufficientBalance)
+				}
+				amount = wallet.Cash //不够就扣除全部
+			}
+			amount = -amount
+		case constant.SEAMLESS_ACTION_CREDIT:
+			refStatus = constant.SEAMLESS_STATUS_SETTLED
+		case constant.SEAMLESS_ACTION_ROLLBACK:
+			refStatus = constant.SEAMLESS_STATUS_ROLLED_BACK
+			amount = -ref.Amount //按原扣款退回 不信任请求里的金额
+		}
+
+		wallet.SafeAdjustCash(amount)
+		transaction := &entities.SeamlessTransaction{
+			Provider:      req.Provider,
+			ExternalID:    req.ExternalID,
+			UID:           wallet.UID,
+			Action:        action,
+			Amount:        amount,
+			Balance:       wallet.Cash,
+			GameCode:      req.GameCode,
+			RoundID:       req.RoundID,
+			RecordID:      req.RecordID,
+			RefExternalID: req.RefExternalID,
+			FlowType:      req.FlowType,
+			Remark:        req.Remark,
+		}
+		if ref != nil {
+			transaction.RefExternalID = ref.ExternalID
+		}
+		if err := s.Repo.CreateTransactionWithTx(tx, transaction); err != nil {
+			return err
+		}
+		if ref != nil && ref.Action == constant.SEAMLESS_ACTION_DEBIT {
+			if err := s.Repo.UpdateTransactionStatusWithTx(tx, ref.ID, refStatus); err != nil {
+				return err
+			}
+		}
+		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
+			return err
+		}
+		// 下注扣减打码量 冻结不是投注 交易号唯一 重复回调不会重复扣减
+		if action == constant.SEAMLESS_ACTION_DEBIT && req.FlowType != constant.FLOW_TYPE_JHSZ_FREEZE {
+			if err := s.WalletSrv.ConsumeTurnoverWithTx(tx, wallet.UID, -amount); err != nil {
+				return err
+			}
+		}
+
+		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
+			UID:          wallet.UID,
+			FlowType:     req.FlowType,
+			Number:       amount,
+			Balance:      wallet.Cash,
+			Remark:       req.Remark,
+			PromoterCode: wallet.PromoterCode,
+		})
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+
+		result = &entities.SeamlessTxResult{
+			Balance:     wallet.Cash,
+			Amount:      amount,
+			Transaction: transaction,
+		}
+		return nil
+	})
+	if err != nil {
+		// 并发的相同回调 唯一索引冲突时按重复处理
+		if existing, _ := s.Repo.GetTransaction(req.Provider, req.ExternalID); existing != nil {
+			return s.duplicateResult(existing)
+		}
+		logger.ZError("SeamlessWallet "+action, zap.Any("req", req), zap.Error(err))
+		return nil, err
+	}
+	if result == nil {
+		return nil, errors.WithCode(errors.WalletNotExist)
+	}
+	logger.ZInfo("SeamlessWallet "+action, zap.String("provider", req.Provider), zap.String("externalID", req.ExternalID),
+		zap.Uint("uid", req.UID), zap.Float64("amount", result.Amount), zap.Float64("balance", result.Balance))
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/service/repository"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestSeamlessService(t *testing.T) *SeamlessWalletService {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(new(entities.SeamlessTransaction)); err != nil {
+		t.Fatal(err)
+	}
+	debits := []*entities.SeamlessTransaction{
+		{Provider: constant.SEAMLESS_PROVIDER_R8, ExternalID: "t1:withdraw", UID: 1, Action: constant.SEAMLESS_ACTION_DEBIT, RoundID: "r1", RecordID: "b1", FlowType: constant.FLOW_TYPE_R8_WITHDRAW},
+		{Provider: constant.SEAMLESS_PROVIDER_R8, ExternalID: "t2:withdraw", UID: 1, Action: constant.SEAMLESS_ACTION_DEBIT, RoundID: "r2", RecordID: "b2", FlowType: constant.FLOW_TYPE_R8_WITHDRAW, Status: constant.SEAMLESS_STATUS_ROLLED_BACK},
+		{Provider: constant.SEAMLESS_PROVIDER_JHSZ, ExternalID: "u1", UID: 1, Action: constant.SEAMLESS_ACTION_DEBIT, RoundID: "r3", RecordID: "b3", FlowType: constant.FLOW_TYPE_JHSZ_FREEZE},
+		{Provider: constant.SEAMLESS_PROVIDER_JHSZ, ExternalID: "u2", UID: 1, Action: constant.SEAMLESS_ACTION_DEBIT, RoundID: "r3", RecordID: "b3", FlowType: constant.FLOW_TYPE_JHSZ_WITHDRAW},
+	}
+	if err := db.Create(debits).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &SeamlessWalletService{Repo: &repository.SeamlessRepository{DB: db}}
+}
+
+// 派彩/解冻没有带原交易号时 按局号/注单号关联原扣款 并在钱包事务里结算
+func TestSeamlessWalletService_CreditSettlesDebitByRound(t *testing.T) {
+	s := newTestSeamlessService(t)
+	tests := []struct {
+		name     string
+		provider string
+		uid      uint
+		roundID  string
+		recordID string
+		flowType uint16
+		want     string
+	}{
+		{name: "r8 deposit", provider: constant.SEAMLESS_PROVIDER_R8, uid: 1, roundID: "r1", recordID: "b1", want: "t1:withdraw"},
+		{name: "r8 rolled back", provider: constant.SEAMLESS_PROVIDER_R8, uid: 1, roundID: "r2", recordID: "b2", want: ""},
+		{name: "r8 other user", provider: constant.SEAMLESS_PROVIDER_R8, uid: 2, roundID: "r1", recordID: "b1", want: ""},
+		{name: "jhsz unfreeze", provider: constant.SEAMLESS_PROVIDER_JHSZ, uid: 1, roundID: "r3", recordID: "b3", flowType: constant.FLOW_TYPE_JHSZ_FREEZE, want: "u1"},
+		{name: "jhsz deposit", provider: constant.SEAMLESS_PROVIDER_JHSZ, uid: 1, roundID: "r3", recordID: "b3", flowType: constant.FLOW_TYPE_JHSZ_WITHDRAW, want: "u2"},
+		{name: "no round", provider: constant.SEAMLESS_PROVIDER_R8, uid: 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.OpenDebitExternalID(tt.provider, tt.uid, tt.roundID, tt.recordID, tt.flowType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("OpenDebitExternalID() = %q, want %q", got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+			req := &entities.SeamlessTxReq{Provider: tt.provider, UID: tt.uid, RoundID: tt.roundID, RecordID: tt.recordID, RefExternalID: got}
+			err = s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+				ref, err := s.resolveRef(tx, constant.SEAMLESS_ACTION_CREDIT, tt.uid, req)
+				if err != nil {
+					return err
+				}
+				if ref == nil || ref.ExternalID != tt.want {
+					t.Fatalf("resolveRef() = %v, want %q", ref, tt.want)
+				}
+				return s.Repo.UpdateTransactionStatusWithTx(tx, ref.ID, constant.SEAMLESS_STATUS_SETTLED)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 已结算的扣款不会再被关联
+			if again, _ := s.OpenDebitExternalID(tt.provider, tt.uid, tt.roundID, tt.recordID, tt.flowType); again != "" {
+				t.Fatalf("OpenDebitExternalID() after settle = %q, want empty", again)
+			}
+		})
+	}
+}
+
+// 回滚没有带原交易号时 同一局有多笔未结算的扣款不能随便选一笔
+func TestSeamlessWalletService_RollbackByRoundAmbiguous(t *testing.T) {
+	s := newTestSeamlessService(t)
+	tests := []struct {
+		name     string
+		provider string
+		roundID  string
+		recordID string
+		want     string
+		wantCode int
+	}{
+		{name: "single open debit", provider: constant.SEAMLESS_PROVIDER_R8, roundID: "r1", recordID: "b1", want: "t1:withdraw"},
+		{name: "rolled back", provider: constant.SEAMLESS_PROVIDER_R8, roundID: "r2", recordID: "b2", wantCode: errors.SeamlessTxRolledBack},
+		{name: "two open debits", provider: constant.SEAMLESS_PROVIDER_JHSZ, roundID: "r3", recordID: "b3", wantCode: errors.SeamlessTxAmbiguous},
+		{name: "not exist", provider: constant.SEAMLESS_PROVIDER_R8, roundID: "r9", wantCode: errors.SeamlessTxNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &entities.SeamlessTxReq{Provider: tt.provider, UID: 1, RoundID: tt.roundID, RecordID: tt.recordID}
+			err := s.Repo.DB.Transaction(func(tx *gorm.DB) error {
+				ref, err := s.resolveRef(tx, constant.SEAMLESS_ACTION_ROLLBACK, 1, req)
+				if err != nil {
+					return err
+				}
+				if ref.ExternalID != tt.want {
+					t.Fatalf("resolveRef() = %q, want %q", ref.ExternalID, tt.want)
+				}
+				return nil
+			})
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.IsCode(err, tt.wantCode) {
+				t.Fatalf("resolveRef() err = %v, want code %d", err, tt.wantCode)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"rk-api/internal/app/entities"
+	"strings"
 
 	"github.com/google/wire"
 )
@@ -43,6 +44,23 @@ var SrvSet = wire.NewSet(
 	MineGameServiceSet,
 	DiceGameServiceSet,
 	LimboGameServiceSet,
+	RiskServiceSet,
+	SeamlessWalletServiceSet,
+	ReconcileServiceSet,
+	HistoryServiceSet,
+	GamingServiceSet,
+	VipServiceSet,
+	CashbackServiceSet,
+	AgentStatsServiceSet,
+	CommissionServiceSet,
+	AdminAuthServiceSet,
+	AdminApprovalServiceSet,
+	ArchiveServiceSet,
+	SchedulerServiceSet,
+	OutboxServiceSet,
+	DeadLetterServiceSet,
+	EventBusServiceSet,
+	EventSubscriberServiceSet,
 ) // end
 
 var AsyncServiceManagerSet = wire.NewSet(wire.Struct(new(AsyncServiceManager), "*"))
@@ -62,6 +80,14 @@ type AsyncServiceManager struct {
 	ChainSrv        *ChainService
 	NotificationSrv *NotificationService
 	GameSrv         *GameService
+	ReconcileSrv    *ReconcileService
+	VipSrv          *VipService
+	AgentStatsSrv   *AgentStatsService
+	CommissionSrv   *CommissionService
+	ArchiveSrv      *ArchiveService
+	OutboxSrv       *OutboxService
+	DeadLetterSrv   *DeadLetterService
+	WithdrawSrv     *WithdrawService
 }
 
 //添加了 记得重新wire
@@ -115,10 +141,34 @@ func (m *AsyncServiceManager) SyncThirdPartyData() { //同步第三方游戏数
 	m.StatsSrv.SyncThirdPartyData()
 }
 
+func (m *AsyncServiceManager) ReconcileProviderTransfers() { //三方转账与投注记录对账
+	m.ReconcileSrv.ReconcileProviderTransfers()
+}
+
+func (m *AsyncServiceManager) RecalculateVipLevels() error { //重新评定VIP等级
+	return m.VipSrv.RecalculateVipLevels()
+}
+
+func (m *AsyncServiceManager) RefreshAgentDailyStats() error { //刷新代理团队每日统计
+	return m.AgentStatsSrv.RefreshAgentDailyStats()
+}
+
+func (m *AsyncServiceManager) SettleDueCommission() error { //结算代理佣金方案
+	return m.CommissionSrv.SettleDueCommission()
+}
+
 func (m *AsyncServiceManager) SyncThirdOnlineCount() { //查询第三方在线人数
 	m.GameSrv.SyncThirdOnlineCount()
 }
 
+func (m *AsyncServiceManager) RefreshGameLobbyRank() error { //刷新大厅游戏排序
+	return m.GameSrv.RefreshGameLobbyRank()
+}
+
+func (m *AsyncServiceManager) SyncAllProviderGameList() { //同步三方游戏列表
+	m.GameSrv.SyncAllProviderGameList()
+}
+
 func (m *AsyncServiceManager) QuerySettleExpiredWingos() error { //查询结算wingo
 	return m.WingoSrv.QuerySettleExpiredWingos()
 }
@@ -127,13 +177,29 @@ func (m *AsyncServiceManager) QuerySettleExpiredNines() error { //查询结算ni
 }
 
 func (m *AsyncServiceManager) MonthBackupAndClean(tableNames string) error { ////每月备份并清理数据
-	return m.AdminSrv.CallMonthBackupAndClean(&entities.MonthBackupAndCleaReq{TableNames: tableNames, OptionID: 1})
+	return m.ArchiveSrv.ArchiveTables(strings.Split(tableNames, ","))
+}
+
+func (m *AsyncServiceManager) CleanPublishedOutbox() error { //清理已投递的发件箱消息
+	return m.OutboxSrv.CleanPublishedOutbox()
+}
+
+func (m *AsyncServiceManager) RecordTaskResult(typename string, failed bool) { //队列任务处理计数
+	m.DeadLetterSrv.RecordTaskResult(typename, failed)
+}
+
+func (m *AsyncServiceManager) CheckQueueFailureRate() error { //检查队列失败率
+	return m.DeadLetterSrv.CheckQueueFailureRate()
 }
 
 func (m *AsyncServiceManager) HandleNotification(notification *entities.Notification) error { //处理通知
 	return m.NotificationSrv.HandleNotification(notification)
 }
 
+func (m *AsyncServiceManager) CheckWithdrawRisk(task *entities.WithdrawRiskTask) error { //提现风控评估
+	return m.WithdrawSrv.CheckWithdrawRisk(task)
+}
+
 // func (m *AsyncServiceManager) ProcessChainRetryTransaction(transation *entities.ChainTransaction, failed bool) error { //处理plat chain deposit
 // 	return m.ChainSrv.ProcessChainRetryTransaction(transation, failed)
 // }
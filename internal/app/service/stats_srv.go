@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
 	"rk-api/internal/app/utils"
+	"rk-api/pkg/cjson"
 	"rk-api/pkg/logger"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/wire"
@@ -25,12 +30,26 @@ type IFetchRecords interface {
 	FetchRecords(startTime, endTime time.Time) ([]*entities.GameRecord, error)
 }
 
+// 三方支持按时间段查询总数时实现 用于核对拉取的条数
+type IRecordCounter interface {
+	CountRecords(startTime, endTime time.Time) (int, error)
+}
+
+const (
+	recordSyncStep        = 30 * time.Minute    // 每个同步窗口的时长
+	recordSyncDelay       = 5 * time.Minute     // 三方记录落库有延迟 只同步到当前时间之前
+	recordSyncMaxWindows  = 48                  // 定时任务每次最多同步的窗口数
+	recordSyncMaxPages    = 100                 // 单个窗口最多拉取的页数
+	recordSyncBackfillMax = 31 * 24 * time.Hour // 补录最大时间跨度
+)
+
 type StatsService struct {
-	Repo     *repository.StatsRepository
-	adapters map[string]IFetchRecords
-	AgentSrv *AgentService
-	StateSrv *StateService
-	gameSrv  *GameService
+	Repo      *repository.StatsRepository
+	adapters  map[string]IFetchRecords
+	AgentSrv  *AgentService
+	StateSrv  *StateService
+	gameSrv   *GameService
+	syncLocks sync.Map // 每个来源一把锁 定时同步和补录不能同时进行
 }
 
 func ProvideStatsService(
@@ -76,8 +95,9 @@ func (s *StatsService) SyncThirdPartyData() {
 	}
 
 	for source, adapter := range s.adapters {
-
-		s.syncThirdParty(source, adapter)
+		if err := s.syncThirdParty(source, adapter); err != nil {
+			logger.ZError("syncThirdParty", zap.String("source", source), zap.Error(err))
+		}
 	}
 
 }
@@ -114,7 +134,19 @@ func (s *StatsService) GetProfitLeaderboard() ([]*entities.GameProfitStats, erro
 
 ///////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+func (s *StatsService) syncLock(source string) *sync.Mutex {
+	lock, _ := s.syncLocks.LoadOrStore(source, new(sync.Mutex))
+	return lock.(*sync.Mutex)
+}
+
+// 按窗口推进断点 窗口失败时断点停在上一个成功的窗口 下次从这里继续
 func (s *StatsService) syncThirdParty(source string, adapter IFetchRecords) error {
+	lock := s.syncLock(source)
+	if !lock.TryLock() { //正在补录
+		return nil
+	}
+	defer lock.Unlock()
+
 	logger.ZInfo("sync third party data", zap.String("source", source))
 	// 获取全局同步状态
 	syncStatus, err := s.Repo.GetOrCreateSyncStatus(source)
@@ -122,92 +154,128 @@ func (s *StatsService) syncThirdParty(source string, adapter IFetchRecords) erro
 		return err
 	}
 
-	// 计算时间窗口
-	endTime := time.Now().UTC()
-	startTime := time.Unix(0, (syncStatus.LastRecordTime)*int64(time.Millisecond)).UTC() // 转换 int64 到 time.Time
+	endTime := time.Now().UTC().Add(-recordSyncDelay)
+	startTime := time.UnixMilli(syncStatus.LastRecordTime).UTC() // 上次同步到的时间
 	if syncStatus.LastRecordTime == 0 {
 		// 如果 LastRecordTime 是零值，使用默认的时间窗口
 		startTime = endTime.Add(-time.Duration(syncStatus.SyncWindow) * time.Minute)
-	} else {
-		startTime = startTime.Add(1 * time.Second) // 避免重复处理同一记录
 	}
 
-	retryCount := 0
+	for i := 0; i < recordSyncMaxWindows && startTime.Before(endTime); i++ {
+		windowEnd := startTime.Add(recordSyncStep)
+		if windowEnd.After(endTime) {
+			windowEnd = endTime
+		}
+		if _, err = s.syncWindow(source, adapter, startTime, windowEnd, constant.RECORD_SYNC_TRIGGER_AUTO, 0); err != nil {
+			break
+		}
+		startTime = windowEnd
 
-	// 分页获取数据
-	for {
+		syncStatus.LastRecordTime = windowEnd.UnixMilli()
+		if err := s.Repo.UpdateSyncStatus(syncStatus); err != nil {
+			logger.ZError("UpdateSyncStatus", zap.String("source", source), zap.Error(err))
+		}
+	}
 
-		records, err := adapter.FetchRecords(startTime, endTime)
-		// 格式化时间为字符串（例如：YYYY-MM-DD HH:MM:SS）
-		startFormatted := startTime.Format("2006-01-02 15:04:05")
-		endFormatted := endTime.Format("2006-01-02 15:04:05")
+	syncStatus.LastSyncTime = time.Now().UnixMilli()
+	if err := s.Repo.UpdateSyncStatus(syncStatus); err != nil {
+		logger.ZError("UpdateSyncStatus", zap.String("source", source), zap.Error(err))
+	}
+	return err
+}
 
-		logger.ZInfo("sync third party data len 0 ",
-			zap.Int("retry_count", retryCount),
-			zap.Int("records_count", len(records)),
-			zap.String("start_time", startFormatted),
-			zap.String("end_time", endFormatted),
-		)
+// 同步一个时间窗口 逐页拉取并写入 结果记录到同步窗口表
+func (s *StatsService) syncWindow(source string, adapter IFetchRecords, startTime, endTime time.Time, trigger string, optionID uint) (*entities.GameRecordSyncWindow, error) {
+	window := &entities.GameRecordSyncWindow{
+		Source:        source,
+		StartTime:     startTime.UnixMilli(),
+		EndTime:       endTime.UnixMilli(),
+		ProviderTotal: -1,
+		Status:        constant.RECORD_SYNC_STATUS_RUNNING,
+		TriggerType:   trigger,
+		OptionID:      optionID,
+	}
+	if err := s.Repo.CreateGameRecordSyncWindow(window); err != nil {
+		return nil, err
+	}
 
-		// 打印日志，包含 start 和 end 时间
+	err := s.fetchWindow(source, adapter, startTime, endTime, window)
+	if err != nil {
+		window.Status = constant.RECORD_SYNC_STATUS_FAILED
+		window.Error = errors.TruncateError(err, 255)
+		logger.ZError("sync third party window", zap.String("source", source),
+			zap.Time("start", startTime), zap.Time("end", endTime), zap.Error(err))
+	} else {
+		window.Status = constant.RECORD_SYNC_STATUS_DONE
+		if counter, ok := adapter.(IRecordCounter); ok {
+			if total, err := counter.CountRecords(startTime, endTime); err != nil {
+				logger.ZError("CountRecords", zap.String("source", source), zap.Error(err))
+			} else {
+				window.ProviderTotal = total
+				if total != window.Fetched {
+					window.Status = constant.RECORD_SYNC_STATUS_MISMATCH
+				}
+			}
+		}
+	}
+	if err := s.Repo.UpdateGameRecordSyncWindow(window); err != nil {
+		logger.ZError("UpdateGameRecordSyncWindow", zap.Uint("id", window.ID), zap.Error(err))
+	}
+	return window, err
+}
 
+// 三方按时间返回有限条数 从最后一条的时间继续拉取 直到没有新的记录
+// 重复拉到的记录按record_id幂等处理
+func (s *StatsService) fetchWindow(source string, adapter IFetchRecords, startTime, endTime time.Time, window *entities.GameRecordSyncWindow) error {
+	seen := make(map[string]struct{})
+	cursor := startTime
+	for page := 0; ; page++ {
+		if page >= recordSyncMaxPages {
+			return fmt.Errorf("too many pages in window")
+		}
+		records, err := adapter.FetchRecords(cursor, endTime)
 		if err != nil {
-			logger.ZError("sync third party data", zap.Error(err))
 			return err
 		}
-		if len(records) == 0 {
+		window.Pages++
 
+		fresh := make([]*entities.GameRecord, 0, len(records))
+		for _, record := range records {
+			key := fmt.Sprintf("%s-%d", record.RecordId, record.UID)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			fresh = append(fresh, record)
+		}
+		if len(fresh) == 0 {
 			break
 		}
+		window.Fetched += len(fresh)
 
-		// logger.ZInfo("sync third party data", zap.Int("records_count", len(records)))
-		// 处理并保存记录
-		lastRecordTime, err := s.processRecords(source, records)
+		inserted, updated, lastRecordTime, err := s.processRecords(source, fresh)
 		if err != nil {
-			logger.ZError("sync third party data", zap.Error(err))
-			break
+			return err
 		}
+		window.Inserted += inserted
+		window.Updated += updated
 
-		// 更新游标（使用最后记录时间 +1ms 避免重复）
-		startTime = lastRecordTime.Add(1 * time.Second)
-
-		// 更新同步状态
-		syncStatus.LastRecordTime = lastRecordTime.UnixMilli() // 将时间转换为 int64 时间戳
-
-		// 避免超过当前时间+
-		if startTime.After(endTime) {
-			break
+		if lastRecordTime.After(cursor) {
+			cursor = lastRecordTime
 		}
-
-		// 增加循环计数器
-		retryCount++
-
-		// 检查循环次数是否超过最大值，避免死循环
-		if retryCount >= 10 {
+		if !cursor.Before(endTime) {
 			break
 		}
 	}
-
-	// 更新全局同步状态
-	syncStatus.LastSyncTime = endTime.UnixMilli() // 将时间转换为 int64 时间戳
-	if err := s.Repo.UpdateSyncStatus(syncStatus); err != nil {
-		// do nothing
-	}
 	return nil
 }
 
-func (s *StatsService) processRecords(source string, records []*entities.GameRecord) (time.Time, error) {
-	tx := s.Repo.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
+// 一页记录在一个事务里写入 已存在的记录只更新金额并修正每日统计 新记录才计入统计和返利
+func (s *StatsService) processRecords(source string, records []*entities.GameRecord) (inserted, updated int, lastRecordTime time.Time, err error) {
 	var (
-		gameRecords    []entities.GameRecord
-		dailyStatsMap  = make(map[string]*entities.GamerDailyStats) // key: date+player
-		lastRecordTime time.Time
+		gameRecords   = make([]*entities.GameRecord, 0, len(records))
+		recordIDs     = make([]string, 0, len(records))
+		dailyStatsMap = make(map[string]*entities.GamerDailyStats) // key: date+player
 	)
 
 	// 转换记录
@@ -225,46 +293,90 @@ func (s *StatsService) processRecords(source string, records []*entities.GameRec
 
 		record.RecordId = source + "-" + record.RecordId + "-" + cast.ToString(record.UID) // 记录ID加上来源前缀确保唯一性
 
-		// 保存记录
-		gameRecords = append(gameRecords, *record)
+		gameRecords = append(gameRecords, record)
+		recordIDs = append(recordIDs, record.RecordId)
+	}
+	if len(gameRecords) == 0 {
+		return
+	}
 
+	addDailyStats := func(record *entities.GameRecord, count int, betAmount, profit float64) {
 		// 生成每日统计key
 		dateKey := record.BetTime.Format("2006-01-02")
 		mapKey := fmt.Sprintf("%s:%d", dateKey, record.UID)
 
-		// 更新每日统计
 		if stat, exists := dailyStatsMap[mapKey]; exists {
-			stat.BetCount++
-			stat.BetAmount += record.BetAmount
-			stat.Profit += record.Profit
+			stat.BetCount += count
+			stat.BetAmount += betAmount
+			stat.Profit += profit
 		} else {
 			dailyStatsMap[mapKey] = &entities.GamerDailyStats{
 				Date:      record.BetTime.Truncate(24 * time.Hour),
 				UID:       record.UID,
 				Source:    source,
-				BetCount:  1,
-				BetAmount: record.BetAmount,
-				Profit:    record.Profit,
+				BetCount:  count,
+				BetAmount: betAmount,
+				Profit:    profit,
 				Currency:  record.Currency,
 			}
 		}
 	}
 
-	// 批量写入游戏记录
-	if len(gameRecords) > 0 {
-		s.ProcessRabateRecords(source, records, func(list []*PlayerRecord) {
-			go s.ProcessPlayerRecordGroup(list) //处理返利记录
-		})
+	tx := s.Repo.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			err = fmt.Errorf("processRecords panic: %v", r)
+		}
+	}()
+
+	existingList, err := s.Repo.GetGameRecordsByRecordIDWithTx(tx, recordIDs)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+	existing := make(map[string]*entities.GameRecord, len(existingList))
+	for _, record := range existingList {
+		existing[record.RecordId] = record
+	}
+
+	newRecords := make([]*entities.GameRecord, 0, len(gameRecords))
+	for _, record := range gameRecords {
+		old, ok := existing[record.RecordId]
+		if !ok {
+			newRecords = append(newRecords, record)
+			addDailyStats(record, 1, record.BetAmount, record.Profit)
+			continue
+		}
+		if old.BetAmount == record.BetAmount && old.Amount == record.Amount && old.Profit == record.Profit {
+			continue
+		}
+		// 三方修正了注单金额 只把差额计入每日统计
+		if err = tx.Model(&entities.GameRecord{}).Where("id = ?", old.ID).Updates(map[string]interface{}{
+			"bet_amount": record.BetAmount,
+			"amount":     record.Amount,
+			"profit":     record.Profit,
+		}).Error; err != nil {
+			tx.Rollback()
+			return
+		}
+		addDailyStats(old, 0, record.BetAmount-old.BetAmount, record.Profit-old.Profit)
+		updated++
+	}
 
+	// 批量写入游戏记录
+	if len(newRecords) > 0 {
 		result := tx.Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "record_id"}},
 			DoUpdates: clause.AssignmentColumns([]string{"bet_amount", "amount", "profit"}),
-		}).CreateInBatches(gameRecords, 200)
+		}).CreateInBatches(newRecords, 200)
 
 		if result.Error != nil {
 			tx.Rollback()
-			return time.Time{}, result.Error
+			err = result.Error
+			return
 		}
+		inserted = len(newRecords)
 		logger.ZInfo("third party data CreateInBatches", zap.Int("RowsAffected", int(result.RowsAffected)))
 	}
 
@@ -284,14 +396,121 @@ func (s *StatsService) processRecords(source string, records []*entities.GameRec
 			bet_count = bet_count + VALUES(bet_count),
 			bet_amount = bet_amount + VALUES(bet_amount),
 			profit = profit + VALUES(profit)`, strings.Join(values, ","))
-		err := tx.Exec(isql).Error
-		if err != nil {
+		if err = tx.Exec(isql).Error; err != nil {
 			tx.Rollback()
-			return time.Time{}, err
+			return
 		}
 	}
 
-	return lastRecordTime, tx.Commit().Error
+	if err = tx.Commit().Error; err != nil {
+		return
+	}
+
+	// 提交后再处理返利 重复拉取的记录不会重复返利
+	if len(newRecords) > 0 {
+		s.ProcessRabateRecords(source, newRecords, func(list []*PlayerRecord) {
+			go s.ProcessPlayerRecordGroup(list) //处理返利记录
+		})
+	}
+	return
+}
+
+// 后台补录 指定时间段重新拉取 已存在的记录幂等处理 不影响定时同步的断点
+func (s *StatsService) BackfillGameRecord(req *entities.BackfillGameRecordReq) (err error) {
+	defer func() {
+		log := entities.SystemOptionLog{
+			Type:     constant.SYS_OPTION_TYPE_RECORD_BACKFILL,
+			OptionID: req.OptionID,
+			IP:       req.IP,
+			Content:  cjson.StringifyIgnore(req),
+		}
+		if err != nil {
+			log.Result = "false"
+			log.Remark = "三方游戏记录补录失败"
+		} else {
+			log.Result = "true"
+			log.Remark = "三方游戏记录补录开始"
+		}
+		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+		if err := mq.Publish(optionLogQueue); err != nil {
+			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+		}
+	}()
+
+	adapter, ok := s.adapters[req.Source]
+	if !ok {
+		return errors.WithCode(errors.RecordSyncSourceNotExist)
+	}
+	startTime := time.UnixMilli(req.StartTime).UTC()
+	endTime := time.UnixMilli(req.EndTime).UTC()
+	if !startTime.Before(endTime) || endTime.Sub(startTime) > recordSyncBackfillMax || endTime.After(time.Now()) {
+		return errors.WithCode(errors.RecordSyncInvalidRange)
+	}
+
+	lock := s.syncLock(req.Source)
+	if !lock.TryLock() {
+		return errors.WithCode(errors.RecordSyncRunning)
+	}
+
+	go func() {
+		defer utils.PrintPanicStack()
+		defer lock.Unlock()
+		for start := startTime; start.Before(endTime); start = start.Add(recordSyncStep) {
+			end := start.Add(recordSyncStep)
+			if end.After(endTime) {
+				end = endTime
+			}
+			if _, err := s.syncWindow(req.Source, adapter, start, end, constant.RECORD_SYNC_TRIGGER_BACKFILL, req.OptionID); err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (s *StatsService) GetGameRecordSyncWindowList(req *entities.GetGameRecordSyncWindowListReq) error {
+	return s.Repo.GetGameRecordSyncWindowList(req)
+}
+
+// 同步缺口 时间段内没有被成功窗口覆盖的部分 以及与三方总数不一致的窗口
+func (s *StatsService) GetGameRecordSyncGapList(req *entities.GetGameRecordSyncGapListReq) ([]*entities.GameRecordSyncGap, error) {
+	if _, ok := s.adapters[req.Source]; !ok {
+		return nil, errors.WithCode(errors.RecordSyncSourceNotExist)
+	}
+	if req.StartTime >= req.EndTime {
+		return nil, errors.WithCode(errors.RecordSyncInvalidRange)
+	}
+
+	windows, err := s.Repo.GetGameRecordSyncWindowsInRange(req.Source, req.StartTime, req.EndTime,
+		[]uint8{constant.RECORD_SYNC_STATUS_DONE, constant.RECORD_SYNC_STATUS_MISMATCH})
+	if err != nil {
+		return nil, err
+	}
+
+	gaps := make([]*entities.GameRecordSyncGap, 0)
+	mismatch := make([]*entities.GameRecordSyncGap, 0)
+	covered := req.StartTime
+	for _, window := range windows {
+		if window.StartTime > covered {
+			gaps = append(gaps, &entities.GameRecordSyncGap{StartTime: covered, EndTime: window.StartTime, Reason: constant.RECORD_SYNC_GAP_UNCOVERED})
+		}
+		if window.EndTime > covered {
+			covered = window.EndTime
+		}
+		if window.Status == constant.RECORD_SYNC_STATUS_MISMATCH {
+			mismatch = append(mismatch, &entities.GameRecordSyncGap{
+				StartTime:     window.StartTime,
+				EndTime:       window.EndTime,
+				Reason:        constant.RECORD_SYNC_GAP_MISMATCH,
+				Fetched:       window.Fetched,
+				ProviderTotal: window.ProviderTotal,
+			})
+		}
+	}
+	if covered < req.EndTime {
+		gaps = append(gaps, &entities.GameRecordSyncGap{StartTime: covered, EndTime: req.EndTime, Reason: constant.RECORD_SYNC_GAP_UNCOVERED})
+	}
+	return append(gaps, mismatch...), nil
 }
 
 func (s *StatsService) ProcessRabateRecords(source string, unproceedRecords []*entities.GameRecord, handle func(list []*PlayerRecord)) {
@@ -43,6 +43,8 @@ type UserService struct {
 	FinancialSrv *FinancialService
 	VerifySrv    *VerifyService
 	walletSrv    *WalletService
+	VipSrv       *VipService
+	SessionRepo  *repository.SessionRepository
 	// UserLocks  *entities.RedisUserLock
 
 }
@@ -54,6 +56,8 @@ func ProvideUserService(repo *repository.UserRepository,
 	verifySrv *VerifyService,
 	financialSrv *FinancialService,
 	minioCli *minio.Client,
+	vipSrv *VipService,
+	sessionRepo *repository.SessionRepository,
 ) *UserService {
 	service := &UserService{
 		Repo:         repo,
@@ -62,6 +66,8 @@ func ProvideUserService(repo *repository.UserRepository,
 		FinancialSrv: financialSrv,
 		VerifySrv:    verifySrv,
 		walletSrv:    walletSrv,
+		VipSrv:       vipSrv,
+		SessionRepo:  sessionRepo,
 		minioCli:     minioCli,
 		UserLocks:    entities.NewRedisUserLock(repo.RDS), //分布式锁
 		// UserLocks:  entities.NewRedisUserLock(repo.RDS), //分布式锁
@@ -127,14 +133,6 @@ func (s *UserService) OnLogin(uid uint, hooks func()) error {
 	return nil
 }
 
-func (s *UserService) VerifyLoginToken(uid uint, token string) error {
-	keepToken, _ := s.Repo.GetUserAccessToken(uid)
-	if keepToken != token {
-		return errors.WithCode(errors.AccountLoginExpire)
-	}
-	return nil
-}
-
 func (s *UserService) ChangeNickname(req *entities.EditNicknameReq) error {
 
 	user, err := s.GetUserByUID(req.UID)
@@ -296,6 +294,12 @@ func (s *UserService) GetUserProfile(uid uint) (*entities.UserProfile, error) {
 		Wallet:  walletRes.wallet,
 	}
 
+	vip, err := s.VipSrv.GetVipInfo(uid) //VIP信息失败不影响其他资料
+	if err != nil {
+		logger.ZError("GetUserProfile GetVipInfo", zap.Uint("uid", uid), zap.Error(err))
+	}
+	profile.Vip = vip
+
 	// 组合数据
 
 	return profile, nil
@@ -435,7 +439,7 @@ func (s *UserService) EditUserInfo(req *entities.EditUserInfoReq) (err error) {
 		}
 
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -483,6 +487,12 @@ func (s *UserService) EditUserInfo(req *entities.EditUserInfoReq) (err error) {
 	}
 	s.Repo.ClearUserCache(userForUpdate.ID) //直接清掉redis 缓存 用户获取信息会重新拉去
 
+	if req.Status == constant.USER_STATE_BLOCKED { //封禁后已登录的会话全部下线
+		if err = s.RevokeUserSessions(user.ID, constant.USER_SESSION_REVOKE_BLOCKED); err != nil {
+			return err
+		}
+	}
+
 	if req.BalanceAdd > 0 || req.BalanceAdd < 0 { //有流水变动
 
 		err = s.walletSrv.HandleWallet(req.UID, func(wallet *entities.UserWallet, tx *gorm.DB) error {
@@ -501,8 +511,8 @@ func (s *UserService) EditUserInfo(req *entities.EditUserInfoReq) (err error) {
 				Balance:      wallet.Cash,
 				PromoterCode: user.PromoterCode,
 			})
-			if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-				logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+			if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+				return err
 			}
 
 			return nil
@@ -512,6 +522,20 @@ func (s *UserService) EditUserInfo(req *entities.EditUserInfoReq) (err error) {
 	return nil
 }
 
+// 撤销用户全部会话 老token一并失效
+func (s *UserService) RevokeUserSessions(uid uint, reason string) error {
+	list, err := s.SessionRepo.GetActiveUserSessionList(uid)
+	if err != nil {
+		return err
+	}
+	for _, session := range list {
+		if err := s.SessionRepo.RevokeUserSession(session.SessionID, reason); err != nil {
+			return err
+		}
+	}
+	return s.Repo.ExpireUserAccessToken(uid)
+}
+
 func (r *UserService) CheckAndAddTodayBetTimesLmit(uid string, timesLimit int) bool {
 	todayBetTimes, err := r.Repo.GetBetTimes(uid)
 	if err != nil {
@@ -0,0 +1,443 @@
+package service
+
+import (
+	"fmt"
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
+	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
+	"time"
+
+	"github.com/google/wire"
+	"github.com/orca-zhang/ecache"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	vipTierCacheKey       = "vip_tiers"
+	vipRollingDays        = 30 // 评定等级的滚动周期
+	vipBirthdayWaitDays   = 30 // 设置生日满30天后才发放生日奖励
+	vipRecalculateBatch   = 500
+	vipBirthdayDateLayout = "01-02"
+)
+
+var VipServiceSet = wire.NewSet(
+	ProvideVipService,
+)
+
+// VIP等级 每天按滚动周期内的打码量和充值额重新评定 等级随之升降
+// 升级奖励每个等级只发一次 生日奖励每年一次
+type VipService struct {
+	Repo      *repository.VipRepository
+	UserRepo  *repository.UserRepository
+	WalletSrv *WalletService
+
+	tierCache *ecache.Cache
+}
+
+func ProvideVipService(repo *repository.VipRepository, userRepo *repository.UserRepository, walletSrv *WalletService) *VipService {
+	return &VipService{
+		Repo:      repo,
+		UserRepo:  userRepo,
+		WalletSrv: walletSrv,
+		tierCache: ecache.NewLRUCache(1, 2, time.Minute),
+	}
+}
+
+// 等级配置 按等级升序
+func (s *VipService) GetVipTierList() ([]*entities.VipTier, error) {
+	if val, ok := s.tierCache.Get(vipTierCacheKey); ok {
+		return val.([]*entities.VipTier), nil
+	}
+	list, err := s.Repo.GetVipTierList()
+	if err != nil {
+		return nil, err
+	}
+	s.tierCache.Put(vipTierCacheKey, list)
+	return list, nil
+}
+
+// 等级对应的权益配置 没有配置时返回nil
+func (s *VipService) GetVipTier(level uint8) (*entities.VipTier, error) {
+	list, err := s.GetVipTierList()
+	if err != nil {
+		return nil, err
+	}
+	for _, tier := range list {
+		if tier.Level == level {
+			return tier, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *VipService) GetVipInfo(uid uint) (*entities.VipInfo, error) {
+	vip, err := s.Repo.GetUserVip(uid)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := s.GetVipTierList()
+	if err != nil {
+		return nil, err
+	}
+	info := new(entities.VipInfo)
+	if vip != nil {
+		info.Level = vip.Level
+		info.Turnover = vip.Turnover
+		info.Deposit = vip.Deposit
+		info.Birthday = vip.Birthday
+		info.CalcTime = vip.CalcTime
+	}
+	for _, tier := range tiers {
+		if tier.Level == info.Level {
+			info.Tier = tier
+		} else if tier.Level > info.Level {
+			info.NextTier = tier
+			break
+		}
+	}
+	return info, nil
+}
+
+// 生日只能设置一次 之后需要联系客服修改
+func (s *VipService) SetVipBirthday(req *entities.SetVipBirthdayReq) error {
+	if _, err := time.Parse(vipBirthdayDateLayout, req.Birthday); err != nil || len(req.Birthday) != len(vipBirthdayDateLayout) {
+		return errors.WithCode(errors.VipBirthdayInvalid)
+	}
+	vip, err := s.Repo.GetUserVip(req.UID)
+	if err != nil {
+		return err
+	}
+	if vip == nil {
+		vip = &entities.UserVip{UID: req.UID}
+	}
+	if vip.Birthday != "" {
+		return errors.WithCode(errors.VipBirthdayAlreadySet)
+	}
+	vip.Birthday = req.Birthday
+	vip.BirthdayTime = time.Now().Unix()
+	return s.Repo.SaveUserVip(vip)
+}
+
+func (s *VipService) AdminGetVipTierList() ([]*entities.VipTier, error) {
+	return s.Repo.GetVipTierList()
+}
+
+func (s *VipService) SaveVipTier(req *entities.SaveVipTierReq) (err error) {
+	defer func() {
+		s.writeVipLog(req.OptionID, req.IP, req, err, "保存VIP等级配置")
+	}()
+	if req.MinTurnover < 0 || req.MinDeposit < 0 || req.RakebackRate < 0 || req.RakebackRate > 100 ||
+		req.WithdrawDayCount < 0 || req.WithdrawDayCash < 0 || req.LevelUpBonus < 0 || req.BirthdayBonus < 0 {
+		return errors.WithCode(errors.VipTierInvalid)
+	}
+	tier := new(entities.VipTier)
+	if req.ID > 0 {
+		if tier, err = s.Repo.GetVipTierByID(req.ID); err != nil {
+			return
+		}
+		if tier == nil {
+			return errors.WithCode(errors.VipTierNotExist)
+		}
+	}
+	tier.Level = req.Level
+	tier.Name = req.Name
+	tier.MinTurnover = req.MinTurnover
+	tier.MinDeposit = req.MinDeposit
+	tier.RakebackRate = req.RakebackRate
+	tier.WithdrawDayCount = req.WithdrawDayCount
+	tier.WithdrawDayCash = req.WithdrawDayCash
+	tier.WithdrawPriority = req.WithdrawPriority
+	tier.LevelUpBonus = req.LevelUpBonus
+	tier.BirthdayBonus = req.BirthdayBonus
+	if err = s.Repo.SaveVipTier(tier); err != nil {
+		return
+	}
+	s.tierCache.Del(vipTierCacheKey)
+	return
+}
+
+func (s *VipService) DelVipTier(req *entities.DelVipTierReq) (err error) {
+	defer func() {
+		s.writeVipLog(req.OptionID, req.IP, req, err, "删除VIP等级配置")
+	}()
+	if err = s.Repo.DelVipTier(req.ID); err != nil {
+		return
+	}
+	s.tierCache.Del(vipTierCacheKey)
+	return
+}
+
+// 满足打码量和充值条件的最高等级
+func vipLevelFor(tiers []*entities.VipTier, turnover, deposit float64) uint8 {
+	var level uint8
+	for _, tier := range tiers {
+		if turnover >= tier.MinTurnover && deposit >= tier.MinDeposit && tier.Level > level {
+			level = tier.Level
+		}
+	}
+	return level
+}
+
+// 重新评定周期内有投注/充值的用户以及当前有等级的用户
+// 打码量与返利口径一致: 自研游戏流水(wingo/nine等) + game_record的有效流水
+func (s *VipService) RecalculateVipLevels() error {
+	tiers, err := s.Repo.GetVipTierList()
+	if err != nil {
+		return err
+	}
+	if len(tiers) == 0 {
+		return nil
+	}
+	s.tierCache.Put(vipTierCacheKey, tiers)
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -vipRollingDays)
+	turnover := make(map[uint]float64)
+	deposit := make(map[uint]float64)
+
+	flowBets, err := s.Repo.SumGameFlowBet(since.Unix())
+	if err != nil {
+		return err
+	}
+	records, err := s.Repo.SumGameRecordTurnover(since)
+	if err != nil {
+		return err
+	}
+	recharges, err := s.Repo.SumRecharge(since.Unix())
+	if err != nil {
+		return err
+	}
+	for _, item := range append(flowBets, records...) {
+		turnover[item.UID] += item.Amount
+	}
+	for _, item := range recharges {
+		deposit[item.UID] += item.Amount
+	}
+
+	uidSet := make(map[uint]struct{}, len(turnover)+len(deposit))
+	for uid := range turnover {
+		uidSet[uid] = struct{}{}
+	}
+	for uid := range deposit {
+		uidSet[uid] = struct{}{}
+	}
+	leveled, err := s.Repo.GetLeveledUIDList()
+	if err != nil {
+		return err
+	}
+	for _, uid := range leveled {
+		uidSet[uid] = struct{}{}
+	}
+	uids := make([]uint, 0, len(uidSet))
+	for uid := range uidSet {
+		uids = append(uids, uid)
+	}
+
+	changed := 0
+	for start := 0; start < len(uids); start += vipRecalculateBatch {
+		end := min(start+vipRecalculateBatch, len(uids))
+		vips, err := s.Repo.GetUserVipListByUIDs(uids[start:end])
+		if err != nil {
+			return err
+		}
+		vipMap := make(map[uint]*entities.UserVip, len(vips))
+		for _, vip := range vips {
+			vipMap[vip.UID] = vip
+		}
+		for _, uid := range uids[start:end] {
+			vip, ok := vipMap[uid]
+			if !ok {
+				vip = &entities.UserVip{UID: uid}
+			}
+			level := vipLevelFor(tiers, turnover[uid], deposit[uid])
+			if level == 0 && vip.ID == 0 {
+				continue
+			}
+			oldLevel := vip.Level
+			vip.Level = level
+			vip.Turnover = turnover[uid]
+			vip.Deposit = deposit[uid]
+			vip.CalcTime = now.Unix()
+			if err := s.Repo.SaveUserVip(vip); err != nil {
+				logger.ZError("RecalculateVipLevels SaveUserVip", zap.Uint("uid", uid), zap.Error(err))
+				continue
+			}
+			if level != oldLevel {
+				changed++
+				if err := s.UserRepo.UpdateUserVipLevel(uid, level); err != nil {
+					logger.ZError("RecalculateVipLevels UpdateUserVipLevel", zap.Uint("uid", uid), zap.Error(err))
+				}
+			}
+			if level > vip.MaxLevel {
+				if err := s.levelUp(vip, tiers, level); err != nil {
+					logger.ZError("RecalculateVipLevels levelUp", zap.Uint("uid", uid), zap.Error(err))
+				}
+			}
+		}
+	}
+	logger.ZInfo("RecalculateVipLevels", zap.Int("users", len(uids)), zap.Int("changed", changed))
+
+	s.payBirthdayBonus(tiers, now)
+	return nil
+}
+
+// 充值到账后立即评定该用户 只升不降 降级仍由每日评定处理
+func (s *VipService) RecalculateUserVip(uid uint) error {
+	tiers, err := s.GetVipTierList()
+	if err != nil {
+		return err
+	}
+	if len(tiers) == 0 {
+		return nil
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -vipRollingDays)
+	flowBet, err := s.Repo.SumUserGameFlowBet(uid, since.Unix())
+	if err != nil {
+		return err
+	}
+	recordTurnover, err := s.Repo.SumUserGameRecordTurnover(uid, since)
+	if err != nil {
+		return err
+	}
+	deposit, err := s.Repo.SumUserCompletedRecharge(uid, since.Unix())
+	if err != nil {
+		return err
+	}
+	turnover := entities.AddPrecise(flowBet, recordTurnover)
+
+	vip, err := s.Repo.GetUserVip(uid)
+	if err != nil {
+		return err
+	}
+	if vip == nil {
+		vip = &entities.UserVip{UID: uid}
+	}
+	level := vipLevelFor(tiers, turnover, deposit)
+	if level <= vip.Level {
+		return nil
+	}
+	vip.Level = level
+	vip.Turnover = turnover
+	vip.Deposit = deposit
+	vip.CalcTime = now.Unix()
+	if err := s.Repo.SaveUserVip(vip); err != nil {
+		return err
+	}
+	if err := s.UserRepo.UpdateUserVipLevel(uid, level); err != nil {
+		return err
+	}
+	if level > vip.MaxLevel {
+		return s.levelUp(vip, tiers, level)
+	}
+	return nil
+}
+
+// 发放从原最高等级到新等级之间每个等级的升级奖励
+func (s *VipService) levelUp(vip *entities.UserVip, tiers []*entities.VipTier, level uint8) error {
+	var bonus float64
+	for _, tier := range tiers {
+		if tier.Level > vip.MaxLevel && tier.Level <= level {
+			bonus = entities.AddPrecise(bonus, tier.LevelUpBonus)
+		}
+	}
+	from := vip.MaxLevel
+	mark := func(tx *gorm.DB) (bool, error) {
+		return s.Repo.UpdateUserVipMaxLevelWithTx(tx, vip.UID, from, level)
+	}
+	if err := s.payVipBonus(vip.UID, constant.FLOW_TYPE_VIP_LEVEL_UP, fmt.Sprintf("vip:%d:%d", vip.UID, level), bonus, mark); err != nil {
+		return err
+	}
+	vip.MaxLevel = level
+	return nil
+}
+
+// 今天生日的用户发放当前等级的生日奖励 平年的2月28日同时发放2月29日生日的
+func (s *VipService) payBirthdayBonus(tiers []*entities.VipTier, now time.Time) {
+	birthdays := []string{now.Format(vipBirthdayDateLayout)}
+	if now.Month() == time.February && now.Day() == 28 && now.AddDate(0, 0, 1).Day() == 1 {
+		birthdays = append(birthdays, "02-29")
+	}
+	setBefore := now.AddDate(0, 0, -vipBirthdayWaitDays).Unix()
+	list, err := s.Repo.GetBirthdayUserVipList(birthdays, now.Year(), setBefore)
+	if err != nil {
+		logger.ZError("payBirthdayBonus", zap.Error(err))
+		return
+	}
+	tierMap := make(map[uint8]*entities.VipTier, len(tiers))
+	for _, tier := range tiers {
+		tierMap[tier.Level] = tier
+	}
+	for _, vip := range list {
+		tier := tierMap[vip.Level]
+		if tier == nil || tier.BirthdayBonus <= 0 {
+			continue
+		}
+		uid := vip.UID
+		mark := func(tx *gorm.DB) (bool, error) {
+			return s.Repo.UpdateUserVipBirthdayYearWithTx(tx, uid, now.Year())
+		}
+		if err := s.payVipBonus(uid, constant.FLOW_TYPE_VIP_BIRTHDAY, fmt.Sprintf("birthday:%d:%d", uid, now.Year()), tier.BirthdayBonus, mark); err != nil {
+			logger.ZError("payBirthdayBonus", zap.Uint("uid", uid), zap.Error(err))
+		}
+	}
+}
+
+// 在钱包事务内先标记已发放 标记失败说明已经发过 多节点同时执行也只发一次
+func (s *VipService) payVipBonus(uid uint, flowType uint16, sourceID string, amount float64, mark func(tx *gorm.DB) (bool, error)) error {
+	if amount <= 0 {
+		_, err := mark(s.Repo.DB)
+		return err
+	}
+	return s.WalletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
+		ok, err := mark(tx)
+		if err != nil || !ok {
+			return err
+		}
+		wallet.SafeAdjustCash(amount)
+		if err := s.WalletSrv.AddTurnoverRequirementWithTx(tx, uid, flowType, sourceID, amount); err != nil {
+			return err
+		}
+		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
+			return err
+		}
+		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
+			UID:          uid,
+			FlowType:     flowType,
+			Number:       amount,
+			Balance:      wallet.Cash,
+			PromoterCode: wallet.PromoterCode,
+		})
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
+		}
+		return event.PublishTx(tx, &event.BonusGranted{UID: uid, FlowType: flowType, SourceID: sourceID, Amount: amount})
+	})
+}
+
+func (s *VipService) writeVipLog(optionID uint, ip string, req interface{}, err error, remark string) {
+	log := entities.SystemOptionLog{
+		Type:     constant.SYS_OPTION_TYPE_VIP_TIER,
+		OptionID: optionID,
+		IP:       ip,
+		Content:  cjson.StringifyIgnore(req),
+	}
+	if err != nil {
+		log.Result = "false"
+		log.Remark = remark + "失败"
+	} else {
+		log.Result = "true"
+		log.Remark = remark + "成功"
+	}
+	optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+	if err := mq.Publish(optionLogQueue); err != nil {
+		logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+	}
+}
@@ -1,16 +1,26 @@
 package service
 
 import (
+	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/mq"
+	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
+	"rk-api/pkg/cjson"
+	"rk-api/pkg/logger"
 	"time"
 
 	"github.com/google/wire"
+	"github.com/orca-zhang/ecache"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+const turnoverRuleCacheKey = "turnover_rules"
+
 var WalletServiceSet = wire.NewSet(
 	ProvideWalletService,
 )
@@ -18,16 +28,22 @@ var WalletServiceSet = wire.NewSet(
 type WalletService struct {
 	Repo      *repository.WalletRepository
 	UserLocks *entities.RedisUserLock
+	GamingSrv *GamingService
+
+	turnoverRuleCache *ecache.Cache
 }
 
 func ProvideWalletService(
 	repo *repository.WalletRepository,
-
+	gamingSrv *GamingService,
 ) *WalletService {
 	service := &WalletService{
-		Repo: repo,
+		Repo:      repo,
+		GamingSrv: gamingSrv,
 
 		UserLocks: entities.NewRedisUserLock(repo.RDS), //分布式锁
+
+		turnoverRuleCache: ecache.NewLRUCache(1, 2, time.Minute),
 	}
 	return service
 }
@@ -167,10 +183,150 @@ func (s *WalletService) HandleWallet(uid uint, operation func(wallet *entities.U
 	if err = tx.Commit().Error; err != nil {
 		return err
 	}
+	mq.NotifyOutbox() //事务内写入发件箱的消息立即投递
 	// 提交事务
 	return nil
 }
 
+// 负责任博彩 所有下注扣款前检查限额/冷静期/自我排除
+func (s *WalletService) CheckBetLimit(uid uint, amount float64) error {
+	return s.GamingSrv.CheckBet(uid, amount)
+}
+
+// 负责任博彩 发起充值前检查
+func (s *WalletService) CheckDepositLimit(uid uint, amount float64) error {
+	return s.GamingSrv.CheckDeposit(uid, amount)
+}
+
+func (s *WalletService) RecordGamingUsage(flow *entities.Flow) error {
+	return s.GamingSrv.RecordUsage(flow)
+}
+
 func (s *WalletService) ClearWalletCache(uid uint) error {
 	return s.Repo.ClearWalletCache(uid)
 }
+
+func (s *WalletService) GetTurnoverRuleList() ([]*entities.TurnoverRule, error) {
+	if val, ok := s.turnoverRuleCache.Get(turnoverRuleCacheKey); ok {
+		return val.([]*entities.TurnoverRule), nil
+	}
+	list, err := s.Repo.GetTurnoverRuleList()
+	if err != nil {
+		return nil, err
+	}
+	s.turnoverRuleCache.Put(turnoverRuleCacheKey, list)
+	return list, nil
+}
+
+func (s *WalletService) UpdateTurnoverRule(req *entities.UpdateTurnoverRuleReq) (err error) {
+	defer func() {
+		log := entities.SystemOptionLog{
+			Type:     constant.SYS_OPTION_TYPE_TURNOVER_RULE,
+			OptionID: req.OptionID,
+			IP:       req.IP,
+			Content:  cjson.StringifyIgnore(req),
+		}
+		if err != nil {
+			log.Result = "false"
+			log.Remark = "打码量规则修改失败"
+		} else {
+			log.Result = "true"
+			log.Remark = "打码量规则修改成功"
+		}
+		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
+		if err := mq.Publish(optionLogQueue); err != nil {
+			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
+		}
+	}()
+
+	if err = s.Repo.SaveTurnoverRule(&entities.TurnoverRule{
+		FlowType:   req.FlowType,
+		Multiplier: req.Multiplier,
+		Status:     req.Status,
+	}); err != nil {
+		return err
+	}
+	s.turnoverRuleCache.Del(turnoverRuleCacheKey)
+	return nil
+}
+
+/**
+ * 入账时附加打码量要求, 需在入账的同一事务中调用
+ * @param flowType 入账流水类型 按类型查找倍数, 没有配置或关闭的不产生要求
+ * @param sourceID 来源订单
+ */
+func (s *WalletService) AddTurnoverRequirementWithTx(tx *gorm.DB, uid uint, flowType uint16, sourceID string, amount float64) error {
+	if amount <= 0 {
+		return nil
+	}
+	rules, err := s.GetTurnoverRuleList()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.FlowType != flowType || rule.Status != 1 || rule.Multiplier <= 0 {
+			continue
+		}
+		requirement := &entities.TurnoverRequirement{
+			UID:        uid,
+			FlowType:   flowType,
+			SourceID:   sourceID,
+			Amount:     amount,
+			Multiplier: rule.Multiplier,
+			Required:   decimal.NewFromFloat(amount).Mul(decimal.NewFromFloat(rule.Multiplier)).Round(2).InexactFloat64(),
+			Status:     constant.TURNOVER_STATUS_ACTIVE,
+		}
+		return s.Repo.CreateTurnoverRequirementWithTx(tx, requirement)
+	}
+	return nil
+}
+
+/**
+ * 投注扣减打码量 按入账先后顺序消耗
+ * 需在创建投注单的同一事务中调用 投注单只会创建一次 打码量也只会扣减一次
+ */
+func (s *WalletService) ConsumeTurnoverWithTx(tx *gorm.DB, uid uint, bet float64) error {
+	if bet <= 0 {
+		return nil
+	}
+	list, err := s.Repo.GetActiveTurnoverRequirementListForUpdate(tx, uid)
+	if err != nil {
+		return err
+	}
+	for _, item := range list {
+		if bet <= 0 {
+			break
+		}
+		used := item.Remaining()
+		if bet < used {
+			used = bet
+		}
+		item.Wagered = entities.AddPrecise(item.Wagered, used)
+		bet = entities.AddPrecise(bet, -used)
+		if item.Remaining() <= 0 {
+			item.Status = constant.TURNOVER_STATUS_FINISHED
+			item.FinishTime = time.Now().Unix()
+		}
+		if err := s.Repo.UpdateTurnoverRequirementWithTx(tx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 剩余打码量
+func (s *WalletService) GetTurnoverSummary(uid uint) (*entities.TurnoverSummary, error) {
+	list, err := s.Repo.GetActiveTurnoverRequirementList(uid)
+	if err != nil {
+		return nil, err
+	}
+	summary := &entities.TurnoverSummary{List: list}
+	for _, item := range list {
+		summary.Remaining = entities.AddPrecise(summary.Remaining, item.Remaining())
+	}
+	return summary, nil
+}
+
+func (s *WalletService) GetTurnoverRequirementList(req *entities.GetTurnoverRequirementListReq) error {
+	return s.Repo.GetTurnoverRequirementList(req)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"rk-api/internal/app/constant"
+	"rk-api/internal/app/entities"
+	"rk-api/internal/app/service/repository"
+	"testing"
+	"time"
+
+	"github.com/orca-zhang/ecache"
+	"gorm.io/gorm"
+)
+
+func newTestWalletService(t *testing.T) *WalletService {
+	db := newTestDB(t, new(entities.TurnoverRule), new(entities.TurnoverRequirement))
+	rules := []*entities.TurnoverRule{
+		{FlowType: constant.FLOW_TYPE_RECHARGE_PROMOTION, Multiplier: 3, Status: 1},
+		{FlowType: constant.FLOW_TYPE_CASHBACK, Multiplier: 1},
+	}
+	if err := db.Create(rules).Error; err != nil {
+		t.Fatal(err)
+	}
+	// status默认为1 关闭的规则写入后再改
+	db.Model(rules[1]).UpdateColumn("status", 0)
+	return &WalletService{
+		Repo:              &repository.WalletRepository{DB: db},
+		turnoverRuleCache: ecache.NewLRUCache(1, 2, time.Minute),
+	}
+}
+
+// 入账按规则生成打码量 投注按入账先后消耗 完成的不再参与
+func TestWalletService_ConsumeTurnoverWithTx(t *testing.T) {
+	s := newTestWalletService(t)
+	db := s.Repo.DB
+	const uid = 1
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := s.AddTurnoverRequirementWithTx(tx, uid, constant.FLOW_TYPE_RECHARGE_PROMOTION, "o1", 100); err != nil {
+			return err
+		}
+		if err := s.AddTurnoverRequirementWithTx(tx, uid, constant.FLOW_TYPE_RECHARGE_PROMOTION, "o2", 50); err != nil {
+			return err
+		}
+		// 规则关闭的不生成
+		return s.AddTurnoverRequirementWithTx(tx, uid, constant.FLOW_TYPE_CASHBACK, "c1", 100)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name          string
+		bet           float64
+		wantRemaining float64
+		wantActive    int
+	}{
+		{name: "first partly", bet: 120.5, wantRemaining: 329.5, wantActive: 2},
+		{name: "across two", bet: 200, wantRemaining: 129.5, wantActive: 1},
+		{name: "zero bet", bet: 0, wantRemaining: 129.5, wantActive: 1},
+		{name: "finish all", bet: 1000, wantRemaining: 0, wantActive: 0},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return s.ConsumeTurnoverWithTx(tx, uid, step.bet)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			summary, err := s.GetTurnoverSummary(uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Remaining != step.wantRemaining || len(summary.List) != step.wantActive {
+				t.Fatalf("remaining = %v active = %d, want %v %d", summary.Remaining, len(summary.List), step.wantRemaining, step.wantActive)
+			}
+		})
+	}
+
+	var finished []*entities.TurnoverRequirement
+	db.Where("status = ?", constant.TURNOVER_STATUS_FINISHED).Order("id asc").Find(&finished)
+	if len(finished) != 2 || finished[0].SourceID != "o1" || finished[0].Wagered != 300 || finished[0].FinishTime == 0 {
+		t.Fatalf("finished = %+v", finished)
+	}
+}
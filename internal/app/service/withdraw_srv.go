@@ -7,6 +7,7 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
+	"rk-api/internal/app/event"
 	"rk-api/internal/app/mq"
 	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/pay"
@@ -41,6 +42,8 @@ type WithdrawService struct {
 	FlowSrv   *FlowService
 	WalletSrv *WalletService
 	VerifySrv *VerifyService
+	RiskSrv   *RiskService
+	VipSrv    *VipService
 
 	// 在这里添加一个用户ID到Locker的映射, 这样每个用户都可以拥有自己的独立锁
 	orderLockersMap     sync.Map
@@ -51,7 +54,7 @@ type WithdrawService struct {
 	withdrawImplMap map[string]pay.IWithdraw
 }
 
-func ProvideWithdrawService(repo *repository.WithdrawRepository, userSrv *UserService, flowSrv *FlowService, fundSrv *WalletService, VerifySrv *VerifyService) *WithdrawService {
+func ProvideWithdrawService(repo *repository.WithdrawRepository, userSrv *UserService, flowSrv *FlowService, fundSrv *WalletService, VerifySrv *VerifyService, riskSrv *RiskService, vipSrv *VipService) *WithdrawService {
 	// 初始化你的缓存, 锁和其它实现
 	withdrawImplMap := map[string]pay.IWithdraw{
 		"kb":   new(pay.KBWithdraw),
@@ -71,6 +74,8 @@ func ProvideWithdrawService(repo *repository.WithdrawRepository, userSrv *UserSe
 		FlowSrv:             flowSrv,
 		VerifySrv:           VerifySrv,
 		WalletSrv:           fundSrv,
+		RiskSrv:             riskSrv,
+		VipSrv:              vipSrv,
 		withdrawImplMap:     withdrawImplMap,
 		channelSettingCache: channelSettingCache,
 	}
@@ -126,7 +131,7 @@ func (s *WithdrawService) RejectWithdrawal(req *entities.ReviewWithdrawalReq) (e
 		}
 
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -164,8 +169,8 @@ func (s *WithdrawService) RejectWithdrawal(req *entities.ReviewWithdrawalReq) (e
 			Balance:      wallet.Cash,
 			PromoterCode: wallet.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
 		return nil
 	})
@@ -187,6 +192,18 @@ func (s *WithdrawService) GetRechargeChannelSetting(name string) (*entities.Rech
 	return setting, nil
 }
 
+// 大额提现批准需要复核 订单不存在时交给ReviewWithdrawal报错
+func (s *WithdrawService) NeedWithdrawalApproval(req *entities.ReviewWithdrawalReq) (bool, error) {
+	if req.OptType != constant.WITHDRAW_REVIEW_OPT_APPROVE {
+		return false, nil
+	}
+	record, err := s.Repo.GetWithdrawCardRecordByID(req.ID)
+	if err != nil || record == nil {
+		return false, err
+	}
+	return record.Cash >= constant.ADMIN_APPROVAL_WITHDRAW_THRESHOLD, nil
+}
+
 // 批准提现
 func (s *WithdrawService) ApproveWithdrawal(req *entities.ReviewWithdrawalReq) (err error) {
 	s.withdrawReviewMutex.Lock() //互斥锁
@@ -199,6 +216,10 @@ func (s *WithdrawService) ApproveWithdrawal(req *entities.ReviewWithdrawalReq) (
 	if record.Status != constant.WITHDRAW_STATE_WAIT_REVIEW {
 		return errors.WithCode(errors.InvalidWithdrawalReview) //The order is not in a review status.
 	}
+	held := record.RiskState == constant.WITHDRAW_RISK_STATE_HOLD
+	if held && !req.RiskOverride { //风控挂起的订单 需要确认后才能批准
+		return errors.WithCode(errors.WithdrawalRiskHold)
+	}
 
 	defer func() {
 		log := entities.SystemOptionLog{
@@ -207,6 +228,9 @@ func (s *WithdrawService) ApproveWithdrawal(req *entities.ReviewWithdrawalReq) (
 			IP:       req.IP,
 			Content:  cjson.StringifyIgnore(record),
 		}
+		if held {
+			log.Data = cjson.StringifyIgnore(req) //确认放行风控挂起的订单
+		}
 		if err != nil {
 			log.Result = "false"
 			log.Remark = "运营审核打款提交失败"
@@ -218,8 +242,11 @@ func (s *WithdrawService) ApproveWithdrawal(req *entities.ReviewWithdrawalReq) (
 			log.Remark = "运营审核打款提交成功"
 			logger.ZError("ApproveWithdrawal succ", zap.Any("req", req))
 		}
+		if held {
+			log.Remark += " 风控挂起确认放行"
+		}
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -340,7 +367,7 @@ func (s *WithdrawService) ReverseWithdrawalFailed(req *entities.ReviewWithdrawal
 		}
 
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -404,6 +431,12 @@ func (s *WithdrawService) GetWithdrawDetail(uid uint) (*entities.WithdrawDetail,
 	withdrawDetail.MaxWithdraw = wallet.Cash
 	withdrawDetail.WithdrawCash = wallet.Cash
 
+	turnover, err := s.WalletSrv.GetTurnoverSummary(uid)
+	if err != nil {
+		return nil, err
+	}
+	withdrawDetail.TurnoverRemaining = turnover.Remaining
+
 	return withdrawDetail, nil
 }
 
@@ -411,9 +444,17 @@ func (s *WithdrawService) GetWithdrawCardListByUID(UID uint) ([]*entities.Withdr
 	return s.Repo.GetWithdrawCardListByUID(UID)
 }
 
+// 用户自己的提现记录 不返回风控信息
 func (s *WithdrawService) GetHallWithdrawRecordList(param *entities.GetHallWithdrawRecordListReq) error {
-
-	return s.Repo.GetHallWithdrawRecordList(param)
+	if err := s.Repo.GetHallWithdrawRecordList(param); err != nil {
+		return err
+	}
+	if list, ok := param.List.([]*entities.HallWithdrawRecord); ok {
+		for _, record := range list {
+			record.RiskState, record.RiskReason = 0, ""
+		}
+	}
+	return nil
 }
 
 func (s *WithdrawService) AddUserWithdrawCard(req *entities.AddUserWithdrawCardReq) (err error) {
@@ -442,7 +483,7 @@ func (s *WithdrawService) AddUserWithdrawCard(req *entities.AddUserWithdrawCardR
 		}
 
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -479,7 +520,7 @@ func (s *WithdrawService) DelUserWithdrawCard(req *entities.DelUserWithdrawCardR
 		}
 
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -516,7 +557,7 @@ func (s *WithdrawService) FixUserWithdrawCard(req *entities.FixUserWithdrawCardR
 		}
 
 		optionLogQueue, _ := handle.NewOptionLogQueue(&log)
-		if _, err := mq.MClient.Enqueue(optionLogQueue); err != nil {
+		if err := mq.Publish(optionLogQueue); err != nil {
 			logger.ZError("optionLogQueue", zap.Any("log", &log), zap.Error(err))
 		}
 	}()
@@ -621,6 +662,14 @@ func (s *WithdrawService) ApplyForWithdrawal(param *entities.ApplyForWithdrawalR
 	if wallet.Cash < param.Cash { //金额不足
 		return errors.WithCode(errors.InsufficientBalance)
 	}
+
+	turnover, err := s.WalletSrv.GetTurnoverSummary(param.UID)
+	if err != nil {
+		return err
+	}
+	if turnover.Remaining > 0 { //打码量未完成
+		return errors.WithCodeFields(errors.WithdrawalTurnoverNotMet, errors.ExtraFields{"remaining": turnover.Remaining})
+	}
 	if user.Mobile == "" {
 		return errors.WithCode(errors.MobileNotBind) //手机号未绑定
 	}
@@ -654,10 +703,33 @@ func (s *WithdrawService) ApplyForWithdrawal(param *entities.ApplyForWithdrawalR
 		return err
 	}
 
-	if todayCount >= constant.WITHDRAW_DAY_MAX_COUNT { //每日次数限制
+	tier, err := s.VipSrv.GetVipTier(user.VipLevel) //VIP等级的提现次数/金额/优先级
+	if err != nil {
+		return err
+	}
+	dayMaxCount := int64(constant.WITHDRAW_DAY_MAX_COUNT)
+	var priority uint8
+	if tier != nil {
+		if tier.WithdrawDayCount > 0 {
+			dayMaxCount = int64(tier.WithdrawDayCount)
+		}
+		priority = tier.WithdrawPriority
+	}
+
+	if todayCount >= dayMaxCount { //每日次数限制
 		return errors.WithCode(errors.WithdrawalDayCountLimit)
 	}
 
+	if tier != nil && tier.WithdrawDayCash > 0 {
+		todayCash, err := s.Repo.GetTodayWithdrawCash(user.ID)
+		if err != nil {
+			return err
+		}
+		if todayCash+param.Cash > tier.WithdrawDayCash { //每日金额限制
+			return errors.WithCode(errors.WithdrawalDayCashLimit)
+		}
+	}
+
 	record := entities.HallWithdrawRecord{
 		OrderID:       createWithdrawOrderID(user.ID),
 		UID:           user.ID,
@@ -668,6 +740,7 @@ func (s *WithdrawService) ApplyForWithdrawal(param *entities.ApplyForWithdrawalR
 		Cash:          param.Cash,
 		Rate:          uint(WITHDRAW_RATE), //抽水
 		StartTime:     time.Now().Unix(),
+		Priority:      priority,
 	}
 	record.CalculateFee() //计算抽水
 
@@ -700,12 +773,57 @@ func (s *WithdrawService) ApplyForWithdrawal(param *entities.ApplyForWithdrawalR
 			Balance:      wallet.Cash,
 			PromoterCode: user.PromoterCode,
 		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if err := mq.EnqueueTx(tx, createFlowQueue); err != nil {
+			return err
 		}
-		return nil
+		riskQueue, _ := handle.NewWithdrawRiskQueue(&entities.WithdrawRiskTask{RecordID: record.ID, IP: param.IP, DeviceID: param.DeviceID})
+		if err := mq.EnqueueTxWithKey(tx, riskQueue, record.OrderID); err != nil {
+			return err
+		}
+		return event.PublishTx(tx, &event.WithdrawalRequested{UID: user.ID, OrderID: record.OrderID, Amount: record.Cash})
 	})
+	return err
+}
+
+// 队列调用 风控评估 低风险的自动打款, 命中规则的挂起等待人工审核
+// 已评估或已审核的订单跳过 重复投递不会重复评估
+func (s *WithdrawService) CheckWithdrawRisk(task *entities.WithdrawRiskTask) error {
+	record, err := s.Repo.GetWithdrawCardRecordByID(task.RecordID)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.RiskState != constant.WITHDRAW_RISK_STATE_NONE || record.Status != constant.WITHDRAW_STATE_WAIT_REVIEW {
+		return nil
+	}
+	user, err := s.UserSrv.GetUserByUID(record.UID)
+	if err != nil {
+		return err
+	}
+	param := &entities.ApplyForWithdrawalReq{
+		UID:      record.UID,
+		Cash:     record.Cash,
+		IP:       task.IP,
+		DeviceID: task.DeviceID,
+	}
+	result, log, err := s.RiskSrv.EvaluateWithdrawal(user, record, param)
+	if err != nil {
+		return err
+	}
+	if result.Decision != constant.WITHDRAW_RISK_STATE_PASS {
+		return nil
+	}
 
+	err = s.ApproveWithdrawal(&entities.ReviewWithdrawalReq{
+		ID:      record.ID,
+		IP:      task.IP,
+		Reason:  "风控自动通过",
+		OptType: constant.WITHDRAW_REVIEW_OPT_APPROVE,
+	})
+	if err != nil { //自动打款失败 订单留在待审核 由人工处理
+		s.RiskSrv.UpdateWithdrawRiskLogResult(log, err.Error())
+		return nil
+	}
+	s.RiskSrv.UpdateWithdrawRiskLogResult(log, "succ")
 	return nil
 }
 
@@ -833,7 +951,7 @@ func (s *WithdrawService) HandleBusiAfterTradeSucc(order *entities.HallWithdrawR
 			return err
 		}
 
-		return nil
+		return event.PublishTx(tx, &event.WithdrawalPaid{UID: order.UID, OrderID: order.OrderID, Amount: order.Cash})
 	})
 
 	return err
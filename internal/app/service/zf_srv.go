@@ -9,8 +9,6 @@ import (
 	"rk-api/internal/app/constant"
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/errors"
-	"rk-api/internal/app/mq"
-	"rk-api/internal/app/mq/handle"
 	"rk-api/internal/app/service/repository"
 	resty "rk-api/pkg/http"
 	"rk-api/pkg/logger"
@@ -22,7 +20,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 var ZfServiceSet = wire.NewSet(
@@ -33,6 +30,7 @@ type ZfService struct {
 	Repo         *repository.ZfRepository
 	UserSrv      *UserService
 	WalletSrv    *WalletService
+	SeamlessSrv  *SeamlessWalletService
 	apiUrl       string
 	appID        string
 	appSecret    string
@@ -44,17 +42,19 @@ type ZfService struct {
 func ProvideZfService(repo *repository.ZfRepository,
 	userSrv *UserService,
 	walletSrv *WalletService,
+	seamlessSrv *SeamlessWalletService,
 ) *ZfService {
 	setting := config.Get().ZfSetting
 	logger.ZInfo("ProvideZfService", zap.Any("setting", setting))
 	return &ZfService{
-		Repo:       repo,
-		UserSrv:    userSrv,
-		WalletSrv:  walletSrv,
-		apiUrl:     setting.ApiUrl,
-		appID:      setting.AppID,
-		appSecret:  setting.AppSecret,
-		signSecret: setting.SignSecret,
+		Repo:        repo,
+		UserSrv:     userSrv,
+		WalletSrv:   walletSrv,
+		SeamlessSrv: seamlessSrv,
+		apiUrl:      setting.ApiUrl,
+		appID:       setting.AppID,
+		appSecret:   setting.AppSecret,
+		signSecret:  setting.SignSecret,
 	}
 }
 
@@ -339,6 +339,26 @@ func (s *ZfService) sign(params map[string]interface{}) string {
 	return hex.EncodeToString(bs)
 }
 
+// 单一钱包交易号 同一用户同一局的同一注
+func (s *ZfService) externalID(action string, uid uint, gameCode string, roundID, betID int) string {
+	return fmt.Sprintf("%s:%d:%s:%d:%d", action, uid, gameCode, roundID, betID)
+}
+
+// 单一钱包错误转换为zf的错误信息
+func (s *ZfService) errResp(err error) gin.H {
+	msg := err.Error()
+	if errors.IsCode(err, errors.WalletNotExist) {
+		msg = "user not exist"
+	} else if errors.IsCode(err, errors.InsufficientBalance) {
+		msg = "insufficient balance"
+	} else if errors.IsCode(err, errors.SeamlessTxRolledBack) {
+		msg = "order already refunded"
+	} else if errors.IsCode(err, errors.SeamlessTxSettled) {
+		msg = "order already settled"
+	}
+	return gin.H{"is_success": false, "msg": msg}
+}
+
 func (s *ZfService) Bet(req *entities.ZfBetReq) gin.H {
 	if !s.ValidateSignature(req) {
 		return gin.H{"is_success": false, "err_msg": "sign error"}
@@ -348,59 +368,24 @@ func (s *ZfService) Bet(req *entities.ZfBetReq) gin.H {
 	if err != nil {
 		return gin.H{"is_success": false, "msg": "Invalid username"}
 	}
-	wallet, err := s.WalletSrv.GetWallet(uid)
-	if wallet == nil || err != nil {
-		return gin.H{"is_success": false, "msg": "user not exist"}
-	}
-
-	if wallet.Cash < req.Amount {
-		return gin.H{"is_success": false, "msg": "insufficient balance"}
-	}
 
-	order, err := s.Repo.GetOrderBy(wallet.UID, req.GameCode, req.RoundID, req.BetID)
+	result, err := s.SeamlessSrv.Debit(&entities.SeamlessTxReq{
+		Provider:   constant.SEAMLESS_PROVIDER_ZF,
+		ExternalID: s.externalID("bet", uid, req.GameCode, req.RoundID, req.BetID),
+		UID:        uid,
+		Amount:     req.Amount,
+		GameCode:   req.GameCode,
+		RoundID:    strconv.Itoa(req.RoundID),
+		RecordID:   strconv.Itoa(req.BetID),
+		FlowType:   constant.FLOW_TYPE_ZF_BET,
+		Remark:     fmt.Sprintf("zfgame bet code:%s", req.GameCode),
+	})
 	if err != nil {
-		return gin.H{"is_success": false, "msg": err.Error()}
+		return s.errResp(err)
 	}
-	if order != nil {
+	if result.Duplicate {
 		return gin.H{"is_success": false, "msg": fmt.Sprintf(" order exist uid =%d,game_code =%s,round_id =%d,bet_id= %d", uid, req.GameCode, req.RoundID, req.BetID)}
 	}
-
-	order = &entities.ZfTransferOrder{
-		UID:      wallet.UID,
-		RoundID:  req.RoundID,
-		BetID:    req.BetID,
-		GameCode: req.GameCode,
-		Amount:   req.Amount,
-	}
-
-	err = s.WalletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
-		if err := s.Repo.CreateOrderWithTx(tx, order); err != nil {
-			return err
-		}
-		wallet.SafeAdjustCash(-req.Amount)
-
-		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
-			return err
-		}
-
-		remark := fmt.Sprintf("zfgame bet code:%s", req.GameCode)
-
-		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
-			UID:          wallet.ID,
-			FlowType:     constant.FLOW_TYPE_ZF_BET,
-			Number:       -req.Amount,
-			Balance:      wallet.Cash,
-			Remark:       remark,
-			PromoterCode: wallet.PromoterCode,
-		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
-		}
-		return nil
-	})
-	if err != nil {
-		return gin.H{"is_success": false, "msg": err.Error()}
-	}
 	return gin.H{"is_success": true, "err_msg": "", "currency": "USD"}
 }
 
@@ -414,52 +399,24 @@ func (s *ZfService) Payout(req *entities.ZfPayoutReq) gin.H {
 	if err != nil {
 		return gin.H{"is_success": false, "msg": "Invalid username"}
 	}
-	wallet, err := s.WalletSrv.GetWallet(uid)
-	if wallet == nil || err != nil {
-		return gin.H{"is_success": false, "msg": "user not exist"}
-	}
-
-	order, err := s.Repo.GetOrderBy(wallet.UID, req.GameCode, req.RoundID, req.BetID)
-	if err != nil {
-		return gin.H{"is_success": false, "msg": err.Error()}
-	}
-	if order == nil {
-		return gin.H{"is_success": false, "msg": fmt.Sprintf("not found order with uid =%d,game_code =%s,round_id =%d,bet_id= %d", uid, req.GameCode, req.RoundID, req.BetID)}
-	}
-
-	orderForUpdate := entities.ZfTransferOrder{
-		RewardAmount: req.Amount,
-		Status:       1,
-	}
-	orderForUpdate.ID = order.ID
-
-	err = s.WalletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
-		if err := s.Repo.UpdateOrderWithTx(tx, &orderForUpdate); err != nil {
-			return err
-		}
-		wallet.SafeAdjustCash(req.Amount)
-
-		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
-			return err
-		}
-		tx.Commit()
-		remark := fmt.Sprintf("zfgame reward code:%s", req.GameCode)
-		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
-			UID:          wallet.ID,
-			FlowType:     constant.FLOW_TYPE_ZF_PAYOUT,
-			Number:       req.Amount,
-			Balance:      wallet.Cash,
-			Remark:       remark,
-			PromoterCode: wallet.PromoterCode,
-		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
-		}
-		return nil
 
+	_, err = s.SeamlessSrv.Credit(&entities.SeamlessTxReq{
+		Provider:      constant.SEAMLESS_PROVIDER_ZF,
+		ExternalID:    s.externalID("payout", uid, req.GameCode, req.RoundID, req.BetID),
+		UID:           uid,
+		Amount:        req.Amount,
+		GameCode:      req.GameCode,
+		RoundID:       strconv.Itoa(req.RoundID),
+		RecordID:      strconv.Itoa(req.BetID),
+		RefExternalID: s.externalID("bet", uid, req.GameCode, req.RoundID, req.BetID),
+		FlowType:      constant.FLOW_TYPE_ZF_PAYOUT,
+		Remark:        fmt.Sprintf("zfgame reward code:%s", req.GameCode),
 	})
 	if err != nil {
-		return gin.H{"is_success": false, "msg": err.Error()}
+		if errors.IsCode(err, errors.SeamlessTxNotExist) {
+			return gin.H{"is_success": false, "msg": fmt.Sprintf("not found order with uid =%d,game_code =%s,round_id =%d,bet_id= %d", uid, req.GameCode, req.RoundID, req.BetID)}
+		}
+		return s.errResp(err)
 	}
 
 	return gin.H{"is_success": true, "err_msg": "", "currency": "USD"}
@@ -475,67 +432,64 @@ func (s *ZfService) Refund(req *entities.ZfRefund) gin.H {
 	if err != nil {
 		return gin.H{"is_success": false, "msg": "Invalid username"}
 	}
-	wallet, err := s.WalletSrv.GetUserWallet(uid)
-	if wallet == nil || err != nil {
-		return gin.H{"is_success": false, "msg": "user not exist"}
-	}
 
-	order, err := s.Repo.GetOrderBy(wallet.UID, req.GameCode, req.RoundID, req.BetID)
+	betID := s.externalID("bet", uid, req.GameCode, req.RoundID, req.BetID)
+	order, err := s.SeamlessSrv.GetTransaction(constant.SEAMLESS_PROVIDER_ZF, betID)
 	if err != nil {
 		return gin.H{"is_success": false, "msg": err.Error()}
 	}
 	if order == nil {
-		return gin.H{"is_success": false, "msg": fmt.Sprintf("not found order with uid =%d,game_code =%s,round_id =%d,bet_id= %d", wallet.ID, req.GameCode, req.RoundID, req.BetID)}
+		return gin.H{"is_success": false, "msg": fmt.Sprintf("not found order with uid =%d,game_code =%s,round_id =%d,bet_id= %d", uid, req.GameCode, req.RoundID, req.BetID)}
 	}
-	if math.Abs(order.Amount-req.Amount) > constant.PreciseZero {
-		return gin.H{"is_success": false, "msg": fmt.Sprintf("amount not equal record with uid =%d,game_code =%s,round_id =%d,bet_id= %d", wallet.ID, req.GameCode, req.RoundID, req.BetID)}
+	if math.Abs(math.Abs(order.Amount)-req.Amount) > constant.PreciseZero {
+		return gin.H{"is_success": false, "msg": fmt.Sprintf("amount not equal record with uid =%d,game_code =%s,round_id =%d,bet_id= %d", uid, req.GameCode, req.RoundID, req.BetID)}
 	}
 
-	orderForUpdate := entities.ZfTransferOrder{
-		Type:   req.Type,
-		Status: 1,
-	}
-	orderForUpdate.ID = order.ID
+	var remark string
+	if req.Type == 1 { // 1: refund 2: payout failed 3: issue cancel; 1:退回 2:派彩失败 3:取消
+		req.FlowType = constant.FLOW_TYPE_ZF_REFUND
+		remark = fmt.Sprintf("zfgame refund code:%s", req.GameCode)
 
-	err = s.WalletSrv.HandleWallet(uid, func(wallet *entities.UserWallet, tx *gorm.DB) error {
-		if err := s.Repo.UpdateOrderWithTx(tx, &orderForUpdate); err != nil {
-			return err
-		}
-		wallet.SafeAdjustCash(req.Amount)
+	} else if req.Type == 2 {
+		req.FlowType = constant.FLOW_TYPE_ZF_PAYOUT_FAIL
+		remark = fmt.Sprintf("zfgame payout failed code:%s", req.GameCode)
 
-		if err := s.WalletSrv.UpdateCashWithTx(tx, wallet); err != nil {
-			return err
-		}
-
-		var remark string
-		if req.Type == 1 { // 1: refund 2: payout failed 3: issue cancel; 1:退回 2:派彩失败 3:取消
-			req.FlowType = constant.FLOW_TYPE_ZF_REFUND
-			remark = fmt.Sprintf("zfgame refund code:%s", req.GameCode)
-
-		} else if req.Type == 2 {
-			req.FlowType = constant.FLOW_TYPE_ZF_PAYOUT_FAIL
-			remark = fmt.Sprintf("zfgame payout failed code:%s", req.GameCode)
+	} else if req.Type == 3 {
+		req.FlowType = constant.FLOW_TYPE_ZF_CANCEL
+		remark = fmt.Sprintf("zfgame cancel code:%s", req.GameCode)
+	}
 
-		} else if req.Type == 3 {
-			req.FlowType = constant.FLOW_TYPE_ZF_CANCEL
-			remark = fmt.Sprintf("zfgame cancel code:%s", req.GameCode)
+	txReq := &entities.SeamlessTxReq{
+		Provider:      constant.SEAMLESS_PROVIDER_ZF,
+		ExternalID:    s.externalID(fmt.Sprintf("refund%d", req.Type), uid, req.GameCode, req.RoundID, req.BetID),
+		UID:           uid,
+		Amount:        req.Amount,
+		GameCode:      req.GameCode,
+		RoundID:       strconv.Itoa(req.RoundID),
+		RecordID:      strconv.Itoa(req.BetID),
+		RefExternalID: betID,
+		FlowType:      uint16(req.FlowType),
+		Remark:        remark,
+	}
+	if req.Type == 2 {
+		// 派彩失败 派彩已经把原扣款结算了 不能再回滚扣款 关联到派彩交易上退回下注金额
+		payoutID := s.externalID("payout", uid, req.GameCode, req.RoundID, req.BetID)
+		payout, err := s.SeamlessSrv.GetTransaction(constant.SEAMLESS_PROVIDER_ZF, payoutID)
+		if err != nil {
+			return gin.H{"is_success": false, "msg": err.Error()}
 		}
-
-		createFlowQueue, _ := handle.NewCreateFlowQueue(&entities.Flow{
-			UID:          wallet.UID,
-			FlowType:     uint16(req.FlowType),
-			Number:       req.Amount,
-			Balance:      wallet.Cash,
-			Remark:       remark,
-			PromoterCode: wallet.PromoterCode,
-		})
-		if _, err := mq.MClient.Enqueue(createFlowQueue); err != nil {
-			logger.ZError("createFlowQueue", zap.Any("flow", createFlowQueue), zap.Error(err))
+		if payout != nil {
+			txReq.RefExternalID = payoutID
+			_, err = s.SeamlessSrv.Credit(txReq)
+			if err != nil {
+				return s.errResp(err)
+			}
+			return gin.H{"is_success": true, "err_msg": "", "currency": "USD"}
 		}
-		return nil
-	})
+	}
+	_, err = s.SeamlessSrv.Rollback(txReq)
 	if err != nil {
-		return gin.H{"is_success": false, "msg": err.Error()}
+		return s.errResp(err)
 	}
 
 	return gin.H{"is_success": true, "err_msg": "", "currency": "USD"}
@@ -552,9 +506,8 @@ func (s *ZfService) FetchBalance(req *entities.ZfBalanceReq) gin.H {
 		return gin.H{"is_success": false, "msg": "Invalid username"}
 	}
 
-	wallet, err := s.WalletSrv.GetWallet(uid)
-
-	if wallet == nil || err != nil {
+	balance, err := s.SeamlessSrv.Balance(uid)
+	if err != nil {
 		return gin.H{"is_success": false, "msg": "user not exist"}
 	}
 
@@ -563,7 +516,7 @@ func (s *ZfService) FetchBalance(req *entities.ZfBalanceReq) gin.H {
 		"err_msg":    "",
 		"currency":   "USD",
 		"username":   req.Username,
-		"balance":    wallet.Cash,
+		"balance":    balance,
 	}
 }
 
@@ -571,6 +524,9 @@ func (s *ZfService) Settle(req *entities.ZfSettleReq) gin.H {
 	if !s.ValidateSignature(req) {
 		return gin.H{"is_success": false, "err_msg": "sign error"}
 	}
+	if _, err := s.SeamlessSrv.Settle(constant.SEAMLESS_PROVIDER_ZF, req.GameCode, strconv.Itoa(req.RoundID)); err != nil {
+		logger.ZError("ZfService Settle", zap.Any("req", req), zap.Error(err))
+	}
 	return gin.H{
 		"is_success": true,
 		"err_msg":    "",
@@ -1,122 +1,46 @@
 package task
 
 import (
-	"rk-api/internal/app/config"
+	srv "rk-api/internal/app/service"
 	"rk-api/internal/app/service/async"
-	"time"
-
-	"github.com/robfig/cron/v3"
 )
 
-func Start(service async.IAsyncService) error { //当格式不正确的时候会返回error
-	// c := cron.New()
-
-	// 创建一个新的cron实例，并设定时区为东八区，即北京时间
-	// 设置时区为东八区，即北京时间
-	loc, err := time.LoadLocation(config.Get().ServiceSettings.Timezone)
-	if err != nil {
-		return err
-	}
-	// 创建新的cron实例，设置时区，并添加错误恢复机制
-	c := cron.New(
-		cron.WithLocation(loc),
-		cron.WithChain(
-			cron.Recover(cron.DefaultLogger), // 使用内建的恢复机制，避免panic导致程序崩溃
-		),
-	)
-
-	_, err = c.AddJob("@every 2m", ProcessSyncThirdPartyDataJob{Srv: service}) //同步第三方游戏数据
-	if err != nil {
-		return err // 返回错误而不是结束程序
-	}
-
-	// _, err = c.AddJob("@every 1m", ProcessSyncThirdOnlineCountJob{Srv: service}) //同步第三方游戏在线人数
-	// if err != nil {
-	// 	return err // 返回错误而不是结束程序
-	// }
-
-	// _, err = c.AddJob("1 0 * * *", ProcessInterestJob{Srv: service}) //利息   零点 1分
-	// _, err = c.AddJob("1 0 * * *", ProcessInterestJob{Srv: service})
-	// if err != nil {
-	// 	return err // 返回错误而不是结束程序
-	// }
-
-	// _, err = c.AddJob("@every 20s", ProcessRefundJob{Srv: service}) //游戏返利  现在是直接拉取返利
-	// if err != nil {
-	// 	return err //
-	// }
-
-	// _, err = c.AddJob("@every 5m", ProcessQueryPlatBalanceJob{Srv: service}) //查询支付平台金额
-	// if err != nil {
-	// 	return err //
-	// }
-
-	// _, err = c.AddJob("@every 3m", ProcessSettleExpiredWingoJob{Srv: service}) //处理超时未结算的wingo订单
-	// if err != nil {
-	// 	return err //
-	// }
-
-	// _, err = c.AddJob("@every 3m", ProcessSettleExpiredNineJob{Srv: service}) //处理超时未结算的nine订单
-	// if err != nil {
-	// 	return err //
-	// }
-
-	_, err = c.AddJob("1 0 1 * *", ProcessBackupCleanGameReturnJob{Srv: service}) //每个月的游戏返利备份清理  每个月1号的 凌晨 过 5分钟
-	if err != nil {
-		return err // 返回错误而不是结束程序
-	}
-
-	_, err = c.AddJob("5 0 1 * *", ProcessBackupCleanFlowJob{Srv: service}) //每个月的流水备份清理
-	if err != nil {
-		return err // 返回错误而不是结束程序
-	}
-
-	_, err = c.AddJob("30 1 1 * *", ProcessBackupCleanCrashGameRoundJob{Srv: service}) //每个月的崩溃游戏轮次备份清理
-	if err != nil {
-		return err // 返回错误而不是结束程序
-	}
-
-	// _, err = c.AddJob("10 0 1 * *", ProcessBackupCleanRefundFlowJob{Srv: service}) //每个月的返利流水备份清理
-	// if err != nil {
-	// 	return err // 返回错误而不是结束程序
-	// }
-
-	// _, err = c.AddJob("20 0 2 * *", ProcessBackupCleanRefundLinkGameFlowJob{Srv: service}) //每个月的返利link游戏流水备份清理
-	// if err != nil {
-	// 	return err // 返回错误而不是结束程序
-	// }
-
-	// _, err = c.AddJob("30 0 1 * *", ProcessBackupCleanGameOrderJob{Srv: service}) //每个月的游戏订单备份清理
-	// if err != nil {
-	// 	return err // 返回错误而不是结束程序
-	// }
-
-	// _, err = c.AddJob("40 2 1 * *", ProcessBackupCleanLinkGameOrderJob{Srv: service}) //每个月的外链游戏订单备份清理
-	// if err != nil {
-	// 	return err // 返回错误而不是结束程序
-	// }
-
-	// logger.ZPanic("test-------panic")
-
-	// _, err = c.AddJob("@every 59m", ProcessR8BetRecordJob{Srv: service}) //每个小时处理 r8投注记录
-	// if err != nil {
-	// 	return err //
-	// }
-
-	// _, err = c.AddJob("@every 1h", ProcessZfBetRecordJob{Srv: service}) //每个小时处理 zf投注记录
-	// if err != nil {
-	// 	return err //
-	// }
-
-	// _, err = c.AddJob("@every 10m", ProcessGetGameReturnCashJob{Srv: service}) //自动领取游戏返利
-	//
-
-	// if err != nil {
-	// 	return err // 返回错误而不是结束程序
-	// }
-
-	go c.Start() // 在一个新的goroutine中启动cron调度器
-	return nil
+// 任务注册到集群调度器 时间表/启停/补跑的默认值只在首次建表时写入 之后在后台修改
+// 多个节点开启EnableTask时每次触发只有一个节点执行
+func Start(service async.IAsyncService, scheduler *srv.SchedulerService) error { //当格式不正确的时候会返回error
+	scheduler.Register("sync-third-party-data", "@every 2m", false, "同步第三方游戏数据", ProcessSyncThirdPartyDataJob{Srv: service})
+	scheduler.Register("reconcile-provider-transfer", "20 * * * *", false, "每小时20分 三方转账与投注记录对账", ProcessReconcileJob{Srv: service})
+	scheduler.Register("vip-level", "20 3 * * *", true, "每天3点20 重新评定VIP等级 发放生日奖励", ProcessVipLevelJob{Srv: service})
+	scheduler.Register("agent-stats", "10 * * * *", true, "每小时10分 刷新代理团队当天统计", ProcessAgentStatsJob{Srv: service})
+	scheduler.Register("commission", "30 1 * * *", true, "每天1点30 代理统计定稿后结算上周和上月的佣金方案", ProcessCommissionJob{Srv: service})
+	scheduler.Register("game-lobby-rank", "@every 30m", false, "刷新大厅游戏排序", ProcessGameLobbyRankJob{Srv: service})
+	scheduler.Register("sync-provider-game-list", "40 4 * * *", true, "每天4点40 同步三方游戏列表", ProcessSyncProviderGameListJob{Srv: service})
+
+	// scheduler.Register("sync-third-online-count", "@every 1m", false, "同步第三方游戏在线人数", ProcessSyncThirdOnlineCountJob{Srv: service})
+	// scheduler.Register("interest", "1 0 * * *", true, "利息 零点1分", ProcessInterestJob{Srv: service})
+	// scheduler.Register("refund", "@every 20s", false, "游戏返利 现在是直接拉取返利", ProcessRefundJob{Srv: service})
+	// scheduler.Register("query-plat-balance", "@every 5m", false, "查询支付平台金额", ProcessQueryPlatBalanceJob{Srv: service})
+	// scheduler.Register("settle-expired-wingo", "@every 3m", false, "处理超时未结算的wingo订单", ProcessSettleExpiredWingoJob{Srv: service})
+	// scheduler.Register("settle-expired-nine", "@every 3m", false, "处理超时未结算的nine订单", ProcessSettleExpiredNineJob{Srv: service})
+
+	// 每个月1号凌晨归档上上个月的数据 错过的要补跑
+	scheduler.Register("archive-game-return", "1 0 1 * *", true, "每个月的游戏返利归档", ProcessBackupCleanGameReturnJob{Srv: service})
+	scheduler.Register("archive-flow", "5 0 1 * *", true, "每个月的流水归档", ProcessBackupCleanFlowJob{Srv: service})
+	scheduler.Register("archive-crash-game-round", "30 1 1 * *", true, "每个月的崩溃游戏轮次归档", ProcessBackupCleanCrashGameRoundJob{Srv: service})
+	// scheduler.Register("archive-refund-flow", "10 0 1 * *", true, "每个月的返利流水备份清理", ProcessBackupCleanRefundFlowJob{Srv: service})
+	// scheduler.Register("archive-refund-link-game-flow", "20 0 2 * *", true, "每个月的返利link游戏流水备份清理", ProcessBackupCleanRefundLinkGameFlowJob{Srv: service})
+	// 游戏订单归档之前一直是关闭的 默认暂停 运维确认后在后台启用
+	scheduler.RegisterPaused("archive-game-order", "30 0 1 * *", true, "每个月的游戏订单归档", ProcessBackupCleanGameOrderJob{Srv: service})
+	scheduler.RegisterPaused("archive-link-game-order", "40 2 1 * *", true, "每个月的外链游戏订单归档", ProcessBackupCleanLinkGameOrderJob{Srv: service})
+	scheduler.Register("outbox-clean", "50 4 * * *", false, "每天4点50 清理已投递的队列发件箱消息", ProcessOutboxCleanJob{Srv: service})
+	scheduler.Register("queue-failure-alert", "@every 5m", false, "队列任务失败率突增告警", ProcessQueueFailureAlertJob{Srv: service})
+
+	// 对账依赖r8/zf的投注记录 需要先插入一条起始时间的记录 默认暂停 插入后在后台启用
+	scheduler.RegisterPaused("r8-bet-record", "@every 59m", false, "每个小时处理 r8投注记录", ProcessR8BetRecordJob{Srv: service})
+	scheduler.RegisterPaused("zf-bet-record", "@every 1h", false, "每个小时处理 zf投注记录", ProcessZfBetRecordJob{Srv: service})
+	// scheduler.Register("game-return-cash", "@every 10m", false, "自动领取游戏返利", ProcessGetGameReturnCashJob{Srv: service})
+
+	return scheduler.Start()
 }
 
 // 时间表达式"5 0 1 * *" 表示每个月的1号的0点5分执行。
@@ -127,21 +51,3 @@ func Start(service async.IAsyncService) error { //当格式不正确的时候会
 // 第四个位（月份）：表示每年的哪个月触发任务，这里是任意月，使用通配符*表示。
 // 第五个位（星期）：表示每周的哪一天触发任务，这里也是任意星期，使用通配符*表示。
 // 因此，时间表达式"5 0 1 * *" 表示在每个月的1号的0点5分触发任务。
-
-// // 自定义的恢复中间件
-// func CustomRecover(handler cron.Job) cron.Job {
-//     return cron.FuncJob(func() {
-//         defer func() {
-//             // 使用recover()检查是否有panic发生
-//             if r := recover(); r != nil {
-//                 // 可以在这里添加您自己的逻辑，比如日志记录
-//                 log.Printf("Recovered from a panic in job. Error: %v", r)
-//                 // 这里也可以添加发送警报的代码，比如发送邮件通知等
-//             }
-//         }()
-//         handler.Run() // 执行原本的任务
-//     })
-// }
-
-// // 创建新的cron实例，并使用自定义的恢复中间件
-// c := cron.New(cron.WithChain(CustomRecover))
@@ -88,7 +88,7 @@ func (r ProcessBackupCleanRefundLinkGameFlowJob) Run() {
 	}
 }
 
-// -- CALL MonthBackupAndCleanV4('wingo_order,nine_order');
+// wingo_order nine_order 归档
 type ProcessBackupCleanGameOrderJob struct {
 	Srv async.IAsyncService
 }
@@ -105,9 +105,7 @@ func (r ProcessBackupCleanGameOrderJob) Run() {
 	}
 }
 
-// -- CALL MonthBackupAndCleanV4('r8_transfer_order');
-// -- CALL MonthBackupAndCleanV4('zf_transfer_order');
-
+// r8_transfer_order zf_transfer_order 归档
 type ProcessBackupCleanLinkGameOrderJob struct {
 	Srv async.IAsyncService
 }
@@ -123,3 +121,35 @@ func (r ProcessBackupCleanLinkGameOrderJob) Run() {
 		return
 	}
 }
+
+type ProcessOutboxCleanJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessOutboxCleanLock sync.Mutex
+
+func (r ProcessOutboxCleanJob) Run() {
+	gProcessOutboxCleanLock.Lock()
+	defer gProcessOutboxCleanLock.Unlock()
+	err := r.Srv.CleanPublishedOutbox()
+	if err != nil {
+		logger.ZError("ProcessOutboxCleanJob", zap.Error(err))
+		return
+	}
+}
+
+type ProcessQueueFailureAlertJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessQueueFailureAlertLock sync.Mutex
+
+func (r ProcessQueueFailureAlertJob) Run() {
+	gProcessQueueFailureAlertLock.Lock()
+	defer gProcessQueueFailureAlertLock.Unlock()
+	err := r.Srv.CheckQueueFailureRate()
+	if err != nil {
+		logger.ZError("ProcessQueueFailureAlertJob", zap.Error(err))
+		return
+	}
+}
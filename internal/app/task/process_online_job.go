@@ -36,3 +36,29 @@ func (r ProcessSyncThirdOnlineCountJob) Run() {
 	defer gProcessSyncThirdOnlineCountLock.Unlock()
 	r.Srv.SyncThirdOnlineCount()
 }
+
+type ProcessGameLobbyRankJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessGameLobbyRankLock sync.Mutex
+
+func (r ProcessGameLobbyRankJob) Run() {
+	gProcessGameLobbyRankLock.Lock()
+	defer gProcessGameLobbyRankLock.Unlock()
+	if err := r.Srv.RefreshGameLobbyRank(); err != nil {
+		logger.ZError("ProcessGameLobbyRankJob", zap.Error(err))
+	}
+}
+
+type ProcessSyncProviderGameListJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessSyncProviderGameListLock sync.Mutex
+
+func (r ProcessSyncProviderGameListJob) Run() {
+	gProcessSyncProviderGameListLock.Lock()
+	defer gProcessSyncProviderGameListLock.Unlock()
+	r.Srv.SyncAllProviderGameList()
+}
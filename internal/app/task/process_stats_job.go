@@ -36,3 +36,57 @@ func (r ProcessSyncThirdPartyDataJob) Run() {
 	defer gProcessSyncThirdPartyDataLock.Unlock()
 	r.Srv.SyncThirdPartyData()
 }
+
+type ProcessReconcileJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessReconcileLock sync.Mutex
+
+func (r ProcessReconcileJob) Run() {
+	gProcessReconcileLock.Lock()
+	defer gProcessReconcileLock.Unlock()
+	r.Srv.ReconcileProviderTransfers()
+}
+
+type ProcessVipLevelJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessVipLevelLock sync.Mutex
+
+func (r ProcessVipLevelJob) Run() {
+	gProcessVipLevelLock.Lock()
+	defer gProcessVipLevelLock.Unlock()
+	if err := r.Srv.RecalculateVipLevels(); err != nil {
+		logger.ZError("ProcessVipLevelJob", zap.Error(err))
+	}
+}
+
+type ProcessAgentStatsJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessAgentStatsLock sync.Mutex
+
+func (r ProcessAgentStatsJob) Run() {
+	gProcessAgentStatsLock.Lock()
+	defer gProcessAgentStatsLock.Unlock()
+	if err := r.Srv.RefreshAgentDailyStats(); err != nil {
+		logger.ZError("ProcessAgentStatsJob", zap.Error(err))
+	}
+}
+
+type ProcessCommissionJob struct {
+	Srv async.IAsyncService
+}
+
+var gProcessCommissionLock sync.Mutex
+
+func (r ProcessCommissionJob) Run() {
+	gProcessCommissionLock.Lock()
+	defer gProcessCommissionLock.Unlock()
+	if err := r.Srv.SettleDueCommission(); err != nil {
+		logger.ZError("ProcessCommissionJob", zap.Error(err))
+	}
+}
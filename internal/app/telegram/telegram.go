@@ -49,6 +49,10 @@ func RegisterHandle() {
 
 // SendToBot 主动发送消息机器人消息
 func SendToManage(msg string) {
+	if bot == nil { //机器人没有启动
+		zap.L().Info("telebot not started", zap.String("msg", msg))
+		return
+	}
 	go func() {
 		user := tb.User{
 			ID: config.Get().TelegramSetting.ManagerID,
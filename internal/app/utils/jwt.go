@@ -25,6 +25,19 @@ func GenerateJWT(secretKey string, expireTime time.Duration, userID string) (str
 	return tokenString, nil
 }
 
+// GenerateJWTWithClaims 在userID之外附带自定义的 claims (会话ID/令牌类型等)
+func GenerateJWTWithClaims(secretKey string, expireTime time.Duration, userID string, extra map[string]interface{}) (string, error) {
+	claims := jwt.MapClaims{
+		"userID": userID,
+		"exp":    time.Now().Add(expireTime).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secretKey))
+}
+
 // ParseJWT 解析 JWT 并返回 Claims
 func ParseJWT(tokenString, secretKey string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
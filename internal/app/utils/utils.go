@@ -166,3 +166,12 @@ func HmacSHA256(key, message string) string {
 	signature := hex.EncodeToString(h.Sum(nil))
 	return signature
 }
+
+// 按字符截断 不会截断半个中文
+func TruncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
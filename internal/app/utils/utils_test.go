@@ -0,0 +1,24 @@
+package utils
+
+import "testing"
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{name: "short", s: "abc", n: 5, want: "abc"},
+		{name: "ascii", s: "abcdef", n: 3, want: "abc"},
+		{name: "chinese", s: "新注册账号;连赢异常", n: 5, want: "新注册账号"},
+		{name: "mixed", s: "IP/设备共用", n: 4, want: "IP/设"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TruncateRunes(tt.s, tt.n); got != tt.want {
+				t.Errorf("TruncateRunes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -40,11 +40,30 @@ func BuildInjector() (*Injector, func(), error) {
 	}
 	stateService := service.ProvideStateService()
 	adminService := service.ProvideAdminService(adminRepository, stateService)
+	adminAuthRepository := &repository.AdminAuthRepository{
+		DB:  db,
+		RDS: client,
+	}
+	adminAuthService := service.ProvideAdminAuthService(adminAuthRepository, adminService)
+	adminApprovalRepository := &repository.AdminApprovalRepository{
+		DB:  db,
+		RDS: client,
+	}
+	adminApprovalService := service.ProvideAdminApprovalService(adminApprovalRepository, adminAuthService)
 	walletRepository := &repository.WalletRepository{
 		DB:  db,
 		RDS: client,
 	}
-	walletService := service.ProvideWalletService(walletRepository)
+	sessionRepository := &repository.SessionRepository{
+		DB:  db,
+		RDS: client,
+	}
+	gamingRepository := &repository.GamingRepository{
+		DB:  db,
+		RDS: client,
+	}
+	gamingService := service.ProvideGamingService(gamingRepository, sessionRepository)
+	walletService := service.ProvideWalletService(walletRepository, gamingService)
 	verifyService := service.ProvideVerifyService(userRepository, adminService, stateService)
 	financialRepository := &repository.FinancialRepository{
 		DB:  db,
@@ -52,10 +71,21 @@ func BuildInjector() (*Injector, func(), error) {
 	}
 	financialService := service.ProvideFinancialService(financialRepository)
 	minioClient := provideMinio()
-	userService := service.ProvideUserService(userRepository, adminService, stateService, walletService, verifyService, financialService, minioClient)
-	activityService := service.ProvideActivityService(activityRepository, userService, walletService)
+	vipRepository := &repository.VipRepository{
+		DB:  db,
+		RDS: client,
+	}
+	vipService := service.ProvideVipService(vipRepository, userRepository, walletService)
+	userService := service.ProvideUserService(userRepository, adminService, stateService, walletService, verifyService, financialService, minioClient, vipService, sessionRepository)
+	chatRepository := &repository.ChatRepository{
+		DB:  db,
+		RDS: client,
+	}
+	chatService := service.ProvideChatService(chatRepository, userService)
+	activityService := service.ProvideActivityService(activityRepository, userService, walletService, chatService)
 	activityAPI := &api.ActivityAPI{
-		Srv: activityService,
+		Srv:         activityService,
+		ApprovalSrv: adminApprovalService,
 	}
 	agentRepository := &repository.AgentRepository{
 		DB:  db,
@@ -63,12 +93,28 @@ func BuildInjector() (*Injector, func(), error) {
 	}
 	agentService := service.ProvideAgentService(agentRepository, userService, adminService, walletService)
 	agentAPI := &api.AgentAPI{
-		Srv: agentService,
+		Srv:         agentService,
+		ApprovalSrv: adminApprovalService,
+	}
+	agentStatsRepository := &repository.AgentStatsRepository{
+		DB:  db,
+		RDS: client,
 	}
+	agentStatsService := service.ProvideAgentStatsService(agentStatsRepository, agentService)
 	flowRepository := &repository.FlowRepository{
 		DB: db,
 	}
-	flowService := service.ProvideFlowService(flowRepository, userService, agentService, stateService)
+	cashbackRepository := &repository.CashbackRepository{
+		DB:  db,
+		RDS: client,
+	}
+	cashbackService := service.ProvideCashbackService(cashbackRepository, userRepository, vipService, walletService)
+	flowService := service.ProvideFlowService(flowRepository, userService, agentService, stateService, walletService, cashbackService)
+	commissionRepository := &repository.CommissionRepository{
+		DB:  db,
+		RDS: client,
+	}
+	commissionService := service.ProvideCommissionService(commissionRepository, userRepository, walletService)
 	flowAPI := &api.FlowAPI{
 		Srv: flowService,
 	}
@@ -79,8 +125,9 @@ func BuildInjector() (*Injector, func(), error) {
 	nineService := service.ProvideNineService(nineRepository, userService, walletService)
 	iNine := provideNine(nineService)
 	nineAPI := &api.NineAPI{
-		Srv:  nineService,
-		Nine: iNine,
+		Srv:         nineService,
+		Nine:        iNine,
+		ApprovalSrv: adminApprovalService,
 	}
 	rechargeRepository := &repository.RechargeRepository{
 		DB:  db,
@@ -91,7 +138,8 @@ func BuildInjector() (*Injector, func(), error) {
 		Srv: rechargeService,
 	}
 	userAPI := &api.UserAPI{
-		Srv: userService,
+		Srv:         userService,
+		ApprovalSrv: adminApprovalService,
 	}
 	quizRepository := &repository.QuizRepository{
 		DB: db,
@@ -107,34 +155,48 @@ func BuildInjector() (*Injector, func(), error) {
 	wingoService := service.ProvideWingoService(wingoRepository, userService, adminService, stateService, walletService)
 	iWingo := provideWingo(wingoService)
 	wingoAPI := &api.WingoAPI{
-		Srv:   wingoService,
-		Wingo: iWingo,
+		Srv:         wingoService,
+		Wingo:       iWingo,
+		ApprovalSrv: adminApprovalService,
 	}
 	withdrawRepository := &repository.WithdrawRepository{
 		DB:  db,
 		RDS: client,
 	}
-	withdrawService := service.ProvideWithdrawService(withdrawRepository, userService, flowService, walletService, verifyService)
+	riskRepository := &repository.RiskRepository{
+		DB:  db,
+		RDS: client,
+	}
+	riskService := service.ProvideRiskService(riskRepository)
+	withdrawService := service.ProvideWithdrawService(withdrawRepository, userService, flowService, walletService, verifyService, riskService, vipService)
 	withdrawAPI := &api.WithdrawAPI{
-		Srv: withdrawService,
+		Srv:         withdrawService,
+		RiskSrv:     riskService,
+		ApprovalSrv: adminApprovalService,
 	}
+	seamlessRepository := &repository.SeamlessRepository{
+		DB:  db,
+		RDS: client,
+	}
+	seamlessWalletService := service.ProvideSeamlessWalletService(seamlessRepository, walletService)
 	r8Repository := &repository.R8Repository{
 		DB: db,
 	}
-	r8Service := service.ProvideR8Service(r8Repository, userService, walletService)
+	r8Service := service.ProvideR8Service(r8Repository, userService, walletService, seamlessWalletService)
 	r8API := &api.R8API{
 		Srv: r8Service,
 	}
 	zfRepository := &repository.ZfRepository{
 		DB: db,
 	}
-	zfService := service.ProvideZfService(zfRepository, userService, walletService)
+	zfService := service.ProvideZfService(zfRepository, userService, walletService, seamlessWalletService)
 	zfAPI := &api.ZfAPI{
 		Srv: zfService,
 	}
 	adminAPI := &api.AdminAPI{
-		Srv:     adminService,
-		UserSrv: userService,
+		Srv:         adminService,
+		UserSrv:     userService,
+		ApprovalSrv: adminApprovalService,
 	}
 	gameRepository := &repository.GameRepository{
 		DB:  db,
@@ -143,8 +205,8 @@ func BuildInjector() (*Injector, func(), error) {
 	jhszRepository := &repository.JhszRepository{
 		DB: db,
 	}
-	authService := service.ProvideAuthService(userRepository, adminService, userService, verifyService, stateService)
-	jhszService := service.ProvideJhszService(jhszRepository, userService, walletService, authService)
+	authService := service.ProvideAuthService(userRepository, sessionRepository, adminService, userService, verifyService, stateService)
+	jhszService := service.ProvideJhszService(jhszRepository, userService, walletService, authService, seamlessWalletService)
 	gameService := service.ProvideGameService(gameRepository, userService, jhszService)
 	gameAPI := &api.GameAPI{
 		Srv: gameService,
@@ -185,11 +247,6 @@ func BuildInjector() (*Injector, func(), error) {
 	notificationAPI := &api.NotificationAPI{
 		Srv: notificationService,
 	}
-	chatRepository := &repository.ChatRepository{
-		DB:  db,
-		RDS: client,
-	}
-	chatService := service.ProvideChatService(chatRepository, userService)
 	chatAPI := &api.ChatAPI{
 		Srv: chatService,
 	}
@@ -219,8 +276,81 @@ func BuildInjector() (*Injector, func(), error) {
 		RDS: client,
 	}
 	statsService := service.ProvideStatsService(statsRepository, jhszService, agentService, stateService, gameService)
+	reconcileRepository := &repository.ReconcileRepository{
+		DB:  db,
+		RDS: client,
+	}
+	reconcileService := service.ProvideReconcileService(reconcileRepository)
 	statsAPI := &api.StatsAPI{
-		Srv: statsService,
+		Srv:          statsService,
+		ReconcileSrv: reconcileService,
+	}
+	historyRepository := &repository.HistoryRepository{
+		DB:  db,
+		RDS: client,
+	}
+	historyService := service.ProvideHistoryService(historyRepository, gameRepository)
+	historyAPI := &api.HistoryAPI{
+		Srv:     historyService,
+		GameSrv: gameService,
+	}
+	gamingAPI := &api.GamingAPI{
+		Srv: gamingService,
+	}
+	vipAPI := &api.VipAPI{
+		Srv: vipService,
+	}
+	cashbackAPI := &api.CashbackAPI{
+		Srv: cashbackService,
+	}
+	agentStatsAPI := &api.AgentStatsAPI{
+		Srv: agentStatsService,
+	}
+	commissionAPI := &api.CommissionAPI{
+		Srv: commissionService,
+	}
+	adminAuthAPI := &api.AdminAuthAPI{
+		Srv: adminAuthService,
+	}
+	adminApprovalAPI := &api.AdminApprovalAPI{
+		Srv:         adminApprovalService,
+		AdminSrv:    adminService,
+		UserSrv:     userService,
+		AgentSrv:    agentService,
+		ActivitySrv: activityService,
+		WithdrawSrv: withdrawService,
+		Wingo:       iWingo,
+		Nine:        iNine,
+	}
+	archiveRepository := &repository.ArchiveRepository{
+		DB:  db,
+		RDS: client,
+	}
+	archiveService := service.ProvideArchiveService(archiveRepository, stateService)
+	archiveAPI := &api.ArchiveAPI{
+		Srv: archiveService,
+	}
+	schedulerRepository := &repository.SchedulerRepository{
+		DB:  db,
+		RDS: client,
+	}
+	schedulerService := service.ProvideSchedulerService(schedulerRepository)
+	schedulerAPI := &api.SchedulerAPI{
+		Srv: schedulerService,
+	}
+	deadLetterRepository := &repository.DeadLetterRepository{
+		RDS: client,
+	}
+	deadLetterService := service.ProvideDeadLetterService(deadLetterRepository)
+	deadLetterAPI := &api.DeadLetterAPI{
+		Srv: deadLetterService,
+	}
+	eventRepository := &repository.EventRepository{
+		RDS: client,
+	}
+	eventBusService := service.ProvideEventBusService(eventRepository)
+	eventAPI := &api.EventAPI{
+		Srv: eventBusService,
 	}
 	hashGameRepository := &repository.HashGameRepository{
 		DB: db,
@@ -298,8 +428,26 @@ func BuildInjector() (*Injector, func(), error) {
 		MineGameAPI:     mineGameAPI,
 		DiceGameAPI:     diceGameAPI,
 		LimboGameAPI:    limboGameAPI,
+		HistoryAPI:      historyAPI,
+		GamingAPI:       gamingAPI,
+		VipAPI:          vipAPI,
+		CashbackAPI:     cashbackAPI,
+		AgentStatsAPI:   agentStatsAPI,
+		CommissionAPI:   commissionAPI,
+		AdminAuthAPI:    adminAuthAPI,
+		ApprovalAPI:     adminApprovalAPI,
+		ArchiveAPI:      archiveAPI,
+		SchedulerAPI:    schedulerAPI,
+		DeadLetterAPI:   deadLetterAPI,
+		EventAPI:        eventAPI,
 	}
 	engine := InitGinEngine(routerRouter)
+	outboxRepository := &repository.OutboxRepository{
+		DB:  db,
+		RDS: client,
+	}
+	outboxService := service.ProvideOutboxService(outboxRepository, eventBusService)
+	eventSubscriberService := service.ProvideEventSubscriberService(eventBusService, agentService, rechargeService, vipService)
 	asyncServiceManager := &service.AsyncServiceManager{
 		FlowSrv:         flowService,
 		UserSrv:         userService,
@@ -315,13 +463,24 @@ func BuildInjector() (*Injector, func(), error) {
 		ChainSrv:        chainService,
 		NotificationSrv: notificationService,
 		GameSrv:         gameService,
+		ReconcileSrv:    reconcileService,
+		VipSrv:          vipService,
+		AgentStatsSrv:   agentStatsService,
+		CommissionSrv:   commissionService,
+		ArchiveSrv:      archiveService,
+		OutboxSrv:       outboxService,
+		DeadLetterSrv:   deadLetterService,
+		WithdrawSrv:     withdrawService,
 	}
 	iAsyncService := provideService(asyncServiceManager)
 	injector := &Injector{
-		Engine:  engine,
-		Service: iAsyncService,
-		Nine:    iNine,
-		Wingo:   iWingo,
+		Engine:    engine,
+		Service:   iAsyncService,
+		Nine:      iNine,
+		Wingo:     iWingo,
+		Scheduler: schedulerService,
+		Outbox:    outboxService,
+		Events:    eventSubscriberService,
 	}
 	return injector, func() {
 	}, nil
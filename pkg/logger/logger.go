@@ -155,6 +155,13 @@ func GetLogger() *zap.Logger {
 	return zapLogger
 }
 
+// 替换全局logger 单元测试中使用 不创建日志文件
+func ReplaceLogger(l *zap.Logger) {
+	once.Do(func() {})
+	zapLogger = l
+	zap.ReplaceGlobals(l)
+}
+
 // 自定义的时间格式器
 func customTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	enc.AppendString(t.Format("2006-01-02 15:04:05")) // 使用Go的时间格式化布局
@@ -0,0 +1,332 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 内存倒排索引 支持前缀/拼写容错匹配和分面统计
+// 适合游戏目录这类数据量小、变动少的场景 索引建好后只读 变动时整体重建后替换
+
+const (
+	qualityExact  = 1.0
+	qualityPrefix = 0.8
+	qualityFuzzy  = 0.6
+)
+
+type Field struct {
+	Text  string
+	Boost float64 // 字段权重 为0时按1计算
+}
+
+type Document struct {
+	ID     uint
+	Fields []Field             // 参与检索的文本字段
+	Facets map[string][]string // 分面/过滤字段 一个字段可以有多个值
+}
+
+type Query struct {
+	Text    string
+	Filters map[string][]string // 同一字段内任一值匹配即可 不同字段之间需要全部匹配
+	Facets  []string            // 需要统计的分面字段
+	Offset  int
+	Limit   int // 为0时返回全部
+}
+
+type Hit struct {
+	ID    uint
+	Score float64
+}
+
+type FacetTerm struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type Result struct {
+	Total  int
+	Hits   []Hit
+	Facets map[string][]FacetTerm
+}
+
+type posting struct {
+	doc    int
+	weight float64
+}
+
+type Index struct {
+	docs     []*Document
+	postings map[string][]posting
+	terms    []string // 有序 用于前缀查找
+}
+
+// 相关度相同时按文档加入的顺序排序 调用方应先按默认排序整理好文档
+func New(docs []*Document) *Index {
+	idx := &Index{
+		docs:     docs,
+		postings: make(map[string][]posting),
+	}
+	for i, doc := range docs {
+		weights := make(map[string]float64)
+		for _, field := range doc.Fields {
+			boost := field.Boost
+			if boost == 0 {
+				boost = 1
+			}
+			tokens := Tokenize(field.Text)
+			// 多个词的字段额外索引连写形式 如 sugar rush -> sugarrush
+			if len(tokens) > 1 {
+				tokens = append(tokens, strings.Join(tokens, ""))
+			}
+			for _, token := range tokens {
+				if boost > weights[token] {
+					weights[token] = boost
+				}
+			}
+		}
+		for token, weight := range weights {
+			idx.postings[token] = append(idx.postings[token], posting{doc: i, weight: weight})
+		}
+	}
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+	return idx
+}
+
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+// 小写后按字母/数字切词 汉字等表意文字逐字切分
+func Tokenize(text string) []string {
+	tokens := make([]string, 0)
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// 查询词之间为且的关系 每个词取精确/前缀/容错匹配中得分最高的
+func (idx *Index) Search(q Query) *Result {
+	var scores map[int]float64
+	tokens := Tokenize(q.Text)
+	if len(tokens) > 0 {
+		for _, token := range tokens {
+			matched := idx.match(token)
+			if scores == nil {
+				scores = matched
+			} else {
+				for doc, score := range scores {
+					if s, ok := matched[doc]; ok {
+						scores[doc] = score + s
+					} else {
+						delete(scores, doc)
+					}
+				}
+			}
+			if len(scores) == 0 {
+				break
+			}
+		}
+	} else {
+		scores = make(map[int]float64, len(idx.docs))
+		for i := range idx.docs {
+			scores[i] = 0
+		}
+	}
+
+	result := &Result{
+		Hits:   make([]Hit, 0),
+		Facets: make(map[string][]FacetTerm, len(q.Facets)),
+	}
+
+	// 分面统计时忽略自身字段的过滤条件 这样选中某个分类后仍能看到其他分类的数量
+	for _, facet := range q.Facets {
+		counts := make(map[string]int)
+		for doc := range scores {
+			if !idx.matchFilters(idx.docs[doc], q.Filters, facet) {
+				continue
+			}
+			for _, value := range idx.docs[doc].Facets[facet] {
+				counts[value]++
+			}
+		}
+		terms := make([]FacetTerm, 0, len(counts))
+		for value, count := range counts {
+			terms = append(terms, FacetTerm{Value: value, Count: count})
+		}
+		sort.Slice(terms, func(i, j int) bool {
+			if terms[i].Count != terms[j].Count {
+				return terms[i].Count > terms[j].Count
+			}
+			return terms[i].Value < terms[j].Value
+		})
+		result.Facets[facet] = terms
+	}
+
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		if idx.matchFilters(idx.docs[doc], q.Filters, "") {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if scores[docs[i]] != scores[docs[j]] {
+			return scores[docs[i]] > scores[docs[j]]
+		}
+		return docs[i] < docs[j]
+	})
+
+	result.Total = len(docs)
+	if q.Offset > 0 {
+		if q.Offset >= len(docs) {
+			docs = docs[:0]
+		} else {
+			docs = docs[q.Offset:]
+		}
+	}
+	if q.Limit > 0 && len(docs) > q.Limit {
+		docs = docs[:q.Limit]
+	}
+	for _, doc := range docs {
+		result.Hits = append(result.Hits, Hit{ID: idx.docs[doc].ID, Score: scores[doc]})
+	}
+	return result
+}
+
+func (idx *Index) matchFilters(doc *Document, filters map[string][]string, skip string) bool {
+	for field, values := range filters {
+		if field == skip || len(values) == 0 {
+			continue
+		}
+		found := false
+		for _, value := range doc.Facets[field] {
+			for _, want := range values {
+				if value == want {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// 单个查询词命中的文档及得分
+func (idx *Index) match(token string) map[int]float64 {
+	result := make(map[int]float64)
+	add := func(term string, quality float64) {
+		for _, p := range idx.postings[term] {
+			if score := p.weight * quality; score > result[p.doc] {
+				result[p.doc] = score
+			}
+		}
+	}
+
+	add(token, qualityExact)
+
+	// 前缀匹配 词越完整得分越高
+	tokenLen := len([]rune(token))
+	for i := sort.SearchStrings(idx.terms, token); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], token); i++ {
+		term := idx.terms[i]
+		if term == token {
+			continue
+		}
+		add(term, qualityPrefix*(0.5+0.5*float64(tokenLen)/float64(len([]rune(term)))))
+	}
+
+	// 拼写容错 短词不做容错 避免误命中
+	maxDist := maxEditDistance(tokenLen)
+	if maxDist == 0 {
+		return result
+	}
+	source := []rune(token)
+	for _, term := range idx.terms {
+		target := []rune(term)
+		if abs(len(target)-len(source)) > maxDist {
+			continue
+		}
+		dist := editDistance(source, target, maxDist)
+		if dist == 0 || dist > maxDist {
+			continue
+		}
+		add(term, qualityFuzzy/float64(dist))
+	}
+	return result
+}
+
+func maxEditDistance(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// 编辑距离 相邻字符交换算一次 超过limit时提前返回limit+1
+func editDistance(a, b []rune, limit int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
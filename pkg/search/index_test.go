@@ -0,0 +1,73 @@
+package search_test
+
+import (
+	"rk-api/pkg/search"
+	"testing"
+)
+
+func newGameIndex() *search.Index {
+	return search.New([]*search.Document{
+		{ID: 1, Fields: []search.Field{{Text: "Aviator", Boost: 3}, {Text: "飞行员", Boost: 3}, {Text: "crash"}},
+			Facets: map[string][]string{"category": {"crash"}, "provider": {"jhsz"}}},
+		{ID: 2, Fields: []search.Field{{Text: "Sugar Rush", Boost: 3}, {Text: "slots"}},
+			Facets: map[string][]string{"category": {"slots"}, "provider": {"r8"}}},
+		{ID: 3, Fields: []search.Field{{Text: "Crash X", Boost: 3}, {Text: "crash"}},
+			Facets: map[string][]string{"category": {"crash"}, "provider": {"zf"}}},
+	})
+}
+
+func hitIDs(result *search.Result) []uint {
+	ids := make([]uint, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func TestSearchIndex(t *testing.T) {
+	idx := newGameIndex()
+
+	cases := []struct {
+		text string
+		want []uint
+	}{
+		{"aviator", []uint{1}},
+		{"avia", []uint{1}},       // 前缀
+		{"aviatr", []uint{1}},     // 漏字
+		{"suagr rush", []uint{2}}, // 字母交换
+		{"sugarrush", []uint{2}},  // 连写
+		{"飞行", []uint{1}},         // 汉字
+		{"crash", []uint{3, 1}},   // 名称命中的排在前面
+	}
+	for _, c := range cases {
+		got := hitIDs(idx.Search(search.Query{Text: c.text}))
+		if len(got) != len(c.want) {
+			t.Fatalf("search %q: got %v want %v", c.text, got, c.want)
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Fatalf("search %q: got %v want %v", c.text, got, c.want)
+			}
+		}
+	}
+}
+
+func TestSearchIndexFacets(t *testing.T) {
+	idx := newGameIndex()
+
+	result := idx.Search(search.Query{
+		Filters: map[string][]string{"category": {"crash"}},
+		Facets:  []string{"category", "provider"},
+		Limit:   1,
+	})
+	if result.Total != 2 || len(result.Hits) != 1 {
+		t.Fatalf("total %d hits %d", result.Total, len(result.Hits))
+	}
+	// 分类分面忽略自身过滤 三方分面只统计过滤后的结果
+	if len(result.Facets["category"]) != 2 || result.Facets["category"][0].Value != "crash" || result.Facets["category"][0].Count != 2 {
+		t.Fatalf("category facets %+v", result.Facets["category"])
+	}
+	if len(result.Facets["provider"]) != 2 {
+		t.Fatalf("provider facets %+v", result.Facets["provider"])
+	}
+}
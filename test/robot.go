@@ -10,7 +10,6 @@ import (
 	"rk-api/internal/app/entities"
 	"rk-api/internal/app/game"
 	"rk-api/internal/app/ginx"
-	"rk-api/internal/app/middleware"
 	"rk-api/pkg/http"
 	"rk-api/pkg/logger"
 	"rk-api/pkg/structure"
@@ -391,9 +390,9 @@ func (r *ApiRobot) recharge(amount float64) error {
 		BalanceAdd: amount,
 		UID:        r.uid,
 	}
-	timezone := "Asia/Shanghai"
-	token := middleware.GenerateMD5Token(fmt.Sprintf("%d", 1), timezone, "julier@landing2023")
-	_, err := r.request(fmt.Sprintf("/user/admin/edit-user?token=%s&uid=%d&timezone=%s", token, 1, timezone), data)
+	// 后台接口需要后台登录后的token 通过环境变量传入
+	token := os.Getenv("ROBOT_ADMIN_TOKEN")
+	_, err := r.request(fmt.Sprintf("/user/admin/edit-user?token=%s", token), data)
 	if err != nil {
 		return err
 	}